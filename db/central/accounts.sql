@@ -31,6 +31,7 @@ CREATE TABLE personalAccounts(
 	activatedOn DATETIME NULL,
 	newEmailConfirmationCode VARCHAR(100) NULL,
 	resetPwdCode VARCHAR(100) NULL,
+	notificationDelivery TINYINT UNSIGNED NOT NULL DEFAULT 0, #0 immediate, 1 dailyDigest, 2 none
     PRIMARY KEY (id),
     UNIQUE INDEX (email)
 );
@@ -43,6 +44,21 @@ CREATE TABLE memberships(
     UNIQUE INDEX (member, account)
 );
 
+DROP TABLE IF EXISTS emailOutbox;
+CREATE TABLE emailOutbox(
+	id BINARY(16) NOT NULL,
+	sendTo VARCHAR(2500) NOT NULL,
+	content TEXT NOT NULL,
+	createdOn DATETIME NOT NULL,
+	attemptCount SMALLINT UNSIGNED NOT NULL DEFAULT 0,
+	nextAttemptOn DATETIME(6) NOT NULL,
+	lastError VARCHAR(1250) NULL,
+	isDeadLettered BOOL NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (id),
+    INDEX (isDeadLettered, nextAttemptOn, id),
+    INDEX (isDeadLettered, createdOn, id)
+);
+
 DROP PROCEDURE IF EXISTS createPersonalAccount;
 CREATE PROCEDURE createPersonalAccount(_id BINARY(16), _name VARCHAR(50), _displayName VARCHAR(100), _createdOn DATETIME, _region CHAR(3), _newRegion CHAR(3), _shard MEDIUMINT, _hasAvatar BOOL, _email VARCHAR(250), _language VARCHAR(50), _theme TINYINT UNSIGNED, _newEmail VARCHAR(250), _activationCode VARCHAR(100), _activatedOn DATETIME, _newEmailConfirmationCode VARCHAR(100), _resetPwdCode VARCHAR(100)) 
 BEGIN
@@ -23,6 +23,10 @@ CREATE TABLE accountMembers(
   hasAvatar BOOL NOT NULL DEFAULT FALSE,
   isActive BOOL NOT NULL DEFAULT TRUE,
   role TINYINT UNSIGNED NOT NULL DEFAULT 2, #0 owner, 1 admin, 2 memberOfAllProjects, 3 memberOfOnlySpecificProjects
+  email VARCHAR(250) NULL, #copied from central so notifications can be emailed from the region
+  notificationDelivery TINYINT UNSIGNED NOT NULL DEFAULT 0, #0 immediate, 1 dailyDigest, 2 none
+  nextNotificationOn DATETIME(6) NULL, #when pending notifications can next be emailed, NULL for as soon as possible
+  hourlyRate BIGINT UNSIGNED NULL, #in the smallest currency unit, the default for every project the member is in
   PRIMARY KEY (account, isActive, role, name),
   UNIQUE INDEX (account, isActive, role, displayName, name),
   UNIQUE INDEX (account, isActive, name, role),
@@ -57,6 +61,7 @@ CREATE TABLE projectMembers(
   totalRemainingTime BIGINT UNSIGNED NOT NULL,
   totalLoggedTime BIGINT UNSIGNED NOT NULL,
   role TINYINT UNSIGNED NOT NULL, #0 admin, 1 writer, 2 reader
+  hourlyRate BIGINT UNSIGNED NULL, #in the smallest currency unit, null to use the members account hourlyRate
   PRIMARY KEY (account, project, isActive, role, name),
   UNIQUE INDEX (account, project, isActive, role, displayName, name),
   UNIQUE INDEX (account, project, isActive, name, role),
@@ -104,6 +109,9 @@ CREATE TABLE projects(
   fileCount BIGINT UNSIGNED NOT NULL,
   fileSize BIGINT UNSIGNED NOT NULL,
   isPublic BOOL NOT NULL DEFAULT FALSE,
+  isTemplate BOOL NOT NULL DEFAULT FALSE,
+  timeLogMaxAgeDays SMALLINT UNSIGNED NULL, #how many days back members can log time, null for no limit, project admins are never limited
+  budget BIGINT UNSIGNED NULL, #in the smallest currency unit, the project is over budget when its totalCost is greater
   PRIMARY KEY (account, id),
   INDEX(account, isArchived, name, createdOn, id),
   INDEX(account, isArchived, createdOn, name, id),
@@ -126,18 +134,34 @@ CREATE TABLE tasks(
   createdOn DATETIME NOT NULL,
   totalRemainingTime BIGINT UNSIGNED NOT NULL,
   totalLoggedTime BIGINT UNSIGNED NOT NULL,
+  totalCost BIGINT UNSIGNED NOT NULL DEFAULT 0, #in the smallest currency unit, the cost of all the time logged on the task and its descendants
+  totalRevenue BIGINT UNSIGNED NOT NULL DEFAULT 0, #the part of totalCost that is billable
   minimumRemainingTime BIGINT UNSIGNED NOT NULL,
   linkedFileCount INT UNSIGNED NOT NULL,
   chatCount BIGINT UNSIGNED NOT NULL,
   childCount BIGINT UNSIGNED NOT NULL,
   descendantCount BIGINT UNSIGNED NOT NULL,
   isParallel BOOL NOT NULL DEFAULT FALSE,
-  member BINARY(16) NULL,
+  state BINARY(16) NULL, #only concrete tasks have a state
   PRIMARY KEY (account, project, id),
-  UNIQUE INDEX(account, member, id),
   UNIQUE INDEX(account, project, parent, id),
   UNIQUE INDEX(account, project, nextSibling, id),
-  UNIQUE INDEX(account, project, member, id)
+  UNIQUE INDEX(account, project, state, id),
+  FULLTEXT INDEX(name, description)
+);
+
+#the members assigned to a concrete task and each ones share of its totalRemainingTime, any of the tasks totalRemainingTime
+#not covered by its members shares is unassigned, a project members totalRemainingTime is the sum of their shares
+DROP TABLE IF EXISTS taskMembers;
+CREATE TABLE taskMembers(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  task BINARY(16) NOT NULL,
+  member BINARY(16) NOT NULL,
+  remainingTime BIGINT UNSIGNED NOT NULL,
+  PRIMARY KEY(account, project, task, member),
+  UNIQUE INDEX(account, project, member, task),
+  UNIQUE INDEX(account, member, project, task)
 );
 
 DROP TABLE IF EXISTS timeLogs;
@@ -152,10 +176,328 @@ CREATE TABLE timeLogs(
   taskName VARCHAR(250) NOT NULL,
   duration BIGINT UNSIGNED NOT NULL,
   note VARCHAR(250) NULL,
+  isBillable BOOL NOT NULL DEFAULT TRUE,
+  hourlyRate BIGINT UNSIGNED NOT NULL DEFAULT 0, #the members rate when the time was logged, rate changes don't alter existing logs
+  cost BIGINT UNSIGNED NOT NULL DEFAULT 0, #ROUND(duration * hourlyRate / 60)
   PRIMARY KEY(account, project, task, loggedOn, member),
   UNIQUE INDEX(account, project, id),
   UNIQUE INDEX(account, project, member, loggedOn, task),
-  UNIQUE INDEX(account, project, loggedOn, member, task)
+  UNIQUE INDEX(account, project, loggedOn, member, task),
+  INDEX(account, loggedOn, member)
+);
+
+#a members running or paused timer, a member can only have one per account, stopping it creates a timeLog for its task
+DROP TABLE IF EXISTS timers;
+CREATE TABLE timers(
+	account BINARY(16) NOT NULL,
+  member BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  task BINARY(16) NOT NULL,
+  startedOn DATETIME(6) NOT NULL,
+  runningSince DATETIME(6) NULL, #null while paused
+  elapsed BIGINT UNSIGNED NOT NULL, #seconds run before runningSince
+  note VARCHAR(250) NULL,
+  PRIMARY KEY(account, member),
+  INDEX(account, project, task)
+);
+
+#every change to a concrete tasks totalRemainingTime, used to build burndown/burnup charts
+DROP TABLE IF EXISTS remainingTimeChanges;
+CREATE TABLE remainingTimeChanges(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  task BINARY(16) NOT NULL,
+  occurredOn DATETIME(6) NOT NULL,
+  member BINARY(16) NULL,
+  oldValue BIGINT UNSIGNED NOT NULL,
+  newValue BIGINT UNSIGNED NOT NULL,
+  PRIMARY KEY(account, project, task, occurredOn),
+  INDEX(account, project, occurredOn)
+);
+
+DROP TABLE IF EXISTS comments;
+CREATE TABLE comments(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  task BINARY(16) NOT NULL,
+  id BINARY(16) NOT NULL,
+  member BINARY(16) NOT NULL,
+  createdOn DATETIME(6) NOT NULL,
+  editedOn DATETIME(6) NULL,
+  body VARCHAR(5000) NOT NULL,
+  PRIMARY KEY(account, project, task, createdOn, id),
+  UNIQUE INDEX(account, project, id)
+);
+
+DROP TABLE IF EXISTS commentMentions;
+CREATE TABLE commentMentions(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  comment BINARY(16) NOT NULL,
+  member BINARY(16) NOT NULL,
+  PRIMARY KEY(account, project, comment, member),
+  INDEX(account, project, member)
+);
+
+DROP TABLE IF EXISTS taskDependencies;
+CREATE TABLE taskDependencies(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  task BINARY(16) NOT NULL, #the task that can not start until dependsOn has finished
+  dependsOn BINARY(16) NOT NULL,
+  commonAncestor BINARY(16) NOT NULL, #the lowest task with both ends beneath it, the only task whose minimumRemainingTime the dependency changes directly
+  PRIMARY KEY(account, project, task, dependsOn),
+  UNIQUE INDEX(account, project, dependsOn, task),
+  INDEX(account, project, commonAncestor)
+);
+
+DROP TABLE IF EXISTS files;
+CREATE TABLE files(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  task BINARY(16) NOT NULL,
+  id BINARY(16) NOT NULL,
+  member BINARY(16) NOT NULL,
+  createdOn DATETIME(6) NOT NULL,
+  name VARCHAR(250) NOT NULL,
+  mimeType VARCHAR(250) NOT NULL,
+  size BIGINT UNSIGNED NOT NULL,
+  PRIMARY KEY(account, project, task, createdOn, id),
+  UNIQUE INDEX(account, project, id)
+);
+
+DROP TABLE IF EXISTS labels;
+CREATE TABLE labels(
+	account BINARY(16) NOT NULL,
+  id BINARY(16) NOT NULL,
+  name VARCHAR(50) NOT NULL,
+  colour CHAR(7) NOT NULL, ##rrggbb
+  PRIMARY KEY(account, id),
+  UNIQUE INDEX(account, name)
+);
+
+DROP TABLE IF EXISTS taskLabels;
+CREATE TABLE taskLabels(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  task BINARY(16) NOT NULL,
+  label BINARY(16) NOT NULL,
+  PRIMARY KEY(account, project, task, label),
+  UNIQUE INDEX(account, label, project, task)
+);
+
+#ordered workflow states of a project, exactly one of them is the done state
+DROP TABLE IF EXISTS projectStates;
+CREATE TABLE projectStates(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  id BINARY(16) NOT NULL,
+  position INT UNSIGNED NOT NULL,
+  name VARCHAR(50) NOT NULL,
+  isDone BOOL NOT NULL,
+  PRIMARY KEY(account, project, id),
+  UNIQUE INDEX(account, project, name),
+  INDEX(account, project, position)
+);
+
+#typed extra fields project admins define for every task in the project
+DROP TABLE IF EXISTS customFields;
+CREATE TABLE customFields(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  id BINARY(16) NOT NULL,
+  createdOn DATETIME(6) NOT NULL,
+  name VARCHAR(50) NOT NULL,
+  type VARCHAR(10) NOT NULL,
+  options VARCHAR(1300) NULL, #newline separated, only select fields
+  PRIMARY KEY(account, project, id),
+  UNIQUE INDEX(account, project, name),
+  INDEX(account, project, createdOn)
+);
+
+#a tasks value for a custom field, only the column matching the fields type is set, text is used by text and select fields
+DROP TABLE IF EXISTS taskCustomFieldValues;
+CREATE TABLE taskCustomFieldValues(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  task BINARY(16) NOT NULL,
+  customField BINARY(16) NOT NULL,
+  textValue VARCHAR(1250) NULL,
+  numberValue DOUBLE NULL,
+  dateValue DATETIME NULL,
+  PRIMARY KEY(account, project, task, customField),
+  INDEX(account, project, customField, task)
+);
+
+#the number of concrete descendants an abstract task has in each state, maintained in _setAncestralChainStateCounts
+DROP TABLE IF EXISTS taskStateCounts;
+CREATE TABLE taskStateCounts(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  task BINARY(16) NOT NULL,
+  state BINARY(16) NOT NULL,
+  count BIGINT NOT NULL,
+  PRIMARY KEY(account, project, task, state),
+  INDEX(account, project, state)
+);
+
+#rows staged by the task import endpoint, importTasks moves a whole batch in to tasks under the project lock and deletes it
+DROP TABLE IF EXISTS taskImportRows;
+CREATE TABLE taskImportRows(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  batch BINARY(16) NOT NULL,
+  idx INT UNSIGNED NOT NULL, #1 based depth first order, every row comes after its parent
+  id BINARY(16) NOT NULL,
+  parent BINARY(16) NULL, #NULL for rows directly under the import parent
+  firstChild BINARY(16) NULL,
+  nextSibling BINARY(16) NULL,
+  isAbstract BOOL NOT NULL,
+	name VARCHAR(250) NOT NULL,
+	description VARCHAR(1250) NULL,
+  totalRemainingTime BIGINT UNSIGNED NOT NULL,
+  member BINARY(16) NULL,
+  PRIMARY KEY(account, project, batch, idx)
+);
+
+#the roots of deleted task subtrees, a subtree can be restored from trashedTasks until it is purged after the retention period
+DROP TABLE IF EXISTS trash;
+CREATE TABLE trash(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  id BINARY(16) NOT NULL, #the deleted subtrees root task
+  deletedOn DATETIME(6) NOT NULL,
+  member BINARY(16) NOT NULL, #who deleted it
+  parent BINARY(16) NOT NULL, #where restoring puts it back by default
+  previousSibling BINARY(16) NULL,
+  isAbstract BOOL NOT NULL,
+	name VARCHAR(250) NOT NULL,
+  totalRemainingTime BIGINT UNSIGNED NOT NULL,
+  totalLoggedTime BIGINT UNSIGNED NOT NULL,
+  descendantCount BIGINT UNSIGNED NOT NULL,
+  PRIMARY KEY(account, project, id),
+  UNIQUE INDEX(account, project, deletedOn, id),
+  INDEX(deletedOn) #for the trash purger to find expired items across projects
+);
+
+#every task in a deleted subtree as it was when it was deleted, comments, files, labels and state counts stay in their own tables until the subtree is purged
+DROP TABLE IF EXISTS trashedTasks;
+CREATE TABLE trashedTasks(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  trash BINARY(16) NOT NULL,
+  id BINARY(16) NOT NULL,
+  parent BINARY(16) NULL,
+  firstChild BINARY(16) NULL,
+  nextSibling BINARY(16) NULL,
+  isAbstract BOOL NOT NULL,
+	name VARCHAR(250) NOT NULL,
+	description VARCHAR(1250) NULL,
+  createdOn DATETIME NOT NULL,
+  totalRemainingTime BIGINT UNSIGNED NOT NULL,
+  totalLoggedTime BIGINT UNSIGNED NOT NULL,
+  totalCost BIGINT UNSIGNED NOT NULL DEFAULT 0, #in the smallest currency unit, the cost of all the time logged on the task and its descendants
+  totalRevenue BIGINT UNSIGNED NOT NULL DEFAULT 0, #the part of totalCost that is billable
+  minimumRemainingTime BIGINT UNSIGNED NOT NULL,
+  linkedFileCount INT UNSIGNED NOT NULL,
+  chatCount BIGINT UNSIGNED NOT NULL,
+  childCount BIGINT UNSIGNED NOT NULL,
+  descendantCount BIGINT UNSIGNED NOT NULL,
+  isParallel BOOL NOT NULL,
+  state BINARY(16) NULL,
+  PRIMARY KEY(account, project, id),
+  UNIQUE INDEX(account, project, trash, id),
+  UNIQUE INDEX(account, project, state, id)
+);
+
+#the taskMembers rows of every task in a deleted subtree
+DROP TABLE IF EXISTS trashedTaskMembers;
+CREATE TABLE trashedTaskMembers(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  trash BINARY(16) NOT NULL,
+  task BINARY(16) NOT NULL,
+  member BINARY(16) NOT NULL,
+  remainingTime BIGINT UNSIGNED NOT NULL,
+  PRIMARY KEY(account, project, task, member),
+  UNIQUE INDEX(account, project, trash, task, member)
+);
+
+#members watching a task, or with includeDescendants a whole subtree, are notified of project activities under it
+DROP TABLE IF EXISTS taskWatchers;
+CREATE TABLE taskWatchers(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  task BINARY(16) NOT NULL,
+  member BINARY(16) NOT NULL,
+  includeDescendants BOOL NOT NULL,
+  watchedOn DATETIME(6) NOT NULL,
+  PRIMARY KEY(account, project, task, member),
+  UNIQUE INDEX(account, member, project, task)
+);
+
+#the last project activity notifications have been generated for, only projects with watchers have a cursor
+DROP TABLE IF EXISTS notificationCursors;
+CREATE TABLE notificationCursors(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  lastOccurredOn DATETIME(6) NOT NULL,
+  PRIMARY KEY(account, project)
+);
+
+DROP TABLE IF EXISTS notifications;
+CREATE TABLE notifications(
+	account BINARY(16) NOT NULL,
+  member BINARY(16) NOT NULL, #who is notified
+	project BINARY(16) NOT NULL,
+  occurredOn DATETIME(6) NOT NULL,
+  actor BINARY(16) NOT NULL, #who made the change
+  item BINARY(16) NOT NULL,
+  itemType VARCHAR(100) NOT NULL,
+  action VARCHAR(100) NOT NULL,
+  itemName VARCHAR(250) NULL,
+  extraInfo VARCHAR(1250) NULL,
+  task BINARY(16) NOT NULL, #the task item belongs to
+  isRead BOOL NOT NULL DEFAULT FALSE,
+  emailPending BOOL NOT NULL,
+  PRIMARY KEY(account, member, occurredOn, project, item, actor),
+  UNIQUE INDEX(account, member, isRead, occurredOn, project, item, actor),
+  INDEX(emailPending, account, member, occurredOn)
+);
+
+#rules that create a concrete task from their template under parent each time they come round, nextOccurrenceOn is the
+#rules own next date and nextRunOn is that date moved on to the projects next working day, when the task is actually created
+DROP TABLE IF EXISTS recurrences;
+CREATE TABLE recurrences(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  id BINARY(16) NOT NULL,
+  parent BINARY(16) NOT NULL,
+	name VARCHAR(250) NOT NULL,
+	description VARCHAR(1250) NULL,
+  totalRemainingTime BIGINT UNSIGNED NOT NULL,
+  frequency VARCHAR(10) NOT NULL,
+  weekDays TINYINT UNSIGNED NOT NULL, #bit n set means weekday n where 0 is sunday, only used by weekly rules
+  monthDay TINYINT UNSIGNED NOT NULL, #only used by monthly rules
+  isActive BOOL NOT NULL,
+  nextOccurrenceOn DATETIME NULL, #only active rules have a next occurrence
+  nextRunOn DATETIME NULL,
+  createdBy BINARY(16) NOT NULL, #who the created tasks activities are attributed to
+  createdOn DATETIME NOT NULL,
+  PRIMARY KEY(account, project, id),
+  UNIQUE INDEX(account, project, parent, id),
+  INDEX(isActive, nextRunOn)
+);
+
+#the members each recurring task is assigned to and their share of its totalRemainingTime
+DROP TABLE IF EXISTS recurrenceMembers;
+CREATE TABLE recurrenceMembers(
+	account BINARY(16) NOT NULL,
+	project BINARY(16) NOT NULL,
+  recurrence BINARY(16) NOT NULL,
+  member BINARY(16) NOT NULL,
+  remainingTime BIGINT UNSIGNED NOT NULL,
+  PRIMARY KEY(account, project, recurrence, member)
 );
 
 DROP PROCEDURE IF EXISTS registerAccount;
@@ -180,6 +522,15 @@ BEGIN
 	UPDATE projectMembers SET displayName=_newDisplayName WHERE account=_account AND id=_member;
 END;
 
+DROP PROCEDURE IF EXISTS setMemberNotificationSettings;
+CREATE PROCEDURE setMemberNotificationSettings(_account BINARY(16), _member BINARY(16), _email VARCHAR(250), _notificationDelivery TINYINT UNSIGNED)
+BEGIN
+	UPDATE accountMembers SET email=_email, notificationDelivery=_notificationDelivery, nextNotificationOn=NULL WHERE account=_account AND id=_member;
+  IF _notificationDelivery = 2 THEN
+    UPDATE notifications SET emailPending=FALSE WHERE account=_account AND member=_member AND emailPending=TRUE;
+  END IF;
+END;
+
 DROP PROCEDURE IF EXISTS setAccountMemberInactive;
 CREATE PROCEDURE setAccountMemberInactive(_account BINARY(16), _member BINARY(16))
 BEGIN
@@ -191,16 +542,17 @@ BEGIN
   );
   UPDATE accountMembers SET isActive=FALSE, role=3 WHERE account=_account AND id=_member;
   UPDATE projectMembers SET isActive=FALSE, totalRemainingTime=0, role=2 WHERE account=_account AND id=_member;
-  INSERT INTO tempUpdatedTasks SELECT project, id FROM tasks WHERE account=_account AND member=_member;
-  UPDATE tasks SET member=NULL WHERE account=_account AND member=_member;
+  #their shares of their tasks remaining time are left on the tasks unassigned
+  INSERT INTO tempUpdatedTasks SELECT project, task FROM taskMembers WHERE account=_account AND member=_member;
+  DELETE FROM taskMembers WHERE account=_account AND member=_member;
   SELECT project, id FROM tempUpdatedTasks;
   DROP TEMPORARY TABLE IF EXISTS tempUpdatedTasks;
 END;
 
 DROP PROCEDURE IF EXISTS updateMembersAndSetActive;
-CREATE PROCEDURE updateMembersAndSetActive(_account BINARY(16), _member BINARY(16), _memberName VARCHAR(50), _displayName VARCHAR(100), _hasAvatar BOOL, _role TINYINT UNSIGNED)
+CREATE PROCEDURE updateMembersAndSetActive(_account BINARY(16), _member BINARY(16), _memberName VARCHAR(50), _displayName VARCHAR(100), _hasAvatar BOOL, _role TINYINT UNSIGNED, _email VARCHAR(250), _notificationDelivery TINYINT UNSIGNED)
   BEGIN
-    UPDATE accountMembers SET isActive=TRUE, role=_role, name=_memberName, displayName=_displayName, hasAvatar=_hasAvatar WHERE account=_account AND id=_member;
+    UPDATE accountMembers SET isActive=TRUE, role=_role, name=_memberName, displayName=_displayName, hasAvatar=_hasAvatar, email=_email, notificationDelivery=_notificationDelivery WHERE account=_account AND id=_member;
     UPDATE projectMembers SET name=_memberName, displayName=_displayName WHERE account=_account AND id=_member;
   END;
 
@@ -215,7 +567,29 @@ CREATE PROCEDURE deleteAccount(_account BINARY(16))
     DELETE FROM projectActivities WHERE account=_account;
     DELETE FROM projects WHERE account=_account;
     DELETE FROM tasks WHERE account=_account;
+    DELETE FROM taskMembers WHERE account=_account;
     DELETE FROM timeLogs WHERE account=_account;
+    DELETE FROM timers WHERE account=_account;
+    DELETE FROM taskDependencies WHERE account=_account;
+    DELETE FROM remainingTimeChanges WHERE account=_account;
+    DELETE FROM comments WHERE account=_account;
+    DELETE FROM commentMentions WHERE account=_account;
+    DELETE FROM files WHERE account=_account;
+    DELETE FROM labels WHERE account=_account;
+    DELETE FROM taskLabels WHERE account=_account;
+    DELETE FROM customFields WHERE account=_account;
+    DELETE FROM taskCustomFieldValues WHERE account=_account;
+    DELETE FROM projectStates WHERE account=_account;
+    DELETE FROM taskStateCounts WHERE account=_account;
+    DELETE FROM taskImportRows WHERE account=_account;
+    DELETE FROM trash WHERE account=_account;
+    DELETE FROM trashedTasks WHERE account=_account;
+    DELETE FROM trashedTaskMembers WHERE account=_account;
+    DELETE FROM taskWatchers WHERE account=_account;
+    DELETE FROM notificationCursors WHERE account=_account;
+    DELETE FROM notifications WHERE account=_account;
+    DELETE FROM recurrences WHERE account=_account;
+    DELETE FROM recurrenceMembers WHERE account=_account;
   END;
 
 DROP PROCEDURE IF EXISTS editAccount;
@@ -257,18 +631,79 @@ CREATE PROCEDURE setAccountMemberRole(_account BINARY(16), _me BINARY(16), _memb
     SELECT memberExists;
   END;
 
+DROP PROCEDURE IF EXISTS setAccountMemberHourlyRate;
+CREATE PROCEDURE setAccountMemberHourlyRate(_account BINARY(16), _me BINARY(16), _member BINARY(16), _hourlyRate BIGINT UNSIGNED)
+  BEGIN
+    DECLARE memberExists BOOL DEFAULT FALSE;
+    START TRANSACTION;
+    SELECT COUNT(*)=1 INTO memberExists  FROM accountMembers WHERE account = _account AND id = _member AND isActive = TRUE FOR UPDATE;
+    IF memberExists THEN
+      UPDATE accountMembers SET hourlyRate=_hourlyRate WHERE account = _account AND id = _member AND isActive = TRUE;
+      INSERT INTO accountActivities (account, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+        _account, UTC_TIMESTAMP(6), _me, _member, 'member', 'setHourlyRate', NULL, CAST(_hourlyRate as char character set utf8));
+    END IF;
+    COMMIT;
+    SELECT memberExists;
+  END;
+
 DROP PROCEDURE IF EXISTS createProject;
-CREATE PROCEDURE createProject(_account BINARY(16), _project BINARY(16), _me BINARY(16), _name VARCHAR(250), _description VARCHAR(1250), _hoursPerDay TINYINT UNSIGNED, _daysPerWeek TINYINT UNSIGNED, _createdOn DATETIME, _startOn DATETIME, _dueOn DATETIME, _isParallel BOOL, _isPublic BOOL)
+CREATE PROCEDURE createProject(_account BINARY(16), _project BINARY(16), _me BINARY(16), _name VARCHAR(250), _description VARCHAR(1250), _hoursPerDay TINYINT UNSIGNED, _daysPerWeek TINYINT UNSIGNED, _createdOn DATETIME, _startOn DATETIME, _dueOn DATETIME, _isParallel BOOL, _isPublic BOOL, _isTemplate BOOL, _todoState BINARY(16), _doingState BINARY(16), _reviewState BINARY(16), _doneState BINARY(16))
   BEGIN
     INSERT INTO projectLocks (account, id) VALUES(_account, _project);
-    INSERT INTO projects (account, id, isArchived, name, hoursPerDay, daysPerWeek, createdOn, startOn, dueOn, fileCount, fileSize, isPublic) VALUES (_account, _project, FALSE, _name, _hoursPerDay, _daysPerWeek, _createdOn, _startOn, _dueOn, 0, 0, _isPublic);
-    INSERT INTO tasks (account, project, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, member) VALUES (_account, _project, _project, NULL, NULL, NULL, TRUE, _name, _description, _createdOn, 0, 0, 0, 0, 0, 0, 0, _isParallel, NULL);
+    INSERT INTO projects (account, id, isArchived, name, hoursPerDay, daysPerWeek, createdOn, startOn, dueOn, fileCount, fileSize, isPublic, isTemplate) VALUES (_account, _project, FALSE, _name, _hoursPerDay, _daysPerWeek, _createdOn, _startOn, _dueOn, 0, 0, _isPublic, _isTemplate);
+    INSERT INTO tasks (account, project, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel) VALUES (_account, _project, _project, NULL, NULL, NULL, TRUE, _name, _description, _createdOn, 0, 0, 0, 0, 0, 0, 0, _isParallel);
+    #every project starts with the default workflow states
+    INSERT INTO projectStates (account, project, id, position, name, isDone) VALUES (_account, _project, _todoState, 0, 'todo', FALSE), (_account, _project, _doingState, 1, 'doing', FALSE), (_account, _project, _reviewState, 2, 'review', FALSE), (_account, _project, _doneState, 3, 'done', TRUE);
     INSERT INTO accountActivities (account, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, UTC_TIMESTAMP(6), _me, _project, 'project', 'create', _name, NULL);
     INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _project, 'project', 'create', NULL, NULL);
   END;
 
+## moves the states, tasks, members, custom fields, labels, dependencies, time logs and activities staged under _batch on to the project and creates it in one transaction,
+## used by project imports, the staged aggregate values ignore dependencies so every dependencies common ancestor chain is recalculated once it is moved
+DROP PROCEDURE IF EXISTS importProject;
+CREATE PROCEDURE importProject(_account BINARY(16), _project BINARY(16), _batch BINARY(16), _me BINARY(16), _name VARCHAR(250), _hoursPerDay TINYINT UNSIGNED, _daysPerWeek TINYINT UNSIGNED, _createdOn DATETIME, _startOn DATETIME, _dueOn DATETIME, _isPublic BOOL)
+  BEGIN
+    DECLARE idVariable BINARY(16) DEFAULT NULL;
+    DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+    CREATE TEMPORARY TABLE tempUpdatedIds(
+      id BINARY(16) NOT NULL,
+      PRIMARY KEY (id)
+    );
+    DROP TEMPORARY TABLE IF EXISTS tempDependencyParents;
+    CREATE TEMPORARY TABLE tempDependencyParents(
+      id BINARY(16) NOT NULL,
+      PRIMARY KEY (id)
+    );
+    START TRANSACTION;
+    UPDATE projectStates SET project = _project WHERE account = _account AND project = _batch;
+    UPDATE tasks SET project = _project WHERE account = _account AND project = _batch;
+    UPDATE taskMembers SET project = _project WHERE account = _account AND project = _batch;
+    UPDATE taskStateCounts SET project = _project WHERE account = _account AND project = _batch;
+    UPDATE remainingTimeChanges SET project = _project WHERE account = _account AND project = _batch;
+    UPDATE projectMembers SET project = _project WHERE account = _account AND project = _batch;
+    UPDATE timeLogs SET project = _project WHERE account = _account AND project = _batch;
+    UPDATE customFields SET project = _project WHERE account = _account AND project = _batch;
+    UPDATE taskCustomFieldValues SET project = _project WHERE account = _account AND project = _batch;
+    UPDATE taskLabels SET project = _project WHERE account = _account AND project = _batch;
+    UPDATE taskDependencies SET project = _project WHERE account = _account AND project = _batch;
+    UPDATE projectActivities SET project = _project WHERE account = _account AND project = _batch;
+    INSERT INTO tempDependencyParents SELECT DISTINCT commonAncestor FROM taskDependencies WHERE account = _account AND project = _project;
+    WHILE (SELECT COUNT(*) FROM tempDependencyParents) > 0 DO
+      SELECT id INTO idVariable FROM tempDependencyParents LIMIT 1;
+      CALL _setAncestralChainAggregateValuesFromTask(_account, _project, idVariable);
+      DELETE FROM tempDependencyParents WHERE id = idVariable;
+    END WHILE;
+    INSERT INTO projectLocks (account, id) VALUES(_account, _project);
+    INSERT INTO projects (account, id, isArchived, name, hoursPerDay, daysPerWeek, createdOn, startOn, dueOn, fileCount, fileSize, isPublic, isTemplate) VALUES (_account, _project, FALSE, _name, _hoursPerDay, _daysPerWeek, _createdOn, _startOn, _dueOn, 0, 0, _isPublic, FALSE);
+    INSERT INTO accountActivities (account, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, UTC_TIMESTAMP(6), _me, _project, 'project', 'import', _name, NULL);
+    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _project, 'project', 'import', NULL, NULL);
+    COMMIT;
+    DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+    DROP TEMPORARY TABLE IF EXISTS tempDependencyParents;
+  END;
+
 DROP PROCEDURE IF EXISTS editProject;
-CREATE PROCEDURE editProject(_account BINARY(16), _project BINARY(16), _me BINARY(16), _setIsPublic BOOL, _isPublic BOOL, _setIsArchived BOOL, _isArchived BOOL, _setHoursPerDay BOOL, _hoursPerDay TINYINT UNSIGNED, _setDaysPerWeek BOOL, _daysPerWeek TINYINT UNSIGNED, _setStartOn BOOL, _startOn DATETIME, _setDueOn BOOL, _dueOn DATETIME)
+CREATE PROCEDURE editProject(_account BINARY(16), _project BINARY(16), _me BINARY(16), _setIsPublic BOOL, _isPublic BOOL, _setIsArchived BOOL, _isArchived BOOL, _setHoursPerDay BOOL, _hoursPerDay TINYINT UNSIGNED, _setDaysPerWeek BOOL, _daysPerWeek TINYINT UNSIGNED, _setStartOn BOOL, _startOn DATETIME, _setDueOn BOOL, _dueOn DATETIME, _setTimeLogMaxAgeDays BOOL, _timeLogMaxAgeDays SMALLINT UNSIGNED, _setBudget BOOL, _budget BIGINT UNSIGNED)
   BEGIN
     DECLARE projName VARCHAR(250);
     SELECT name INTO projName FROM projects WHERE account = _account AND id = _project;
@@ -328,6 +763,16 @@ CREATE PROCEDURE editProject(_account BINARY(16), _project BINARY(16), _me BINAR
       INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
         _account, _project, ADDTIME(UTC_TIMESTAMP(6), '0:0:0.000005'), _me, _project, 'project', 'setDueOn', NULL, CAST(_dueOn as char character set utf8));
     END IF;
+    IF _setTimeLogMaxAgeDays THEN
+      UPDATE projects SET timeLogMaxAgeDays=_timeLogMaxAgeDays WHERE account = _account AND id = _project;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+        _account, _project, ADDTIME(UTC_TIMESTAMP(6), '0:0:0.000006'), _me, _project, 'project', 'setTimeLogMaxAgeDays', NULL, CAST(_timeLogMaxAgeDays as char character set utf8));
+    END IF;
+    IF _setBudget THEN
+      UPDATE projects SET budget=_budget WHERE account = _account AND id = _project;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+        _account, _project, ADDTIME(UTC_TIMESTAMP(6), '0:0:0.000007'), _me, _project, 'project', 'setBudget', NULL, CAST(_budget as char character set utf8));
+    END IF;
   END;
 
 DROP PROCEDURE IF EXISTS deleteProject;
@@ -340,7 +785,28 @@ BEGIN
 	DELETE FROM projectActivities WHERE account=_account AND project = _project;
 	DELETE FROM projects WHERE account=_account AND id = _project;
 	DELETE FROM tasks WHERE account=_account AND project = _project;
+	DELETE FROM taskMembers WHERE account=_account AND project = _project;
 	DELETE FROM timeLogs WHERE account=_account AND project = _project;
+	DELETE FROM timers WHERE account=_account AND project = _project;
+	DELETE FROM taskDependencies WHERE account=_account AND project = _project;
+	DELETE FROM remainingTimeChanges WHERE account=_account AND project = _project;
+	DELETE FROM comments WHERE account=_account AND project = _project;
+	DELETE FROM commentMentions WHERE account=_account AND project = _project;
+	DELETE FROM files WHERE account=_account AND project = _project;
+	DELETE FROM taskLabels WHERE account=_account AND project = _project;
+	DELETE FROM customFields WHERE account=_account AND project = _project;
+	DELETE FROM taskCustomFieldValues WHERE account=_account AND project = _project;
+	DELETE FROM projectStates WHERE account=_account AND project = _project;
+	DELETE FROM taskStateCounts WHERE account=_account AND project = _project;
+	DELETE FROM taskImportRows WHERE account=_account AND project = _project;
+	DELETE FROM trash WHERE account=_account AND project = _project;
+	DELETE FROM trashedTasks WHERE account=_account AND project = _project;
+	DELETE FROM trashedTaskMembers WHERE account=_account AND project = _project;
+	DELETE FROM taskWatchers WHERE account=_account AND project = _project;
+	DELETE FROM notificationCursors WHERE account=_account AND project = _project;
+	DELETE FROM notifications WHERE account=_account AND project = _project;
+	DELETE FROM recurrences WHERE account=_account AND project = _project;
+	DELETE FROM recurrenceMembers WHERE account=_account AND project = _project;
   INSERT INTO accountActivities (account, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, UTC_TIMESTAMP(6), _me, _project, 'project', 'delete', projName, NULL);
   UPDATE accountActivities SET itemHasBeenDeleted=TRUE WHERE account=_account AND item=_project;
 END;
@@ -388,7 +854,7 @@ BEGIN
     IF projectExists THEN
       SELECT COUNT(*)=1 INTO projMemberExists FROM projectMembers WHERE account = _account AND project = _project AND id = _member AND isActive = true FOR UPDATE;
       IF projMemberExists THEN
-        UPDATE tasks SET member=NULL WHERE account = _account AND project = _project AND member = _member;
+        DELETE FROM taskMembers WHERE account = _account AND project = _project AND member = _member; #their shares are left on the tasks unassigned
         UPDATE projectMembers SET totalRemainingTime=0 WHERE account = _account AND project = _project AND id = _member;
         INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
           _account, _project, UTC_TIMESTAMP(6), _me, _member, 'member', 'remove', NULL, NULL);
@@ -414,76 +880,314 @@ CREATE PROCEDURE setProjectMemberRole(_account BINARY(16), _project BINARY(16),
     SELECT memberExists;
   END;
 
-DROP PROCEDURE IF EXISTS createTask;
-CREATE PROCEDURE createTask(_account BINARY(16), _project BINARY(16), _parent BINARY(16), _me BINARY(16), _previousSibling BINARY(16), _task BINARY(16), _isAbstract BOOL, _name VARCHAR(250), _description VARCHAR(1250), _createdOn DATETIME, _totalRemainingTime BIGINT UNSIGNED, _isParallel BOOL, _member BINARY(16))
+DROP PROCEDURE IF EXISTS setProjectMemberHourlyRate;
+CREATE PROCEDURE setProjectMemberHourlyRate(_account BINARY(16), _project BINARY(16), _me BINARY(16), _member BINARY(16), _hourlyRate BIGINT UNSIGNED)
+  BEGIN
+    DECLARE memberExists BOOL DEFAULT FALSE;
+    START TRANSACTION;
+    SELECT COUNT(*)=1 INTO memberExists  FROM projectMembers WHERE account = _account AND project = _project AND id = _member AND isActive = TRUE FOR UPDATE;
+    IF memberExists THEN
+      UPDATE projectMembers SET hourlyRate=_hourlyRate WHERE account = _account AND project = _project AND id = _member AND isActive = TRUE;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+        _account, _project, UTC_TIMESTAMP(6), _me, _member, 'member', 'setHourlyRate', NULL, CAST(_hourlyRate as char character set utf8));
+    END IF;
+    COMMIT;
+    SELECT memberExists;
+  END;
+
+DROP PROCEDURE IF EXISTS createProjectState;
+CREATE PROCEDURE createProjectState(_account BINARY(16), _project BINARY(16), _state BINARY(16), _me BINARY(16), _name VARCHAR(50), _isDone BOOL)
 BEGIN
-	DECLARE projectExists BOOL DEFAULT FALSE;
-  DECLARE parentExists BOOL DEFAULT FALSE;
-	DECLARE previousSiblingExists BOOL DEFAULT FALSE;
-	DECLARE nextSiblingToUse BINARY(16) DEFAULT NULL;
-  DECLARE memberExistsAndIsActive BOOL DEFAULT FALSE;
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE nextPosition INT UNSIGNED DEFAULT 0;
   DECLARE changeMade BOOL DEFAULT FALSE;
-  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
-  CREATE TEMPORARY TABLE tempUpdatedIds(
-    id BINARY(16) NOT NULL,
-    PRIMARY KEY (id)
-  );
-
-  #assume parameters are already validated against themselves in the business logic
-
   START TRANSACTION;
-  #validate parameters against the database, i.e. make sure parent/sibiling/member exist and are active and have sufficient privileges
-	#lock the project and lock the project member (member needs "for update" lock if remaining time is greater than zero, otherwise only "lock in share mode" is needed)
-	SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
-  IF _parent = _project THEN
-    SET parentExists=projectExists;
-  ELSE
-    SELECT COUNT(*)=1 INTO parentExists FROM tasks WHERE account = _account AND project = _project AND id = _parent AND isAbstract = TRUE;
-  END IF;
-  IF _previousSibling IS NULL THEN
-    SELECT firstChild INTO nextSiblingToUse FROM tasks WHERE account = _account AND project = _project AND id = _parent AND isAbstract = TRUE;
-    SET previousSiblingExists=TRUE;
-	ELSE
-    SELECT COUNT(*)=1, nextSibling INTO previousSiblingExists, nextSiblingToUse FROM tasks WHERE account = _account AND project = _project AND parent = _parent AND id = _previousSibling;
-  END IF;
-  IF _member IS NOT NULL AND _totalRemainingTime <> 0 THEN
-    SELECT COUNT(*)=1 INTO memberExistsAndIsActive FROM projectMembers WHERE account = _account AND project = _project AND id = _member AND isActive = TRUE AND role < 2 FOR UPDATE; #less than 2 means 0->projectAdmin or 1->projectWriter
-  ELSEIF _member IS NOT NULL AND _totalRemainingTime=0 THEN
-    SELECT COUNT(*)=1 INTO memberExistsAndIsActive FROM projectMembers WHERE account = _account AND project = _project AND id = _member AND isActive = TRUE AND role < 2 LOCK IN SHARE MODE;
-  ELSE
-    SET memberExistsAndIsActive=TRUE;
-  END IF;
-  IF _member IS NOT NULL AND _isAbstract THEN
-    SET memberExistsAndIsActive=FALSE; #set this to false to fail the validation check so we dont create an invalid abstract task with an assigned member
-  END IF;
-  IF projectExists AND parentExists AND previousSiblingExists AND memberExistsAndIsActive THEN
-    SET changeMade=TRUE;
-		#write the task row
-    if NOT _isAbstract THEN
-      SET _isParallel=FALSE;
-    END IF;
-    INSERT INTO tasks (account,	project, id, parent, firstChild, nextSibling, isAbstract, name,	description, createdOn, totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, member) VALUES (
-      _account, _project, _task, _parent, NULL, nextSiblingToUse, _isAbstract, _name, _description, _createdOn, _totalRemainingTime, 0, _totalRemainingTime, 0, 0, 0, 0, _isParallel, _member);
-    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
-      _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'create', _name, NULL);
-    #update siblings and parent firstChild value if required
-    IF _previousSibling IS NULL THEN #update parents firstChild
-      UPDATE tasks SET firstChild=_task WHERE account = _account AND project = _project AND id = _parent;
-      INSERT INTO tempUpdatedIds VALUES (_parent) ON DUPLICATE KEY UPDATE id =id;
-    ELSE #update previousSiblings nextSibling value
-      UPDATE tasks SET nextSibling=_task WHERE account = _account AND project = _project AND id = _previousSibling;
-      INSERT INTO tempUpdatedIds VALUES (_previousSibling) ON DUPLICATE KEY UPDATE id =id;
-    END IF;
-    #update member if needed
-    IF _member IS NOT NULL AND _totalRemainingTime <> 0 THEN
-      UPDATE projectMembers SET totalRemainingTime=totalRemainingTime + _totalRemainingTime WHERE account = _account AND project = _project AND id = _member;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists AND NOT EXISTS(SELECT * FROM projectStates WHERE account = _account AND project = _project AND name = _name) THEN
+    SELECT COUNT(*) INTO nextPosition FROM projectStates WHERE account = _account AND project = _project;
+    IF _isDone THEN #only one done state per project
+      UPDATE projectStates SET isDone=FALSE WHERE account = _account AND project = _project;
     END IF;
-
-    CALL _setAncestralChainAggregateValuesFromTask(_account, _project, _parent);
+    INSERT INTO projectStates (account, project, id, position, name, isDone) VALUES (_account, _project, _state, nextPosition, _name, _isDone);
+    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _state, 'state', 'create', _name, NULL);
+    SET changeMade = TRUE;
   END IF;
   COMMIT;
-  SELECT * FROM tempUpdatedIds;
-  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  SELECT changeMade;
+END;
+
+## Pass NULL in _name to not rename the state, _isDone can only be used to make a state the done state
+DROP PROCEDURE IF EXISTS editProjectState;
+CREATE PROCEDURE editProjectState(_account BINARY(16), _project BINARY(16), _state BINARY(16), _me BINARY(16), _name VARCHAR(50), _isDone BOOL)
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE stateExists BOOL DEFAULT FALSE;
+  DECLARE currentName VARCHAR(50) DEFAULT '';
+  DECLARE currentIsDone BOOL DEFAULT FALSE;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT COUNT(*)=1, name, isDone INTO stateExists, currentName, currentIsDone FROM projectStates WHERE account = _account AND project = _project AND id = _state;
+    IF stateExists AND _name IS NOT NULL AND _name <> currentName AND NOT EXISTS(SELECT * FROM projectStates WHERE account = _account AND project = _project AND name = _name) THEN
+      UPDATE projectStates SET name=_name WHERE account = _account AND project = _project AND id = _state;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _state, 'state', 'setName', NULL, currentName);
+      UPDATE projectActivities SET itemName=_name WHERE account = _account AND project = _project AND item = _state;
+      SET changeMade = TRUE;
+    END IF;
+    IF stateExists AND _isDone AND NOT currentIsDone THEN
+      UPDATE projectStates SET isDone=(id = _state) WHERE account = _account AND project = _project;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _state, 'state', 'setIsDone', COALESCE(_name, currentName), 'true');
+      SET changeMade = TRUE;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+END;
+
+DROP PROCEDURE IF EXISTS moveProjectState;
+CREATE PROCEDURE moveProjectState(_account BINARY(16), _project BINARY(16), _state BINARY(16), _me BINARY(16), _newPosition INT UNSIGNED)
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE stateExists BOOL DEFAULT FALSE;
+  DECLARE stateName VARCHAR(50) DEFAULT '';
+  DECLARE currentPosition INT UNSIGNED DEFAULT 0;
+  DECLARE stateCount INT UNSIGNED DEFAULT 0;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT COUNT(*)=1, name, position INTO stateExists, stateName, currentPosition FROM projectStates WHERE account = _account AND project = _project AND id = _state;
+    SELECT COUNT(*) INTO stateCount FROM projectStates WHERE account = _account AND project = _project;
+    IF _newPosition >= stateCount THEN
+      SET _newPosition = stateCount - 1;
+    END IF;
+    IF stateExists AND _newPosition <> currentPosition THEN
+      IF _newPosition < currentPosition THEN
+        UPDATE projectStates SET position=position+1 WHERE account = _account AND project = _project AND position >= _newPosition AND position < currentPosition;
+      ELSE
+        UPDATE projectStates SET position=position-1 WHERE account = _account AND project = _project AND position > currentPosition AND position <= _newPosition;
+      END IF;
+      UPDATE projectStates SET position=_newPosition WHERE account = _account AND project = _project AND id = _state;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _state, 'state', 'move', stateName, CAST(_newPosition as char character set utf8));
+      SET changeMade = TRUE;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+END;
+
+## tasks in the deleted state are moved to _replacement, the done state can not be deleted
+DROP PROCEDURE IF EXISTS deleteProjectState;
+CREATE PROCEDURE deleteProjectState(_account BINARY(16), _project BINARY(16), _state BINARY(16), _me BINARY(16), _replacement BINARY(16))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE stateExists BOOL DEFAULT FALSE;
+  DECLARE replacementExists BOOL DEFAULT FALSE;
+  DECLARE stateName VARCHAR(50) DEFAULT '';
+  DECLARE stateIsDone BOOL DEFAULT FALSE;
+  DECLARE deletedPosition INT UNSIGNED DEFAULT 0;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  DROP TEMPORARY TABLE IF EXISTS tempTaskStateCounts;
+  CREATE TEMPORARY TABLE tempTaskStateCounts(
+    task BINARY(16) NOT NULL,
+    countChange BIGINT NOT NULL,
+    PRIMARY KEY (task)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists AND _state <> _replacement THEN
+    SELECT COUNT(*)=1, name, isDone, position INTO stateExists, stateName, stateIsDone, deletedPosition FROM projectStates WHERE account = _account AND project = _project AND id = _state;
+    SELECT COUNT(*)=1 INTO replacementExists FROM projectStates WHERE account = _account AND project = _project AND id = _replacement;
+    IF stateExists AND replacementExists AND NOT stateIsDone THEN
+      UPDATE tasks SET state=_replacement WHERE account = _account AND project = _project AND state = _state;
+      UPDATE trashedTasks SET state=_replacement WHERE account = _account AND project = _project AND state = _state;
+      #merge the deleted states counts into the replacement states counts
+      INSERT INTO tempTaskStateCounts SELECT task, count FROM taskStateCounts WHERE account = _account AND project = _project AND state = _state;
+      INSERT INTO taskStateCounts (account, project, task, state, count) SELECT _account, _project, task, _replacement, countChange FROM tempTaskStateCounts ON DUPLICATE KEY UPDATE count=count+VALUES(count);
+      DELETE FROM taskStateCounts WHERE account = _account AND project = _project AND state = _state;
+      DELETE FROM projectStates WHERE account = _account AND project = _project AND id = _state;
+      UPDATE projectStates SET position=position-1 WHERE account = _account AND project = _project AND position > deletedPosition;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _state, 'state', 'delete', stateName, LOWER(HEX(_replacement)));
+      UPDATE projectActivities SET itemHasBeenDeleted=TRUE WHERE account = _account AND project = _project AND item = _state;
+      SET changeMade = TRUE;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+  DROP TEMPORARY TABLE IF EXISTS tempTaskStateCounts;
+END;
+
+## replaces the states of the empty project _project with copies of _sourceProject's states, positions are used to pick each copy's id out of _newIdsStr
+DROP PROCEDURE IF EXISTS createCustomField;
+CREATE PROCEDURE createCustomField(_account BINARY(16), _project BINARY(16), _customField BINARY(16), _me BINARY(16), _createdOn DATETIME(6), _name VARCHAR(50), _type VARCHAR(10), _options VARCHAR(1300))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists AND NOT EXISTS(SELECT * FROM customFields WHERE account = _account AND project = _project AND name = _name) THEN
+    INSERT INTO customFields (account, project, id, createdOn, name, type, options) VALUES (_account, _project, _customField, _createdOn, _name, _type, _options);
+    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _customField, 'customField', 'create', _name, _type);
+    SET changeMade = TRUE;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+END;
+
+## a custom fields type can't be changed, the caller must check no task holds an option that is being removed
+DROP PROCEDURE IF EXISTS editCustomField;
+CREATE PROCEDURE editCustomField(_account BINARY(16), _project BINARY(16), _customField BINARY(16), _me BINARY(16), _name VARCHAR(50), _options VARCHAR(1300))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE customFieldExists BOOL DEFAULT FALSE;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists AND NOT EXISTS(SELECT * FROM customFields WHERE account = _account AND project = _project AND name = _name AND id <> _customField) THEN
+    SELECT COUNT(*)=1 INTO customFieldExists FROM customFields WHERE account = _account AND project = _project AND id = _customField;
+    IF customFieldExists THEN
+      UPDATE customFields SET name=_name, options=_options WHERE account = _account AND project = _project AND id = _customField;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _customField, 'customField', 'edit', _name, NULL);
+      UPDATE projectActivities SET itemName=_name WHERE account = _account AND project = _project AND item = _customField;
+      SET changeMade = TRUE;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+END;
+
+DROP PROCEDURE IF EXISTS deleteCustomField;
+CREATE PROCEDURE deleteCustomField(_account BINARY(16), _project BINARY(16), _customField BINARY(16), _me BINARY(16))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE customFieldName VARCHAR(50) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempValuedTasks;
+  CREATE TEMPORARY TABLE tempValuedTasks(
+    id BINARY(16) NOT NULL,
+    parent BINARY(16) NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT name INTO customFieldName FROM customFields WHERE account = _account AND project = _project AND id = _customField;
+    IF customFieldName IS NOT NULL THEN
+      INSERT INTO tempValuedTasks SELECT v.task, t.parent FROM taskCustomFieldValues v INNER JOIN tasks t ON t.account = v.account AND t.project = v.project AND t.id = v.task WHERE v.account = _account AND v.project = _project AND v.customField = _customField;
+      DELETE FROM taskCustomFieldValues WHERE account = _account AND project = _project AND customField = _customField;
+      DELETE FROM customFields WHERE account = _account AND project = _project AND id = _customField;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _customField, 'customField', 'delete', customFieldName, NULL);
+      UPDATE projectActivities SET itemHasBeenDeleted=TRUE WHERE account = _account AND project = _project AND item = _customField;
+    END IF;
+  END IF;
+  COMMIT;
+  #the tasks which were holding a value, the caller needs these to clear them from the cache
+  SELECT id, parent FROM tempValuedTasks;
+  DROP TEMPORARY TABLE IF EXISTS tempValuedTasks;
+END;
+
+DROP PROCEDURE IF EXISTS copyProjectStates;
+CREATE PROCEDURE copyProjectStates(_account BINARY(16), _sourceProject BINARY(16), _project BINARY(16), _newIdsStr VARCHAR(640)) #640 == 20 uuids
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE projectIsEmpty BOOL DEFAULT FALSE;
+  DECLARE stateCount INT UNSIGNED DEFAULT 0;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  SELECT firstChild IS NULL INTO projectIsEmpty FROM tasks WHERE account = _account AND project = _project AND id = _project;
+  SELECT COUNT(*) INTO stateCount FROM projectStates WHERE account = _account AND project = _sourceProject;
+  IF projectExists AND projectIsEmpty AND _sourceProject <> _project AND stateCount > 0 AND LENGTH(_newIdsStr) = stateCount * 32 THEN
+    DELETE FROM projectStates WHERE account = _account AND project = _project;
+    INSERT INTO projectStates (account, project, id, position, name, isDone) SELECT _account, _project, UNHEX(SUBSTRING(_newIdsStr, position * 32 + 1, 32)), position, name, isDone FROM projectStates WHERE account = _account AND project = _sourceProject;
+    SET changeMade = TRUE;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+END;
+
+DROP PROCEDURE IF EXISTS createTask;
+CREATE PROCEDURE createTask(_account BINARY(16), _project BINARY(16), _parent BINARY(16), _me BINARY(16), _previousSibling BINARY(16), _task BINARY(16), _isAbstract BOOL, _name VARCHAR(250), _description VARCHAR(1250), _createdOn DATETIME, _totalRemainingTime BIGINT UNSIGNED, _isParallel BOOL, _member BINARY(16), _state BINARY(16))
+BEGIN
+	DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE parentExists BOOL DEFAULT FALSE;
+	DECLARE previousSiblingExists BOOL DEFAULT FALSE;
+	DECLARE nextSiblingToUse BINARY(16) DEFAULT NULL;
+  DECLARE memberExistsAndIsActive BOOL DEFAULT FALSE;
+  DECLARE stateIsValid BOOL DEFAULT FALSE;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+
+  #assume parameters are already validated against themselves in the business logic
+
+  START TRANSACTION;
+  #validate parameters against the database, i.e. make sure parent/sibiling/member exist and are active and have sufficient privileges
+	#lock the project and lock the project member (member needs "for update" lock if remaining time is greater than zero, otherwise only "lock in share mode" is needed)
+	SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF _parent = _project THEN
+    SET parentExists=projectExists;
+  ELSE
+    SELECT COUNT(*)=1 INTO parentExists FROM tasks WHERE account = _account AND project = _project AND id = _parent AND isAbstract = TRUE;
+  END IF;
+  IF _previousSibling IS NULL THEN
+    SELECT firstChild INTO nextSiblingToUse FROM tasks WHERE account = _account AND project = _project AND id = _parent AND isAbstract = TRUE;
+    SET previousSiblingExists=TRUE;
+	ELSE
+    SELECT COUNT(*)=1, nextSibling INTO previousSiblingExists, nextSiblingToUse FROM tasks WHERE account = _account AND project = _project AND parent = _parent AND id = _previousSibling;
+  END IF;
+  IF _member IS NOT NULL AND _totalRemainingTime <> 0 THEN
+    SELECT COUNT(*)=1 INTO memberExistsAndIsActive FROM projectMembers WHERE account = _account AND project = _project AND id = _member AND isActive = TRUE AND role < 2 FOR UPDATE; #less than 2 means 0->projectAdmin or 1->projectWriter
+  ELSEIF _member IS NOT NULL AND _totalRemainingTime=0 THEN
+    SELECT COUNT(*)=1 INTO memberExistsAndIsActive FROM projectMembers WHERE account = _account AND project = _project AND id = _member AND isActive = TRUE AND role < 2 LOCK IN SHARE MODE;
+  ELSE
+    SET memberExistsAndIsActive=TRUE;
+  END IF;
+  IF _member IS NOT NULL AND _isAbstract THEN
+    SET memberExistsAndIsActive=FALSE; #set this to false to fail the validation check so we dont create an invalid abstract task with an assigned member
+  END IF;
+  IF _isAbstract THEN
+    SET stateIsValid = _state IS NULL;
+  ELSE
+    SELECT COUNT(*)=1 INTO stateIsValid FROM projectStates WHERE account = _account AND project = _project AND id = _state;
+  END IF;
+  IF projectExists AND parentExists AND previousSiblingExists AND memberExistsAndIsActive AND stateIsValid THEN
+    SET changeMade=TRUE;
+		#write the task row
+    if NOT _isAbstract THEN
+      SET _isParallel=FALSE;
+    END IF;
+    INSERT INTO tasks (account,	project, id, parent, firstChild, nextSibling, isAbstract, name,	description, createdOn, totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state) VALUES (
+      _account, _project, _task, _parent, NULL, nextSiblingToUse, _isAbstract, _name, _description, _createdOn, _totalRemainingTime, 0, _totalRemainingTime, 0, 0, 0, 0, _isParallel, _state);
+    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+      _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'create', _name, NULL);
+    IF NOT _isAbstract AND _totalRemainingTime > 0 THEN
+      INSERT INTO remainingTimeChanges (account, project, task, occurredOn, member, oldValue, newValue) VALUES (_account, _project, _task, UTC_TIMESTAMP(6), _me, 0, _totalRemainingTime);
+    END IF;
+    #update siblings and parent firstChild value if required
+    IF _previousSibling IS NULL THEN #update parents firstChild
+      UPDATE tasks SET firstChild=_task WHERE account = _account AND project = _project AND id = _parent;
+      INSERT INTO tempUpdatedIds VALUES (_parent) ON DUPLICATE KEY UPDATE id =id;
+    ELSE #update previousSiblings nextSibling value
+      UPDATE tasks SET nextSibling=_task WHERE account = _account AND project = _project AND id = _previousSibling;
+      INSERT INTO tempUpdatedIds VALUES (_previousSibling) ON DUPLICATE KEY UPDATE id =id;
+    END IF;
+    #update member if needed, the initial member takes all of the tasks remaining time
+    IF _member IS NOT NULL THEN
+      INSERT INTO taskMembers (account, project, task, member, remainingTime) VALUES (_account, _project, _task, _member, _totalRemainingTime);
+    END IF;
+    IF _member IS NOT NULL AND _totalRemainingTime <> 0 THEN
+      UPDATE projectMembers SET totalRemainingTime=totalRemainingTime + _totalRemainingTime WHERE account = _account AND project = _project AND id = _member;
+    END IF;
+
+    CALL _setAncestralChainAggregateValuesFromTask(_account, _project, _parent);
+  END IF;
+  COMMIT;
+  SELECT * FROM tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
 END;
 
 DROP PROCEDURE IF EXISTS setTaskName;
@@ -527,11 +1231,17 @@ BEGIN
   DECLARE currentIsAbstract BOOL DEFAULT FALSE;
   DECLARE currentTotalLoggedTime BIGINT UNSIGNED;
   DECLARE currentChildCount BIGINT UNSIGNED;
+  DECLARE newState BINARY(16) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
   START TRANSACTION;
   SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE; #set project lock to ensure data integrity
   IF projectExists THEN
     SELECT COUNT(*)=1, isAbstract, parent, totalLoggedTime, childCount INTO taskExists, currentIsAbstract, taskParent, currentTotalLoggedTime, currentChildCount FROM tasks WHERE account = _account AND project = _project AND id=_task AND project <> _task;
-    IF taskExists AND _isAbstract <> currentIsAbstract AND currentTotalLoggedTime = 0 AND currentChildCount = 0 THEN #make sure we are making a change otherwise, no need to update anything
+    IF taskExists AND _isAbstract <> currentIsAbstract AND currentTotalLoggedTime = 0 AND currentChildCount = 0 AND NOT EXISTS(SELECT * FROM taskDependencies WHERE account = _account AND project = _project AND (task = _task OR dependsOn = _task)) AND NOT EXISTS(SELECT * FROM taskMembers WHERE account = _account AND project = _project AND task = _task) THEN #make sure we are making a change otherwise, no need to update anything, concrete tasks with dependencies or members can't be made abstract
       IF _isAbstract THEN
         INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
           _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'setIsAbstract', NULL, 'true');
@@ -539,13 +1249,49 @@ BEGIN
         INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
           _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'setIsAbstract', NULL, 'false');
       END IF;
-      UPDATE tasks SET isAbstract=_isAbstract WHERE account = _account AND project = _project AND id = _task;
-    ELSE
-      SET taskParent = NULL;
+      #abstract tasks have no state, concrete tasks start in the projects first state
+      IF NOT _isAbstract THEN
+        SELECT id INTO newState FROM projectStates WHERE account = _account AND project = _project ORDER BY position ASC LIMIT 1;
+      END IF;
+      UPDATE tasks SET isAbstract=_isAbstract, state=newState WHERE account = _account AND project = _project AND id = _task;
+      INSERT INTO tempUpdatedIds VALUES (_task), (taskParent) ON DUPLICATE KEY UPDATE id=id;
+      CALL _setAncestralChainStateCounts(_account, _project, taskParent);
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT * FROM tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+END;
+
+DROP PROCEDURE IF EXISTS setTaskState;
+CREATE PROCEDURE setTaskState(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16), _state BINARY(16))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE stateExists BOOL DEFAULT FALSE;
+  DECLARE taskExistsAndIsConcrete BOOL DEFAULT FALSE;
+  DECLARE currentState BINARY(16) DEFAULT NULL;
+  DECLARE taskParent BINARY(16) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE; #set project lock to ensure data integrity
+  IF projectExists THEN
+    SELECT COUNT(*)=1 INTO stateExists FROM projectStates WHERE account = _account AND project = _project AND id = _state;
+    SELECT COUNT(*)=1, state, parent INTO taskExistsAndIsConcrete, currentState, taskParent FROM tasks WHERE account = _account AND project = _project AND id = _task AND isAbstract = FALSE;
+    IF stateExists AND taskExistsAndIsConcrete AND (currentState IS NULL OR currentState <> _state) THEN
+      UPDATE tasks SET state=_state WHERE account = _account AND project = _project AND id = _task;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+        _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'setState', NULL, LOWER(HEX(_state)));
+      INSERT INTO tempUpdatedIds VALUES (_task), (taskParent) ON DUPLICATE KEY UPDATE id=id;
+      CALL _setAncestralChainStateCounts(_account, _project, taskParent);
     END IF;
   END IF;
   COMMIT;
-  SELECT taskParent;
+  SELECT * FROM tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
 END;
 
 DROP PROCEDURE IF EXISTS setTaskIsParallel;
@@ -589,123 +1335,331 @@ BEGIN
   DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
 END;
 
+## makes _member the only member of the task with all of its remaining time, or removes all of its members when _member is NULL
 DROP PROCEDURE IF EXISTS setTaskMember;
 CREATE PROCEDURE setTaskMember(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16), _member BINARY(16))
 BEGIN
   DECLARE projectExists BOOL DEFAULT FALSE;
   DECLARE memberExistsAndIsActive BOOL DEFAULT TRUE;
   DECLARE taskExistsAndIsConcrete BOOL DEFAULT FALSE;
+  DECLARE alreadySet BOOL DEFAULT FALSE;
   DECLARE changeMade BOOL DEFAULT FALSE;
   DECLARE taskTotalRemainingTime BIGINT UNSIGNED DEFAULT 0;
-  DECLARE existingMemberTotalRemainingTime BIGINT UNSIGNED DEFAULT 0;
-  DECLARE existingMember BINARY(16) DEFAULT NULL;
   DECLARE taskParent BINARY(16) DEFAULT NULL;
-  DECLARE existingMemberExists BOOL DEFAULT FALSE; #seems pointless but it's justa var to stick a value in when locking the row
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+  CREATE TEMPORARY TABLE tempUpdatedMembers(
+    id BINARY(16) NOT NULL,
+    totalRemainingTimeReduction BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (id)
+  );
   START TRANSACTION;
   SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE; #set project lock to ensure data integrity
   IF projectExists THEN
-    SELECT COUNT(*)=1, totalRemainingTime, parent, member INTO taskExistsAndIsConcrete, taskTotalRemainingTime, taskParent, existingMember FROM tasks WHERE account =
-                                                                                                                                        _account AND project = _project AND id = _task AND isAbstract = FALSE;
-    IF (existingMember <> _member OR (existingMember IS NOT NULL AND _member IS NULL) OR (existingMember IS NULL AND _member IS NOT NULL)) AND taskExistsAndIsConcrete THEN
-      IF taskTotalRemainingTime > 0 THEN
-        IF _member IS NOT NULL THEN
-          SELECT COUNT(*)=1 INTO memberExistsAndIsActive FROM projectMembers WHERE account = _account AND project = _project AND id = _member AND isActive = TRUE AND role < 2 FOR UPDATE; #less than 2 means 0->projectAdmin or 1->projectWriter
-          IF memberExistsAndIsActive THEN
-            UPDATE projectMembers SET totalRemainingTime=totalRemainingTime+taskTotalRemainingTime WHERE account =
-                                                                                                         _account AND project = _project AND id = _member;
-          END IF;
-        END IF;
-        IF existingMember IS NOT NULL THEN
-          SELECT COUNT(*)=1, totalRemainingTime INTO existingMemberExists, existingMemberTotalRemainingTime FROM projectMembers WHERE account =
-                                                                                                                                      _account AND project = _project AND id = existingMember FOR UPDATE; #less than 2 means 0->projectAdmin or 1->projectWriter
-          IF existingMemberTotalRemainingTime >= taskTotalRemainingTime THEN
-            UPDATE projectMembers SET totalRemainingTime=totalRemainingTime-taskTotalRemainingTime WHERE account =
-                                                                                                         _account AND project = _project AND id = existingMember;
-          END IF;
-        END IF;
+    SELECT COUNT(*)=1, totalRemainingTime, parent INTO taskExistsAndIsConcrete, taskTotalRemainingTime, taskParent FROM tasks WHERE account = _account AND project = _project AND id = _task AND isAbstract = FALSE;
+    IF _member IS NULL THEN
+      SELECT COUNT(*)=0 INTO alreadySet FROM taskMembers WHERE account = _account AND project = _project AND task = _task;
+    ELSE
+      SELECT COUNT(*)=1 AND MAX(member)=_member AND MAX(remainingTime)=taskTotalRemainingTime INTO alreadySet FROM taskMembers WHERE account = _account AND project = _project AND task = _task;
+      SELECT COUNT(*)=1 INTO memberExistsAndIsActive FROM projectMembers WHERE account = _account AND project = _project AND id = _member AND isActive = TRUE AND role < 2 FOR UPDATE; #less than 2 means 0->projectAdmin or 1->projectWriter
+    END IF;
+    IF taskExistsAndIsConcrete AND NOT alreadySet AND memberExistsAndIsActive THEN
+      SET changeMade = TRUE;
+      #take the existing members shares off them, then give all of the tasks remaining time to the new member
+      INSERT INTO tempUpdatedMembers SELECT member, remainingTime FROM taskMembers WHERE account = _account AND project = _project AND task = _task;
+      UPDATE projectMembers pm
+        INNER JOIN tempUpdatedMembers tum
+        ON pm.account = _account AND pm.project = _project AND pm.id = tum.id
+        SET pm.totalRemainingTime = IF(pm.totalRemainingTime >= tum.totalRemainingTimeReduction, pm.totalRemainingTime - tum.totalRemainingTimeReduction, 0);
+      DELETE FROM taskMembers WHERE account = _account AND project = _project AND task = _task;
+      IF _member IS NOT NULL THEN
+        INSERT INTO taskMembers (account, project, task, member, remainingTime) VALUES (_account, _project, _task, _member, taskTotalRemainingTime);
+        UPDATE projectMembers SET totalRemainingTime = totalRemainingTime + taskTotalRemainingTime WHERE account = _account AND project = _project AND id = _member;
+        INSERT INTO tempUpdatedMembers VALUES (_member, 0) ON DUPLICATE KEY UPDATE id=id;
+        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+          _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'setMember', NULL, LOWER(HEX(_member)));
       ELSE
-        IF _member IS NOT NULL THEN
-          SELECT COUNT(*)=1 INTO memberExistsAndIsActive FROM projectMembers WHERE account = _account AND project = _project AND id = _member AND isActive = TRUE AND role < 2 LOCK IN SHARE MODE; #less than 2 means 0->projectAdmin or 1->projectWriter
-        END IF;
-      END IF;
-      IF memberExistsAndIsActive THEN
-        UPDATE tasks SET member=_member WHERE account = _account AND project = _project AND id = _task;
-        SET changeMade = TRUE;
-        IF _member IS NOT NULL THEN
-          INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
-            _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'setMember', NULL, LOWER(HEX(_member)));
-        ELSE
-          INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
-            _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'setMember', NULL, NULL);
-        END IF;
+        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+          _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'setMember', NULL, NULL);
       END IF;
     END IF;
   END IF;
   COMMIT;
-  SELECT changeMade, taskParent, existingMember;
+  SELECT _task, 't' FROM DUAL WHERE changeMade
+  UNION
+  SELECT taskParent, 't' FROM DUAL WHERE changeMade
+  UNION
+  SELECT id, 'm' FROM tempUpdatedMembers;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
 END;
 
-## Pass NULL in _timeRemaining to not set a new TotalTimeRemaining value, pass NULL or zero to _duration to not log time
-DROP PROCEDURE IF EXISTS setRemainingTimeAndOrLogTime;
-CREATE PROCEDURE setRemainingTimeAndOrLogTime(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me bINARY(16), _timeRemaining BIGINT UNSIGNED, _timeLog BINARY(16), _loggedOn DATETIME, _duration BIGINT UNSIGNED, _note VARCHAR(250))
+## makes _member the only member of every concrete task in _tasksStr with all of its remaining time, or removes all of their members when _member is NULL,
+## only tasks whose members actually change are updated
+DROP PROCEDURE IF EXISTS setTasksMember;
+CREATE PROCEDURE setTasksMember(_account BINARY(16), _project BINARY(16), _tasksStr VARCHAR(3200), _me BINARY(16), _member BINARY(16)) #3200 == 100 uuids
 BEGIN
   DECLARE projectExists BOOL DEFAULT FALSE;
-  DECLARE taskExists BOOL DEFAULT FALSE;
-  DECLARE memberExists BOOL DEFAULT FALSE;
-  DECLARE taskName VARCHAR(250) DEFAULT '';
-  DECLARE existingMemberExists BOOL DEFAULT FALSE;
-  DECLARE existingMember BINARY(16) DEFAULT NULL;
-  DECLARE nextTask BINARY(16) DEFAULT NULL;
-  DECLARE originalMinimumRemainingTime BIGINT UNSIGNED DEFAULT 0;
-  DECLARE changeMade BOOL DEFAULT FALSE;
+  DECLARE memberExistsAndIsActive BOOL DEFAULT TRUE;
+  DECLARE tasksStrLen INT DEFAULT LENGTH(_tasksStr);
+  DECLARE offset INT DEFAULT 0;
+  DECLARE taskCount INT DEFAULT 0;
+  DECLARE concreteTaskCount INT DEFAULT 0;
+  DECLARE totalRemainingTimeIncrease BIGINT UNSIGNED DEFAULT 0;
   DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
   CREATE TEMPORARY TABLE tempUpdatedIds(
     id BINARY(16) NOT NULL,
     PRIMARY KEY (id)
   );
+  DROP TEMPORARY TABLE IF EXISTS tempBulkIds;
+  CREATE TEMPORARY TABLE tempBulkIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedParents;
+  CREATE TEMPORARY TABLE tempUpdatedParents(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+  CREATE TEMPORARY TABLE tempUpdatedMembers(
+    id BINARY(16) NOT NULL,
+    totalRemainingTimeReduction BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (id)
+  );
   START TRANSACTION;
   SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
-  IF projectExists THEN
-    SELECT COUNT(*)=1, name, member, parent, totalRemainingTime INTO taskExists, taskName, existingMember, nextTask, originalMinimumRemainingTime FROM tasks WHERE account =
-                                                                                                                                                                   _account AND project = _project AND id = _task AND isAbstract = FALSE;
-    IF _timeRemaining IS NULL THEN
-      SET _timeRemaining = originalMinimumRemainingTime;
-    END IF;
-    IF _duration IS NULL THEN
-      SET _duration=0;
+  IF projectExists AND tasksStrLen > 0 AND tasksStrLen % 32 = 0 THEN
+    WHILE offset < tasksStrLen DO
+      INSERT INTO tempBulkIds VALUE (UNHEX(SUBSTRING(_tasksStr, offset + 1, 32)));
+      SET offset = offset + 32;
+    END WHILE;
+    SELECT COUNT(*) INTO taskCount FROM tempBulkIds;
+    SELECT COUNT(*) INTO concreteTaskCount FROM tasks WHERE account = _account AND project = _project AND id IN (SELECT id FROM tempBulkIds) AND isAbstract = FALSE;
+    IF _member IS NOT NULL THEN
+      SELECT COUNT(*)=1 INTO memberExistsAndIsActive FROM projectMembers WHERE account = _account AND project = _project AND id = _member AND isActive = TRUE AND role < 2 FOR UPDATE; #less than 2 means 0->projectAdmin or 1->projectWriter
     END IF;
-    IF taskExists AND (originalMinimumRemainingTime <> _timeRemaining OR _duration > 0) THEN
-      SET changeMade = TRUE;
-      IF existingMember IS NOT NULL AND originalMinimumRemainingTime <> _timeRemaining THEN
-        SELECT COUNT(*)=1 INTO existingMemberExists FROM projectMembers WHERE account = _account AND project = _project AND id = existingMember FOR UPDATE;
-        UPDATE projectMembers SET totalRemainingTime=totalRemainingTime+_timeRemaining - originalMinimumRemainingTime WHERE account = _account AND project = _project AND id = existingMember;
-      END IF;
-
-      IF _me IS NOT NULL AND _duration > 0 THEN
-        IF existingMember IS NULL OR existingMember <> _me THEN
-          #we already set the lock and proved it exists as it is the member assigned to the task itself
-          SELECT COUNT(*)=1 INTO memberExists FROM projectMembers WHERE account = _account AND project = _project AND id = _me FOR UPDATE;
-        END IF;
-        INSERT INTO timeLogs (account, project, task, id, member, loggedOn, taskName, duration, note) VALUES (_account, _project, _task, _timeLog, _me, _loggedOn, taskName, _duration, _note);
-        UPDATE projectMembers SET totalLoggedTime=totalLoggedTime+_duration WHERE account = _account AND project = _project AND id = _me;
-        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
-            _account, _project, UTC_TIMESTAMP(6), _me, _timeLog, 'timeLog', 'create', _note, CONCAT('{"duration":', CAST(_duration as char character set utf8), '}'));
+    IF taskCount = concreteTaskCount AND memberExistsAndIsActive THEN
+      IF _member IS NULL THEN
+        INSERT INTO tempUpdatedIds SELECT DISTINCT task FROM taskMembers WHERE account = _account AND project = _project AND task IN (SELECT id FROM tempBulkIds);
+      ELSE
+        INSERT INTO tempUpdatedIds SELECT t.id FROM tasks t LEFT JOIN taskMembers tm ON tm.account = _account AND tm.project = _project AND tm.task = t.id AND tm.member = _member AND tm.remainingTime = t.totalRemainingTime
+          WHERE t.account = _account AND t.project = _project AND t.id IN (SELECT id FROM tempBulkIds) AND (tm.task IS NULL OR (SELECT COUNT(*) FROM taskMembers otm WHERE otm.account = _account AND otm.project = _project AND otm.task = t.id) > 1);
       END IF;
-
-      UPDATE tasks SET totalRemainingTime=_timeRemaining, minimumRemainingTime=_timeRemaining, totalLoggedTime=totalLoggedTime+_duration WHERE account =
-                                                                                                                                               _account AND project = _project AND id = _task;
-      INSERT INTO tempUpdatedIds VALUES (_task) ON DUPLICATE KEY UPDATE id=id;
-      INSERT INTO tempUpdatedIds VALUES (nextTask) ON DUPLICATE KEY UPDATE id=id;
-      IF _timeRemaining <> originalMinimumRemainingTime THEN
-        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
-          _account, _project, ADDTIME(UTC_TIMESTAMP(6), '0:0:0.000001'), _me, _task, 'task', 'setRemainingTime', taskName, CAST(_timeRemaining as char character set utf8));
+      #take the existing members shares of the reassigned tasks off them and give all of their remaining time to the new member
+      INSERT INTO tempUpdatedMembers SELECT member, SUM(remainingTime) FROM taskMembers WHERE account = _account AND project = _project AND task IN (SELECT id FROM tempUpdatedIds) GROUP BY member;
+      UPDATE projectMembers pm
+        INNER JOIN tempUpdatedMembers tum
+        ON pm.account = _account AND pm.project = _project AND pm.id = tum.id
+        SET pm.totalRemainingTime = IF(pm.totalRemainingTime >= tum.totalRemainingTimeReduction, pm.totalRemainingTime - tum.totalRemainingTimeReduction, 0);
+      DELETE FROM taskMembers WHERE account = _account AND project = _project AND task IN (SELECT id FROM tempUpdatedIds);
+      IF _member IS NOT NULL THEN
+        INSERT INTO taskMembers (account, project, task, member, remainingTime) SELECT _account, _project, id, _member, totalRemainingTime FROM tasks WHERE account = _account AND project = _project AND id IN (SELECT id FROM tempUpdatedIds);
+        SELECT COALESCE(SUM(totalRemainingTime), 0) INTO totalRemainingTimeIncrease FROM tasks WHERE account = _account AND project = _project AND id IN (SELECT id FROM tempUpdatedIds);
+        UPDATE projectMembers SET totalRemainingTime = totalRemainingTime + totalRemainingTimeIncrease WHERE account = _account AND project = _project AND id = _member;
+        INSERT INTO tempUpdatedMembers VALUES (_member, 0) ON DUPLICATE KEY UPDATE id=id;
       END IF;
-      CALL _setAncestralChainAggregateValuesFromTask(_account, _project, nextTask);
+      #the parents are returned with the tasks so their children sets are refreshed too
+      INSERT INTO tempUpdatedParents SELECT DISTINCT parent FROM tasks WHERE account = _account AND project = _project AND id IN (SELECT id FROM tempUpdatedIds);
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo)
+        SELECT _account, _project, UTC_TIMESTAMP(6), _me, id, 'task', 'setMember', NULL, LOWER(HEX(_member)) FROM tempUpdatedIds;
     END IF;
   END IF;
   COMMIT;
-  SELECT id, existingMember, taskName FROM tempUpdatedIds;
-  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  SELECT id, 't' FROM tempUpdatedIds
+  UNION
+  SELECT id, 't' FROM tempUpdatedParents
+  UNION
+  SELECT id, 'm' FROM tempUpdatedMembers;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempBulkIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedParents;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+END;
+
+## adds _member to the task, pass NULL in _remainingTime to give them all of the tasks unassigned remaining time,
+## otherwise their share is _remainingTime and it is added on to the tasks totalRemainingTime
+DROP PROCEDURE IF EXISTS addTaskMember;
+CREATE PROCEDURE addTaskMember(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16), _member BINARY(16), _remainingTime BIGINT UNSIGNED)
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE memberExistsAndIsActive BOOL DEFAULT FALSE;
+  DECLARE taskExistsAndIsConcrete BOOL DEFAULT FALSE;
+  DECLARE alreadyAMember BOOL DEFAULT TRUE;
+  DECLARE taskName VARCHAR(250) DEFAULT '';
+  DECLARE taskParent BINARY(16) DEFAULT NULL;
+  DECLARE originalTotalRemainingTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE assignedRemainingTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE newTotalRemainingTime BIGINT UNSIGNED DEFAULT 0;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT COUNT(*)=1, name, parent, totalRemainingTime INTO taskExistsAndIsConcrete, taskName, taskParent, originalTotalRemainingTime FROM tasks WHERE account = _account AND project = _project AND id = _task AND isAbstract = FALSE;
+    SELECT COUNT(*)=1 INTO memberExistsAndIsActive FROM projectMembers WHERE account = _account AND project = _project AND id = _member AND isActive = TRUE AND role < 2 FOR UPDATE; #less than 2 means 0->projectAdmin or 1->projectWriter
+    SELECT COUNT(*)=1 INTO alreadyAMember FROM taskMembers WHERE account = _account AND project = _project AND task = _task AND member = _member;
+    IF taskExistsAndIsConcrete AND memberExistsAndIsActive AND NOT alreadyAMember THEN
+      IF _remainingTime IS NULL THEN
+        SELECT COALESCE(SUM(remainingTime), 0) INTO assignedRemainingTime FROM taskMembers WHERE account = _account AND project = _project AND task = _task;
+        SET _remainingTime = originalTotalRemainingTime - assignedRemainingTime;
+        SET newTotalRemainingTime = originalTotalRemainingTime;
+      ELSE
+        SET newTotalRemainingTime = originalTotalRemainingTime + _remainingTime;
+      END IF;
+      INSERT INTO taskMembers (account, project, task, member, remainingTime) VALUES (_account, _project, _task, _member, _remainingTime);
+      UPDATE projectMembers SET totalRemainingTime = totalRemainingTime + _remainingTime WHERE account = _account AND project = _project AND id = _member;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+        _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'addMember', taskName, LOWER(HEX(_member)));
+      INSERT INTO tempUpdatedIds VALUES (_task);
+      INSERT INTO tempUpdatedIds VALUES (taskParent) ON DUPLICATE KEY UPDATE id=id;
+      IF newTotalRemainingTime <> originalTotalRemainingTime THEN
+        UPDATE tasks SET totalRemainingTime = newTotalRemainingTime, minimumRemainingTime = newTotalRemainingTime WHERE account = _account AND project = _project AND id = _task;
+        INSERT INTO remainingTimeChanges (account, project, task, occurredOn, member, oldValue, newValue) VALUES (_account, _project, _task, UTC_TIMESTAMP(6), _me, originalTotalRemainingTime, newTotalRemainingTime);
+        CALL _setAncestralChainAggregateValuesFromTask(_account, _project, taskParent);
+      END IF;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT id FROM tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+END;
+
+## removes _member from the task, their share of the tasks remaining time is left on it unassigned
+DROP PROCEDURE IF EXISTS removeTaskMember;
+CREATE PROCEDURE removeTaskMember(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16), _member BINARY(16))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE isAMember BOOL DEFAULT FALSE;
+  DECLARE memberRemainingTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE taskName VARCHAR(250) DEFAULT '';
+  DECLARE taskParent BINARY(16) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT COUNT(*)=1, remainingTime INTO isAMember, memberRemainingTime FROM taskMembers WHERE account = _account AND project = _project AND task = _task AND member = _member;
+    IF isAMember THEN
+      SELECT name, parent INTO taskName, taskParent FROM tasks WHERE account = _account AND project = _project AND id = _task;
+      DELETE FROM taskMembers WHERE account = _account AND project = _project AND task = _task AND member = _member;
+      UPDATE projectMembers SET totalRemainingTime = IF(totalRemainingTime >= memberRemainingTime, totalRemainingTime - memberRemainingTime, 0) WHERE account = _account AND project = _project AND id = _member;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+        _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'removeMember', taskName, LOWER(HEX(_member)));
+      INSERT INTO tempUpdatedIds VALUES (_task);
+      INSERT INTO tempUpdatedIds VALUES (taskParent) ON DUPLICATE KEY UPDATE id=id;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT id FROM tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+END;
+
+## Pass NULL in _timeRemaining to not set a new remaining time value, pass NULL or zero to _duration to not log time,
+## _member is whose share of the tasks remaining time _timeRemaining sets, NULL means me when I'm one of the tasks members,
+## otherwise its only member when it has exactly one, otherwise the tasks unassigned remaining time
+## when _timerStartedOn is set the time is logged from _me's timer, which is deleted in the same transaction, and nothing is changed if the timer has already gone
+DROP PROCEDURE IF EXISTS setRemainingTimeAndOrLogTime;
+CREATE PROCEDURE setRemainingTimeAndOrLogTime(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me bINARY(16), _member BINARY(16), _timeRemaining BIGINT UNSIGNED, _timeLog BINARY(16), _loggedOn DATETIME, _duration BIGINT UNSIGNED, _note VARCHAR(250), _isBillable BOOL, _timerStartedOn DATETIME(6))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE timerExists BOOL DEFAULT TRUE;
+  DECLARE timeLogExists BOOL DEFAULT FALSE;
+  DECLARE taskExists BOOL DEFAULT FALSE;
+  DECLARE memberExists BOOL DEFAULT FALSE;
+  DECLARE timeLogHourlyRate BIGINT UNSIGNED DEFAULT 0;
+  DECLARE timeLogCost BIGINT UNSIGNED DEFAULT 0;
+  DECLARE memberIsAssigned BOOL DEFAULT TRUE;
+  DECLARE taskName VARCHAR(250) DEFAULT '';
+  DECLARE taskMemberCount BIGINT UNSIGNED DEFAULT 0;
+  DECLARE assignedRemainingTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE originalShare BIGINT UNSIGNED DEFAULT 0;
+  DECLARE updatedMember BINARY(16) DEFAULT NULL;
+  DECLARE nextTask BINARY(16) DEFAULT NULL;
+  DECLARE originalMinimumRemainingTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE newTotalRemainingTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists AND _timerStartedOn IS NOT NULL THEN
+    SELECT COUNT(*)=1 INTO timerExists FROM timers WHERE account = _account AND member = _me AND startedOn = _timerStartedOn FOR UPDATE;
+  END IF;
+  IF projectExists AND timerExists THEN
+    SELECT COUNT(*)=1, name, parent, totalRemainingTime INTO taskExists, taskName, nextTask, originalMinimumRemainingTime FROM tasks WHERE account =
+                                                                                                                                          _account AND project = _project AND id = _task AND isAbstract = FALSE;
+    SELECT COUNT(*), COALESCE(SUM(remainingTime), 0) INTO taskMemberCount, assignedRemainingTime FROM taskMembers WHERE account = _account AND project = _project AND task = _task;
+    IF _member IS NULL THEN
+      IF EXISTS(SELECT * FROM taskMembers WHERE account = _account AND project = _project AND task = _task AND member = _me) THEN
+        SET _member = _me;
+      ELSEIF taskMemberCount = 1 THEN
+        SELECT member INTO _member FROM taskMembers WHERE account = _account AND project = _project AND task = _task;
+      END IF;
+    END IF;
+    IF _member IS NOT NULL THEN
+      SELECT COUNT(*)=1, COALESCE(MAX(remainingTime), 0) INTO memberIsAssigned, originalShare FROM taskMembers WHERE account = _account AND project = _project AND task = _task AND member = _member;
+    ELSE
+      SET originalShare = originalMinimumRemainingTime - assignedRemainingTime;
+    END IF;
+    IF _timeRemaining IS NULL THEN
+      SET _timeRemaining = originalShare;
+    END IF;
+    SET newTotalRemainingTime = originalMinimumRemainingTime - originalShare + _timeRemaining;
+    IF _duration IS NULL THEN
+      SET _duration=0;
+    END IF;
+    #my time logs on a task clash in the timeLogs primary key if they are at the same second, so check before changing anything
+    IF _me IS NOT NULL AND _duration > 0 THEN
+      SELECT COUNT(*) > 0 INTO timeLogExists FROM timeLogs WHERE account = _account AND project = _project AND task = _task AND member = _me AND loggedOn = _loggedOn;
+    END IF;
+    IF taskExists AND memberIsAssigned AND NOT timeLogExists AND (originalShare <> _timeRemaining OR _duration > 0) THEN
+      SET changeMade = TRUE;
+      IF _me IS NOT NULL AND _duration > 0 THEN
+        SELECT COUNT(*)=1 INTO memberExists FROM projectMembers WHERE account = _account AND project = _project AND id = _me FOR UPDATE;
+        SELECT COALESCE((SELECT hourlyRate FROM projectMembers WHERE account = _account AND project = _project AND id = _me), (SELECT hourlyRate FROM accountMembers WHERE account = _account AND id = _me), 0) INTO timeLogHourlyRate;
+        SET timeLogCost = ROUND(_duration * timeLogHourlyRate / 60);
+        INSERT INTO timeLogs (account, project, task, id, member, loggedOn, taskName, duration, note, isBillable, hourlyRate, cost) VALUES (_account, _project, _task, _timeLog, _me, _loggedOn, taskName, _duration, _note, _isBillable, timeLogHourlyRate, timeLogCost);
+        UPDATE projectMembers SET totalLoggedTime=totalLoggedTime+_duration WHERE account = _account AND project = _project AND id = _me;
+        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+            _account, _project, UTC_TIMESTAMP(6), _me, _timeLog, 'timeLog', 'create', _note, CONCAT('{"duration":', CAST(_duration as char character set utf8), '}'));
+      END IF;
+      IF _timerStartedOn IS NOT NULL THEN
+        DELETE FROM timers WHERE account = _account AND member = _me AND startedOn = _timerStartedOn;
+      END IF;
+      IF _member IS NOT NULL AND originalShare <> _timeRemaining THEN
+        SET updatedMember = _member;
+        SELECT COUNT(*)=1 INTO memberExists FROM projectMembers WHERE account = _account AND project = _project AND id = _member FOR UPDATE;
+        UPDATE taskMembers SET remainingTime=_timeRemaining WHERE account = _account AND project = _project AND task = _task AND member = _member;
+        UPDATE projectMembers SET totalRemainingTime=totalRemainingTime+_timeRemaining - originalShare WHERE account = _account AND project = _project AND id = _member;
+      END IF;
+
+      UPDATE tasks SET totalRemainingTime=newTotalRemainingTime, minimumRemainingTime=newTotalRemainingTime, totalLoggedTime=totalLoggedTime+_duration, totalCost=totalCost+timeLogCost, totalRevenue=totalRevenue+IF(_isBillable, timeLogCost, 0) WHERE account =
+                                                                                                                                                           _account AND project = _project AND id = _task;
+      INSERT INTO tempUpdatedIds VALUES (_task) ON DUPLICATE KEY UPDATE id=id;
+      INSERT INTO tempUpdatedIds VALUES (nextTask) ON DUPLICATE KEY UPDATE id=id;
+      IF newTotalRemainingTime <> originalMinimumRemainingTime THEN
+        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+          _account, _project, ADDTIME(UTC_TIMESTAMP(6), '0:0:0.000001'), _me, _task, 'task', 'setRemainingTime', taskName, CAST(newTotalRemainingTime as char character set utf8));
+        INSERT INTO remainingTimeChanges (account, project, task, occurredOn, member, oldValue, newValue) VALUES (_account, _project, _task, UTC_TIMESTAMP(6), _me, originalMinimumRemainingTime, newTotalRemainingTime);
+      END IF;
+      CALL _setAncestralChainAggregateValuesFromTask(_account, _project, nextTask);
+    END IF;
+  END IF;
+  COMMIT;
+  IF timeLogExists THEN
+    SELECT _task, NULL, taskName, 0, 0, timeLogExists;
+  ELSE
+    SELECT id, updatedMember, taskName, COALESCE(timeLogHourlyRate, 0), COALESCE(ROUND(_duration * timeLogHourlyRate / 60), 0), timeLogExists FROM tempUpdatedIds;
+  END IF;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
 END;
 
 DROP PROCEDURE IF EXISTS moveTask;
@@ -812,6 +1766,7 @@ BEGIN
                 UPDATE tasks SET parent = _newParent, nextSibling =newNextSiblingId WHERE account = _account AND project = _project AND id = _task;
                 INSERT INTO tempUpdatedIds VALUES (_newPreviousSibling), (_task) ON DUPLICATE KEY UPDATE id =id;
               END IF;
+              CALL _setDependencyCommonAncestors(_account, _project, originalParentId);
               CALL _setAncestralChainAggregateValuesFromTask(_account, _project, _newParent);
               SET changeMade=TRUE;
             END IF;
@@ -829,6 +1784,134 @@ BEGIN
   DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
 END;
 
+## moves every task in _tasksStr, in the given order, to be a consecutive run of children of _newParent straight after _newPreviousSibling,
+## the tasks are all detached first so each affected ancestral chain is only recalculated once rather than once per task.
+DROP PROCEDURE IF EXISTS moveTasks;
+CREATE PROCEDURE moveTasks(_account BINARY(16), _project BINARY(16), _tasksStr VARCHAR(3200), _newParent BINARY(16), _me BINARY(16), _newPreviousSibling BINARY(16)) #3200 == 100 uuids
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE tasksStrLen INT DEFAULT LENGTH(_tasksStr);
+  DECLARE offset INT DEFAULT 0;
+  DECLARE taskCount INT DEFAULT 0;
+  DECLARE existingTaskCount INT DEFAULT 0;
+  DECLARE newParentExists BOOL DEFAULT FALSE;
+  DECLARE newParentIsValid BOOL DEFAULT TRUE;
+  DECLARE newPreviousSiblingExists BOOL DEFAULT TRUE;
+  DECLARE nextSiblingToUse BINARY(16) DEFAULT NULL;
+  DECLARE moveIdx INT DEFAULT 0;
+  DECLARE idVariable BINARY(16) DEFAULT NULL;
+  DECLARE originalParentId BINARY(16) DEFAULT NULL;
+  DECLARE originalNextSiblingId BINARY(16) DEFAULT NULL;
+  DECLARE originalPreviousSiblingId BINARY(16) DEFAULT NULL;
+  DECLARE taskName VARCHAR(250) DEFAULT '';
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempBulkIds;
+  CREATE TEMPORARY TABLE tempBulkIds(
+    idx INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (idx),
+    UNIQUE INDEX (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempOriginalParents;
+  CREATE TEMPORARY TABLE tempOriginalParents(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempDependencyParents;
+  CREATE TEMPORARY TABLE tempDependencyParents(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists AND tasksStrLen > 0 AND tasksStrLen % 32 = 0 THEN
+    WHILE offset < tasksStrLen DO
+      INSERT INTO tempBulkIds (id) VALUE (UNHEX(SUBSTRING(_tasksStr, offset + 1, 32)));
+      SET offset = offset + 32;
+    END WHILE;
+    SELECT COUNT(*) INTO taskCount FROM tempBulkIds;
+    SELECT COUNT(*) INTO existingTaskCount FROM tasks WHERE account = _account AND project = _project AND id IN (SELECT id FROM tempBulkIds) AND id <> _project;
+    SELECT COUNT(*)=1 INTO newParentExists FROM tasks WHERE account = _account AND project = _project AND id = _newParent AND isAbstract = TRUE;
+    IF _newPreviousSibling IS NOT NULL THEN
+      SELECT COUNT(*)=1 INTO newPreviousSiblingExists FROM tasks WHERE account = _account AND project = _project AND id = _newPreviousSibling AND parent = _newParent AND id NOT IN (SELECT id FROM tempBulkIds);
+    END IF;
+    IF newParentExists THEN #make sure we're not trying to make any of the tasks a descendant of itself
+      SET idVariable = _newParent;
+      WHILE idVariable IS NOT NULL AND newParentIsValid DO
+        SELECT COUNT(*)=0 INTO newParentIsValid FROM tempBulkIds WHERE id = idVariable;
+        SELECT parent INTO idVariable FROM tasks WHERE account = _account AND project = _project AND id = idVariable;
+      END WHILE;
+    END IF;
+    IF taskCount = existingTaskCount AND newParentExists AND newParentIsValid AND newPreviousSiblingExists THEN
+      #detach every task from its original position, a detached tasks nextSibling is cleared so it can't be mistaken for the previous sibling of the next task detached
+      SET moveIdx = 1;
+      WHILE moveIdx <= taskCount DO
+        SELECT id INTO idVariable FROM tempBulkIds WHERE idx = moveIdx;
+        SELECT parent, nextSibling, name INTO originalParentId, originalNextSiblingId, taskName FROM tasks WHERE account = _account AND project = _project AND id = idVariable;
+        SET originalPreviousSiblingId = NULL;
+        SELECT id INTO originalPreviousSiblingId FROM tasks WHERE account = _account AND project = _project AND nextSibling = idVariable;
+        IF originalPreviousSiblingId IS NULL THEN
+          UPDATE tasks SET firstChild = originalNextSiblingId WHERE account = _account AND project = _project AND id = originalParentId;
+        ELSE
+          UPDATE tasks SET nextSibling = originalNextSiblingId WHERE account = _account AND project = _project AND id = originalPreviousSiblingId;
+          INSERT INTO tempUpdatedIds VALUES (originalPreviousSiblingId) ON DUPLICATE KEY UPDATE id=id;
+        END IF;
+        UPDATE tasks SET parent = NULL, nextSibling = NULL WHERE account = _account AND project = _project AND id = idVariable;
+        INSERT INTO tempOriginalParents VALUES (originalParentId) ON DUPLICATE KEY UPDATE id=id;
+        INSERT INTO tempUpdatedIds VALUES (idVariable), (originalParentId) ON DUPLICATE KEY UPDATE id=id;
+        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+          _account, _project, UTC_TIMESTAMP(6), _me, idVariable, 'task', 'move', taskName, NULL);
+        SET moveIdx = moveIdx + 1;
+      END WHILE;
+      #recalculate each original ancestral chain once, the new parents chain is recalculated after the tasks are attached
+      INSERT INTO tempDependencyParents SELECT id FROM tempOriginalParents;
+      DELETE FROM tempOriginalParents WHERE id = _newParent;
+      WHILE (SELECT COUNT(*) FROM tempOriginalParents) > 0 DO
+        SELECT id INTO idVariable FROM tempOriginalParents LIMIT 1;
+        CALL _setAncestralChainAggregateValuesFromTask(_account, _project, idVariable);
+        DELETE FROM tempOriginalParents WHERE id = idVariable;
+      END WHILE;
+      #attach the tasks under the new parent, building the run backwards from its last task
+      IF _newPreviousSibling IS NULL THEN
+        SELECT firstChild INTO nextSiblingToUse FROM tasks WHERE account = _account AND project = _project AND id = _newParent;
+      ELSE
+        SELECT nextSibling INTO nextSiblingToUse FROM tasks WHERE account = _account AND project = _project AND id = _newPreviousSibling;
+      END IF;
+      SET moveIdx = taskCount;
+      WHILE moveIdx > 0 DO
+        SELECT id INTO idVariable FROM tempBulkIds WHERE idx = moveIdx;
+        UPDATE tasks SET parent = _newParent, nextSibling = nextSiblingToUse WHERE account = _account AND project = _project AND id = idVariable;
+        SET nextSiblingToUse = idVariable;
+        SET moveIdx = moveIdx - 1;
+      END WHILE;
+      IF _newPreviousSibling IS NULL THEN
+        UPDATE tasks SET firstChild = nextSiblingToUse WHERE account = _account AND project = _project AND id = _newParent;
+      ELSE
+        UPDATE tasks SET nextSibling = nextSiblingToUse WHERE account = _account AND project = _project AND id = _newPreviousSibling;
+        INSERT INTO tempUpdatedIds VALUES (_newPreviousSibling) ON DUPLICATE KEY UPDATE id=id;
+      END IF;
+      WHILE (SELECT COUNT(*) FROM tempDependencyParents) > 0 DO
+        SELECT id INTO idVariable FROM tempDependencyParents LIMIT 1;
+        CALL _setDependencyCommonAncestors(_account, _project, idVariable);
+        DELETE FROM tempDependencyParents WHERE id = idVariable;
+      END WHILE;
+      CALL _setAncestralChainAggregateValuesFromTask(_account, _project, _newParent);
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT id FROM tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempBulkIds;
+  DROP TEMPORARY TABLE IF EXISTS tempOriginalParents;
+  DROP TEMPORARY TABLE IF EXISTS tempDependencyParents;
+END;
+
+## moves the _task subtree in to the trash, the tasks comments, files and labels are kept with it so they come back if it is restored,
+## dependencies on or from the subtree are removed.
 DROP PROCEDURE IF EXISTS deleteTask;
 CREATE PROCEDURE deleteTask(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16))
 BEGIN
@@ -842,6 +1925,7 @@ BEGIN
   DECLARE originalTotalLoggedTime BIGINT UNSIGNED DEFAULT 0;
   DECLARE originalMinimumRemainingTime BIGINT UNSIGNED DEFAULT 0;
   DECLARE deleteCount BIGINT UNSIGNED DEFAULT 0;
+  DECLARE taskIsAbstract BOOL DEFAULT FALSE;
   DECLARE taskName VARCHAR(250) DEFAULT '';
   DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
   CREATE TEMPORARY TABLE tempUpdatedIds(
@@ -869,12 +1953,17 @@ BEGIN
     totalRemainingTimeReduction BIGINT UNSIGNED NOT NULL,
     PRIMARY KEY (id)
   );
+  DROP TEMPORARY TABLE IF EXISTS tempDependencyPeers;
+  CREATE TEMPORARY TABLE tempDependencyPeers(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
   START TRANSACTION;
 
   IF _project <> _task THEN
     SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account=_account AND id = _project FOR UPDATE;
     IF projectExists THEN
-      SELECT COUNT(*)=1, parent, descendantCount, totalRemainingTime, totalLoggedTime, minimumRemainingTime, nextSibling, name INTO taskExists, originalParentId, originalDescendantCount, originalTotalRemainingTime, originalTotalLoggedTime, originalMinimumRemainingTime, originalNextSiblingId, taskName FROM tasks WHERE account=_account AND project =
+      SELECT COUNT(*)=1, parent, descendantCount, totalRemainingTime, totalLoggedTime, minimumRemainingTime, nextSibling, isAbstract, name INTO taskExists, originalParentId, originalDescendantCount, originalTotalRemainingTime, originalTotalLoggedTime, originalMinimumRemainingTime, originalNextSiblingId, taskIsAbstract, taskName FROM tasks WHERE account=_account AND project =
                                                                                                                                                                                                                                                                                                                                                       _project AND id = _task;
       IF taskExists THEN
         SELECT id INTO originalPreviousSiblingId FROM tasks WHERE account=_account AND project = _project AND nextSibling = _task;
@@ -894,17 +1983,29 @@ BEGIN
             UPDATE tasks SET nextSibling = originalNextSiblingId WHERE account=_account AND project = _project AND id = originalPreviousSiblingId;
           END IF;
           #update all the projectMembers who totalRemainingTimes have been reduced by having tasks they were assigned to deleted
-          INSERT INTO tempUpdatedMembers SELECT member, SUM(totalRemainingTime) FROM tasks WHERE account=_account AND project=_project AND id IN (SELECT id FROM tempAllIds) AND member IS NOT NULL GROUP BY member;
+          INSERT INTO tempUpdatedMembers SELECT member, SUM(remainingTime) FROM taskMembers WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds) GROUP BY member;
           UPDATE projectMembers pm
             INNER JOIN  tempUpdatedMembers tum
             ON pm.account=_account AND pm.project=_project AND pm.id=tum.id
             SET pm.totalRemainingTime=pm.totalRemainingTime-tum.totalRemainingTimeReduction;
-          #delete the tasks
+          #record the deleted tasks remaining time dropping to zero so the project burndown still adds up
+          INSERT INTO remainingTimeChanges (account, project, task, occurredOn, member, oldValue, newValue) SELECT _account, _project, id, UTC_TIMESTAMP(6), _me, totalRemainingTime, 0 FROM tasks WHERE account=_account AND project=_project AND id IN (SELECT id FROM tempAllIds) AND isAbstract=FALSE AND totalRemainingTime > 0;
+          #move the tasks in to the trash
+          INSERT INTO trash (account, project, id, deletedOn, member, parent, previousSibling, isAbstract, name, totalRemainingTime, totalLoggedTime, descendantCount) VALUES (_account, _project, _task, UTC_TIMESTAMP(6), _me, originalParentId, originalPreviousSiblingId, taskIsAbstract, taskName, originalTotalRemainingTime, originalTotalLoggedTime, originalDescendantCount);
+          INSERT INTO trashedTasks (account, project, trash, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state) SELECT account, project, _task, id, parent, firstChild, IF(id=_task, NULL, nextSibling), isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state FROM tasks WHERE account=_account AND project=_project AND id IN (SELECT id FROM tempAllIds);
+          INSERT INTO trashedTaskMembers (account, project, trash, task, member, remainingTime) SELECT account, project, _task, task, member, remainingTime FROM taskMembers WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds);
+          DELETE FROM taskMembers WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds);
           DELETE FROM tasks WHERE account=_account AND project=_project AND id IN (SELECT id FROM tempAllIds);
           INSERT INTO tempUpdatedIds SELECT id FROM tempAllIds tmpAll ON DUPLICATE KEY UPDATE id=tmpAll.id;
           INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'delete', taskName, CONCAT('{"totalRemainingTime":', CAST(originalTotalRemainingTime as char character set utf8), ',"totalLoggedTime":', CAST(originalTotalLoggedTime as char character set utf8), ',"descendantCount":', CAST(originalDescendantCount as char character set utf8), '}'));
           UPDATE projectActivities SET itemHasBeenDeleted=TRUE WHERE account=_account AND project=_project AND item IN (SELECT id FROM tempAllIds);
           UPDATE timeLogs SET taskHasBeenDeleted=TRUE WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds);
+          #remove any dependencies on or from the deleted tasks, remembering the surviving tasks on the other end of them
+          INSERT INTO tempDependencyPeers SELECT task FROM taskDependencies WHERE account=_account AND project=_project AND dependsOn IN (SELECT id FROM tempAllIds) ON DUPLICATE KEY UPDATE id=id;
+          INSERT INTO tempDependencyPeers SELECT dependsOn FROM taskDependencies WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds) ON DUPLICATE KEY UPDATE id=id;
+          DELETE FROM tempDependencyPeers WHERE id IN (SELECT id FROM tempAllIds);
+          DELETE FROM taskDependencies WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds);
+          DELETE FROM taskDependencies WHERE account=_account AND project=_project AND dependsOn IN (SELECT id FROM tempAllIds);
           CALL _setAncestralChainAggregateValuesFromTask(_account, _project, originalParentId);
         END IF;
       END IF;
@@ -916,190 +2017,1777 @@ BEGIN
   UNION
   SELECT id, id, id, 'm' FROM tempUpdatedMembers
   UNION
+  SELECT id, id, id, 'd' FROM tempDependencyPeers
+  UNION
   SELECT id, task, member, 'tl' FROM timeLogs WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds);
   DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
   DROP TEMPORARY TABLE IF EXISTS tempAllIds;
   DROP TEMPORARY TABLE IF EXISTS tempCurrentIds;
   DROP TEMPORARY TABLE IF EXISTS tempLatestIds;
   DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+  DROP TEMPORARY TABLE IF EXISTS tempDependencyPeers;
 END;
 
-DROP PROCEDURE IF EXISTS getTasks;
-CREATE PROCEDURE getTasks(_account BINARY(16), _project BINARY(16), _taskIdsStr VARCHAR(16000)) #16000 == 500 uuids
+## moves every task in _tasksStr along with their descendants in to the trash, tasks may be listed along with their ancestors,
+## each listed task without a listed ancestor becomes its own trash item and each affected ancestral chain is only recalculated once.
+DROP PROCEDURE IF EXISTS deleteTasks;
+CREATE PROCEDURE deleteTasks(_account BINARY(16), _project BINARY(16), _tasksStr VARCHAR(3200), _me BINARY(16)) #3200 == 100 uuids
 BEGIN
   DECLARE projectExists BOOL DEFAULT FALSE;
-  DECLARE taskIdsStrLen INT DEFAULT LENGTH(_taskIdsStr);
+  DECLARE tasksStrLen INT DEFAULT LENGTH(_tasksStr);
   DECLARE offset INT DEFAULT 0;
-  DROP TEMPORARY TABLE IF EXISTS tempIds;
-  CREATE TEMPORARY TABLE tempIds(
+  DECLARE taskCount INT DEFAULT 0;
+  DECLARE existingTaskCount INT DEFAULT 0;
+  DECLARE rootCount INT DEFAULT 0;
+  DECLARE rootIdx INT DEFAULT 0;
+  DECLARE idVariable BINARY(16) DEFAULT NULL;
+  DECLARE originalParentId BINARY(16) DEFAULT NULL;
+  DECLARE originalNextSiblingId BINARY(16) DEFAULT NULL;
+  DECLARE originalPreviousSiblingId BINARY(16) DEFAULT NULL;
+  DECLARE originalDescendantCount BIGINT UNSIGNED DEFAULT 0;
+  DECLARE originalTotalRemainingTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE originalTotalLoggedTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE taskIsAbstract BOOL DEFAULT FALSE;
+  DECLARE taskName VARCHAR(250) DEFAULT '';
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempBulkIds;
+  CREATE TEMPORARY TABLE tempBulkIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  #the listed tasks that don't have a listed ancestor, only these need detaching from the tree
+  DROP TEMPORARY TABLE IF EXISTS tempRootIds;
+  CREATE TEMPORARY TABLE tempRootIds(
+    idx INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (idx),
+    UNIQUE INDEX (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempOriginalParents;
+  CREATE TEMPORARY TABLE tempOriginalParents(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempAllIds;
+  CREATE TEMPORARY TABLE tempAllIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempCurrentIds;
+  CREATE TEMPORARY TABLE tempCurrentIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempLatestIds;
+  CREATE TEMPORARY TABLE tempLatestIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+  CREATE TEMPORARY TABLE tempUpdatedMembers(
+    id BINARY(16) NOT NULL,
+    totalRemainingTimeReduction BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempDependencyPeers;
+  CREATE TEMPORARY TABLE tempDependencyPeers(
     id BINARY(16) NOT NULL,
     PRIMARY KEY (id)
   );
   START TRANSACTION;
-
-  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project;# LOCK IN SHARE MODE; I dont think this is required
-  IF projectExists AND taskIdsStrLen > 0 AND taskIdsStrLen % 32 = 0 THEN
-    WHILE offset < taskIdsStrLen DO
-      INSERT INTO tempIds VALUE (UNHEX(SUBSTRING(_taskIdsStr, offset + 1, 32)));
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account=_account AND id = _project FOR UPDATE;
+  IF projectExists AND tasksStrLen > 0 AND tasksStrLen % 32 = 0 THEN
+    WHILE offset < tasksStrLen DO
+      INSERT INTO tempBulkIds VALUE (UNHEX(SUBSTRING(_tasksStr, offset + 1, 32)));
       SET offset = offset + 32;
     END WHILE;
+    SELECT COUNT(*) INTO taskCount FROM tempBulkIds;
+    SELECT COUNT(*) INTO existingTaskCount FROM tasks WHERE account=_account AND project=_project AND id IN (SELECT id FROM tempBulkIds) AND id <> _project;
+    IF taskCount = existingTaskCount THEN
+      INSERT INTO tempCurrentIds SELECT id FROM tempBulkIds;
+      WHILE (SELECT COUNT(*) FROM tempCurrentIds) > 0 DO
+        INSERT INTO tempAllIds SELECT id FROM tempCurrentIds;
+        INSERT INTO tempLatestIds SELECT id FROM tasks WHERE account=_account AND project = _project AND parent IN (SELECT id FROM tempCurrentIds) AND id NOT IN (SELECT id FROM tempAllIds);
+        TRUNCATE tempCurrentIds;
+        INSERT INTO tempCurrentIds SELECT id FROM tempLatestIds;
+        TRUNCATE tempLatestIds;
+      END WHILE;
+      INSERT INTO tempRootIds (id) SELECT id FROM tasks WHERE account=_account AND project=_project AND id IN (SELECT id FROM tempBulkIds) AND parent NOT IN (SELECT id FROM tempAllIds);
+      SELECT COUNT(*) INTO rootCount FROM tempRootIds;
+      #detach each root, a detached roots nextSibling is cleared so it can't be mistaken for the previous sibling of the next root detached
+      SET rootIdx = 1;
+      WHILE rootIdx <= rootCount DO
+        SELECT id INTO idVariable FROM tempRootIds WHERE idx = rootIdx;
+        SELECT parent, descendantCount, totalRemainingTime, totalLoggedTime, nextSibling, isAbstract, name INTO originalParentId, originalDescendantCount, originalTotalRemainingTime, originalTotalLoggedTime, originalNextSiblingId, taskIsAbstract, taskName FROM tasks WHERE account=_account AND project=_project AND id = idVariable;
+        SET originalPreviousSiblingId = NULL;
+        SELECT id INTO originalPreviousSiblingId FROM tasks WHERE account=_account AND project = _project AND nextSibling = idVariable;
+        IF originalPreviousSiblingId IS NULL THEN
+          UPDATE tasks SET firstChild = originalNextSiblingId WHERE account=_account AND project = _project AND id = originalParentId;
+        ELSE
+          UPDATE tasks SET nextSibling = originalNextSiblingId WHERE account=_account AND project = _project AND id = originalPreviousSiblingId;
+          INSERT INTO tempUpdatedIds VALUES (originalPreviousSiblingId) ON DUPLICATE KEY UPDATE id=id;
+        END IF;
+        UPDATE tasks SET nextSibling = NULL WHERE account=_account AND project = _project AND id = idVariable;
+        INSERT INTO tempOriginalParents VALUES (originalParentId) ON DUPLICATE KEY UPDATE id=id;
+        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, idVariable, 'task', 'delete', taskName, CONCAT('{"totalRemainingTime":', CAST(originalTotalRemainingTime as char character set utf8), ',"totalLoggedTime":', CAST(originalTotalLoggedTime as char character set utf8), ',"descendantCount":', CAST(originalDescendantCount as char character set utf8), '}'));
+        #move the roots subtree in to the trash as its own item
+        INSERT INTO trash (account, project, id, deletedOn, member, parent, previousSibling, isAbstract, name, totalRemainingTime, totalLoggedTime, descendantCount) VALUES (_account, _project, idVariable, UTC_TIMESTAMP(6), _me, originalParentId, originalPreviousSiblingId, taskIsAbstract, taskName, originalTotalRemainingTime, originalTotalLoggedTime, originalDescendantCount);
+        INSERT INTO tempCurrentIds VALUES (idVariable);
+        WHILE (SELECT COUNT(*) FROM tempCurrentIds) > 0 DO
+          INSERT INTO trashedTasks (account, project, trash, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state) SELECT account, project, idVariable, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state FROM tasks WHERE account=_account AND project=_project AND id IN (SELECT id FROM tempCurrentIds);
+          INSERT INTO trashedTaskMembers (account, project, trash, task, member, remainingTime) SELECT account, project, idVariable, task, member, remainingTime FROM taskMembers WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempCurrentIds);
+          INSERT INTO tempLatestIds SELECT id FROM tasks WHERE account=_account AND project = _project AND parent IN (SELECT id FROM tempCurrentIds);
+          TRUNCATE tempCurrentIds;
+          INSERT INTO tempCurrentIds SELECT id FROM tempLatestIds;
+          TRUNCATE tempLatestIds;
+        END WHILE;
+        SET rootIdx = rootIdx + 1;
+      END WHILE;
+      #update all the projectMembers who totalRemainingTimes have been reduced by having tasks they were assigned to deleted
+      INSERT INTO tempUpdatedMembers SELECT member, SUM(remainingTime) FROM taskMembers WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds) GROUP BY member;
+      UPDATE projectMembers pm
+        INNER JOIN  tempUpdatedMembers tum
+        ON pm.account=_account AND pm.project=_project AND pm.id=tum.id
+        SET pm.totalRemainingTime=pm.totalRemainingTime-tum.totalRemainingTimeReduction;
+      #record the deleted tasks remaining time dropping to zero so the project burndown still adds up
+      INSERT INTO remainingTimeChanges (account, project, task, occurredOn, member, oldValue, newValue) SELECT _account, _project, id, UTC_TIMESTAMP(6), _me, totalRemainingTime, 0 FROM tasks WHERE account=_account AND project=_project AND id IN (SELECT id FROM tempAllIds) AND isAbstract=FALSE AND totalRemainingTime > 0;
+      #remove the trashed tasks from the tree
+      DELETE FROM taskMembers WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds);
+      DELETE FROM tasks WHERE account=_account AND project=_project AND id IN (SELECT id FROM tempAllIds);
+      INSERT INTO tempUpdatedIds SELECT id FROM tempAllIds tmpAll ON DUPLICATE KEY UPDATE id=tmpAll.id;
+      UPDATE projectActivities SET itemHasBeenDeleted=TRUE WHERE account=_account AND project=_project AND item IN (SELECT id FROM tempAllIds);
+      UPDATE timeLogs SET taskHasBeenDeleted=TRUE WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds);
+      #remove any dependencies on or from the deleted tasks, remembering the surviving tasks on the other end of them
+      INSERT INTO tempDependencyPeers SELECT task FROM taskDependencies WHERE account=_account AND project=_project AND dependsOn IN (SELECT id FROM tempAllIds) ON DUPLICATE KEY UPDATE id=id;
+      INSERT INTO tempDependencyPeers SELECT dependsOn FROM taskDependencies WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds) ON DUPLICATE KEY UPDATE id=id;
+      DELETE FROM tempDependencyPeers WHERE id IN (SELECT id FROM tempAllIds);
+      DELETE FROM taskDependencies WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds);
+      DELETE FROM taskDependencies WHERE account=_account AND project=_project AND dependsOn IN (SELECT id FROM tempAllIds);
+      #recalculate each original ancestral chain once
+      WHILE (SELECT COUNT(*) FROM tempOriginalParents) > 0 DO
+        SELECT id INTO idVariable FROM tempOriginalParents LIMIT 1;
+        CALL _setAncestralChainAggregateValuesFromTask(_account, _project, idVariable);
+        DELETE FROM tempOriginalParents WHERE id = idVariable;
+      END WHILE;
+    END IF;
   END IF;
   COMMIT;
-  SELECT id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, member FROM tasks WHERE account = _account AND project = _project AND id IN (SELECT id FROM tempIds);
-  DROP TEMPORARY TABLE IF EXISTS tempIds;
+  SELECT id, id, id, 't' FROM tempUpdatedIds
+  UNION
+  SELECT id, id, id, 'm' FROM tempUpdatedMembers
+  UNION
+  SELECT id, id, id, 'd' FROM tempDependencyPeers
+  UNION
+  SELECT id, task, member, 'tl' FROM timeLogs WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempAllIds);
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempBulkIds;
+  DROP TEMPORARY TABLE IF EXISTS tempRootIds;
+  DROP TEMPORARY TABLE IF EXISTS tempOriginalParents;
+  DROP TEMPORARY TABLE IF EXISTS tempAllIds;
+  DROP TEMPORARY TABLE IF EXISTS tempCurrentIds;
+  DROP TEMPORARY TABLE IF EXISTS tempLatestIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+  DROP TEMPORARY TABLE IF EXISTS tempDependencyPeers;
 END;
 
-DROP PROCEDURE IF EXISTS getChildTasks;
-CREATE PROCEDURE getChildTasks(_account BINARY(16), _project BINARY(16), _parent BINARY(16), _fromSibling BINARY(16), _limit INT)
+## restores the trashed _task subtree under _newParent after _newPreviousSibling, or to where it was deleted from when _newParent is NULL,
+## in which case it becomes the first child if its original previous sibling has since been moved or deleted, members who are no
+## longer active project writers are unassigned from the restored tasks.
+DROP PROCEDURE IF EXISTS restoreTask;
+CREATE PROCEDURE restoreTask(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16), _newParent BINARY(16), _newPreviousSibling BINARY(16))
 BEGIN
   DECLARE projectExists BOOL DEFAULT FALSE;
-  DECLARE idVariable BINARY(16) DEFAULT NULL;
-  DECLARE idx INT DEFAULT 0;
-  DROP TEMPORARY TABLE IF EXISTS tempResult;
-  CREATE TEMPORARY TABLE tempResult(
-    selectOrder INT NOT NULL,
+  DECLARE trashExists BOOL DEFAULT FALSE;
+  DECLARE newParentIsValid BOOL DEFAULT FALSE;
+  DECLARE newPreviousSiblingIsValid BOOL DEFAULT FALSE;
+  DECLARE originalParentId BINARY(16) DEFAULT NULL;
+  DECLARE originalPreviousSiblingId BINARY(16) DEFAULT NULL;
+  DECLARE newNextSiblingId BINARY(16) DEFAULT NULL;
+  DECLARE taskName VARCHAR(250) DEFAULT '';
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
     id BINARY(16) NOT NULL,
-    parent BINARY(16) NULL,
-    firstChild BINARY(16) NULL,
-    nextSibling BINARY(16) NULL,
-    isAbstract BOOL NOT NULL,
-    name VARCHAR(250) NOT NULL,
-    description VARCHAR(1250) NULL,
-    createdOn DATETIME NOT NULL,
-    totalRemainingTime BIGINT UNSIGNED NOT NULL,
-    totalLoggedTime BIGINT UNSIGNED NOT NULL,
-    minimumRemainingTime BIGINT UNSIGNED NOT NULL,
-    linkedFileCount INT UNSIGNED NOT NULL,
-    chatCount BIGINT UNSIGNED NOT NULL,
-    childCount BIGINT UNSIGNED NOT NULL,
-    descendantCount BIGINT UNSIGNED NOT NULL,
-    isParallel BOOL NOT NULL DEFAULT FALSE,
-    member BINARY(16) NULL,
-    PRIMARY KEY (selectOrder)
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempRestoredIds;
+  CREATE TEMPORARY TABLE tempRestoredIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+  CREATE TEMPORARY TABLE tempUpdatedMembers(
+    id BINARY(16) NOT NULL,
+    totalRemainingTimeIncrease BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (id)
   );
   START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account=_account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT COUNT(*)=1, parent, previousSibling, name INTO trashExists, originalParentId, originalPreviousSiblingId, taskName FROM trash WHERE account=_account AND project=_project AND id=_task;
+    IF trashExists THEN
+      IF _newParent IS NULL THEN
+        SET _newParent = originalParentId;
+        SET _newPreviousSibling = NULL;
+        SELECT COUNT(*)=1 INTO newPreviousSiblingIsValid FROM tasks WHERE account=_account AND project=_project AND id=originalPreviousSiblingId AND parent=_newParent;
+        IF newPreviousSiblingIsValid THEN
+          SET _newPreviousSibling = originalPreviousSiblingId;
+        END IF;
+        SET newPreviousSiblingIsValid = TRUE;
+      ELSEIF _newPreviousSibling IS NULL THEN
+        SET newPreviousSiblingIsValid = TRUE;
+      ELSE
+        SELECT COUNT(*)=1 INTO newPreviousSiblingIsValid FROM tasks WHERE account=_account AND project=_project AND id=_newPreviousSibling AND parent=_newParent;
+      END IF;
+      SELECT COUNT(*)=1 INTO newParentIsValid FROM tasks WHERE account=_account AND project=_project AND id=_newParent AND isAbstract=TRUE;
+      IF newParentIsValid AND newPreviousSiblingIsValid THEN
+        IF _newPreviousSibling IS NULL THEN
+          SELECT firstChild INTO newNextSiblingId FROM tasks WHERE account=_account AND project=_project AND id=_newParent;
+          UPDATE tasks SET firstChild=_task WHERE account=_account AND project=_project AND id=_newParent;
+        ELSE
+          SELECT nextSibling INTO newNextSiblingId FROM tasks WHERE account=_account AND project=_project AND id=_newPreviousSibling;
+          UPDATE tasks SET nextSibling=_task WHERE account=_account AND project=_project AND id=_newPreviousSibling;
+          INSERT INTO tempUpdatedIds VALUES (_newPreviousSibling);
+        END IF;
+        INSERT INTO tempRestoredIds SELECT id FROM trashedTasks WHERE account=_account AND project=_project AND trash=_task;
+        #members may have left the project or lost write access while the subtree was in the trash
+        DELETE FROM trashedTaskMembers WHERE account=_account AND project=_project AND trash=_task AND member NOT IN (SELECT id FROM projectMembers WHERE account=_account AND project=_project AND isActive=TRUE AND role<2);
+        #comments and files can still be deleted while their task is in the trash
+        UPDATE trashedTasks tt SET chatCount=(SELECT COUNT(*) FROM comments c WHERE c.account=_account AND c.project=_project AND c.task=tt.id), linkedFileCount=(SELECT COUNT(*) FROM files f WHERE f.account=_account AND f.project=_project AND f.task=tt.id) WHERE tt.account=_account AND tt.project=_project AND tt.trash=_task;
+        INSERT INTO tasks (account, project, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state) SELECT account, project, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state FROM trashedTasks WHERE account=_account AND project=_project AND trash=_task;
+        INSERT INTO taskMembers (account, project, task, member, remainingTime) SELECT account, project, task, member, remainingTime FROM trashedTaskMembers WHERE account=_account AND project=_project AND trash=_task;
+        UPDATE tasks SET parent=_newParent, nextSibling=newNextSiblingId WHERE account=_account AND project=_project AND id=_task;
+        #give the restored tasks remaining time back to their members and the project burndown
+        INSERT INTO tempUpdatedMembers SELECT member, SUM(remainingTime) FROM trashedTaskMembers WHERE account=_account AND project=_project AND trash=_task GROUP BY member;
+        UPDATE projectMembers pm
+          INNER JOIN  tempUpdatedMembers tum
+          ON pm.account=_account AND pm.project=_project AND pm.id=tum.id
+          SET pm.totalRemainingTime=pm.totalRemainingTime+tum.totalRemainingTimeIncrease;
+        INSERT INTO remainingTimeChanges (account, project, task, occurredOn, member, oldValue, newValue) SELECT _account, _project, id, UTC_TIMESTAMP(6), _me, 0, totalRemainingTime FROM tasks WHERE account=_account AND project=_project AND id IN (SELECT id FROM tempRestoredIds) AND isAbstract=FALSE AND totalRemainingTime > 0;
+        UPDATE timeLogs SET taskHasBeenDeleted=FALSE WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempRestoredIds);
+        UPDATE projectActivities SET itemHasBeenDeleted=FALSE WHERE account=_account AND project=_project AND item IN (SELECT id FROM tempRestoredIds);
+        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'restore', taskName, LOWER(HEX(_newParent)));
+        DELETE FROM trashedTaskMembers WHERE account=_account AND project=_project AND trash=_task;
+        DELETE FROM trashedTasks WHERE account=_account AND project=_project AND trash=_task;
+        DELETE FROM trash WHERE account=_account AND project=_project AND id=_task;
+        INSERT INTO tempUpdatedIds SELECT id FROM tempRestoredIds tmpRes ON DUPLICATE KEY UPDATE id=tmpRes.id;
+        CALL _setAncestralChainAggregateValuesFromTask(_account, _project, _newParent);
+      END IF;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT id, id, id, 't' FROM tempUpdatedIds
+  UNION
+  SELECT id, id, id, 'm' FROM tempUpdatedMembers
+  UNION
+  SELECT id, task, member, 'tl' FROM timeLogs WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempRestoredIds);
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempRestoredIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+END;
+
+## permanently deletes every trash item in the project that was deleted before _before, along with the comments, files, labels
+## and state counts of its tasks, called by the trash purger which removes the stored file data using the returned file ids.
+DROP PROCEDURE IF EXISTS purgeTrash;
+CREATE PROCEDURE purgeTrash(_account BINARY(16), _project BINARY(16), _before DATETIME(6))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE deletedFileCount BIGINT UNSIGNED DEFAULT 0;
+  DECLARE deletedFileSize BIGINT UNSIGNED DEFAULT 0;
+  DROP TEMPORARY TABLE IF EXISTS tempPurgedTrash;
+  CREATE TEMPORARY TABLE tempPurgedTrash(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempPurgedIds;
+  CREATE TEMPORARY TABLE tempPurgedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempDeletedFiles;
+  CREATE TEMPORARY TABLE tempDeletedFiles(
+    id BINARY(16) NOT NULL,
+    task BINARY(16) NOT NULL,
+    size BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account=_account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    INSERT INTO tempPurgedTrash SELECT id FROM trash WHERE account=_account AND project=_project AND deletedOn < _before;
+    INSERT INTO tempPurgedIds SELECT id FROM trashedTasks WHERE account=_account AND project=_project AND trash IN (SELECT id FROM tempPurgedTrash);
+    #comments are deleted along with their task
+    UPDATE projectActivities SET itemHasBeenDeleted=TRUE WHERE account=_account AND project=_project AND item IN (SELECT id FROM comments WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempPurgedIds));
+    DELETE FROM commentMentions WHERE account=_account AND project=_project AND comment IN (SELECT id FROM comments WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempPurgedIds));
+    DELETE FROM comments WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempPurgedIds);
+    #files are deleted along with their task
+    INSERT INTO tempDeletedFiles SELECT id, task, size FROM files WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempPurgedIds);
+    DELETE FROM files WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempPurgedIds);
+    UPDATE projectActivities SET itemHasBeenDeleted=TRUE WHERE account=_account AND project=_project AND item IN (SELECT id FROM tempDeletedFiles);
+    SELECT COUNT(*), COALESCE(SUM(size), 0) INTO deletedFileCount, deletedFileSize FROM tempDeletedFiles;
+    UPDATE projects SET fileCount=fileCount-deletedFileCount, fileSize=fileSize-deletedFileSize WHERE account=_account AND id=_project;
+    DELETE FROM taskLabels WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempPurgedIds);
+    DELETE FROM taskCustomFieldValues WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempPurgedIds);
+    DELETE FROM taskStateCounts WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempPurgedIds);
+    DELETE FROM taskWatchers WHERE account=_account AND project=_project AND task IN (SELECT id FROM tempPurgedIds);
+    DELETE FROM trashedTaskMembers WHERE account=_account AND project=_project AND trash IN (SELECT id FROM tempPurgedTrash);
+    DELETE FROM trashedTasks WHERE account=_account AND project=_project AND trash IN (SELECT id FROM tempPurgedTrash);
+    DELETE FROM trash WHERE account=_account AND project=_project AND id IN (SELECT id FROM tempPurgedTrash);
+  END IF;
+  COMMIT;
+  SELECT id, id, 't' FROM tempPurgedIds
+  UNION
+  SELECT id, task, 'f' FROM tempDeletedFiles;
+  DROP TEMPORARY TABLE IF EXISTS tempPurgedTrash;
+  DROP TEMPORARY TABLE IF EXISTS tempPurgedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempDeletedFiles;
+END;
+
+## deep copies the _task subtree from _sourceProject to _newParent in _project, _newIdsStr must hold exactly one id per copied task,
+## copying a project node copies its children onto the root of the empty project _project instead.
+DROP PROCEDURE IF EXISTS copyTask;
+CREATE PROCEDURE copyTask(_account BINARY(16), _sourceProject BINARY(16), _task BINARY(16), _project BINARY(16), _newParent BINARY(16), _me BINARY(16), _newPreviousSibling BINARY(16), _copyEstimates BOOL, _copyMembers BOOL, _createdOn DATETIME, _newIdsStr VARCHAR(32000)) #32000 == 1000 uuids
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE sourceProjectExists BOOL DEFAULT FALSE;
+  DECLARE taskExists BOOL DEFAULT FALSE;
+  DECLARE taskName VARCHAR(250) DEFAULT '';
+  DECLARE originalDescendantCount BIGINT UNSIGNED DEFAULT 0;
+  DECLARE newParentExists BOOL DEFAULT FALSE;
+  DECLARE newParentFirstChildId BINARY(16) DEFAULT NULL;
+  DECLARE newPreviousSiblingExists BOOL DEFAULT TRUE;
+  DECLARE nextSiblingToUse BINARY(16) DEFAULT NULL;
+  DECLARE copyingProject BOOL DEFAULT _task = _sourceProject;
+  DECLARE copyCount BIGINT UNSIGNED DEFAULT 0;
+  DECLARE copyIdx BIGINT UNSIGNED DEFAULT 0;
+  DECLARE copyRoot BINARY(16) DEFAULT NULL;
+  DECLARE copyRootFirstChild BINARY(16) DEFAULT NULL;
+  DECLARE copyIsAbstract BOOL DEFAULT FALSE;
+  DECLARE idVariable BINARY(16) DEFAULT NULL;
+  DECLARE firstState BINARY(16) DEFAULT NULL;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempCurrentIds;
+  CREATE TEMPORARY TABLE tempCurrentIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempLatestIds;
+  CREATE TEMPORARY TABLE tempLatestIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  #idx orders the subtree breadth first so every task comes after its parent
+  DROP TEMPORARY TABLE IF EXISTS tempCopyIds;
+  CREATE TEMPORARY TABLE tempCopyIds(
+    idx BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    oldId BINARY(16) NOT NULL,
+    newId BINARY(16) NULL,
+    PRIMARY KEY (idx),
+    UNIQUE INDEX (oldId)
+  );
+  #temp tables can only be referenced once per statement so the id mapping is duplicated for each tree pointer column
+  DROP TEMPORARY TABLE IF EXISTS tempParentIds;
+  CREATE TEMPORARY TABLE tempParentIds(
+    oldId BINARY(16) NOT NULL,
+    newId BINARY(16) NOT NULL,
+    PRIMARY KEY (oldId)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempFirstChildIds;
+  CREATE TEMPORARY TABLE tempFirstChildIds(
+    oldId BINARY(16) NOT NULL,
+    newId BINARY(16) NOT NULL,
+    PRIMARY KEY (oldId)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempNextSiblingIds;
+  CREATE TEMPORARY TABLE tempNextSiblingIds(
+    oldId BINARY(16) NOT NULL,
+    newId BINARY(16) NOT NULL,
+    PRIMARY KEY (oldId)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+  CREATE TEMPORARY TABLE tempUpdatedMembers(
+    id BINARY(16) NOT NULL,
+    totalRemainingTimeIncrease BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF _sourceProject = _project THEN
+    SET sourceProjectExists = projectExists;
+  ELSE
+    SELECT COUNT(*)=1 INTO sourceProjectExists FROM projectLocks WHERE account = _account AND id = _sourceProject LOCK IN SHARE MODE;
+  END IF;
+  SELECT COUNT(*)=1, name, descendantCount INTO taskExists, taskName, originalDescendantCount FROM tasks WHERE account = _account AND project = _sourceProject AND id = _task;
+  SELECT COUNT(*)=1, firstChild INTO newParentExists, newParentFirstChildId FROM tasks WHERE account = _account AND project = _project AND id = _newParent AND isAbstract = TRUE;
+  IF _newPreviousSibling IS NULL THEN
+    SET nextSiblingToUse = newParentFirstChildId;
+  ELSE
+    SELECT COUNT(*)=1, nextSibling INTO newPreviousSiblingExists, nextSiblingToUse FROM tasks WHERE account = _account AND project = _project AND parent = _newParent AND id = _newPreviousSibling;
+  END IF;
+  IF copyingProject THEN #project nodes may only be copied onto the root of a different and empty project
+    SET newParentExists = newParentExists AND _newParent = _project AND _sourceProject <> _project AND newParentFirstChildId IS NULL AND _newPreviousSibling IS NULL;
+  END IF;
+  SELECT id INTO firstState FROM projectStates WHERE account = _account AND project = _project ORDER BY position ASC LIMIT 1;
+  IF projectExists AND sourceProjectExists AND taskExists AND newParentExists AND newPreviousSiblingExists AND LENGTH(_newIdsStr) = (originalDescendantCount + 1) * 32 THEN
+    INSERT INTO tempCopyIds (oldId) VALUES (_task);
+    INSERT INTO tempCurrentIds VALUES (_task);
+    WHILE (SELECT COUNT(*) FROM tempCurrentIds) > 0 DO
+      INSERT INTO tempLatestIds SELECT id FROM tasks WHERE account = _account AND project = _sourceProject AND parent IN (SELECT id FROM tempCurrentIds);
+      INSERT INTO tempCopyIds (oldId) SELECT id FROM tempLatestIds;
+      TRUNCATE tempCurrentIds;
+      INSERT INTO tempCurrentIds SELECT id FROM tempLatestIds;
+      TRUNCATE tempLatestIds;
+    END WHILE;
+    SELECT COUNT(*) INTO copyCount FROM tempCopyIds;
+    IF copyCount = originalDescendantCount + 1 THEN
+      SET changeMade = TRUE;
+      UPDATE tempCopyIds SET newId = UNHEX(SUBSTRING(_newIdsStr, (idx - 1) * 32 + 1, 32));
+      IF copyingProject THEN #the project node is copied onto the existing root of _project
+        UPDATE tempCopyIds SET newId = _project WHERE idx = 1;
+      END IF;
+      INSERT INTO tempParentIds SELECT oldId, newId FROM tempCopyIds;
+      INSERT INTO tempFirstChildIds SELECT oldId, newId FROM tempCopyIds;
+      INSERT INTO tempNextSiblingIds SELECT oldId, newId FROM tempCopyIds;
+      SELECT newId INTO copyRoot FROM tempCopyIds WHERE idx = 1;
+      #the copied subtree root is left detached, with a NULL parent and nextSibling, until all of the copies aggregate values have been set
+      #copies keep their states where _project has a state of the same name, otherwise they start in _project's first state
+      INSERT INTO tasks (account, project, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state)
+        SELECT _account, _project, c.newId, p.newId, fc.newId, ns.newId, t.isAbstract, t.name, t.description, _createdOn, IF(_copyEstimates AND NOT t.isAbstract, t.totalRemainingTime, 0), 0, IF(_copyEstimates AND NOT t.isAbstract, t.totalRemainingTime, 0), 0, 0, 0, 0, t.isParallel, IF(t.isAbstract, NULL, COALESCE(ts.id, firstState))
+        FROM tasks t
+        INNER JOIN tempCopyIds c ON t.id = c.oldId
+        LEFT JOIN tempParentIds p ON t.parent = p.oldId
+        LEFT JOIN tempFirstChildIds fc ON t.firstChild = fc.oldId
+        LEFT JOIN tempNextSiblingIds ns ON t.nextSibling = ns.oldId
+        LEFT JOIN projectStates ss ON ss.account = _account AND ss.project = _sourceProject AND ss.id = t.state
+        LEFT JOIN projectStates ts ON ts.account = _account AND ts.project = _project AND ts.name = ss.name
+        WHERE t.account = _account AND t.project = _sourceProject AND c.idx > IF(copyingProject, 1, 0);
+      IF copyingProject THEN
+        SELECT fc.newId INTO copyRootFirstChild FROM tasks t INNER JOIN tempFirstChildIds fc ON t.firstChild = fc.oldId WHERE t.account = _account AND t.project = _sourceProject AND t.id = _task;
+        UPDATE tasks SET firstChild = copyRootFirstChild WHERE account = _account AND project = _project AND id = _project;
+      END IF;
+      INSERT INTO taskLabels (account, project, task, label) SELECT _account, _project, c.newId, tl.label FROM taskLabels tl INNER JOIN tempCopyIds c ON tl.task = c.oldId WHERE tl.account = _account AND tl.project = _sourceProject AND c.idx > IF(copyingProject, 1, 0);
+      IF _sourceProject = _project THEN #custom fields belong to their project so values are only kept when copying within it
+        INSERT INTO taskCustomFieldValues (account, project, task, customField, textValue, numberValue, dateValue) SELECT _account, _project, c.newId, v.customField, v.textValue, v.numberValue, v.dateValue FROM taskCustomFieldValues v INNER JOIN tempCopyIds c ON v.task = c.oldId WHERE v.account = _account AND v.project = _sourceProject;
+      END IF;
+      INSERT INTO remainingTimeChanges (account, project, task, occurredOn, member, oldValue, newValue) SELECT _account, _project, id, UTC_TIMESTAMP(6), _me, 0, totalRemainingTime FROM tasks WHERE account = _account AND project = _project AND id IN (SELECT newId FROM tempCopyIds) AND isAbstract = FALSE AND totalRemainingTime > 0;
+      IF _copyMembers THEN #only members who can still be assigned tasks in _project are copied, the shares of any others are left unassigned
+        INSERT INTO taskMembers (account, project, task, member, remainingTime)
+          SELECT _account, _project, c.newId, tm.member, IF(_copyEstimates, tm.remainingTime, 0)
+          FROM taskMembers tm
+          INNER JOIN tempCopyIds c ON tm.task = c.oldId
+          INNER JOIN projectMembers pm ON pm.account = _account AND pm.project = _project AND pm.id = tm.member AND pm.isActive = TRUE AND pm.role < 2 #less than 2 means 0->projectAdmin or 1->projectWriter
+          WHERE tm.account = _account AND tm.project = _sourceProject;
+      END IF;
+      INSERT INTO tempUpdatedMembers SELECT member, SUM(remainingTime) FROM taskMembers WHERE account = _account AND project = _project AND task IN (SELECT newId FROM tempCopyIds) GROUP BY member;
+      UPDATE projectMembers pm
+        INNER JOIN tempUpdatedMembers tum
+        ON pm.account = _account AND pm.project = _project AND pm.id = tum.id
+        SET pm.totalRemainingTime = pm.totalRemainingTime + tum.totalRemainingTimeIncrease;
+      #set the copies aggregate values bottom up, every abstract copy's children are complete before it is visited
+      SET copyIdx = copyCount;
+      WHILE copyIdx > 0 DO
+        SELECT newId INTO idVariable FROM tempCopyIds WHERE idx = copyIdx;
+        SELECT isAbstract INTO copyIsAbstract FROM tasks WHERE account = _account AND project = _project AND id = idVariable;
+        IF copyIsAbstract THEN
+          CALL _setAncestralChainAggregateValuesFromTask(_account, _project, idVariable);
+        END IF;
+        INSERT INTO tempUpdatedIds VALUES (idVariable) ON DUPLICATE KEY UPDATE id=id;
+        SET copyIdx = copyIdx - 1;
+      END WHILE;
+      IF NOT copyingProject THEN #attach the copied subtree root in its new position
+        UPDATE tasks SET parent = _newParent, nextSibling = nextSiblingToUse WHERE account = _account AND project = _project AND id = copyRoot;
+        IF _newPreviousSibling IS NULL THEN
+          UPDATE tasks SET firstChild = copyRoot WHERE account = _account AND project = _project AND id = _newParent;
+        ELSE
+          UPDATE tasks SET nextSibling = copyRoot WHERE account = _account AND project = _project AND id = _newPreviousSibling;
+          INSERT INTO tempUpdatedIds VALUES (_newPreviousSibling) ON DUPLICATE KEY UPDATE id=id;
+        END IF;
+        CALL _setAncestralChainAggregateValuesFromTask(_account, _project, _newParent);
+      END IF;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+        _account, _project, UTC_TIMESTAMP(6), _me, copyRoot, IF(copyingProject, 'project', 'task'), 'copy', IF(copyingProject, NULL, taskName), LOWER(HEX(_task)));
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT id, 't' FROM tempUpdatedIds
+  UNION
+  SELECT id, 'm' FROM tempUpdatedMembers;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempCurrentIds;
+  DROP TEMPORARY TABLE IF EXISTS tempLatestIds;
+  DROP TEMPORARY TABLE IF EXISTS tempCopyIds;
+  DROP TEMPORARY TABLE IF EXISTS tempParentIds;
+  DROP TEMPORARY TABLE IF EXISTS tempFirstChildIds;
+  DROP TEMPORARY TABLE IF EXISTS tempNextSiblingIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+END;
+
+DROP PROCEDURE IF EXISTS importTasks;
+CREATE PROCEDURE importTasks(_account BINARY(16), _project BINARY(16), _parent BINARY(16), _me BINARY(16), _previousSibling BINARY(16), _batch BINARY(16), _createdOn DATETIME)
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE parentExists BOOL DEFAULT FALSE;
+  DECLARE parentName VARCHAR(250) DEFAULT NULL;
+  DECLARE parentFirstChildId BINARY(16) DEFAULT NULL;
+  DECLARE previousSiblingExists BOOL DEFAULT TRUE;
+  DECLARE nextSiblingToUse BINARY(16) DEFAULT NULL;
+  DECLARE invalidMemberCount BIGINT UNSIGNED DEFAULT 0;
+  DECLARE firstState BINARY(16) DEFAULT NULL;
+  DECLARE importCount BIGINT UNSIGNED DEFAULT 0;
+  DECLARE importIdx BIGINT UNSIGNED DEFAULT 0;
+  DECLARE firstRoot BINARY(16) DEFAULT NULL;
+  DECLARE lastRoot BINARY(16) DEFAULT NULL;
+  DECLARE idVariable BINARY(16) DEFAULT NULL;
+  DECLARE importIsAbstract BOOL DEFAULT FALSE;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+  CREATE TEMPORARY TABLE tempUpdatedMembers(
+    id BINARY(16) NOT NULL,
+    totalRemainingTimeIncrease BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (id)
+  );
+
+  #assume the staged rows are already validated against themselves in the business logic
+
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  SELECT COUNT(*)=1, name, firstChild INTO parentExists, parentName, parentFirstChildId FROM tasks WHERE account = _account AND project = _project AND id = _parent AND isAbstract = TRUE;
+  IF _previousSibling IS NULL THEN
+    SET nextSiblingToUse = parentFirstChildId;
+  ELSE
+    SELECT COUNT(*)=1, nextSibling INTO previousSiblingExists, nextSiblingToUse FROM tasks WHERE account = _account AND project = _project AND parent = _parent AND id = _previousSibling;
+  END IF;
+  SELECT COUNT(*) INTO invalidMemberCount FROM taskImportRows r LEFT JOIN projectMembers pm ON pm.account = _account AND pm.project = _project AND pm.id = r.member AND pm.isActive = TRUE AND pm.role < 2 WHERE r.account = _account AND r.project = _project AND r.batch = _batch AND r.member IS NOT NULL AND pm.id IS NULL; #less than 2 means 0->projectAdmin or 1->projectWriter
+  SELECT id INTO firstState FROM projectStates WHERE account = _account AND project = _project ORDER BY position ASC LIMIT 1;
+  SELECT COUNT(*) INTO importCount FROM taskImportRows WHERE account = _account AND project = _project AND batch = _batch;
+  IF projectExists AND parentExists AND previousSiblingExists AND invalidMemberCount = 0 AND firstState IS NOT NULL AND importCount > 0 THEN
+    #the top level rows are left detached, with a NULL parent, until all of the imported tasks aggregate values have been set, concrete tasks start in the projects first state
+    INSERT INTO tasks (account, project, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state)
+      SELECT _account, _project, id, parent, firstChild, nextSibling, isAbstract, name, description, _createdOn, totalRemainingTime, 0, totalRemainingTime, 0, 0, 0, 0, FALSE, IF(isAbstract, NULL, firstState)
+      FROM taskImportRows WHERE account = _account AND project = _project AND batch = _batch;
+    INSERT INTO taskMembers (account, project, task, member, remainingTime) SELECT _account, _project, id, member, totalRemainingTime FROM taskImportRows WHERE account = _account AND project = _project AND batch = _batch AND member IS NOT NULL;
+    INSERT INTO remainingTimeChanges (account, project, task, occurredOn, member, oldValue, newValue) SELECT _account, _project, id, UTC_TIMESTAMP(6), _me, 0, totalRemainingTime FROM taskImportRows WHERE account = _account AND project = _project AND batch = _batch AND isAbstract = FALSE AND totalRemainingTime > 0;
+    INSERT INTO tempUpdatedMembers SELECT member, SUM(totalRemainingTime) FROM taskImportRows WHERE account = _account AND project = _project AND batch = _batch AND member IS NOT NULL GROUP BY member;
+    UPDATE projectMembers pm
+      INNER JOIN tempUpdatedMembers tum
+      ON pm.account = _account AND pm.project = _project AND pm.id = tum.id
+      SET pm.totalRemainingTime = pm.totalRemainingTime + tum.totalRemainingTimeIncrease;
+    #set the imported tasks aggregate values bottom up, walking the depth first order backwards completes every abstract tasks children before it is visited
+    SET importIdx = importCount;
+    WHILE importIdx > 0 DO
+      SELECT id, isAbstract INTO idVariable, importIsAbstract FROM taskImportRows WHERE account = _account AND project = _project AND batch = _batch AND idx = importIdx;
+      IF importIsAbstract THEN
+        CALL _setAncestralChainAggregateValuesFromTask(_account, _project, idVariable);
+      END IF;
+      INSERT INTO tempUpdatedIds VALUES (idVariable) ON DUPLICATE KEY UPDATE id=id;
+      SET importIdx = importIdx - 1;
+    END WHILE;
+    #attach the top level rows in their new position
+    SELECT id INTO firstRoot FROM taskImportRows WHERE account = _account AND project = _project AND batch = _batch AND parent IS NULL ORDER BY idx ASC LIMIT 1;
+    SELECT id INTO lastRoot FROM taskImportRows WHERE account = _account AND project = _project AND batch = _batch AND parent IS NULL ORDER BY idx DESC LIMIT 1;
+    UPDATE tasks SET parent = _parent WHERE account = _account AND project = _project AND id IN (SELECT id FROM taskImportRows WHERE account = _account AND project = _project AND batch = _batch AND parent IS NULL);
+    UPDATE tasks SET nextSibling = nextSiblingToUse WHERE account = _account AND project = _project AND id = lastRoot;
+    IF _previousSibling IS NULL THEN
+      UPDATE tasks SET firstChild = firstRoot WHERE account = _account AND project = _project AND id = _parent;
+    ELSE
+      UPDATE tasks SET nextSibling = firstRoot WHERE account = _account AND project = _project AND id = _previousSibling;
+      INSERT INTO tempUpdatedIds VALUES (_previousSibling) ON DUPLICATE KEY UPDATE id=id;
+    END IF;
+    CALL _setAncestralChainAggregateValuesFromTask(_account, _project, _parent);
+    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+      _account, _project, UTC_TIMESTAMP(6), _me, _parent, IF(_parent = _project, 'project', 'task'), 'import', IF(_parent = _project, NULL, parentName), importCount);
+  END IF;
+  DELETE FROM taskImportRows WHERE account = _account AND project = _project AND batch = _batch;
+  COMMIT;
+  SELECT id, 't' FROM tempUpdatedIds
+  UNION
+  SELECT id, 'm' FROM tempUpdatedMembers;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+END;
+
+DROP PROCEDURE IF EXISTS getTaskTimeHistory;
+CREATE PROCEDURE getTaskTimeHistory(_account BINARY(16), _project BINARY(16), _task BINARY(16), _before DATETIME(6))
+BEGIN
+  DROP TEMPORARY TABLE IF EXISTS tempAllIds;
+  CREATE TEMPORARY TABLE tempAllIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempCurrentIds;
+  CREATE TEMPORARY TABLE tempCurrentIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempLatestIds;
+  CREATE TEMPORARY TABLE tempLatestIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  IF _project = _task THEN #the project covers every task, including deleted ones
+    SELECT 'r', task, occurredOn, newValue FROM remainingTimeChanges WHERE account=_account AND project=_project AND occurredOn < _before
+    UNION ALL
+    SELECT 'l', task, loggedOn, duration FROM timeLogs WHERE account=_account AND project=_project AND loggedOn < _before;
+  ELSE
+    INSERT INTO tempCurrentIds VALUES (_task);
+    WHILE (SELECT COUNT(*) FROM tempCurrentIds) > 0 DO
+      INSERT INTO tempLatestIds SELECT id FROM tasks WHERE account=_account AND project = _project AND parent IN (SELECT id FROM tempCurrentIds);
+      INSERT INTO tempAllIds SELECT id FROM tempCurrentIds;
+      TRUNCATE tempCurrentIds;
+      INSERT INTO tempCurrentIds SELECT id FROM tempLatestIds;
+      TRUNCATE tempLatestIds;
+    END WHILE;
+    #a temporary table can only be referenced once per query so copy the ids for the timeLogs half of the union
+    INSERT INTO tempLatestIds SELECT id FROM tempAllIds;
+    SELECT 'r', task, occurredOn, newValue FROM remainingTimeChanges WHERE account=_account AND project=_project AND occurredOn < _before AND task IN (SELECT id FROM tempAllIds)
+    UNION ALL
+    SELECT 'l', task, loggedOn, duration FROM timeLogs WHERE account=_account AND project=_project AND loggedOn < _before AND task IN (SELECT id FROM tempLatestIds);
+  END IF;
+  DROP TEMPORARY TABLE IF EXISTS tempAllIds;
+  DROP TEMPORARY TABLE IF EXISTS tempCurrentIds;
+  DROP TEMPORARY TABLE IF EXISTS tempLatestIds;
+END;
+
+DROP PROCEDURE IF EXISTS getTasks;
+CREATE PROCEDURE getTasks(_account BINARY(16), _project BINARY(16), _taskIdsStr VARCHAR(16000)) #16000 == 500 uuids
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE taskIdsStrLen INT DEFAULT LENGTH(_taskIdsStr);
+  DECLARE offset INT DEFAULT 0;
+  DROP TEMPORARY TABLE IF EXISTS tempIds;
+  CREATE TEMPORARY TABLE tempIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project;# LOCK IN SHARE MODE; I dont think this is required
+  IF projectExists AND taskIdsStrLen > 0 AND taskIdsStrLen % 32 = 0 THEN
+    WHILE offset < taskIdsStrLen DO
+      INSERT INTO tempIds VALUE (UNHEX(SUBSTRING(_taskIdsStr, offset + 1, 32)));
+      SET offset = offset + 32;
+    END WHILE;
+  END IF;
+  COMMIT;
+  SELECT id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state FROM tasks WHERE account = _account AND project = _project AND id IN (SELECT id FROM tempIds);
+  DROP TEMPORARY TABLE IF EXISTS tempIds;
+END;
+
+DROP PROCEDURE IF EXISTS getChildTasks;
+CREATE PROCEDURE getChildTasks(_account BINARY(16), _project BINARY(16), _parent BINARY(16), _fromSibling BINARY(16), _limit INT, _labelIdsStr VARCHAR(3200)) #3200 == 100 uuids
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE idVariable BINARY(16) DEFAULT NULL;
+  DECLARE idx INT DEFAULT 0;
+  DECLARE labelIdsStrLen INT DEFAULT LENGTH(_labelIdsStr);
+  DECLARE labelCount INT DEFAULT 0;
+  DECLARE offset INT DEFAULT 0;
+  DROP TEMPORARY TABLE IF EXISTS tempLabelIds;
+  CREATE TEMPORARY TABLE tempLabelIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempResult;
+  CREATE TEMPORARY TABLE tempResult(
+    selectOrder INT NOT NULL,
+    id BINARY(16) NOT NULL,
+    parent BINARY(16) NULL,
+    firstChild BINARY(16) NULL,
+    nextSibling BINARY(16) NULL,
+    isAbstract BOOL NOT NULL,
+    name VARCHAR(250) NOT NULL,
+    description VARCHAR(1250) NULL,
+    createdOn DATETIME NOT NULL,
+    totalRemainingTime BIGINT UNSIGNED NOT NULL,
+    totalLoggedTime BIGINT UNSIGNED NOT NULL,
+    totalCost BIGINT UNSIGNED NOT NULL,
+    totalRevenue BIGINT UNSIGNED NOT NULL,
+    minimumRemainingTime BIGINT UNSIGNED NOT NULL,
+    linkedFileCount INT UNSIGNED NOT NULL,
+    chatCount BIGINT UNSIGNED NOT NULL,
+    childCount BIGINT UNSIGNED NOT NULL,
+    descendantCount BIGINT UNSIGNED NOT NULL,
+    isParallel BOOL NOT NULL DEFAULT FALSE,
+    state BINARY(16) NULL,
+    PRIMARY KEY (selectOrder)
+  );
+  START TRANSACTION;
+
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project; #LOCK IN SHARE MODE; I dont think this is required
+  IF projectExists THEN
+    IF _fromSibling IS NOT NULL THEN
+      SELECT nextSibling INTO idVariable FROM tasks WHERE account = _account AND project = _project AND id = _fromSibling AND parent = _parent;
+    ELSE
+      SELECT firstChild INTO idVariable FROM tasks WHERE account = _account AND project = _project AND id = _parent;
+    END IF;
+    IF labelIdsStrLen > 0 AND labelIdsStrLen % 32 = 0 THEN
+      WHILE offset < labelIdsStrLen DO
+        INSERT INTO tempLabelIds VALUE (UNHEX(SUBSTRING(_labelIdsStr, offset + 1, 32))) ON DUPLICATE KEY UPDATE id=id;
+        SET offset = offset + 32;
+      END WHILE;
+      SELECT COUNT(*) INTO labelCount FROM tempLabelIds;
+    END IF;
+    WHILE idVariable IS NOT NULL AND idx < _limit DO
+      #when filtering by labels only children carrying every one of them are returned, the rest are skipped over
+      IF labelCount = 0 OR (SELECT COUNT(*) FROM taskLabels WHERE account = _account AND project = _project AND task = idVariable AND label IN (SELECT id FROM tempLabelIds)) = labelCount THEN
+        INSERT INTO tempResult SELECT idx, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state FROM tasks WHERE account =
+                                                                                                                                                                                                                                                              _account AND project = _project AND id = idVariable;
+        SET idx = idx + 1;
+      END IF;
+      SELECT nextSibling INTO idVariable FROM tasks WHERE account = _account AND project = _project AND id = idVariable;
+    END WHILE;
+  END IF;
+  COMMIT;
+  SELECT id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state FROM tempResult ORDER BY selectOrder ASC;
+  DROP TEMPORARY TABLE IF EXISTS tempResult;
+  DROP TEMPORARY TABLE IF EXISTS tempLabelIds;
+END;
+
+DROP PROCEDURE IF EXISTS getSubtreeTasks;
+CREATE PROCEDURE getSubtreeTasks(_account BINARY(16), _project BINARY(16), _root BINARY(16), _after BINARY(16), _depth INT, _limit INT)
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE idVariable BINARY(16) DEFAULT NULL;
+  DECLARE parentVariable BINARY(16) DEFAULT NULL;
+  DECLARE firstChildVariable BINARY(16) DEFAULT NULL;
+  DECLARE nextSiblingVariable BINARY(16) DEFAULT NULL;
+  DECLARE currentDepth INT DEFAULT 0;
+  DECLARE idx INT DEFAULT 0;
+  DROP TEMPORARY TABLE IF EXISTS tempResult;
+  CREATE TEMPORARY TABLE tempResult(
+    selectOrder INT NOT NULL,
+    depth INT NOT NULL,
+    id BINARY(16) NOT NULL,
+    parent BINARY(16) NULL,
+    firstChild BINARY(16) NULL,
+    nextSibling BINARY(16) NULL,
+    isAbstract BOOL NOT NULL,
+    name VARCHAR(250) NOT NULL,
+    description VARCHAR(1250) NULL,
+    createdOn DATETIME NOT NULL,
+    totalRemainingTime BIGINT UNSIGNED NOT NULL,
+    totalLoggedTime BIGINT UNSIGNED NOT NULL,
+    totalCost BIGINT UNSIGNED NOT NULL,
+    totalRevenue BIGINT UNSIGNED NOT NULL,
+    minimumRemainingTime BIGINT UNSIGNED NOT NULL,
+    linkedFileCount INT UNSIGNED NOT NULL,
+    chatCount BIGINT UNSIGNED NOT NULL,
+    childCount BIGINT UNSIGNED NOT NULL,
+    descendantCount BIGINT UNSIGNED NOT NULL,
+    isParallel BOOL NOT NULL DEFAULT FALSE,
+    state BINARY(16) NULL,
+    PRIMARY KEY (selectOrder)
+  );
+  START TRANSACTION;
+
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project;# LOCK IN SHARE MODE; I dont think this is required
+  IF projectExists THEN
+    IF _after IS NULL THEN
+      SELECT firstChild INTO idVariable FROM tasks WHERE account = _account AND project = _project AND id = _root;
+      SET currentDepth = 1;
+    ELSE
+      #walk up from _after to find its depth below _root, if it isn't a descendant of _root there is nothing to resume from
+      SET idVariable = _after;
+      WHILE idVariable IS NOT NULL AND NOT (idVariable <=> _root) DO
+        SET parentVariable = NULL;
+        SELECT parent INTO parentVariable FROM tasks WHERE account = _account AND project = _project AND id = idVariable;
+        SET idVariable = parentVariable;
+        SET currentDepth = currentDepth + 1;
+      END WHILE;
+      IF idVariable IS NOT NULL AND currentDepth <= _depth THEN
+        SET idVariable = _after;
+      ELSE
+        SET idVariable = NULL;
+      END IF;
+    END IF;
+    #depth first in sibling order, so each task comes straight after its parent or its previous siblings subtree
+    WHILE idVariable IS NOT NULL AND idx < _limit DO
+      IF NOT (idVariable <=> _after) THEN
+        INSERT INTO tempResult SELECT idx, currentDepth, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state FROM tasks WHERE account = _account AND project = _project AND id = idVariable;
+        SET idx = idx + 1;
+      END IF;
+      SELECT parent, firstChild, nextSibling INTO parentVariable, firstChildVariable, nextSiblingVariable FROM tasks WHERE account = _account AND project = _project AND id = idVariable;
+      IF firstChildVariable IS NOT NULL AND currentDepth < _depth THEN
+        SET idVariable = firstChildVariable;
+        SET currentDepth = currentDepth + 1;
+      ELSE
+        WHILE nextSiblingVariable IS NULL AND currentDepth > 1 DO
+          SET idVariable = parentVariable;
+          SET currentDepth = currentDepth - 1;
+          SELECT parent, nextSibling INTO parentVariable, nextSiblingVariable FROM tasks WHERE account = _account AND project = _project AND id = idVariable;
+        END WHILE;
+        SET idVariable = nextSiblingVariable;
+      END IF;
+    END WHILE;
+  END IF;
+  COMMIT;
+  SELECT id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state FROM tempResult ORDER BY selectOrder ASC;
+  DROP TEMPORARY TABLE IF EXISTS tempResult;
+END;
+
+DROP PROCEDURE IF EXISTS getAncestorTasks;
+CREATE PROCEDURE getAncestorTasks(_account BINARY(16), _project BINARY(16), _task BINARY(16), _limit INT)
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE idx INT DEFAULT 0;
+  DROP TEMPORARY TABLE IF EXISTS tempResult;
+  CREATE TEMPORARY TABLE tempResult(
+    selectOrder INT NOT NULL,
+    id BINARY(16) NOT NULL,
+    name VARCHAR(250) NOT NULL,
+    parent BINARY(16) NULL,
+    PRIMARY KEY (selectOrder)
+  );
+  START TRANSACTION;
+
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project;# LOCK IN SHARE MODE; I dont think this is required
+  IF projectExists THEN
+    SELECT parent INTO _task FROM tasks WHERE account = _account AND project = _project AND id = _task;
+    WHILE _task IS NOT NULL AND idx < _limit DO
+      INSERT INTO tempResult SELECT idx, id, name, parent FROM tasks WHERE account=_account AND project=_project AND id=_task;
+      SELECT parent INTO _task FROM tempResult WHERE selectOrder = idx;
+      SET idx = idx + 1;
+    END WHILE;
+  END IF;
+  COMMIT;
+  SELECT id, name FROM tempResult ORDER BY selectOrder DESC;
+  DROP TEMPORARY TABLE IF EXISTS tempResult;
+END;
+
+DROP PROCEDURE IF EXISTS addTaskDependency;
+CREATE PROCEDURE addTaskDependency(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16), _dependsOn BINARY(16))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE taskExists BOOL DEFAULT FALSE;
+  DECLARE dependsOnExists BOOL DEFAULT FALSE;
+  DECLARE dependencyExists BOOL DEFAULT FALSE;
+  DECLARE createsCycle BOOL DEFAULT FALSE;
+  DECLARE taskName VARCHAR(250) DEFAULT '';
+  DECLARE lowestCommonAncestor BINARY(16) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempVisitedIds;
+  CREATE TEMPORARY TABLE tempVisitedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempCurrentIds;
+  CREATE TEMPORARY TABLE tempCurrentIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempLatestIds;
+  CREATE TEMPORARY TABLE tempLatestIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists AND _task <> _dependsOn THEN
+    #dependencies are only between concrete tasks
+    SELECT COUNT(*)=1, name INTO taskExists, taskName FROM tasks WHERE account = _account AND project = _project AND id = _task AND isAbstract = FALSE;
+    SELECT COUNT(*)=1 INTO dependsOnExists FROM tasks WHERE account = _account AND project = _project AND id = _dependsOn AND isAbstract = FALSE;
+    SELECT COUNT(*)=1 INTO dependencyExists FROM taskDependencies WHERE account = _account AND project = _project AND task = _task AND dependsOn = _dependsOn;
+    IF taskExists AND dependsOnExists AND NOT dependencyExists THEN
+      #walk the dependencies of _dependsOn, if _task is reachable then adding this dependency would create a cycle
+      INSERT INTO tempCurrentIds VALUES (_dependsOn);
+      WHILE NOT createsCycle AND (SELECT COUNT(*) FROM tempCurrentIds) > 0 DO
+        IF (SELECT COUNT(*) FROM tempCurrentIds WHERE id = _task) > 0 THEN
+          SET createsCycle = TRUE;
+        END IF;
+        INSERT INTO tempVisitedIds SELECT id FROM tempCurrentIds tmpCurrent ON DUPLICATE KEY UPDATE id=tmpCurrent.id;
+        INSERT INTO tempLatestIds SELECT DISTINCT dependsOn FROM taskDependencies WHERE account = _account AND project = _project AND task IN (SELECT id FROM tempCurrentIds);
+        DELETE FROM tempLatestIds WHERE id IN (SELECT id FROM tempVisitedIds);
+        TRUNCATE tempCurrentIds;
+        INSERT INTO tempCurrentIds SELECT id FROM tempLatestIds;
+        TRUNCATE tempLatestIds;
+      END WHILE;
+      IF NOT createsCycle THEN
+        CALL _getLowestCommonAncestor(_account, _project, _task, _dependsOn, lowestCommonAncestor);
+        INSERT INTO taskDependencies (account, project, task, dependsOn, commonAncestor) VALUES (_account, _project, _task, _dependsOn, lowestCommonAncestor);
+        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+          _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'addDependency', taskName, LOWER(HEX(_dependsOn)));
+        INSERT INTO tempUpdatedIds VALUES (_task), (_dependsOn) ON DUPLICATE KEY UPDATE id=id;
+        CALL _setAncestralChainAggregateValuesFromTask(_account, _project, lowestCommonAncestor);
+      END IF;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT * FROM tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempVisitedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempCurrentIds;
+  DROP TEMPORARY TABLE IF EXISTS tempLatestIds;
+END;
+
+DROP PROCEDURE IF EXISTS removeTaskDependency;
+CREATE PROCEDURE removeTaskDependency(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16), _dependsOn BINARY(16))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE dependencyExists BOOL DEFAULT FALSE;
+  DECLARE taskName VARCHAR(250) DEFAULT '';
+  DECLARE lowestCommonAncestor BINARY(16) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT COUNT(*)=1, MAX(commonAncestor) INTO dependencyExists, lowestCommonAncestor FROM taskDependencies WHERE account = _account AND project = _project AND task = _task AND dependsOn = _dependsOn;
+    IF dependencyExists THEN
+      SELECT name INTO taskName FROM tasks WHERE account = _account AND project = _project AND id = _task;
+      DELETE FROM taskDependencies WHERE account = _account AND project = _project AND task = _task AND dependsOn = _dependsOn;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+        _account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'removeDependency', taskName, LOWER(HEX(_dependsOn)));
+      INSERT INTO tempUpdatedIds VALUES (_task), (_dependsOn) ON DUPLICATE KEY UPDATE id=id;
+      CALL _setAncestralChainAggregateValuesFromTask(_account, _project, lowestCommonAncestor);
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT * FROM tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+END;
+
+DROP PROCEDURE IF EXISTS setTimeLogDuration;
+CREATE PROCEDURE setTimeLogDuration(_account BINARY(16), _project BINARY(16), _timeLog BINARY(16), _me BINARY(16), _duration BIGINT UNSIGNED)
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE taskId BINARY(16) DEFAULT NULL;
+  DECLARE currentDuration BIGINT UNSIGNED DEFAULT 0;
+  DECLARE currentCost BIGINT UNSIGNED DEFAULT 0;
+  DECLARE newCost BIGINT UNSIGNED DEFAULT 0;
+  DECLARE timeLogIsBillable BOOL DEFAULT FALSE;
+  DECLARE currentNote VARCHAR(250) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT task, duration, cost, isBillable, note INTO taskId, currentDuration, currentCost, timeLogIsBillable, currentNote FROM timeLogs WHERE account=_account AND project=_project AND id=_timeLog;
+    IF currentDuration <> _duration AND _duration <> 0 THEN
+      UPDATE timeLogs SET duration=_duration, cost=ROUND(_duration * hourlyRate / 60) WHERE account=_account AND project=_project AND id=_timeLog;
+      SELECT cost INTO newCost FROM timeLogs WHERE account=_account AND project=_project AND id=_timeLog;
+      UPDATE tasks SET totalLoggedTime=totalLoggedTime+_duration-currentDuration, totalCost=totalCost+newCost-currentCost, totalRevenue=totalRevenue+IF(timeLogIsBillable, newCost, 0)-IF(timeLogIsBillable, currentCost, 0) WHERE account=_account AND project=_project AND id=taskId;
+      CALL _setTrashedAncestralChainLoggedTimeAndCosts(_account, _project, taskId, currentDuration, _duration, currentCost, newCost, IF(timeLogIsBillable, currentCost, 0), IF(timeLogIsBillable, newCost, 0));
+      INSERT INTO tempUpdatedIds VALUES (taskId);
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _timeLog, 'timeLog', 'setDuration', currentNote, CONCAT('{"duration":', CAST(_duration as char character set utf8), '}'));
+      SELECT parent INTO taskId FROM tasks WHERE account=_account AND project=_project AND id=taskId;
+      CALL _setAncestralChainAggregateValuesFromTask(_account, _project, taskId);
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT * FROM tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+END;
+
+## fails on the timeLogs primary key if the member already has another time log on the same task at _loggedOn
+DROP PROCEDURE IF EXISTS setTimeLogLoggedOn;
+CREATE PROCEDURE setTimeLogLoggedOn(_account BINARY(16), _project BINARY(16), _timeLog BINARY(16), _me BINARY(16), _loggedOn DATETIME)
+BEGIN
+  DECLARE timeLogExists BOOL DEFAULT FALSE;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  SELECT COUNT(*)=1 INTO timeLogExists FROM timeLogs WHERE account=_account AND project=_project AND id=_timeLog;
+  IF timeLogExists THEN
+    UPDATE timeLogs SET loggedOn=_loggedOn WHERE account=_account AND project=_project AND id=_timeLog;
+    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _timeLog, 'timeLog', 'setLoggedOn', NULL, CAST(_loggedOn as char character set utf8));
+    SET changeMade=TRUE;
+  END IF;
+  SELECT changeMade;
+END;
+
+DROP PROCEDURE IF EXISTS setTimeLogNote;
+CREATE PROCEDURE setTimeLogNote(_account BINARY(16), _project BINARY(16), _timeLog BINARY(16), _me BINARY(16), _note VARCHAR(250))
+BEGIN
+  DECLARE timeLogExists BOOL DEFAULT FALSE;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  SELECT COUNT(*)=1 INTO timeLogExists FROM timeLogs WHERE account=_account AND project=_project AND id=_timeLog;
+  IF timeLogExists THEN
+    UPDATE timeLogs SET note=_note WHERE account=_account AND project=_project AND id=_timeLog;
+    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _timeLog, 'timeLog', 'setNote', _note, NULL);
+    SET changeMade=TRUE;
+  END IF;
+  SELECT changeMade;
+END;
+
+DROP PROCEDURE IF EXISTS setTimeLogIsBillable;
+CREATE PROCEDURE setTimeLogIsBillable(_account BINARY(16), _project BINARY(16), _timeLog BINARY(16), _me BINARY(16), _isBillable BOOL)
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE taskId BINARY(16) DEFAULT NULL;
+  DECLARE currentIsBillable BOOL DEFAULT FALSE;
+  DECLARE currentCost BIGINT UNSIGNED DEFAULT 0;
+  DECLARE currentNote VARCHAR(250) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT task, isBillable, cost, note INTO taskId, currentIsBillable, currentCost, currentNote FROM timeLogs WHERE account=_account AND project=_project AND id=_timeLog;
+    IF taskId IS NOT NULL AND currentIsBillable <> _isBillable THEN
+      UPDATE timeLogs SET isBillable=_isBillable WHERE account=_account AND project=_project AND id=_timeLog;
+      UPDATE tasks SET totalRevenue=totalRevenue+IF(_isBillable, currentCost, 0)-IF(_isBillable, 0, currentCost) WHERE account=_account AND project=_project AND id=taskId;
+      CALL _setTrashedAncestralChainLoggedTimeAndCosts(_account, _project, taskId, 0, 0, currentCost, currentCost, IF(_isBillable, 0, currentCost), IF(_isBillable, currentCost, 0));
+      INSERT INTO tempUpdatedIds VALUES (taskId);
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _timeLog, 'timeLog', 'setIsBillable', currentNote, IF(_isBillable, 'true', 'false'));
+      SELECT parent INTO taskId FROM tasks WHERE account=_account AND project=_project AND id=taskId;
+      CALL _setAncestralChainAggregateValuesFromTask(_account, _project, taskId);
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT * FROM tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+END;
+
+DROP PROCEDURE IF EXISTS deleteTimeLog;
+CREATE PROCEDURE deleteTimeLog(_account BINARY(16), _project BINARY(16), _timeLog BINARY(16), _me BINARY(16))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE taskId BINARY(16) DEFAULT NULL;
+  DECLARE currentDuration BIGINT UNSIGNED DEFAULT 0;
+  DECLARE currentCost BIGINT UNSIGNED DEFAULT 0;
+  DECLARE timeLogIsBillable BOOL DEFAULT FALSE;
+  DECLARE currentNote VARCHAR(250) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  CREATE TEMPORARY TABLE tempUpdatedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT task, duration, cost, isBillable, note INTO taskId, currentDuration, currentCost, timeLogIsBillable, currentNote FROM timeLogs WHERE account=_account AND project=_project AND id=_timeLog;
+    DELETE FROM timeLogs WHERE account=_account AND project=_project AND id=_timeLog;
+    UPDATE tasks SET totalLoggedTime=totalLoggedTime-currentDuration, totalCost=totalCost-currentCost, totalRevenue=totalRevenue-IF(timeLogIsBillable, currentCost, 0) WHERE account=_account AND project=_project AND id=taskId;
+    CALL _setTrashedAncestralChainLoggedTimeAndCosts(_account, _project, taskId, currentDuration, 0, currentCost, 0, IF(timeLogIsBillable, currentCost, 0), 0);
+    INSERT INTO tempUpdatedIds VALUES (taskId);
+    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _timeLog, 'timeLog', 'delete', currentNote, CONCAT('{"duration":', CAST(currentDuration as char character set utf8), '}'));
+    UPDATE projectActivities SET itemHasBeenDeleted=TRUE WHERE account=_account AND project=_project AND item=_timeLog;
+    SELECT parent INTO taskId FROM tasks WHERE account=_account AND project=_project AND id=taskId;
+    CALL _setAncestralChainAggregateValuesFromTask(_account, _project, taskId);
+  END IF;
+  COMMIT;
+  SELECT * FROM tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+END;
+
+DROP PROCEDURE IF EXISTS createComment;
+CREATE PROCEDURE createComment(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16), _comment BINARY(16), _createdOn DATETIME(6), _body VARCHAR(5000), _mentionIdsStr VARCHAR(3200)) #3200 == 100 uuids
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE taskExists BOOL DEFAULT FALSE;
+  DECLARE taskName VARCHAR(250) DEFAULT '';
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT COUNT(*)=1, name INTO taskExists, taskName FROM tasks WHERE account = _account AND project = _project AND id = _task;
+    IF taskExists THEN
+      INSERT INTO comments (account, project, task, id, member, createdOn, editedOn, body) VALUES (_account, _project, _task, _comment, _me, _createdOn, NULL, _body);
+      CALL _setCommentMentions(_account, _project, _comment, _mentionIdsStr);
+      UPDATE tasks SET chatCount=chatCount+1 WHERE account = _account AND project = _project AND id = _task;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _comment, 'comment', 'create', taskName, LOWER(HEX(_task)));
+      SET changeMade=TRUE;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+END;
+
+DROP PROCEDURE IF EXISTS editComment;
+CREATE PROCEDURE editComment(_account BINARY(16), _project BINARY(16), _comment BINARY(16), _me BINARY(16), _body VARCHAR(5000), _mentionIdsStr VARCHAR(3200)) #3200 == 100 uuids
+BEGIN
+  DECLARE commentExists BOOL DEFAULT FALSE;
+  DECLARE taskId BINARY(16) DEFAULT NULL;
+  DECLARE taskName VARCHAR(250) DEFAULT '';
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1, task INTO commentExists, taskId FROM comments WHERE account=_account AND project=_project AND id=_comment FOR UPDATE;
+  IF commentExists THEN
+    SELECT name INTO taskName FROM tasks WHERE account = _account AND project = _project AND id = taskId;
+    UPDATE comments SET body=_body, editedOn=UTC_TIMESTAMP(6) WHERE account=_account AND project=_project AND id=_comment;
+    CALL _setCommentMentions(_account, _project, _comment, _mentionIdsStr);
+    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _comment, 'comment', 'edit', taskName, LOWER(HEX(taskId)));
+    SET changeMade=TRUE;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+END;
+
+DROP PROCEDURE IF EXISTS deleteComment;
+CREATE PROCEDURE deleteComment(_account BINARY(16), _project BINARY(16), _comment BINARY(16), _me BINARY(16))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE commentExists BOOL DEFAULT FALSE;
+  DECLARE taskId BINARY(16) DEFAULT NULL;
+  DECLARE taskName VARCHAR(250) DEFAULT '';
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT COUNT(*)=1, task INTO commentExists, taskId FROM comments WHERE account=_account AND project=_project AND id=_comment;
+    IF commentExists THEN
+      SELECT name INTO taskName FROM tasks WHERE account = _account AND project = _project AND id = taskId;
+      DELETE FROM comments WHERE account=_account AND project=_project AND id=_comment;
+      DELETE FROM commentMentions WHERE account=_account AND project=_project AND comment=_comment;
+      UPDATE tasks SET chatCount=chatCount-1 WHERE account = _account AND project = _project AND id = taskId;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _comment, 'comment', 'delete', taskName, LOWER(HEX(taskId)));
+      UPDATE projectActivities SET itemHasBeenDeleted=TRUE WHERE account=_account AND project=_project AND item=_comment;
+      SET changeMade=TRUE;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+END;
+
+DROP PROCEDURE IF EXISTS createFile;
+CREATE PROCEDURE createFile(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16), _file BINARY(16), _createdOn DATETIME(6), _name VARCHAR(250), _mimeType VARCHAR(250), _size BIGINT UNSIGNED, _accountQuota BIGINT UNSIGNED)
+BEGIN
+  DECLARE accountExists BOOL DEFAULT FALSE;
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE taskExists BOOL DEFAULT FALSE;
+  DECLARE accountFileSize BIGINT UNSIGNED DEFAULT 0;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  #the quota spans every project in the account so lock the account row before the project
+  SELECT COUNT(*)=1 INTO accountExists FROM accounts WHERE id = _account FOR UPDATE;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF accountExists AND projectExists THEN
+    SELECT COUNT(*)=1 INTO taskExists FROM tasks WHERE account = _account AND project = _project AND id = _task;
+    SELECT COALESCE(SUM(fileSize), 0) INTO accountFileSize FROM projects WHERE account = _account;
+    IF taskExists AND accountFileSize + _size <= _accountQuota THEN
+      INSERT INTO files (account, project, task, id, member, createdOn, name, mimeType, size) VALUES (_account, _project, _task, _file, _me, _createdOn, _name, _mimeType, _size);
+      UPDATE tasks SET linkedFileCount=linkedFileCount+1 WHERE account = _account AND project = _project AND id = _task;
+      UPDATE projects SET fileCount=fileCount+1, fileSize=fileSize+_size WHERE account = _account AND id = _project;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _file, 'file', 'create', _name, LOWER(HEX(_task)));
+      SET changeMade=TRUE;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+END;
+
+DROP PROCEDURE IF EXISTS deleteFile;
+CREATE PROCEDURE deleteFile(_account BINARY(16), _project BINARY(16), _file BINARY(16), _me BINARY(16))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE fileExists BOOL DEFAULT FALSE;
+  DECLARE taskId BINARY(16) DEFAULT NULL;
+  DECLARE fileName VARCHAR(250) DEFAULT '';
+  DECLARE deletedFileSize BIGINT UNSIGNED DEFAULT 0;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT COUNT(*)=1, task, name, size INTO fileExists, taskId, fileName, deletedFileSize FROM files WHERE account=_account AND project=_project AND id=_file;
+    IF fileExists THEN
+      DELETE FROM files WHERE account=_account AND project=_project AND id=_file;
+      UPDATE tasks SET linkedFileCount=linkedFileCount-1 WHERE account = _account AND project = _project AND id = taskId;
+      UPDATE projects SET fileCount=fileCount-1, fileSize=fileSize-deletedFileSize WHERE account = _account AND id = _project;
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _file, 'file', 'delete', fileName, LOWER(HEX(taskId)));
+      UPDATE projectActivities SET itemHasBeenDeleted=TRUE WHERE account=_account AND project=_project AND item=_file;
+      SET changeMade=TRUE;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+END;
+
+DROP PROCEDURE IF EXISTS createLabel;
+CREATE PROCEDURE createLabel(_account BINARY(16), _label BINARY(16), _me BINARY(16), _name VARCHAR(50), _colour CHAR(7))
+BEGIN
+  INSERT INTO labels (account, id, name, colour) VALUES (_account, _label, _name, _colour);
+  INSERT INTO accountActivities (account, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, UTC_TIMESTAMP(6), _me, _label, 'label', 'create', _name, _colour);
+END;
+
+DROP PROCEDURE IF EXISTS editLabel;
+CREATE PROCEDURE editLabel(_account BINARY(16), _label BINARY(16), _me BINARY(16), _name VARCHAR(50), _colour CHAR(7))
+BEGIN
+  DECLARE labelExists BOOL DEFAULT FALSE;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO labelExists FROM labels WHERE account=_account AND id=_label FOR UPDATE;
+  IF labelExists THEN
+    UPDATE labels SET name=_name, colour=_colour WHERE account=_account AND id=_label;
+    INSERT INTO accountActivities (account, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, UTC_TIMESTAMP(6), _me, _label, 'label', 'edit', _name, _colour);
+    UPDATE accountActivities SET itemName=_name WHERE account=_account AND item=_label;
+    SET changeMade=TRUE;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+END;
+
+DROP PROCEDURE IF EXISTS deleteLabel;
+CREATE PROCEDURE deleteLabel(_account BINARY(16), _label BINARY(16), _me BINARY(16))
+BEGIN
+  DECLARE labelName VARCHAR(50) DEFAULT '';
+  DROP TEMPORARY TABLE IF EXISTS tempLabelledTasks;
+  CREATE TEMPORARY TABLE tempLabelledTasks(
+    project BINARY(16) NOT NULL,
+    id BINARY(16) NOT NULL,
+    parent BINARY(16) NULL,
+    PRIMARY KEY (project, id)
+  );
+  START TRANSACTION;
+  SELECT name INTO labelName FROM labels WHERE account=_account AND id=_label FOR UPDATE;
+  INSERT INTO tempLabelledTasks SELECT tl.project, tl.task, t.parent FROM taskLabels tl INNER JOIN tasks t ON t.account=tl.account AND t.project=tl.project AND t.id=tl.task WHERE tl.account=_account AND tl.label=_label;
+  DELETE FROM taskLabels WHERE account=_account AND label=_label;
+  DELETE FROM labels WHERE account=_account AND id=_label;
+  INSERT INTO accountActivities (account, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, UTC_TIMESTAMP(6), _me, _label, 'label', 'delete', labelName, NULL);
+  UPDATE accountActivities SET itemHasBeenDeleted=TRUE WHERE account=_account AND item=_label;
+  COMMIT;
+  #the tasks which were carrying the label, the caller needs these to clear them from the cache
+  SELECT project, id, parent FROM tempLabelledTasks;
+  DROP TEMPORARY TABLE IF EXISTS tempLabelledTasks;
+END;
+
+## pass NULL for all of the values to clear the tasks value
+DROP PROCEDURE IF EXISTS setTaskCustomFieldValue;
+CREATE PROCEDURE setTaskCustomFieldValue(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16), _customField BINARY(16), _textValue VARCHAR(1250), _numberValue DOUBLE, _dateValue DATETIME)
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE taskExists BOOL DEFAULT FALSE;
+  DECLARE taskParent BINARY(16) DEFAULT NULL;
+  DECLARE customFieldName VARCHAR(50) DEFAULT NULL;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT COUNT(*)=1, parent INTO taskExists, taskParent FROM tasks WHERE account = _account AND project = _project AND id = _task;
+    SELECT name INTO customFieldName FROM customFields WHERE account = _account AND project = _project AND id = _customField;
+    IF taskExists AND customFieldName IS NOT NULL THEN
+      IF _textValue IS NULL AND _numberValue IS NULL AND _dateValue IS NULL THEN
+        DELETE FROM taskCustomFieldValues WHERE account = _account AND project = _project AND task = _task AND customField = _customField;
+      ELSE
+        INSERT INTO taskCustomFieldValues (account, project, task, customField, textValue, numberValue, dateValue) VALUES (_account, _project, _task, _customField, _textValue, _numberValue, _dateValue) ON DUPLICATE KEY UPDATE textValue=_textValue, numberValue=_numberValue, dateValue=_dateValue;
+      END IF;
+      IF ROW_COUNT() > 0 THEN
+        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'setCustomField', NULL, customFieldName);
+        SET changeMade = TRUE;
+      END IF;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT changeMade, taskParent;
+END;
+
+DROP PROCEDURE IF EXISTS addTaskLabels;
+CREATE PROCEDURE addTaskLabels(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16), _labelIdsStr VARCHAR(3200)) #3200 == 100 uuids
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE taskExists BOOL DEFAULT FALSE;
+  DECLARE taskParent BINARY(16) DEFAULT NULL;
+  DECLARE labelIdsStrLen INT DEFAULT LENGTH(_labelIdsStr);
+  DECLARE offset INT DEFAULT 0;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  DROP TEMPORARY TABLE IF EXISTS tempLabelIds;
+  CREATE TEMPORARY TABLE tempLabelIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists AND labelIdsStrLen > 0 AND labelIdsStrLen % 32 = 0 THEN
+    SELECT COUNT(*)=1, parent INTO taskExists, taskParent FROM tasks WHERE account = _account AND project = _project AND id = _task;
+    IF taskExists THEN
+      WHILE offset < labelIdsStrLen DO
+        INSERT INTO tempLabelIds VALUE (UNHEX(SUBSTRING(_labelIdsStr, offset + 1, 32))) ON DUPLICATE KEY UPDATE id=id;
+        SET offset = offset + 32;
+      END WHILE;
+      #only labels belonging to the account can be attached
+      INSERT IGNORE INTO taskLabels (account, project, task, label) SELECT _account, _project, _task, id FROM labels WHERE account = _account AND id IN (SELECT id FROM tempLabelIds);
+      IF ROW_COUNT() > 0 THEN
+        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'addLabels', NULL, LOWER(_labelIdsStr));
+        SET changeMade=TRUE;
+      END IF;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT changeMade, taskParent;
+  DROP TEMPORARY TABLE IF EXISTS tempLabelIds;
+END;
+
+DROP PROCEDURE IF EXISTS removeTaskLabels;
+CREATE PROCEDURE removeTaskLabels(_account BINARY(16), _project BINARY(16), _task BINARY(16), _me BINARY(16), _labelIdsStr VARCHAR(3200)) #3200 == 100 uuids
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE taskExists BOOL DEFAULT FALSE;
+  DECLARE taskParent BINARY(16) DEFAULT NULL;
+  DECLARE labelIdsStrLen INT DEFAULT LENGTH(_labelIdsStr);
+  DECLARE offset INT DEFAULT 0;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  DROP TEMPORARY TABLE IF EXISTS tempLabelIds;
+  CREATE TEMPORARY TABLE tempLabelIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists AND labelIdsStrLen > 0 AND labelIdsStrLen % 32 = 0 THEN
+    SELECT COUNT(*)=1, parent INTO taskExists, taskParent FROM tasks WHERE account = _account AND project = _project AND id = _task;
+    IF taskExists THEN
+      WHILE offset < labelIdsStrLen DO
+        INSERT INTO tempLabelIds VALUE (UNHEX(SUBSTRING(_labelIdsStr, offset + 1, 32))) ON DUPLICATE KEY UPDATE id=id;
+        SET offset = offset + 32;
+      END WHILE;
+      DELETE FROM taskLabels WHERE account = _account AND project = _project AND task = _task AND label IN (SELECT id FROM tempLabelIds);
+      IF ROW_COUNT() > 0 THEN
+        INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _task, 'task', 'removeLabels', NULL, LOWER(_labelIdsStr));
+        SET changeMade=TRUE;
+      END IF;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT changeMade, taskParent;
+  DROP TEMPORARY TABLE IF EXISTS tempLabelIds;
+END;
+
+DROP PROCEDURE IF EXISTS watchTask;
+CREATE PROCEDURE watchTask(_account BINARY(16), _project BINARY(16), _task BINARY(16), _member BINARY(16), _includeDescendants BOOL)
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE taskExists BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    SELECT COUNT(*)=1 INTO taskExists FROM tasks WHERE account = _account AND project = _project AND id = _task;
+    IF taskExists THEN
+      #the first watcher in a project starts its cursor, activities from before then are never notified
+      INSERT IGNORE INTO notificationCursors (account, project, lastOccurredOn) VALUES (_account, _project, UTC_TIMESTAMP(6));
+      INSERT INTO taskWatchers (account, project, task, member, includeDescendants, watchedOn) VALUES (_account, _project, _task, _member, _includeDescendants, UTC_TIMESTAMP(6)) ON DUPLICATE KEY UPDATE includeDescendants=_includeDescendants;
+    END IF;
+  END IF;
+  COMMIT;
+  SELECT taskExists;
+END;
+
+DROP PROCEDURE IF EXISTS unwatchTask;
+CREATE PROCEDURE unwatchTask(_account BINARY(16), _project BINARY(16), _task BINARY(16), _member BINARY(16))
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF projectExists THEN
+    DELETE FROM taskWatchers WHERE account = _account AND project = _project AND task = _task AND member = _member;
+    IF (SELECT COUNT(*) FROM taskWatchers WHERE account = _account AND project = _project) = 0 THEN
+      DELETE FROM notificationCursors WHERE account = _account AND project = _project;
+    END IF;
+  END IF;
+  COMMIT;
+END;
+
+## turns the project activities after the projects notification cursor, up to _upTo, into notifications for every active
+## member watching the activities task, or one of its ancestors with includeDescendants, other than the member who did it.
+## Activities on accounts, members, states and labels, and on comments and files that no longer exist, have no task and are skipped.
+DROP PROCEDURE IF EXISTS generateNotifications;
+CREATE PROCEDURE generateNotifications(_account BINARY(16), _project BINARY(16), _upTo DATETIME(6))
+BEGIN
+  DECLARE lastOn DATETIME(6) DEFAULT NULL;
+  DECLARE newLastOn DATETIME(6) DEFAULT NULL;
+  DECLARE projectIsPublic BOOL DEFAULT FALSE;
+  DECLARE actOccurredOn DATETIME(6) DEFAULT NULL;
+  DECLARE actMember BINARY(16) DEFAULT NULL;
+  DECLARE actItem BINARY(16) DEFAULT NULL;
+  DECLARE actItemType VARCHAR(100) DEFAULT NULL;
+  DECLARE actAction VARCHAR(100) DEFAULT NULL;
+  DECLARE actItemName VARCHAR(250) DEFAULT NULL;
+  DECLARE actExtraInfo VARCHAR(1250) DEFAULT NULL;
+  DECLARE actTask BINARY(16) DEFAULT NULL;
+  DECLARE node BINARY(16) DEFAULT NULL;
+  DECLARE nextNode BINARY(16) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempNotifyActivities;
+  CREATE TEMPORARY TABLE tempNotifyActivities(
+    occurredOn DATETIME(6) NOT NULL,
+    member BINARY(16) NOT NULL,
+    item BINARY(16) NOT NULL,
+    itemType VARCHAR(100) NOT NULL,
+    action VARCHAR(100) NOT NULL,
+    itemName VARCHAR(250) NULL,
+    extraInfo VARCHAR(1250) NULL,
+    PRIMARY KEY (occurredOn, item, member)
+  );
+  SELECT lastOccurredOn INTO lastOn FROM notificationCursors WHERE account = _account AND project = _project;
+  SELECT MAX(occurredOn) INTO newLastOn FROM projectActivities WHERE account = _account AND project = _project AND occurredOn > lastOn AND occurredOn <= _upTo;
+  IF newLastOn IS NOT NULL THEN
+    SELECT isPublic INTO projectIsPublic FROM projects WHERE account = _account AND id = _project;
+    INSERT INTO tempNotifyActivities SELECT occurredOn, member, item, itemType, action, itemName, extraInfo FROM projectActivities WHERE account = _account AND project = _project AND occurredOn > lastOn AND occurredOn <= newLastOn;
+    WHILE (SELECT COUNT(*) FROM tempNotifyActivities) > 0 DO
+      SELECT occurredOn, member, item, itemType, action, itemName, extraInfo INTO actOccurredOn, actMember, actItem, actItemType, actAction, actItemName, actExtraInfo FROM tempNotifyActivities ORDER BY occurredOn ASC, item ASC, member ASC LIMIT 1;
+      DELETE FROM tempNotifyActivities WHERE occurredOn = actOccurredOn AND item = actItem AND member = actMember;
+      SET actTask = NULL;
+      IF actItemType = 'task' THEN
+        SET actTask = actItem;
+      ELSEIF actItemType = 'project' THEN
+        SET actTask = _project;
+      ELSEIF actItemType = 'comment' THEN
+        SELECT task INTO actTask FROM comments WHERE account = _account AND project = _project AND id = actItem;
+      ELSEIF actItemType = 'file' THEN
+        SELECT task INTO actTask FROM files WHERE account = _account AND project = _project AND id = actItem;
+      ELSEIF actItemType = 'timeLog' THEN
+        SELECT task INTO actTask FROM timeLogs WHERE account = _account AND project = _project AND id = actItem;
+      ELSEIF actItemType = 'recurrence' THEN
+        SELECT parent INTO actTask FROM recurrences WHERE account = _account AND project = _project AND id = actItem;
+      END IF;
+      SET node = actTask;
+      WHILE node IS NOT NULL DO
+        INSERT IGNORE INTO notifications (account, member, project, occurredOn, actor, item, itemType, action, itemName, extraInfo, task, isRead, emailPending)
+          SELECT _account, w.member, _project, actOccurredOn, actMember, actItem, actItemType, actAction, actItemName, actExtraInfo, actTask, FALSE, am.email IS NOT NULL AND am.notificationDelivery <> 2
+          FROM taskWatchers w INNER JOIN accountMembers am ON am.account = w.account AND am.id = w.member
+          WHERE w.account = _account AND w.project = _project AND w.task = node AND (node = actTask OR w.includeDescendants) AND w.member <> actMember AND w.watchedOn <= actOccurredOn
+          AND am.isActive AND (projectIsPublic OR am.role < 2 OR EXISTS(SELECT 1 FROM projectMembers pm WHERE pm.account = _account AND pm.project = _project AND pm.id = w.member AND pm.isActive));
+        SET nextNode = NULL;
+        SELECT parent INTO nextNode FROM tasks WHERE account = _account AND project = _project AND id = node;
+        IF nextNode IS NULL THEN
+          #deleted tasks are notified through their trashed ancestors
+          SELECT parent INTO nextNode FROM trashedTasks WHERE account = _account AND project = _project AND id = node;
+        END IF;
+        SET node = nextNode;
+      END WHILE;
+    END WHILE;
+    UPDATE notificationCursors SET lastOccurredOn = newLastOn WHERE account = _account AND project = _project AND lastOccurredOn < newLastOn;
+  END IF;
+  DROP TEMPORARY TABLE IF EXISTS tempNotifyActivities;
+END;
+
+DROP PROCEDURE IF EXISTS createRecurrence;
+CREATE PROCEDURE createRecurrence(_account BINARY(16), _project BINARY(16), _recurrence BINARY(16), _parent BINARY(16), _me BINARY(16), _name VARCHAR(250), _description VARCHAR(1250), _totalRemainingTime BIGINT UNSIGNED, _frequency VARCHAR(10), _weekDays TINYINT UNSIGNED, _monthDay TINYINT UNSIGNED, _nextOccurrenceOn DATETIME, _nextRunOn DATETIME, _createdOn DATETIME, _membersStr VARCHAR(2880)) #2880 == 60 members
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE parentExists BOOL DEFAULT FALSE;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
+  IF _parent = _project THEN
+    SET parentExists=projectExists;
+  ELSE
+    SELECT COUNT(*)=1 INTO parentExists FROM tasks WHERE account = _account AND project = _project AND id = _parent AND isAbstract = TRUE;
+  END IF;
+  IF projectExists AND parentExists THEN
+    INSERT INTO recurrences (account, project, id, parent, name, description, totalRemainingTime, frequency, weekDays, monthDay, isActive, nextOccurrenceOn, nextRunOn, createdBy, createdOn) VALUES (
+      _account, _project, _recurrence, _parent, _name, _description, _totalRemainingTime, _frequency, _weekDays, _monthDay, TRUE, _nextOccurrenceOn, _nextRunOn, _me, _createdOn);
+    CALL _setRecurrenceMembers(_account, _project, _recurrence, _membersStr);
+    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+      _account, _project, UTC_TIMESTAMP(6), _me, _recurrence, 'recurrence', 'create', _name, NULL);
+    SET changeMade=TRUE;
+  END IF;
+  COMMIT;
+  SELECT changeMade;
+END;
 
-  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project; #LOCK IN SHARE MODE; I dont think this is required
+## overwrites a recurrences template and rule, a stopped recurrence is passed NULL next occurrence and run dates
+DROP PROCEDURE IF EXISTS editRecurrence;
+CREATE PROCEDURE editRecurrence(_account BINARY(16), _project BINARY(16), _recurrence BINARY(16), _me BINARY(16), _name VARCHAR(250), _description VARCHAR(1250), _totalRemainingTime BIGINT UNSIGNED, _frequency VARCHAR(10), _weekDays TINYINT UNSIGNED, _monthDay TINYINT UNSIGNED, _isActive BOOL, _nextOccurrenceOn DATETIME, _nextRunOn DATETIME, _membersStr VARCHAR(2880)) #2880 == 60 members
+BEGIN
+  DECLARE projectExists BOOL DEFAULT FALSE;
+  DECLARE recurrenceExists BOOL DEFAULT FALSE;
+  DECLARE changeMade BOOL DEFAULT FALSE;
+  START TRANSACTION;
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
   IF projectExists THEN
-    IF _fromSibling IS NOT NULL THEN
-      SELECT nextSibling INTO idVariable FROM tasks WHERE account = _account AND project = _project AND id = _fromSibling AND parent = _parent;
-    ELSE
-      SELECT firstChild INTO idVariable FROM tasks WHERE account = _account AND project = _project AND id = _parent;
-    END IF;
-    WHILE idVariable IS NOT NULL AND idx < _limit DO
-      INSERT INTO tempResult SELECT idx, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, member FROM tasks WHERE account =
-                                                                                                                                                                                                                                                            _account AND project = _project AND id = idVariable;
-      SELECT nextSibling INTO idVariable FROM tempResult WHERE selectOrder = idx;
-      SET idx = idx + 1;
-    END WHILE;
+    SELECT COUNT(*)=1 INTO recurrenceExists FROM recurrences WHERE account = _account AND project = _project AND id = _recurrence FOR UPDATE;
+  END IF;
+  IF recurrenceExists THEN
+    UPDATE recurrences SET name=_name, description=_description, totalRemainingTime=_totalRemainingTime, frequency=_frequency, weekDays=_weekDays, monthDay=_monthDay, isActive=_isActive, nextOccurrenceOn=_nextOccurrenceOn, nextRunOn=_nextRunOn WHERE account = _account AND project = _project AND id = _recurrence;
+    CALL _setRecurrenceMembers(_account, _project, _recurrence, _membersStr);
+    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+      _account, _project, UTC_TIMESTAMP(6), _me, _recurrence, 'recurrence', IF(_isActive, 'edit', 'stop'), _name, NULL);
+    UPDATE projectActivities SET itemName=_name WHERE account=_account AND project=_project AND item=_recurrence;
+    SET changeMade=TRUE;
   END IF;
   COMMIT;
-  SELECT id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, member FROM tempResult ORDER BY selectOrder ASC;
-  DROP TEMPORARY TABLE IF EXISTS tempResult;
+  SELECT changeMade;
 END;
 
-DROP PROCEDURE IF EXISTS getAncestorTasks;
-CREATE PROCEDURE getAncestorTasks(_account BINARY(16), _project BINARY(16), _task BINARY(16), _limit INT)
+DROP PROCEDURE IF EXISTS deleteRecurrence;
+CREATE PROCEDURE deleteRecurrence(_account BINARY(16), _project BINARY(16), _recurrence BINARY(16), _me BINARY(16))
 BEGIN
   DECLARE projectExists BOOL DEFAULT FALSE;
-  DECLARE idx INT DEFAULT 0;
-  DROP TEMPORARY TABLE IF EXISTS tempResult;
-  CREATE TEMPORARY TABLE tempResult(
-    selectOrder INT NOT NULL,
-    id BINARY(16) NOT NULL,
-    name VARCHAR(250) NOT NULL,
-    parent BINARY(16) NULL,
-    PRIMARY KEY (selectOrder)
-  );
+  DECLARE recurrenceExists BOOL DEFAULT FALSE;
+  DECLARE recName VARCHAR(250) DEFAULT '';
+  DECLARE changeMade BOOL DEFAULT FALSE;
   START TRANSACTION;
-
-  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project;# LOCK IN SHARE MODE; I dont think this is required
+  SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
   IF projectExists THEN
-    SELECT parent INTO _task FROM tasks WHERE account = _account AND project = _project AND id = _task;
-    WHILE _task IS NOT NULL AND idx < _limit DO
-      INSERT INTO tempResult SELECT idx, id, name, parent FROM tasks WHERE account=_account AND project=_project AND id=_task;
-      SELECT parent INTO _task FROM tempResult WHERE selectOrder = idx;
-      SET idx = idx + 1;
-    END WHILE;
+    SELECT COUNT(*)=1, name INTO recurrenceExists, recName FROM recurrences WHERE account = _account AND project = _project AND id = _recurrence FOR UPDATE;
+  END IF;
+  IF recurrenceExists THEN
+    DELETE FROM recurrences WHERE account = _account AND project = _project AND id = _recurrence;
+    DELETE FROM recurrenceMembers WHERE account = _account AND project = _project AND recurrence = _recurrence;
+    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+      _account, _project, UTC_TIMESTAMP(6), _me, _recurrence, 'recurrence', 'delete', recName, NULL);
+    UPDATE projectActivities SET itemHasBeenDeleted=TRUE WHERE account=_account AND project=_project AND item=_recurrence;
+    SET changeMade=TRUE;
   END IF;
   COMMIT;
-  SELECT id, name FROM tempResult ORDER BY selectOrder DESC;
-  DROP TEMPORARY TABLE IF EXISTS tempResult;
+  SELECT changeMade;
 END;
 
-DROP PROCEDURE IF EXISTS setTimeLogDuration;
-CREATE PROCEDURE setTimeLogDuration(_account BINARY(16), _project BINARY(16), _timeLog BINARY(16), _me BINARY(16), _duration BIGINT UNSIGNED)
+## creates the task for a recurrence that is due at _runOn as the last child of its parent and moves the recurrence on to its next run,
+## members who are no longer active project writers are left off the task with their share unassigned, if the parent has since been
+## deleted or made concrete the recurrence is stopped instead, and runs that come due while the project is archived are skipped
+DROP PROCEDURE IF EXISTS runRecurrence;
+CREATE PROCEDURE runRecurrence(_account BINARY(16), _project BINARY(16), _recurrence BINARY(16), _task BINARY(16), _runOn DATETIME, _nextOccurrenceOn DATETIME, _nextRunOn DATETIME, _createdOn DATETIME)
 BEGIN
   DECLARE projectExists BOOL DEFAULT FALSE;
-  DECLARE taskId BINARY(16) DEFAULT NULL;
-  DECLARE currentDuration BIGINT UNSIGNED DEFAULT 0;
-  DECLARE currentNote VARCHAR(250) DEFAULT NULL;
+  DECLARE projectIsArchived BOOL DEFAULT FALSE;
+  DECLARE recurrenceIsDue BOOL DEFAULT FALSE;
+  DECLARE parentExists BOOL DEFAULT FALSE;
+  DECLARE recParent BINARY(16) DEFAULT NULL;
+  DECLARE recName VARCHAR(250) DEFAULT '';
+  DECLARE recDescription VARCHAR(1250) DEFAULT NULL;
+  DECLARE recTotalRemainingTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE recCreatedBy BINARY(16) DEFAULT NULL;
+  DECLARE lastChild BINARY(16) DEFAULT NULL;
+  DECLARE firstState BINARY(16) DEFAULT NULL;
   DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
   CREATE TEMPORARY TABLE tempUpdatedIds(
     id BINARY(16) NOT NULL,
     PRIMARY KEY (id)
   );
-
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
+  CREATE TEMPORARY TABLE tempUpdatedMembers(
+    id BINARY(16) NOT NULL,
+    remainingTime BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (id)
+  );
   START TRANSACTION;
   SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
   IF projectExists THEN
-    SELECT task, duration, note INTO taskId, currentDuration, currentNote FROM timeLogs WHERE account=_account AND project=_project AND id=_timeLog;
-    IF currentDuration <> _duration AND _duration <> 0 THEN
-      UPDATE timeLogs SET duration=_duration WHERE account=_account AND project=_project AND id=_timeLog;
-      UPDATE tasks SET totalLoggedTime=totalLoggedTime+_duration-currentDuration WHERE account=_account AND project=_project AND id=taskId;
-      INSERT INTO tempUpdatedIds VALUES (taskId);
-      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _timeLog, 'timeLog', 'setDuration', currentNote, CONCAT('{"duration":', CAST(_duration as char character set utf8), '}'));
-      SELECT parent INTO taskId FROM tasks WHERE account=_account AND project=_project AND id=taskId;
-      CALL _setAncestralChainAggregateValuesFromTask(_account, _project, taskId);
+    #only the first worker to find the recurrence still due at _runOn gets to run it
+    SELECT COUNT(*)=1, parent, name, description, totalRemainingTime, createdBy INTO recurrenceIsDue, recParent, recName, recDescription, recTotalRemainingTime, recCreatedBy FROM recurrences WHERE account = _account AND project = _project AND id = _recurrence AND isActive = TRUE AND nextRunOn = _runOn FOR UPDATE;
+  END IF;
+  IF recurrenceIsDue THEN
+    SELECT isArchived INTO projectIsArchived FROM projects WHERE account = _account AND id = _project;
+    IF recParent = _project THEN
+      SET parentExists=TRUE;
+    ELSE
+      SELECT COUNT(*)=1 INTO parentExists FROM tasks WHERE account = _account AND project = _project AND id = recParent AND isAbstract = TRUE;
+    END IF;
+    IF NOT parentExists THEN
+      UPDATE recurrences SET isActive=FALSE, nextOccurrenceOn=NULL, nextRunOn=NULL WHERE account = _account AND project = _project AND id = _recurrence;
+    ELSE
+      UPDATE recurrences SET nextOccurrenceOn=_nextOccurrenceOn, nextRunOn=_nextRunOn WHERE account = _account AND project = _project AND id = _recurrence;
+    END IF;
+    IF parentExists AND NOT projectIsArchived THEN
+      SELECT id INTO lastChild FROM tasks WHERE account = _account AND project = _project AND parent = recParent AND nextSibling IS NULL;
+      SELECT id INTO firstState FROM projectStates WHERE account = _account AND project = _project ORDER BY position ASC LIMIT 1;
+      INSERT INTO tempUpdatedMembers SELECT rm.member, rm.remainingTime FROM recurrenceMembers rm INNER JOIN projectMembers pm ON pm.account = rm.account AND pm.project = rm.project AND pm.id = rm.member WHERE rm.account = _account AND rm.project = _project AND rm.recurrence = _recurrence AND pm.isActive = TRUE AND pm.role < 2; #less than 2 means 0->projectAdmin or 1->projectWriter
+      INSERT INTO tasks (account,	project, id, parent, firstChild, nextSibling, isAbstract, name,	description, createdOn, totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state) VALUES (
+        _account, _project, _task, recParent, NULL, NULL, FALSE, recName, recDescription, _createdOn, recTotalRemainingTime, 0, recTotalRemainingTime, 0, 0, 0, 0, FALSE, firstState);
+      INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (
+        _account, _project, UTC_TIMESTAMP(6), recCreatedBy, _task, 'task', 'create', recName, NULL);
+      IF recTotalRemainingTime > 0 THEN
+        INSERT INTO remainingTimeChanges (account, project, task, occurredOn, member, oldValue, newValue) VALUES (_account, _project, _task, UTC_TIMESTAMP(6), recCreatedBy, 0, recTotalRemainingTime);
+      END IF;
+      IF lastChild IS NULL THEN
+        UPDATE tasks SET firstChild=_task WHERE account = _account AND project = _project AND id = recParent;
+      ELSE
+        UPDATE tasks SET nextSibling=_task WHERE account = _account AND project = _project AND id = lastChild;
+        INSERT INTO tempUpdatedIds VALUES (lastChild) ON DUPLICATE KEY UPDATE id=id;
+      END IF;
+      INSERT INTO tempUpdatedIds VALUES (recParent) ON DUPLICATE KEY UPDATE id=id;
+      INSERT INTO taskMembers (account, project, task, member, remainingTime) SELECT _account, _project, _task, id, remainingTime FROM tempUpdatedMembers;
+      UPDATE projectMembers pm
+        INNER JOIN tempUpdatedMembers tum
+        ON pm.account = _account AND pm.project = _project AND pm.id = tum.id
+        SET pm.totalRemainingTime = pm.totalRemainingTime + tum.remainingTime;
+      CALL _setAncestralChainAggregateValuesFromTask(_account, _project, recParent);
     END IF;
   END IF;
   COMMIT;
-  SELECT * FROM tempUpdatedIds;
+  SELECT id, 't' FROM tempUpdatedIds
+  UNION
+  SELECT id, 'm' FROM tempUpdatedMembers;
   DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempUpdatedMembers;
 END;
 
-DROP PROCEDURE IF EXISTS setTimeLogNote;
-CREATE PROCEDURE setTimeLogNote(_account BINARY(16), _project BINARY(16), _timeLog BINARY(16), _me BINARY(16), _note VARCHAR(250))
-BEGIN
-  DECLARE timeLogExists BOOL DEFAULT FALSE;
-  DECLARE changeMade BOOL DEFAULT FALSE;
-  SELECT COUNT(*)=1 INTO timeLogExists FROM timeLogs WHERE account=_account AND project=_project AND id=_timeLog;
-  IF timeLogExists THEN
-    UPDATE timeLogs SET note=_note WHERE account=_account AND project=_project AND id=_timeLog;
-    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _timeLog, 'timeLog', 'setNote', _note, NULL);
-    SET changeMade=TRUE;
-  END IF;
-  SELECT changeMade;
-END;
-
-DROP PROCEDURE IF EXISTS deleteTimeLog;
-CREATE PROCEDURE deleteTimeLog(_account BINARY(16), _project BINARY(16), _timeLog BINARY(16), _me BINARY(16))
+## recomputes every tasks aggregate values bottom up from its children, or from its time logs for concrete tasks, and checks
+## each tasks firstChild/nextSibling list links exactly its children, returning a row for every discrepancy found. Tasks that
+## can't be reached from the project task are reported against their parent. The recomputed values are only kept when _repair
+## is set, broken sibling lists are only ever reported as there is no way to tell what order the children should be in.
+DROP PROCEDURE IF EXISTS verifyProjectTree;
+CREATE PROCEDURE verifyProjectTree(_account BINARY(16), _project BINARY(16), _repair BOOL)
 BEGIN
   DECLARE projectExists BOOL DEFAULT FALSE;
-  DECLARE taskId BINARY(16) DEFAULT NULL;
-  DECLARE currentDuration BIGINT UNSIGNED DEFAULT 0;
-  DECLARE currentNote VARCHAR(250) DEFAULT NULL;
-  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
-  CREATE TEMPORARY TABLE tempUpdatedIds(
+  DECLARE projectHasDependencies BOOL DEFAULT FALSE;
+  DECLARE currentDepth INT DEFAULT 0;
+  DECLARE idVariable BINARY(16) DEFAULT NULL;
+  DECLARE lastIdVariable BINARY(16) DEFAULT NULL;
+  DECLARE listedIdVariable BINARY(16) DEFAULT NULL;
+  DECLARE listedParentVariable BINARY(16) DEFAULT NULL;
+  DECLARE currentIsAbstract BOOL DEFAULT FALSE;
+  DECLARE currentIsParallel BOOL DEFAULT FALSE;
+  DECLARE listIsBroken BOOL DEFAULT FALSE;
+  DECLARE listedCount BIGINT UNSIGNED DEFAULT 0;
+  DECLARE newTotalRemainingTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE newTotalLoggedTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE newTotalCost BIGINT UNSIGNED DEFAULT 0;
+  DECLARE newTotalRevenue BIGINT UNSIGNED DEFAULT 0;
+  DECLARE newMinimumRemainingTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE newChildCount BIGINT UNSIGNED DEFAULT 0;
+  DECLARE newDescendantCount BIGINT UNSIGNED DEFAULT 0;
+  DROP TEMPORARY TABLE IF EXISTS tempTaskDepths;
+  CREATE TEMPORARY TABLE tempTaskDepths(
+    id BINARY(16) NOT NULL,
+    depth INT NOT NULL,
+    PRIMARY KEY (id),
+    INDEX (depth, id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempCurrentIds;
+  CREATE TEMPORARY TABLE tempCurrentIds(
     id BINARY(16) NOT NULL,
     PRIMARY KEY (id)
   );
-
+  DROP TEMPORARY TABLE IF EXISTS tempLatestIds;
+  CREATE TEMPORARY TABLE tempLatestIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempListedIds;
+  CREATE TEMPORARY TABLE tempListedIds(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempStoredAggregates;
+  CREATE TEMPORARY TABLE tempStoredAggregates(
+    id BINARY(16) NOT NULL,
+    totalRemainingTime BIGINT UNSIGNED NOT NULL,
+    totalLoggedTime BIGINT UNSIGNED NOT NULL,
+    totalCost BIGINT UNSIGNED NOT NULL,
+    totalRevenue BIGINT UNSIGNED NOT NULL,
+    minimumRemainingTime BIGINT UNSIGNED NOT NULL,
+    childCount BIGINT UNSIGNED NOT NULL,
+    descendantCount BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (id)
+  );
+  #MEMORY so the discrepancies survive the rollback when not repairing
+  DROP TEMPORARY TABLE IF EXISTS tempDiscrepancies;
+  CREATE TEMPORARY TABLE tempDiscrepancies(
+    task BINARY(16) NOT NULL,
+    field VARCHAR(50) NOT NULL,
+    stored BIGINT UNSIGNED NOT NULL,
+    computed BIGINT UNSIGNED NOT NULL
+  ) ENGINE=MEMORY;
   START TRANSACTION;
+
   SELECT COUNT(*)=1 INTO projectExists FROM projectLocks WHERE account = _account AND id = _project FOR UPDATE;
   IF projectExists THEN
-    SELECT task, duration, note INTO taskId, currentDuration, currentNote FROM timeLogs WHERE account=_account AND project=_project AND id=_timeLog;
-    DELETE FROM timeLogs WHERE account=_account AND project=_project AND id=_timeLog;
-    UPDATE tasks SET totalLoggedTime=totalLoggedTime-currentDuration WHERE account=_account AND project=_project AND id=taskId;
-    INSERT INTO tempUpdatedIds VALUES (taskId);
-    INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (_account, _project, UTC_TIMESTAMP(6), _me, _timeLog, 'timeLog', 'delete', currentNote, CONCAT('{"duration":', CAST(currentDuration as char character set utf8), '}'));
-    UPDATE projectActivities SET itemHasBeenDeleted=TRUE WHERE account=_account AND project=_project AND item=_timeLog;
-    SELECT parent INTO taskId FROM tasks WHERE account=_account AND project=_project AND id=taskId;
-    CALL _setAncestralChainAggregateValuesFromTask(_account, _project, taskId);
+    SELECT COUNT(*) > 0 INTO projectHasDependencies FROM taskDependencies WHERE account = _account AND project = _project;
+    IF projectHasDependencies THEN
+      CALL _setDependencyCommonAncestors(_account, _project, NULL);
+    END IF;
+    INSERT INTO tempStoredAggregates SELECT id, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, childCount, descendantCount FROM tasks WHERE account = _account AND project = _project;
+
+    #find the depth of every task reachable from the project task by following parent links down
+    INSERT INTO tempCurrentIds VALUES (_project);
+    WHILE (SELECT COUNT(*) FROM tempCurrentIds) > 0 DO
+      INSERT INTO tempTaskDepths SELECT id, currentDepth FROM tempCurrentIds;
+      INSERT INTO tempLatestIds SELECT id FROM tasks WHERE account = _account AND project = _project AND parent IN (SELECT id FROM tempCurrentIds);
+      TRUNCATE tempCurrentIds;
+      INSERT INTO tempCurrentIds SELECT id FROM tempLatestIds;
+      TRUNCATE tempLatestIds;
+      SET currentDepth = currentDepth + 1;
+    END WHILE;
+    INSERT INTO tempDiscrepancies SELECT COALESCE(parent, id), 'unreachableChild', 0, 0 FROM tasks WHERE account = _account AND project = _project AND id NOT IN (SELECT id FROM tempTaskDepths);
+
+    #concrete tasks are the leaves, their logged time and costs are the sums of their time logs
+    UPDATE tasks t SET t.totalLoggedTime = (SELECT COALESCE(SUM(tl.duration), 0) FROM timeLogs tl WHERE tl.account = _account AND tl.project = _project AND tl.task = t.id), t.totalCost = (SELECT COALESCE(SUM(tl.cost), 0) FROM timeLogs tl WHERE tl.account = _account AND tl.project = _project AND tl.task = t.id), t.totalRevenue = (SELECT COALESCE(SUM(IF(tl.isBillable, tl.cost, 0)), 0) FROM timeLogs tl WHERE tl.account = _account AND tl.project = _project AND tl.task = t.id), t.minimumRemainingTime = t.totalRemainingTime, t.childCount = 0, t.descendantCount = 0 WHERE t.account = _account AND t.project = _project AND t.isAbstract = FALSE;
+
+    #abstract tasks deepest first so every child is correct before its parent is summed, exactly as _setAncestralChainAggregateValuesFromTask does
+    WHILE currentDepth > 0 DO
+      SET currentDepth = currentDepth - 1;
+      SET idVariable = NULL;
+      SELECT id INTO idVariable FROM tempTaskDepths WHERE depth = currentDepth ORDER BY id ASC LIMIT 1;
+      WHILE idVariable IS NOT NULL DO
+        SELECT isAbstract, isParallel INTO currentIsAbstract, currentIsParallel FROM tasks WHERE account = _account AND project = _project AND id = idVariable;
+        IF currentIsAbstract THEN
+          IF currentIsParallel THEN
+            SELECT SUM(totalRemainingTime), SUM(totalLoggedTime), SUM(totalCost), SUM(totalRevenue), MAX(minimumRemainingTime), COUNT(*), SUM(descendantCount) INTO newTotalRemainingTime, newTotalLoggedTime, newTotalCost, newTotalRevenue, newMinimumRemainingTime, newChildCount, newDescendantCount FROM tasks WHERE account = _account AND project = _project AND parent = idVariable;
+            IF projectHasDependencies AND newChildCount > 0 THEN
+              CALL _getParallelMinimumRemainingTime(_account, _project, idVariable, newMinimumRemainingTime);
+            END IF;
+          ELSE
+            SELECT SUM(totalRemainingTime), SUM(totalLoggedTime), SUM(totalCost), SUM(totalRevenue), SUM(minimumRemainingTime), COUNT(*), SUM(descendantCount) INTO newTotalRemainingTime, newTotalLoggedTime, newTotalCost, newTotalRevenue, newMinimumRemainingTime, newChildCount, newDescendantCount FROM tasks WHERE account = _account AND project = _project AND parent = idVariable;
+          END IF;
+          IF newTotalRemainingTime IS NULL THEN
+            SELECT 0, 0, 0, 0, 0, 0, 0 INTO newTotalRemainingTime, newTotalLoggedTime, newTotalCost, newTotalRevenue, newMinimumRemainingTime, newChildCount, newDescendantCount;
+          END IF;
+          UPDATE tasks SET totalRemainingTime = newTotalRemainingTime, totalLoggedTime = newTotalLoggedTime, totalCost = newTotalCost, totalRevenue = newTotalRevenue, minimumRemainingTime = newMinimumRemainingTime, childCount = newChildCount, descendantCount = newDescendantCount + newChildCount WHERE account = _account AND project = _project AND id = idVariable;
+        END IF;
+
+        #walk the sibling list, it is broken if it leaves the children, loops or misses any of them
+        SET listIsBroken = FALSE;
+        SET listedCount = 0;
+        TRUNCATE tempListedIds;
+        SELECT COUNT(*) INTO newChildCount FROM tasks WHERE account = _account AND project = _project AND parent = idVariable;
+        SELECT firstChild INTO listedIdVariable FROM tasks WHERE account = _account AND project = _project AND id = idVariable;
+        WHILE listedIdVariable IS NOT NULL AND NOT listIsBroken DO
+          SET listedParentVariable = NULL;
+          SELECT parent INTO listedParentVariable FROM tasks WHERE account = _account AND project = _project AND id = listedIdVariable;
+          IF NOT (listedParentVariable <=> idVariable) OR (SELECT COUNT(*) FROM tempListedIds WHERE id = listedIdVariable) > 0 THEN
+            SET listIsBroken = TRUE;
+          ELSE
+            INSERT INTO tempListedIds VALUES (listedIdVariable);
+            SET listedCount = listedCount + 1;
+            SELECT nextSibling INTO listedIdVariable FROM tasks WHERE account = _account AND project = _project AND id = listedIdVariable;
+          END IF;
+        END WHILE;
+        IF listIsBroken OR listedCount <> newChildCount THEN
+          INSERT INTO tempDiscrepancies VALUES (idVariable, 'siblingList', listedCount, newChildCount);
+        END IF;
+
+        SET lastIdVariable = idVariable;
+        SET idVariable = NULL;
+        SELECT id INTO idVariable FROM tempTaskDepths WHERE depth = currentDepth AND id > lastIdVariable ORDER BY id ASC LIMIT 1;
+      END WHILE;
+    END WHILE;
+
+    INSERT INTO tempDiscrepancies SELECT t.id, 'totalRemainingTime', s.totalRemainingTime, t.totalRemainingTime FROM tasks t INNER JOIN tempStoredAggregates s ON s.id = t.id WHERE t.account = _account AND t.project = _project AND s.totalRemainingTime <> t.totalRemainingTime;
+    INSERT INTO tempDiscrepancies SELECT t.id, 'totalLoggedTime', s.totalLoggedTime, t.totalLoggedTime FROM tasks t INNER JOIN tempStoredAggregates s ON s.id = t.id WHERE t.account = _account AND t.project = _project AND s.totalLoggedTime <> t.totalLoggedTime;
+    INSERT INTO tempDiscrepancies SELECT t.id, 'totalCost', s.totalCost, t.totalCost FROM tasks t INNER JOIN tempStoredAggregates s ON s.id = t.id WHERE t.account = _account AND t.project = _project AND s.totalCost <> t.totalCost;
+    INSERT INTO tempDiscrepancies SELECT t.id, 'totalRevenue', s.totalRevenue, t.totalRevenue FROM tasks t INNER JOIN tempStoredAggregates s ON s.id = t.id WHERE t.account = _account AND t.project = _project AND s.totalRevenue <> t.totalRevenue;
+    INSERT INTO tempDiscrepancies SELECT t.id, 'minimumRemainingTime', s.minimumRemainingTime, t.minimumRemainingTime FROM tasks t INNER JOIN tempStoredAggregates s ON s.id = t.id WHERE t.account = _account AND t.project = _project AND s.minimumRemainingTime <> t.minimumRemainingTime;
+    INSERT INTO tempDiscrepancies SELECT t.id, 'childCount', s.childCount, t.childCount FROM tasks t INNER JOIN tempStoredAggregates s ON s.id = t.id WHERE t.account = _account AND t.project = _project AND s.childCount <> t.childCount;
+    INSERT INTO tempDiscrepancies SELECT t.id, 'descendantCount', s.descendantCount, t.descendantCount FROM tasks t INNER JOIN tempStoredAggregates s ON s.id = t.id WHERE t.account = _account AND t.project = _project AND s.descendantCount <> t.descendantCount;
   END IF;
-  COMMIT;
-  SELECT * FROM tempUpdatedIds;
-  DROP TEMPORARY TABLE IF EXISTS tempUpdatedIds;
+  IF _repair THEN
+    COMMIT;
+  ELSE
+    ROLLBACK;
+  END IF;
+  SELECT task, field, stored, computed FROM tempDiscrepancies;
+  DROP TEMPORARY TABLE IF EXISTS tempTaskDepths;
+  DROP TEMPORARY TABLE IF EXISTS tempCurrentIds;
+  DROP TEMPORARY TABLE IF EXISTS tempLatestIds;
+  DROP TEMPORARY TABLE IF EXISTS tempListedIds;
+  DROP TEMPORARY TABLE IF EXISTS tempStoredAggregates;
+  DROP TEMPORARY TABLE IF EXISTS tempDiscrepancies;
 END;
 
 #!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
@@ -1110,8 +3798,11 @@ END;
 DROP PROCEDURE IF EXISTS _setAncestralChainAggregateValuesFromTask;
 CREATE PROCEDURE _setAncestralChainAggregateValuesFromTask(_account BINARY(16), _project BINARY(16), _task BINARY(16))
 BEGIN
+  DECLARE originalTask BINARY(16) DEFAULT _task;
   DECLARE originalTotalRemainingTime BIGINT UNSIGNED DEFAULT 0;
   DECLARE originalTotalLoggedTime BIGINT UNSIGNED DEFAULT 0;
+  DECLARE originalTotalCost BIGINT DEFAULT 0;
+  DECLARE originalTotalRevenue BIGINT DEFAULT 0;
   DECLARE currentMinimumRemainingTime BIGINT UNSIGNED DEFAULT 0;
   DECLARE originalChildCount BIGINT UNSIGNED DEFAULT 0;
   DECLARE originalDescendantCount BIGINT UNSIGNED DEFAULT 0;
@@ -1119,6 +3810,8 @@ BEGIN
   DECLARE nextTask BINARY(16) DEFAULT NULL;
   DECLARE totalRemainingTimeChange BIGINT UNSIGNED DEFAULT 0;
   DECLARE totalLoggedTimeChange BIGINT UNSIGNED DEFAULT 0;
+  DECLARE totalCostChange BIGINT DEFAULT 0; #signed so it doesn't double the sign cases below
+  DECLARE totalRevenueChange BIGINT DEFAULT 0;
   DECLARE preChangeMinimumRemainingTime BIGINT UNSIGNED DEFAULT 0;
   DECLARE postChangeMinimumRemainingTime BIGINT UNSIGNED DEFAULT 0;
   DECLARE newChildCount BIGINT UNSIGNED DEFAULT 0;
@@ -1127,21 +3820,26 @@ BEGIN
   DECLARE totalLoggedTimeChangeIsPositive BOOL DEFAULT TRUE;
   DECLARE descendantCountChangeIsPositive BOOL DEFAULT TRUE;
   DECLARE minimumRemainingTimeIsChanging BOOL DEFAULT TRUE;
+  DECLARE projectHasDependencies BOOL DEFAULT FALSE;
 
-  SELECT totalRemainingTime, totalLoggedTime, minimumRemainingTime, childCount, descendantCount, isParallel, parent INTO originalTotalRemainingTime, originalTotalLoggedTime, preChangeMinimumRemainingTime, originalChildCount, originalDescendantCount, currentIsParallel, nextTask FROM tasks WHERE account = _account AND project = _project AND id = _task;
+  SELECT COUNT(*) > 0 INTO projectHasDependencies FROM taskDependencies WHERE account = _account AND project = _project;
+  SELECT totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, childCount, descendantCount, isParallel, parent INTO originalTotalRemainingTime, originalTotalLoggedTime, originalTotalCost, originalTotalRevenue, preChangeMinimumRemainingTime, originalChildCount, originalDescendantCount, currentIsParallel, nextTask FROM tasks WHERE account = _account AND project = _project AND id = _task;
   IF currentIsParallel THEN
-    SELECT SUM(totalRemainingTime), SUM(totalLoggedTime), MAX(minimumRemainingTime), COUNT(*), SUM(descendantCount) INTO totalRemainingTimeChange, totalLoggedTimeChange, postChangeMinimumRemainingTime, newChildCount, descendantCountChange FROM tasks WHERE account = _account AND project = _project AND parent = _task;
+    SELECT SUM(totalRemainingTime), SUM(totalLoggedTime), SUM(totalCost), SUM(totalRevenue), MAX(minimumRemainingTime), COUNT(*), SUM(descendantCount) INTO totalRemainingTimeChange, totalLoggedTimeChange, totalCostChange, totalRevenueChange, postChangeMinimumRemainingTime, newChildCount, descendantCountChange FROM tasks WHERE account = _account AND project = _project AND parent = _task;
+    IF projectHasDependencies AND newChildCount > 0 THEN
+      CALL _getParallelMinimumRemainingTime(_account, _project, _task, postChangeMinimumRemainingTime);
+    END IF;
   ELSE                                                   #this is the only difference#
-    SELECT SUM(totalRemainingTime), SUM(totalLoggedTime), SUM(minimumRemainingTime), COUNT(*), SUM(descendantCount) INTO totalRemainingTimeChange, totalLoggedTimeChange, postChangeMinimumRemainingTime, newChildCount, descendantCountChange FROM tasks WHERE account = _account AND project = _project AND parent = _task;
+    SELECT SUM(totalRemainingTime), SUM(totalLoggedTime), SUM(totalCost), SUM(totalRevenue), SUM(minimumRemainingTime), COUNT(*), SUM(descendantCount) INTO totalRemainingTimeChange, totalLoggedTimeChange, totalCostChange, totalRevenueChange, postChangeMinimumRemainingTime, newChildCount, descendantCountChange FROM tasks WHERE account = _account AND project = _project AND parent = _task;
   END IF;
   SET descendantCountChange = descendantCountChange + newChildCount;
   #if we just deleted the only child node of an abstract task then all these values will be NULL so we need to manually set them to zero here
   IF totalRemainingTimeChange IS NULL THEN
-    SELECT 0, 0, 0, 0, 0 INTO totalRemainingTimeChange, totalLoggedTimeChange, postChangeMinimumRemainingTime, newChildCount, descendantCountChange;
+    SELECT 0, 0, 0, 0, 0, 0, 0 INTO totalRemainingTimeChange, totalLoggedTimeChange, totalCostChange, totalRevenueChange, postChangeMinimumRemainingTime, newChildCount, descendantCountChange;
   END IF;
 
   #the first task updated is special, it could have had a new child added or removed from it, so the childCount can be updated, no other ancestor will have the childCount updated
-  UPDATE tasks SET totalRemainingTime = totalRemainingTimeChange, totalLoggedTime = totalLoggedTimeChange, minimumRemainingTime = postChangeMinimumRemainingTime, childCount = newChildCount, descendantCount = descendantCountChange WHERE account = _account AND project = _project AND id = _task;
+  UPDATE tasks SET totalRemainingTime = totalRemainingTimeChange, totalLoggedTime = totalLoggedTimeChange, totalCost = totalCostChange, totalRevenue = totalRevenueChange, minimumRemainingTime = postChangeMinimumRemainingTime, childCount = newChildCount, descendantCount = descendantCountChange WHERE account = _account AND project = _project AND id = _task;
   INSERT INTO tempUpdatedIds VALUES (_task) ON DUPLICATE KEY UPDATE id=id;
 
   IF totalRemainingTimeChange >= originalTotalRemainingTime THEN
@@ -1158,6 +3856,9 @@ BEGIN
     SET totalLoggedTimeChangeIsPositive = FALSE;
   END IF;
 
+  SET totalCostChange = totalCostChange - originalTotalCost;
+  SET totalRevenueChange = totalRevenueChange - originalTotalRevenue;
+
   IF descendantCountChange >= originalDescendantCount THEN
     SET descendantCountChange = descendantCountChange - originalDescendantCount;
   ELSE
@@ -1167,12 +3868,17 @@ BEGIN
 
   SET _task= nextTask;
 
-  WHILE _task IS NOT NULL AND (totalRemainingTimeChange > 0 OR totalLoggedTimeChange > 0 OR preChangeMinimumRemainingTime <> postChangeMinimumRemainingTime OR descendantCountChange > 0) DO
+  WHILE _task IS NOT NULL AND (totalRemainingTimeChange > 0 OR totalLoggedTimeChange > 0 OR totalCostChange <> 0 OR totalRevenueChange <> 0 OR preChangeMinimumRemainingTime <> postChangeMinimumRemainingTime OR descendantCountChange > 0) DO
     IF preChangeMinimumRemainingTime <> postChangeMinimumRemainingTime THEN #updating minimumRemainingTime and others
       #get values needed to update current task
       SELECT isParallel, minimumRemainingTime, parent INTO currentIsParallel, currentMinimumRemainingTime, nextTask FROM tasks WHERE account =
                                                                                                                                      _account AND project = _project AND id = _task;
-      IF currentIsParallel AND currentMinimumRemainingTime < postChangeMinimumRemainingTime THEN
+      IF currentIsParallel AND projectHasDependencies THEN #dependencies between children can chain them together so the full value must be recalculated
+        CALL _getParallelMinimumRemainingTime(_account, _project, _task, postChangeMinimumRemainingTime);
+        IF postChangeMinimumRemainingTime = currentMinimumRemainingTime THEN
+          SET minimumRemainingTimeIsChanging=FALSE;
+        END IF;
+      ELSEIF currentIsParallel AND currentMinimumRemainingTime < postChangeMinimumRemainingTime THEN
         SET postChangeMinimumRemainingTime = postChangeMinimumRemainingTime; #pointless assignment but this if case is necessary
       ELSEIF currentIsParallel AND currentMinimumRemainingTime = preChangeMinimumRemainingTime THEN
         SELECT MAX(minimumRemainingTime) INTO postChangeMinimumRemainingTime FROM tasks WHERE account = _account AND project = _project AND parent = _task;
@@ -1191,54 +3897,54 @@ BEGIN
     #do the actual update, needs a bunch of bool logic to work out +/- sign usage, 16 cases for all combinations, but the task is updated in a single update statement :)
     IF minimumRemainingTimeIsChanging THEN
       IF totalRemainingTimeChangeIsPositive AND totalLoggedTimeChangeIsPositive AND descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount+descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount+descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                                                                                  _account AND project = _project AND id = _task;
       ELSEIF totalRemainingTimeChangeIsPositive AND totalLoggedTimeChangeIsPositive AND NOT descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount-descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount-descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                                                                                  _account AND project = _project AND id = _task;
       ELSEIF totalRemainingTimeChangeIsPositive AND NOT totalLoggedTimeChangeIsPositive AND descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount+descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount+descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                                                                                  _account AND project = _project AND id = _task;
       ELSEIF totalRemainingTimeChangeIsPositive AND NOT totalLoggedTimeChangeIsPositive AND NOT descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount-descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount-descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                                                                                  _account AND project = _project AND id = _task;
       ELSEIF NOT totalRemainingTimeChangeIsPositive AND totalLoggedTimeChangeIsPositive AND descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount+descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount+descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                                                                                  _account AND project = _project AND id = _task;
       ELSEIF NOT totalRemainingTimeChangeIsPositive AND totalLoggedTimeChangeIsPositive AND NOT descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount-descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount-descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                                                                                  _account AND project = _project AND id = _task;
       ELSEIF NOT totalRemainingTimeChangeIsPositive AND NOT totalLoggedTimeChangeIsPositive AND descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount+descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount+descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                                                                                  _account AND project = _project AND id = _task;
       ELSEIF NOT totalRemainingTimeChangeIsPositive AND NOT totalLoggedTimeChangeIsPositive AND NOT descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount-descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, minimumRemainingTime=postChangeMinimumRemainingTime, descendantCount=descendantCount-descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                                                                                  _account AND project = _project AND id = _task;
       END IF;
     ELSE
       IF totalRemainingTimeChangeIsPositive AND totalLoggedTimeChangeIsPositive AND descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, descendantCount=descendantCount+descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, descendantCount=descendantCount+descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                             _account AND project = _project AND id = _task;
       ELSEIF totalRemainingTimeChangeIsPositive AND totalLoggedTimeChangeIsPositive AND NOT descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, descendantCount=descendantCount-descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, descendantCount=descendantCount-descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                             _account AND project = _project AND id = _task;
       ELSEIF totalRemainingTimeChangeIsPositive AND NOT totalLoggedTimeChangeIsPositive AND descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, descendantCount=descendantCount+descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, descendantCount=descendantCount+descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                             _account AND project = _project AND id = _task;
       ELSEIF totalRemainingTimeChangeIsPositive AND NOT totalLoggedTimeChangeIsPositive AND NOT descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, descendantCount=descendantCount-descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime+totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, descendantCount=descendantCount-descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                             _account AND project = _project AND id = _task;
       ELSEIF NOT totalRemainingTimeChangeIsPositive AND totalLoggedTimeChangeIsPositive AND descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, descendantCount=descendantCount+descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, descendantCount=descendantCount+descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                             _account AND project = _project AND id = _task;
       ELSEIF NOT totalRemainingTimeChangeIsPositive AND totalLoggedTimeChangeIsPositive AND NOT descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, descendantCount=descendantCount-descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime+totalLoggedTimeChange, descendantCount=descendantCount-descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                             _account AND project = _project AND id = _task;
       ELSEIF NOT totalRemainingTimeChangeIsPositive AND NOT totalLoggedTimeChangeIsPositive AND descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, descendantCount=descendantCount+descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, descendantCount=descendantCount+descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                             _account AND project = _project AND id = _task;
       ELSEIF NOT totalRemainingTimeChangeIsPositive AND NOT totalLoggedTimeChangeIsPositive AND NOT descendantCountChangeIsPositive THEN
-        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, descendantCount=descendantCount-descendantCountChange WHERE account =
+        UPDATE tasks SET totalRemainingTime=totalRemainingTime-totalRemainingTimeChange, totalLoggedTime=totalLoggedTime-totalLoggedTimeChange, descendantCount=descendantCount-descendantCountChange, totalCost=totalCost+totalCostChange, totalRevenue=totalRevenue+totalRevenueChange WHERE account =
                                                                                                                                                                                                             _account AND project = _project AND id = _task;
       END IF;
     END IF;
@@ -1251,6 +3957,248 @@ BEGIN
   IF _task IS NOT NULL THEN
     INSERT INTO tempUpdatedIds VALUES (_task) ON DUPLICATE KEY UPDATE id=id;
   END IF;
+  #state counts are maintained alongside descendantCount but can not share the early exit above
+  CALL _setAncestralChainStateCounts(_account, _project, originalTask);
+END;
+
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+#********************************MAGIC PROCEDURE WARNING*********************************#
+# THIS PROCEDURE MUST ONLY BE CALLED INTERNALLY BY THE ABOVE STORED PROCEDURES THAT HAVE #
+# SET THEIR OWN TRANSACTIONS AND PROJECTID LOCKS AND HAVE VALIDATED ALL INPUT PARAMS.    #
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+#recounts _task's per state counts from its children and applies the difference to _task and all of its ancestors
+DROP PROCEDURE IF EXISTS _setAncestralChainStateCounts;
+CREATE PROCEDURE _setAncestralChainStateCounts(_account BINARY(16), _project BINARY(16), _task BINARY(16))
+BEGIN
+  DECLARE nextTask BINARY(16) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempStateCountChanges;
+  CREATE TEMPORARY TABLE tempStateCountChanges(
+    state BINARY(16) NOT NULL,
+    countChange BIGINT NOT NULL,
+    PRIMARY KEY (state)
+  );
+  #concrete children count once towards their own state, abstract children pass up their own counts
+  INSERT INTO tempStateCountChanges SELECT state, COUNT(*) FROM tasks WHERE account = _account AND project = _project AND parent = _task AND isAbstract = FALSE AND state IS NOT NULL GROUP BY state;
+  INSERT INTO tempStateCountChanges SELECT tsc.state, SUM(tsc.count) FROM taskStateCounts tsc INNER JOIN tasks t ON tsc.account = t.account AND tsc.project = t.project AND tsc.task = t.id WHERE t.account = _account AND t.project = _project AND t.parent = _task GROUP BY tsc.state ON DUPLICATE KEY UPDATE countChange=countChange+VALUES(countChange);
+  INSERT INTO tempStateCountChanges SELECT state, 0-count FROM taskStateCounts WHERE account = _account AND project = _project AND task = _task ON DUPLICATE KEY UPDATE countChange=countChange+VALUES(countChange);
+  DELETE FROM tempStateCountChanges WHERE countChange = 0;
+  WHILE _task IS NOT NULL AND (SELECT COUNT(*) FROM tempStateCountChanges) > 0 DO
+    INSERT INTO taskStateCounts (account, project, task, state, count) SELECT _account, _project, _task, state, countChange FROM tempStateCountChanges ON DUPLICATE KEY UPDATE count=count+VALUES(count);
+    DELETE FROM taskStateCounts WHERE account = _account AND project = _project AND task = _task AND count = 0;
+    INSERT INTO tempUpdatedIds VALUES (_task) ON DUPLICATE KEY UPDATE id=id;
+    SELECT parent INTO nextTask FROM tasks WHERE account = _account AND project = _project AND id = _task;
+    SET _task=nextTask;
+  END WHILE;
+  DROP TEMPORARY TABLE IF EXISTS tempStateCountChanges;
+END;
+
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+#********************************MAGIC PROCEDURE WARNING*********************************#
+# THIS PROCEDURE MUST ONLY BE CALLED INTERNALLY BY THE ABOVE STORED PROCEDURES THAT HAVE #
+# SET THEIR OWN TRANSACTIONS AND PROJECTID LOCKS AND HAVE VALIDATED ALL INPUT PARAMS.    #
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+#applies a change in a trashed concrete tasks logged time and costs to it and its trashed ancestors, and the logged time to its
+#trash item, so the totals are right if it is restored, does nothing if _task isn't in the trash
+DROP PROCEDURE IF EXISTS _setTrashedAncestralChainLoggedTimeAndCosts;
+CREATE PROCEDURE _setTrashedAncestralChainLoggedTimeAndCosts(_account BINARY(16), _project BINARY(16), _task BINARY(16), _oldDuration BIGINT UNSIGNED, _newDuration BIGINT UNSIGNED, _oldCost BIGINT UNSIGNED, _newCost BIGINT UNSIGNED, _oldRevenue BIGINT UNSIGNED, _newRevenue BIGINT UNSIGNED)
+BEGIN
+  DECLARE nextTask BINARY(16) DEFAULT NULL;
+  WHILE _task IS NOT NULL DO
+    SET nextTask = NULL;
+    SELECT parent INTO nextTask FROM trashedTasks WHERE account = _account AND project = _project AND id = _task;
+    UPDATE trashedTasks SET totalLoggedTime=totalLoggedTime+_newDuration-_oldDuration, totalCost=totalCost+_newCost-_oldCost, totalRevenue=totalRevenue+_newRevenue-_oldRevenue WHERE account = _account AND project = _project AND id = _task;
+    UPDATE trash SET totalLoggedTime=totalLoggedTime+_newDuration-_oldDuration WHERE account = _account AND project = _project AND id = _task;
+    SET _task=nextTask;
+  END WHILE;
+END;
+
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+#********************************MAGIC PROCEDURE WARNING*********************************#
+# THIS PROCEDURE MUST ONLY BE CALLED INTERNALLY BY THE ABOVE STORED PROCEDURES THAT HAVE #
+# SET THEIR OWN TRANSACTIONS AND PROJECTID LOCKS AND HAVE VALIDATED ALL INPUT PARAMS.    #
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+# The minimumRemainingTime of a parallel task is normally the max of its childrens, but a dependency between tasks in
+# two different child subtrees means those children can not run in parallel, so it is the longest chain of children
+# linked by dependencies. Only the dependencies whose commonAncestor is _task link two of its children.
+DROP PROCEDURE IF EXISTS _getParallelMinimumRemainingTime;
+CREATE PROCEDURE _getParallelMinimumRemainingTime(_account BINARY(16), _project BINARY(16), _task BINARY(16), OUT _minimumRemainingTime BIGINT UNSIGNED)
+BEGIN
+  DECLARE childTaskCount BIGINT UNSIGNED DEFAULT 0;
+  DECLARE iterations BIGINT UNSIGNED DEFAULT 0;
+  DECLARE finishChanged BOOL DEFAULT TRUE;
+  DROP TEMPORARY TABLE IF EXISTS tempDependencyWalk;
+  CREATE TEMPORARY TABLE tempDependencyWalk(
+    task BINARY(16) NOT NULL,
+    dependsOn BINARY(16) NOT NULL,
+    taskAncestor BINARY(16) NOT NULL,
+    dependsOnAncestor BINARY(16) NOT NULL,
+    PRIMARY KEY (task, dependsOn)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempDependencyEdges;
+  CREATE TEMPORARY TABLE tempDependencyEdges(
+    fromChild BINARY(16) NOT NULL,
+    toChild BINARY(16) NOT NULL,
+    PRIMARY KEY (fromChild, toChild)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempChildFinishes;
+  CREATE TEMPORARY TABLE tempChildFinishes(
+    id BINARY(16) NOT NULL,
+    minimumRemainingTime BIGINT UNSIGNED NOT NULL,
+    finish BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempPreviousChildFinishes;
+  CREATE TEMPORARY TABLE tempPreviousChildFinishes(
+    id BINARY(16) NOT NULL,
+    finish BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (id)
+  );
+  SET _minimumRemainingTime = NULL;
+
+  #walk both ends of every dependency between two of _tasks child subtrees up the tree until they reach the child of _task
+  INSERT INTO tempDependencyWalk SELECT task, dependsOn, task, dependsOn FROM taskDependencies WHERE account = _account AND project = _project AND commonAncestor = _task;
+  WHILE (SELECT COUNT(*) FROM tempDependencyWalk w INNER JOIN tasks t ON t.account = _account AND t.project = _project AND t.id = w.taskAncestor WHERE t.parent IS NOT NULL AND t.parent <> _task) > 0 DO
+    UPDATE tempDependencyWalk w INNER JOIN tasks t ON t.account = _account AND t.project = _project AND t.id = w.taskAncestor SET w.taskAncestor = t.parent WHERE t.parent IS NOT NULL AND t.parent <> _task;
+  END WHILE;
+  WHILE (SELECT COUNT(*) FROM tempDependencyWalk w INNER JOIN tasks t ON t.account = _account AND t.project = _project AND t.id = w.dependsOnAncestor WHERE t.parent IS NOT NULL AND t.parent <> _task) > 0 DO
+    UPDATE tempDependencyWalk w INNER JOIN tasks t ON t.account = _account AND t.project = _project AND t.id = w.dependsOnAncestor SET w.dependsOnAncestor = t.parent WHERE t.parent IS NOT NULL AND t.parent <> _task;
+  END WHILE;
+  INSERT INTO tempDependencyEdges SELECT DISTINCT w.dependsOnAncestor, w.taskAncestor FROM tempDependencyWalk w INNER JOIN tasks t1 ON t1.account = _account AND t1.project = _project AND t1.id = w.taskAncestor INNER JOIN tasks t2 ON t2.account = _account AND t2.project = _project AND t2.id = w.dependsOnAncestor WHERE t1.parent = _task AND t2.parent = _task AND w.taskAncestor <> w.dependsOnAncestor;
+
+  IF (SELECT COUNT(*) FROM tempDependencyEdges) > 0 THEN
+    INSERT INTO tempChildFinishes SELECT id, minimumRemainingTime, minimumRemainingTime FROM tasks WHERE account = _account AND project = _project AND parent = _task;
+    SELECT COUNT(*) INTO childTaskCount FROM tempChildFinishes;
+    #a chain of children can be at most childTaskCount long, if the finishes are still changing after that many passes the children are linked in a cycle
+    #(the task level dependencies are acyclic but they can still form one between whole subtrees), so give up and use what we have
+    WHILE finishChanged AND iterations < childTaskCount DO
+      TRUNCATE tempPreviousChildFinishes;
+      INSERT INTO tempPreviousChildFinishes SELECT id, finish FROM tempChildFinishes;
+      UPDATE tempChildFinishes c SET c.finish = c.minimumRemainingTime + COALESCE((SELECT MAX(p.finish) FROM tempDependencyEdges e INNER JOIN tempPreviousChildFinishes p ON p.id = e.fromChild WHERE e.toChild = c.id), 0);
+      SELECT COUNT(*) > 0 INTO finishChanged FROM tempChildFinishes c INNER JOIN tempPreviousChildFinishes p ON p.id = c.id WHERE c.finish <> p.finish;
+      SET iterations = iterations + 1;
+    END WHILE;
+    SELECT MAX(finish) INTO _minimumRemainingTime FROM tempChildFinishes;
+  END IF;
+
+  IF _minimumRemainingTime IS NULL THEN
+    SELECT MAX(minimumRemainingTime) INTO _minimumRemainingTime FROM tasks WHERE account = _account AND project = _project AND parent = _task;
+  END IF;
+  DROP TEMPORARY TABLE IF EXISTS tempDependencyWalk;
+  DROP TEMPORARY TABLE IF EXISTS tempDependencyEdges;
+  DROP TEMPORARY TABLE IF EXISTS tempChildFinishes;
+  DROP TEMPORARY TABLE IF EXISTS tempPreviousChildFinishes;
+END;
+
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+#********************************MAGIC PROCEDURE WARNING*********************************#
+# THIS PROCEDURE MUST ONLY BE CALLED INTERNALLY BY THE ABOVE STORED PROCEDURES THAT HAVE #
+# SET THEIR OWN TRANSACTIONS AND PROJECTID LOCKS AND HAVE VALIDATED ALL INPUT PARAMS.    #
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+#********************************MAGIC PROCEDURE WARNING*********************************#
+# THIS PROCEDURE MUST ONLY BE CALLED INTERNALLY BY THE ABOVE STORED PROCEDURES THAT HAVE #
+# SET THEIR OWN TRANSACTIONS AND PROJECTID LOCKS AND HAVE VALIDATED ALL INPUT PARAMS.    #
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+# Moving a subtree away from _formerParent can only change the commonAncestor of dependencies that crossed in to it, and
+# they all had one of _formerParent or its ancestors as their commonAncestor, so those are the only ones recalculated.
+# Pass NULL in _formerParent to recalculate every dependency in the project.
+DROP PROCEDURE IF EXISTS _setDependencyCommonAncestors;
+CREATE PROCEDURE _setDependencyCommonAncestors(_account BINARY(16), _project BINARY(16), _formerParent BINARY(16))
+BEGIN
+  DECLARE idVariable BINARY(16) DEFAULT _formerParent;
+  DECLARE taskVariable BINARY(16) DEFAULT NULL;
+  DECLARE dependsOnVariable BINARY(16) DEFAULT NULL;
+  DECLARE lowestCommonAncestor BINARY(16) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempFormerAncestors;
+  CREATE TEMPORARY TABLE tempFormerAncestors(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  DROP TEMPORARY TABLE IF EXISTS tempStaleDependencies;
+  CREATE TEMPORARY TABLE tempStaleDependencies(
+    task BINARY(16) NOT NULL,
+    dependsOn BINARY(16) NOT NULL,
+    PRIMARY KEY (task, dependsOn)
+  );
+  IF _formerParent IS NULL THEN
+    INSERT INTO tempStaleDependencies SELECT task, dependsOn FROM taskDependencies WHERE account = _account AND project = _project;
+  ELSE
+    WHILE idVariable IS NOT NULL DO
+      INSERT INTO tempFormerAncestors VALUES (idVariable);
+      SELECT parent INTO idVariable FROM tasks WHERE account = _account AND project = _project AND id = idVariable;
+    END WHILE;
+    INSERT INTO tempStaleDependencies SELECT task, dependsOn FROM taskDependencies WHERE account = _account AND project = _project AND commonAncestor IN (SELECT id FROM tempFormerAncestors);
+  END IF;
+  WHILE (SELECT COUNT(*) FROM tempStaleDependencies) > 0 DO
+    SELECT task, dependsOn INTO taskVariable, dependsOnVariable FROM tempStaleDependencies LIMIT 1;
+    CALL _getLowestCommonAncestor(_account, _project, taskVariable, dependsOnVariable, lowestCommonAncestor);
+    UPDATE taskDependencies SET commonAncestor = lowestCommonAncestor WHERE account = _account AND project = _project AND task = taskVariable AND dependsOn = dependsOnVariable;
+    DELETE FROM tempStaleDependencies WHERE task = taskVariable AND dependsOn = dependsOnVariable;
+  END WHILE;
+  DROP TEMPORARY TABLE IF EXISTS tempFormerAncestors;
+  DROP TEMPORARY TABLE IF EXISTS tempStaleDependencies;
+END;
+
+DROP PROCEDURE IF EXISTS _getLowestCommonAncestor;
+CREATE PROCEDURE _getLowestCommonAncestor(_account BINARY(16), _project BINARY(16), _taskA BINARY(16), _taskB BINARY(16), OUT _commonAncestor BINARY(16))
+BEGIN
+  DECLARE idVariable BINARY(16) DEFAULT NULL;
+  DROP TEMPORARY TABLE IF EXISTS tempTaskAAncestors;
+  CREATE TEMPORARY TABLE tempTaskAAncestors(
+    id BINARY(16) NOT NULL,
+    PRIMARY KEY (id)
+  );
+  SELECT parent INTO idVariable FROM tasks WHERE account = _account AND project = _project AND id = _taskA;
+  WHILE idVariable IS NOT NULL DO
+    INSERT INTO tempTaskAAncestors VALUES (idVariable);
+    SELECT parent INTO idVariable FROM tasks WHERE account = _account AND project = _project AND id = idVariable;
+  END WHILE;
+  SELECT parent INTO _commonAncestor FROM tasks WHERE account = _account AND project = _project AND id = _taskB;
+  WHILE _commonAncestor IS NOT NULL AND (SELECT COUNT(*) FROM tempTaskAAncestors WHERE id = _commonAncestor) = 0 DO
+    SELECT parent INTO _commonAncestor FROM tasks WHERE account = _account AND project = _project AND id = _commonAncestor;
+  END WHILE;
+  DROP TEMPORARY TABLE IF EXISTS tempTaskAAncestors;
+END;
+
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+#********************************MAGIC PROCEDURE WARNING*********************************#
+# THIS PROCEDURE MUST ONLY BE CALLED INTERNALLY BY THE ABOVE STORED PROCEDURES THAT HAVE #
+# SET THEIR OWN TRANSACTIONS AND PROJECTID LOCKS AND HAVE VALIDATED ALL INPUT PARAMS.    #
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+#replaces a comments mentions, ids that aren't active project members are ignored
+DROP PROCEDURE IF EXISTS _setCommentMentions;
+CREATE PROCEDURE _setCommentMentions(_account BINARY(16), _project BINARY(16), _comment BINARY(16), _mentionIdsStr VARCHAR(3200))
+BEGIN
+  DECLARE mentionIdsStrLen INT DEFAULT LENGTH(_mentionIdsStr);
+  DECLARE offset INT DEFAULT 0;
+  DELETE FROM commentMentions WHERE account=_account AND project=_project AND comment=_comment;
+  IF mentionIdsStrLen > 0 AND mentionIdsStrLen % 32 = 0 THEN
+    WHILE offset < mentionIdsStrLen DO
+      INSERT INTO commentMentions (account, project, comment, member) SELECT _account, _project, _comment, id FROM projectMembers WHERE account=_account AND project=_project AND id=UNHEX(SUBSTRING(_mentionIdsStr, offset + 1, 32)) AND isActive=TRUE ON DUPLICATE KEY UPDATE member=member;
+      SET offset = offset + 32;
+    END WHILE;
+  END IF;
+END;
+
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+#********************************MAGIC PROCEDURE WARNING*********************************#
+# THIS PROCEDURE MUST ONLY BE CALLED INTERNALLY BY THE ABOVE STORED PROCEDURES THAT HAVE #
+# SET THEIR OWN TRANSACTIONS AND PROJECTID LOCKS AND HAVE VALIDATED ALL INPUT PARAMS.    #
+#!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!#
+#replaces a recurrences members, _membersStr is each members 32 hex char id followed by their 16 hex char remaining time share,
+#ids that aren't active project writers are ignored
+DROP PROCEDURE IF EXISTS _setRecurrenceMembers;
+CREATE PROCEDURE _setRecurrenceMembers(_account BINARY(16), _project BINARY(16), _recurrence BINARY(16), _membersStr VARCHAR(2880))
+BEGIN
+  DECLARE membersStrLen INT DEFAULT LENGTH(_membersStr);
+  DECLARE offset INT DEFAULT 0;
+  DELETE FROM recurrenceMembers WHERE account=_account AND project=_project AND recurrence=_recurrence;
+  IF membersStrLen > 0 AND membersStrLen % 48 = 0 THEN
+    WHILE offset < membersStrLen DO
+      INSERT INTO recurrenceMembers (account, project, recurrence, member, remainingTime) SELECT _account, _project, _recurrence, id, CAST(CONV(SUBSTRING(_membersStr, offset + 33, 16), 16, 10) AS UNSIGNED) FROM projectMembers WHERE account=_account AND project=_project AND id=UNHEX(SUBSTRING(_membersStr, offset + 1, 32)) AND isActive=TRUE AND role < 2 ON DUPLICATE KEY UPDATE member=member;
+      SET offset = offset + 48;
+    END WHILE;
+  END IF;
 END;
 
 DROP USER IF EXISTS 't_r_trees'@'%';
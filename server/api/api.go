@@ -3,14 +3,21 @@ package api
 import (
 	"github.com/0xor1/trees/server/api/v1/account"
 	"github.com/0xor1/trees/server/api/v1/central"
+	"github.com/0xor1/trees/server/api/v1/comment"
+	"github.com/0xor1/trees/server/api/v1/file"
+	"github.com/0xor1/trees/server/api/v1/label"
+	"github.com/0xor1/trees/server/api/v1/notification"
 	"github.com/0xor1/trees/server/api/v1/project"
+	"github.com/0xor1/trees/server/api/v1/recurrence"
 	"github.com/0xor1/trees/server/api/v1/task"
 	"github.com/0xor1/trees/server/api/v1/timelog"
 	utilaccount "github.com/0xor1/trees/server/util/account"
 	"github.com/0xor1/trees/server/util/activity"
 	"github.com/0xor1/trees/server/util/clientsession"
 	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/customfield"
 	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/recur"
 	tlog "github.com/0xor1/trees/server/util/timelog"
 	"io"
 	"time"
@@ -25,11 +32,16 @@ type API struct {
 }
 
 type V1 struct {
-	Central *centralClient
-	Account *accountClient
-	Project *projectClient
-	Task    *taskClient
-	TimeLog *timeLogClient
+	Central      *centralClient
+	Account      *accountClient
+	Project      *projectClient
+	Task         *taskClient
+	TimeLog      *timeLogClient
+	Comment      *commentClient
+	File         *fileClient
+	Label        *labelClient
+	Notification *notificationClient
+	Recurrence   *recurrenceClient
 }
 
 type centralClient struct {
@@ -53,6 +65,10 @@ func (c *centralClient) ResendMyNewEmailConfirmationEmail() error {
 	return c.client.ResendMyNewEmailConfirmationEmail(c.css)
 }
 
+func (c *centralClient) SetMyNotificationDelivery(notificationDelivery cnst.NotificationDelivery) error {
+	return c.client.SetMyNotificationDelivery(c.css, notificationDelivery)
+}
+
 func (c *centralClient) SetAccountName(account id.Id, newName string) error {
 	return c.client.SetAccountName(c.css, account, newName)
 }
@@ -106,6 +122,10 @@ func (c *accountClient) SetMemberRole(region cnst.Region, shard int, account, me
 	return c.client.SetMemberRole(c.css, region, shard, account, member, role)
 }
 
+func (c *accountClient) SetMemberHourlyRate(region cnst.Region, shard int, account, member id.Id, hourlyRate *uint64) error {
+	return c.client.SetMemberHourlyRate(c.css, region, shard, account, member, hourlyRate)
+}
+
 func (c *accountClient) GetMembers(region cnst.Region, shard int, account id.Id, role *cnst.AccountRole, nameOrDisplayNamePrefix *string, after *id.Id, limit int) (*account.GetMembersResp, error) {
 	return c.client.GetMembers(c.css, region, shard, account, role, nameOrDisplayNamePrefix, after, limit)
 }
@@ -151,6 +171,10 @@ func (c *projectClient) SetMemberRole(region cnst.Region, shard int, account, pr
 	return c.client.SetMemberRole(c.css, region, shard, account, project, member, role)
 }
 
+func (c *projectClient) SetMemberHourlyRate(region cnst.Region, shard int, account, project id.Id, member id.Id, hourlyRate *uint64) error {
+	return c.client.SetMemberHourlyRate(c.css, region, shard, account, project, member, hourlyRate)
+}
+
 func (c *projectClient) RemoveMembers(region cnst.Region, shard int, account, project id.Id, members []id.Id) error {
 	return c.client.RemoveMembers(c.css, region, shard, account, project, members)
 }
@@ -171,6 +195,62 @@ func (c *projectClient) GetActivities(region cnst.Region, shard int, account, pr
 	return c.client.GetActivities(c.css, region, shard, account, project, item, member, occurredAfter, occurredBefore, limit)
 }
 
+func (c *projectClient) CreateState(region cnst.Region, shard int, account, project id.Id, name string, isDone bool) (*project.State, error) {
+	return c.client.CreateState(c.css, region, shard, account, project, name, isDone)
+}
+
+func (c *projectClient) EditState(region cnst.Region, shard int, account, project, state id.Id, name *string, isDone *bool) error {
+	return c.client.EditState(c.css, region, shard, account, project, state, name, isDone)
+}
+
+func (c *projectClient) MoveState(region cnst.Region, shard int, account, project, state id.Id, newPosition int) error {
+	return c.client.MoveState(c.css, region, shard, account, project, state, newPosition)
+}
+
+func (c *projectClient) DeleteState(region cnst.Region, shard int, account, project, state, replacement id.Id) error {
+	return c.client.DeleteState(c.css, region, shard, account, project, state, replacement)
+}
+
+func (c *projectClient) GetStates(region cnst.Region, shard int, account, project id.Id) ([]*project.State, error) {
+	return c.client.GetStates(c.css, region, shard, account, project)
+}
+
+func (c *projectClient) CreateCustomField(region cnst.Region, shard int, account, project id.Id, name string, fieldType cnst.CustomFieldType, options []string) (*customfield.Field, error) {
+	return c.client.CreateCustomField(c.css, region, shard, account, project, name, fieldType, options)
+}
+
+func (c *projectClient) EditCustomField(region cnst.Region, shard int, account, project, customField id.Id, name *string, options []string) error {
+	return c.client.EditCustomField(c.css, region, shard, account, project, customField, name, options)
+}
+
+func (c *projectClient) DeleteCustomField(region cnst.Region, shard int, account, project, customField id.Id) error {
+	return c.client.DeleteCustomField(c.css, region, shard, account, project, customField)
+}
+
+func (c *projectClient) GetCustomFields(region cnst.Region, shard int, account, project id.Id) ([]*customfield.Field, error) {
+	return c.client.GetCustomFields(c.css, region, shard, account, project)
+}
+
+func (c *projectClient) SaveAsTemplate(region cnst.Region, shard int, account, project id.Id, name string, copyEstimates bool) (*project.Project, error) {
+	return c.client.SaveAsTemplate(c.css, region, shard, account, project, name, copyEstimates)
+}
+
+func (c *projectClient) CreateFromTemplate(region cnst.Region, shard int, account, template id.Id, name string, description *string, startOn, dueOn *time.Time, isPublic bool, members []*project.AddProjectMember) (*project.Project, error) {
+	return c.client.CreateFromTemplate(c.css, region, shard, account, template, name, description, startOn, dueOn, isPublic, members)
+}
+
+func (c *projectClient) GetTemplates(region cnst.Region, shard int, account id.Id, nameContains *string, after *id.Id, limit int) (*project.GetSetResult, error) {
+	return c.client.GetTemplates(c.css, region, shard, account, nameContains, after, limit)
+}
+
+func (c *projectClient) Export(region cnst.Region, shard int, account, project id.Id) (*project.Export, error) {
+	return c.client.Export(c.css, region, shard, account, project)
+}
+
+func (c *projectClient) Import(region cnst.Region, shard int, account id.Id, export *project.Export) (*project.Project, error) {
+	return c.client.Import(c.css, region, shard, account, export)
+}
+
 type taskClient struct {
 	css    *clientsession.Store
 	client task.Client
@@ -184,37 +264,121 @@ func (c *taskClient) Edit(region cnst.Region, shard int, account, project, task
 	return c.client.Edit(c.css, region, shard, account, project, task, fields)
 }
 
+func (c *taskClient) AddMember(region cnst.Region, shard int, account, project, task, member id.Id, remainingTime *uint64) error {
+	return c.client.AddMember(c.css, region, shard, account, project, task, member, remainingTime)
+}
+
+func (c *taskClient) RemoveMember(region cnst.Region, shard int, account, project, task, member id.Id) error {
+	return c.client.RemoveMember(c.css, region, shard, account, project, task, member)
+}
+
+func (c *taskClient) SetMemberRemainingTime(region cnst.Region, shard int, account, project, task, member id.Id, remainingTime uint64) error {
+	return c.client.SetMemberRemainingTime(c.css, region, shard, account, project, task, member, remainingTime)
+}
+
+func (c *taskClient) BulkSetMember(region cnst.Region, shard int, account, project id.Id, tasks []id.Id, member *id.Id) error {
+	return c.client.BulkSetMember(c.css, region, shard, account, project, tasks, member)
+}
+
 func (c *taskClient) Move(region cnst.Region, shard int, account, project, task, parent id.Id, nextSibling *id.Id) error {
 	return c.client.Move(c.css, region, shard, account, project, task, parent, nextSibling)
 }
 
+func (c *taskClient) BulkMove(region cnst.Region, shard int, account, project id.Id, tasks []id.Id, newParent id.Id, newPreviousSibling *id.Id) error {
+	return c.client.BulkMove(c.css, region, shard, account, project, tasks, newParent, newPreviousSibling)
+}
+
+func (c *taskClient) Copy(region cnst.Region, shard int, account, sourceProject, task, project, newParent id.Id, newPreviousSibling *id.Id, copyEstimates, copyMembers bool) (*task.Task, error) {
+	return c.client.Copy(c.css, region, shard, account, sourceProject, task, project, newParent, newPreviousSibling, copyEstimates, copyMembers)
+}
+
+func (c *taskClient) Import(region cnst.Region, shard int, account, project, parent id.Id, previousSibling *id.Id, format cnst.ImportFormat, data string) (*task.ImportResp, error) {
+	return c.client.Import(c.css, region, shard, account, project, parent, previousSibling, format, data)
+}
+
 func (c *taskClient) Delete(region cnst.Region, shard int, account, project, task id.Id) error {
 	return c.client.Delete(c.css, region, shard, account, project, task)
 }
 
+func (c *taskClient) BulkDelete(region cnst.Region, shard int, account, project id.Id, tasks []id.Id) error {
+	return c.client.BulkDelete(c.css, region, shard, account, project, tasks)
+}
+
+func (c *taskClient) GetTrash(region cnst.Region, shard int, account, project id.Id, after *id.Id, limit int) (*task.GetTrashResp, error) {
+	return c.client.GetTrash(c.css, region, shard, account, project, after, limit)
+}
+
+func (c *taskClient) Restore(region cnst.Region, shard int, account, project, task id.Id, newParent, newPreviousSibling *id.Id) error {
+	return c.client.Restore(c.css, region, shard, account, project, task, newParent, newPreviousSibling)
+}
+
 func (c *taskClient) Get(region cnst.Region, shard int, account, project id.Id, task id.Id) (*task.Task, error) {
 	return c.client.Get(c.css, region, shard, account, project, task)
 }
 
-func (c *taskClient) GetChildren(region cnst.Region, shard int, account, project, parent id.Id, fromSibling *id.Id, limit int) (*task.GetChildrenResp, error) {
-	return c.client.GetChildren(c.css, region, shard, account, project, parent, fromSibling, limit)
+func (c *taskClient) GetChildren(region cnst.Region, shard int, account, project, parent id.Id, fromSibling *id.Id, limit int, labels []id.Id, customFieldFilters []*customfield.Value) (*task.GetChildrenResp, error) {
+	return c.client.GetChildren(c.css, region, shard, account, project, parent, fromSibling, limit, labels, customFieldFilters)
 }
 
 func (c *taskClient) GetAncestors(region cnst.Region, shard int, account, project, child id.Id, limit int) (*task.GetAncestorsResp, error) {
 	return c.client.GetAncestors(c.css, region, shard, account, project, child, limit)
 }
 
+func (c *taskClient) GetSubtree(region cnst.Region, shard int, account, project, root id.Id, depth int, after *id.Id, limit int, customFieldFilters []*customfield.Value) (*task.GetSubtreeResp, error) {
+	return c.client.GetSubtree(c.css, region, shard, account, project, root, depth, after, limit, customFieldFilters)
+}
+
+func (c *taskClient) GetByLabels(region cnst.Region, shard int, account, project id.Id, labels []id.Id, after *id.Id, limit int) (*task.GetChildrenResp, error) {
+	return c.client.GetByLabels(c.css, region, shard, account, project, labels, after, limit)
+}
+
+func (c *taskClient) Search(region cnst.Region, shard int, account, project id.Id, query string, offset, limit int) (*task.SearchResp, error) {
+	return c.client.Search(c.css, region, shard, account, project, query, offset, limit)
+}
+
+func (c *taskClient) AddLabels(region cnst.Region, shard int, account, project, task id.Id, labels []id.Id) error {
+	return c.client.AddLabels(c.css, region, shard, account, project, task, labels)
+}
+
+func (c *taskClient) RemoveLabels(region cnst.Region, shard int, account, project, task id.Id, labels []id.Id) error {
+	return c.client.RemoveLabels(c.css, region, shard, account, project, task, labels)
+}
+
+func (c *taskClient) AddDependency(region cnst.Region, shard int, account, project, task, dependsOn id.Id) error {
+	return c.client.AddDependency(c.css, region, shard, account, project, task, dependsOn)
+}
+
+func (c *taskClient) RemoveDependency(region cnst.Region, shard int, account, project, task, dependsOn id.Id) error {
+	return c.client.RemoveDependency(c.css, region, shard, account, project, task, dependsOn)
+}
+
+func (c *taskClient) GetDependencies(region cnst.Region, shard int, account, project, task id.Id) (*task.GetDependenciesResp, error) {
+	return c.client.GetDependencies(c.css, region, shard, account, project, task)
+}
+
+func (c *taskClient) GetCriticalPath(region cnst.Region, shard int, account, project id.Id) (*task.GetCriticalPathResp, error) {
+	return c.client.GetCriticalPath(c.css, region, shard, account, project)
+}
+
+func (c *taskClient) GetForecast(region cnst.Region, shard int, account, project id.Id) (*task.GetForecastResp, error) {
+	return c.client.GetForecast(c.css, region, shard, account, project)
+}
+
+func (c *taskClient) GetBurndown(region cnst.Region, shard int, account, project, task id.Id, from, to time.Time) (*task.GetBurndownResp, error) {
+	return c.client.GetBurndown(c.css, region, shard, account, project, task, from, to)
+}
+
 type timeLogClient struct {
 	css    *clientsession.Store
 	client timelog.Client
 }
 
-func (c *timeLogClient) Create(region cnst.Region, shard int, account, project, task id.Id, duration uint64, note *string) (*tlog.TimeLog, error) {
-	return c.client.Create(c.css, region, shard, account, project, task, duration, note)
+func (c *timeLogClient) Create(region cnst.Region, shard int, account, project, task id.Id, duration uint64, loggedOn *time.Time, isBillable *bool, note *string) (*tlog.TimeLog, error) {
+	return c.client.Create(c.css, region, shard, account, project, task, duration, loggedOn, isBillable, note)
 }
 
-func (c *timeLogClient) CreateAndSetRemainingTime(region cnst.Region, shard int, account, project, task id.Id, remainingTime uint64, duration uint64, note *string) (*tlog.TimeLog, error) {
-	return c.client.CreateAndSetRemainingTime(c.css, region, shard, account, project, task, remainingTime, duration, note)
+func (c *timeLogClient) CreateAndSetRemainingTime(region cnst.Region, shard int, account, project, task id.Id, remainingTime uint64, duration uint64, loggedOn *time.Time, isBillable *bool, note *string) (*tlog.TimeLog, error) {
+	return c.client.CreateAndSetRemainingTime(c.css, region, shard, account, project, task, remainingTime, duration, loggedOn, isBillable, note)
 }
 
 func (c *timeLogClient) Edit(region cnst.Region, shard int, account, project, timeLog id.Id, fields timelog.Fields) error {
@@ -225,8 +389,145 @@ func (c *timeLogClient) Delete(region cnst.Region, shard int, account, project,
 	return c.client.Delete(c.css, region, shard, account, project, timeLog)
 }
 
-func (c *timeLogClient) Get(region cnst.Region, shard int, account, project id.Id, task, member, timeLog *id.Id, sortAsc bool, after *id.Id, limit int) (*timelog.GetResp, error) {
-	return c.client.Get(c.css, region, shard, account, project, task, member, timeLog, sortAsc, after, limit)
+func (c *timeLogClient) Get(region cnst.Region, shard int, account, project id.Id, task, member, timeLog *id.Id, loggedOnAfter, loggedOnBefore *time.Time, sortAsc bool, after *id.Id, limit int) (*timelog.GetResp, error) {
+	return c.client.Get(c.css, region, shard, account, project, task, member, timeLog, loggedOnAfter, loggedOnBefore, sortAsc, after, limit)
+}
+
+func (c *timeLogClient) GetTimesheet(region cnst.Region, shard int, account id.Id, member *id.Id, loggedOnAfter, loggedOnBefore time.Time, format *cnst.ReportFormat) (*timelog.GetTimesheetResp, error) {
+	return c.client.GetTimesheet(c.css, region, shard, account, member, loggedOnAfter, loggedOnBefore, format)
+}
+
+func (c *timeLogClient) StartTimer(region cnst.Region, shard int, account, project, task id.Id, note *string) (*timelog.Timer, error) {
+	return c.client.StartTimer(c.css, region, shard, account, project, task, note)
+}
+
+func (c *timeLogClient) PauseTimer(region cnst.Region, shard int, account id.Id) (*timelog.Timer, error) {
+	return c.client.PauseTimer(c.css, region, shard, account)
+}
+
+func (c *timeLogClient) ResumeTimer(region cnst.Region, shard int, account id.Id) (*timelog.Timer, error) {
+	return c.client.ResumeTimer(c.css, region, shard, account)
+}
+
+func (c *timeLogClient) StopTimer(region cnst.Region, shard int, account id.Id, remainingTime *uint64, note *string) (*tlog.TimeLog, error) {
+	return c.client.StopTimer(c.css, region, shard, account, remainingTime, note)
+}
+
+func (c *timeLogClient) DiscardTimer(region cnst.Region, shard int, account id.Id) error {
+	return c.client.DiscardTimer(c.css, region, shard, account)
+}
+
+func (c *timeLogClient) GetTimer(region cnst.Region, shard int, account id.Id) (*timelog.Timer, error) {
+	return c.client.GetTimer(c.css, region, shard, account)
+}
+
+type commentClient struct {
+	css    *clientsession.Store
+	client comment.Client
+}
+
+func (c *commentClient) Create(region cnst.Region, shard int, account, project, task id.Id, body string) (*comment.Comment, error) {
+	return c.client.Create(c.css, region, shard, account, project, task, body)
+}
+
+func (c *commentClient) Edit(region cnst.Region, shard int, account, project, comment id.Id, body string) error {
+	return c.client.Edit(c.css, region, shard, account, project, comment, body)
+}
+
+func (c *commentClient) Delete(region cnst.Region, shard int, account, project, comment id.Id) error {
+	return c.client.Delete(c.css, region, shard, account, project, comment)
+}
+
+func (c *commentClient) Get(region cnst.Region, shard int, account, project, task id.Id, sortAsc bool, after *id.Id, limit int) (*comment.GetResp, error) {
+	return c.client.Get(c.css, region, shard, account, project, task, sortAsc, after, limit)
+}
+
+type fileClient struct {
+	css    *clientsession.Store
+	client file.Client
+}
+
+func (c *fileClient) Upload(region cnst.Region, shard int, account, project, task id.Id, name, mimeType string, data io.ReadCloser) (*file.File, error) {
+	return c.client.Upload(c.css, region, shard, account, project, task, name, mimeType, data)
+}
+
+func (c *fileClient) Download(region cnst.Region, shard int, account, project, file id.Id) (*file.DownloadResp, error) {
+	return c.client.Download(c.css, region, shard, account, project, file)
+}
+
+func (c *fileClient) Delete(region cnst.Region, shard int, account, project, file id.Id) error {
+	return c.client.Delete(c.css, region, shard, account, project, file)
+}
+
+func (c *fileClient) Get(region cnst.Region, shard int, account, project, task id.Id, sortAsc bool, after *id.Id, limit int) (*file.GetResp, error) {
+	return c.client.Get(c.css, region, shard, account, project, task, sortAsc, after, limit)
+}
+
+type labelClient struct {
+	css    *clientsession.Store
+	client label.Client
+}
+
+func (c *labelClient) Create(region cnst.Region, shard int, account id.Id, name, colour string) (*label.Label, error) {
+	return c.client.Create(c.css, region, shard, account, name, colour)
+}
+
+func (c *labelClient) Edit(region cnst.Region, shard int, account, label id.Id, name, colour *string) error {
+	return c.client.Edit(c.css, region, shard, account, label, name, colour)
+}
+
+func (c *labelClient) Delete(region cnst.Region, shard int, account, label id.Id) error {
+	return c.client.Delete(c.css, region, shard, account, label)
+}
+
+func (c *labelClient) Get(region cnst.Region, shard int, account id.Id, project *id.Id) ([]*label.Label, error) {
+	return c.client.Get(c.css, region, shard, account, project)
+}
+
+type notificationClient struct {
+	css    *clientsession.Store
+	client notification.Client
+}
+
+func (c *notificationClient) Watch(region cnst.Region, shard int, account, project, task id.Id, includeDescendants bool) error {
+	return c.client.Watch(c.css, region, shard, account, project, task, includeDescendants)
+}
+
+func (c *notificationClient) Unwatch(region cnst.Region, shard int, account, project, task id.Id) error {
+	return c.client.Unwatch(c.css, region, shard, account, project, task)
+}
+
+func (c *notificationClient) GetWatches(region cnst.Region, shard int, account, project id.Id) ([]*notification.Watch, error) {
+	return c.client.GetWatches(c.css, region, shard, account, project)
+}
+
+func (c *notificationClient) Get(region cnst.Region, shard int, account id.Id, unreadOnly bool, occurredBefore *time.Time, limit int) ([]*notification.Notification, error) {
+	return c.client.Get(c.css, region, shard, account, unreadOnly, occurredBefore, limit)
+}
+
+func (c *notificationClient) MarkRead(region cnst.Region, shard int, account id.Id, occurredOn time.Time) error {
+	return c.client.MarkRead(c.css, region, shard, account, occurredOn)
+}
+
+type recurrenceClient struct {
+	css    *clientsession.Store
+	client recurrence.Client
+}
+
+func (c *recurrenceClient) Create(region cnst.Region, shard int, account, project, parent id.Id, name string, description *string, totalRemainingTime uint64, members []*recurrence.Member, rule recur.Rule, startOn *time.Time) (*recurrence.Recurrence, error) {
+	return c.client.Create(c.css, region, shard, account, project, parent, name, description, totalRemainingTime, members, rule, startOn)
+}
+
+func (c *recurrenceClient) Edit(region cnst.Region, shard int, account, project, rec id.Id, fields recurrence.Fields) error {
+	return c.client.Edit(c.css, region, shard, account, project, rec, fields)
+}
+
+func (c *recurrenceClient) Delete(region cnst.Region, shard int, account, project, rec id.Id) error {
+	return c.client.Delete(c.css, region, shard, account, project, rec)
+}
+
+func (c *recurrenceClient) Get(region cnst.Region, shard int, account, project id.Id, parent *id.Id) ([]*recurrence.Recurrence, error) {
+	return c.client.Get(c.css, region, shard, account, project, parent)
 }
 
 // New returns a new API configured for
@@ -237,6 +538,11 @@ func New(host, email, pwd string) (*API, error) {
 	project := project.NewClient(host)
 	task := task.NewClient(host)
 	timeLog := timelog.NewClient(host)
+	comment := comment.NewClient(host)
+	file := file.NewClient(host)
+	label := label.NewClient(host)
+	notification := notification.NewClient(host)
+	recurrence := recurrence.NewClient(host)
 
 	authResp, err := central.Authenticate(css, email, pwd)
 	if err != nil {
@@ -266,6 +572,26 @@ func New(host, email, pwd string) (*API, error) {
 				css:    css,
 				client: timeLog,
 			},
+			Comment: &commentClient{
+				css:    css,
+				client: comment,
+			},
+			File: &fileClient{
+				css:    css,
+				client: file,
+			},
+			Label: &labelClient{
+				css:    css,
+				client: label,
+			},
+			Notification: &notificationClient{
+				css:    css,
+				client: notification,
+			},
+			Recurrence: &recurrenceClient{
+				css:    css,
+				client: recurrence,
+			},
 		},
 	}, nil
 }
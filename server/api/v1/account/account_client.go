@@ -16,6 +16,8 @@ type Client interface {
 	Get(css *clientsession.Store, region cnst.Region, shard int, account id.Id) (*account.Account, error)
 	//must be account owner/admin
 	SetMemberRole(css *clientsession.Store, region cnst.Region, shard int, account, member id.Id, role cnst.AccountRole) error
+	//must be account owner/admin, nil hourlyRate clears the members default rate
+	SetMemberHourlyRate(css *clientsession.Store, region cnst.Region, shard int, account, member id.Id, hourlyRate *uint64) error
 	//pointers are optional filters
 	GetMembers(css *clientsession.Store, region cnst.Region, shard int, account id.Id, role *cnst.AccountRole, nameOrDisplayNamePrefix *string, after *id.Id, limit int) (*GetMembersResp, error)
 	//either one or both of OccurredAfter/Before must be nil
@@ -64,6 +66,16 @@ func (c *client) SetMemberRole(css *clientsession.Store, region cnst.Region, sha
 	return e
 }
 
+func (c *client) SetMemberHourlyRate(css *clientsession.Store, region cnst.Region, shard int, account, member id.Id, hourlyRate *uint64) error {
+	_, e := setMemberHourlyRate.DoRequest(css, c.host, region, &setMemberHourlyRateArgs{
+		Shard:      shard,
+		Account:    account,
+		Member:     member,
+		HourlyRate: hourlyRate,
+	}, nil, nil)
+	return e
+}
+
 func (c *client) GetMembers(css *clientsession.Store, region cnst.Region, shard int, account id.Id, role *cnst.AccountRole, nameOrDisplayNamePrefix *string, after *id.Id, limit int) (*GetMembersResp, error) {
 	val, e := getMembers.DoRequest(css, c.host, region, &getMembersArgs{
 		Shard:                   shard,
@@ -52,14 +52,19 @@ func dbSetMemberRole(ctx ctx.Ctx, shard int, account, member id.Id, role cnst.Ac
 	ctx.TouchDlms(cachekey.NewSetDlms().AccountMember(account, member).AccountActivities(account))
 }
 
+func dbSetMemberHourlyRate(ctx ctx.Ctx, shard int, account, member id.Id, hourlyRate *uint64) {
+	db.MakeChangeHelper(ctx, shard, `CALL setAccountMemberHourlyRate(?, ?, ?, ?)`, account, ctx.Me(), member, hourlyRate)
+	ctx.TouchDlms(cachekey.NewSetDlms().AccountMember(account, member).AccountActivities(account))
+}
+
 func dbGetMember(ctx ctx.Ctx, shard int, account, mem id.Id) *Member {
 	res := Member{}
 	cacheKey := cachekey.NewGet("account.dbGetMember", shard, account, mem).AccountMember(account, mem)
 	if ctx.GetCacheValue(&res, cacheKey) {
 		return &res
 	}
-	row := ctx.TreeQueryRow(shard, `SELECT id, name, displayName, hasAvatar, isActive, role FROM accountMembers WHERE account=? AND id=?`, account, mem)
-	panic.IfNotNil(row.Scan(&res.Id, &res.Name, &res.DisplayName, &res.HasAvatar, &res.IsActive, &res.Role))
+	row := ctx.TreeQueryRow(shard, `SELECT id, name, displayName, hasAvatar, isActive, role, hourlyRate FROM accountMembers WHERE account=? AND id=?`, account, mem)
+	panic.IfNotNil(row.Scan(&res.Id, &res.Name, &res.DisplayName, &res.HasAvatar, &res.IsActive, &res.Role, &res.HourlyRate))
 	ctx.SetCacheValue(res, cacheKey)
 	return &res
 }
@@ -106,7 +111,7 @@ func dbGetMembers(ctx ctx.Ctx, shard int, account id.Id, role *cnst.AccountRole,
 	if ctx.GetCacheValue(&res, cacheKey) {
 		return &res
 	}
-	query := bytes.NewBufferString(`SELECT a1.id, a1.name, a1.displayName, a1.hasAvatar, a1.isActive, a1.role FROM accountMembers a1`)
+	query := bytes.NewBufferString(`SELECT a1.id, a1.name, a1.displayName, a1.hasAvatar, a1.isActive, a1.role, a1.hourlyRate FROM accountMembers a1`)
 	args := make([]interface{}, 0, 7)
 	if after != nil {
 		query.WriteString(`, accountMembers a2`)
@@ -137,7 +142,7 @@ func dbGetMembers(ctx ctx.Ctx, shard int, account id.Id, role *cnst.AccountRole,
 	memSet := make([]*Member, 0, limit+1)
 	for rows.Next() {
 		mem := Member{}
-		panic.IfNotNil(rows.Scan(&mem.Id, &mem.Name, &mem.DisplayName, &mem.HasAvatar, &mem.IsActive, &mem.Role))
+		panic.IfNotNil(rows.Scan(&mem.Id, &mem.Name, &mem.DisplayName, &mem.HasAvatar, &mem.IsActive, &mem.Role, &mem.HourlyRate))
 		memSet = append(memSet, &mem)
 	}
 	if len(memSet) == limit+1 {
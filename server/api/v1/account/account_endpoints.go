@@ -82,6 +82,27 @@ var setMemberRole = &endpoint.Endpoint{
 	},
 }
 
+type setMemberHourlyRateArgs struct {
+	Shard      int     `json:"shard"`
+	Account    id.Id   `json:"account"`
+	Member     id.Id   `json:"member"`
+	HourlyRate *uint64 `json:"hourlyRate,omitempty"`
+}
+
+var setMemberHourlyRate = &endpoint.Endpoint{
+	Path:            "/api/v1/account/setMemberHourlyRate",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &setMemberHourlyRateArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*setMemberHourlyRateArgs)
+		validate.MemberHasAccountAdminAccess(db.GetAccountRole(ctx, args.Shard, args.Account, ctx.Me()))
+		dbSetMemberHourlyRate(ctx, args.Shard, args.Account, args.Member, args.HourlyRate)
+		return nil
+	},
+}
+
 type getMembersArgs struct {
 	Shard                   int               `json:"shard"`
 	Account                 id.Id             `json:"account"`
@@ -157,6 +178,7 @@ var Endpoints = []*endpoint.Endpoint{
 	edit,
 	get,
 	setMemberRole,
+	setMemberHourlyRate,
 	getMembers,
 	getActivities,
 	getMe,
@@ -175,4 +197,5 @@ type Member struct {
 	HasAvatar   bool             `json:"hasAvatar"`
 	Role        cnst.AccountRole `json:"role"`
 	IsActive    bool             `json:"isActive"`
+	HourlyRate  *uint64          `json:"hourlyRate,omitempty"` //the default rate for time logged on any project in the account
 }
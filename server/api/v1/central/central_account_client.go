@@ -29,6 +29,7 @@ type Client interface {
 	SetMyPwd(css *clientsession.Store, oldPwd, newPwd string) error
 	SetMyEmail(css *clientsession.Store, newEmail string) error
 	ResendMyNewEmailConfirmationEmail(css *clientsession.Store) error
+	SetMyNotificationDelivery(css *clientsession.Store, notificationDelivery cnst.NotificationDelivery) error
 	SetAccountName(css *clientsession.Store, account id.Id, newName string) error
 	SetAccountDisplayName(css *clientsession.Store, account id.Id, newDisplayName *string) error
 	SetAccountAvatar(css *clientsession.Store, account id.Id, avatar io.ReadCloser) error
@@ -39,6 +40,9 @@ type Client interface {
 	//member centric - must be an owner or admin
 	AddMembers(css *clientsession.Store, account id.Id, newMembers []*AddMember) error
 	RemoveMembers(css *clientsession.Store, account id.Id, existingMembers []id.Id) error
+	//private - requires the regional private client secret
+	GetDeadLetteredEmails(after *id.Id, limit int) (*GetDeadLetteredEmailsResult, error)
+	ResendDeadLetteredEmails(emails []id.Id) error
 }
 
 func NewClient(host string) Client {
@@ -183,6 +187,13 @@ func (c *client) ResendMyNewEmailConfirmationEmail(css *clientsession.Store) err
 	return e
 }
 
+func (c *client) SetMyNotificationDelivery(css *clientsession.Store, notificationDelivery cnst.NotificationDelivery) error {
+	_, e := setMyNotificationDelivery.DoRequest(css, c.host, cnst.CentralRegion, &setMyNotificationDeliveryArgs{
+		NotificationDelivery: notificationDelivery,
+	}, nil, nil)
+	return e
+}
+
 func (c *client) SetAccountName(css *clientsession.Store, account id.Id, newName string) error {
 	_, e := setAccountName.DoRequest(css, c.host, cnst.CentralRegion, &setAccountNameArgs{
 		Account: account,
@@ -271,3 +282,21 @@ func (c *client) RemoveMembers(css *clientsession.Store, account id.Id, existing
 	}, nil, nil)
 	return e
 }
+
+func (c *client) GetDeadLetteredEmails(after *id.Id, limit int) (*GetDeadLetteredEmailsResult, error) {
+	val, e := getDeadLetteredEmails.DoRequest(nil, c.host, cnst.CentralRegion, &getDeadLetteredEmailsArgs{
+		After: after,
+		Limit: limit,
+	}, nil, &GetDeadLetteredEmailsResult{})
+	if val != nil {
+		return val.(*GetDeadLetteredEmailsResult), e
+	}
+	return nil, e
+}
+
+func (c *client) ResendDeadLetteredEmails(emails []id.Id) error {
+	_, e := resendDeadLetteredEmails.DoRequest(nil, c.host, cnst.CentralRegion, &resendDeadLetteredEmailsArgs{
+		Emails: emails,
+	}, nil, nil)
+	return e
+}
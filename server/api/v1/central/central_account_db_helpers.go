@@ -2,10 +2,13 @@ package central
 
 import (
 	"bytes"
+	"encoding/json"
 	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/cnst"
 	"github.com/0xor1/trees/server/util/ctx"
 	"github.com/0xor1/trees/server/util/err"
 	"github.com/0xor1/trees/server/util/id"
+	t "github.com/0xor1/trees/server/util/time"
 	"github.com/0xor1/trees/server/util/validate"
 	"net/http"
 	"strings"
@@ -36,20 +39,20 @@ func dbCreatePersonalAccount(ctx ctx.Ctx, account *fullPersonalAccountInfo, pwdI
 }
 
 func dbGetPersonalAccountByEmail(ctx ctx.Ctx, email string) *fullPersonalAccountInfo {
-	row := ctx.AccountQueryRow(`SELECT a.id, a.name, a.displayName, a.createdOn, a.region, a.newRegion, a.shard, a.hasAvatar, p.email, p.language, p.theme, p.newEmail, p.activationCode, p.activatedOn, p.newEmailConfirmationCode, p.resetPwdCode FROM accounts a, personalAccounts p WHERE a.id = (SELECT id FROM personalAccounts WHERE email = ?) AND p.email = ?`, email, email)
+	row := ctx.AccountQueryRow(`SELECT a.id, a.name, a.displayName, a.createdOn, a.region, a.newRegion, a.shard, a.hasAvatar, p.email, p.language, p.theme, p.newEmail, p.activationCode, p.activatedOn, p.newEmailConfirmationCode, p.resetPwdCode, p.notificationDelivery FROM accounts a, personalAccounts p WHERE a.id = (SELECT id FROM personalAccounts WHERE email = ?) AND p.email = ?`, email, email)
 	account := fullPersonalAccountInfo{}
 	account.IsPersonal = true
-	if err.IsSqlErrNoRowsElsePanicIf(row.Scan(&account.Id, &account.Name, &account.DisplayName, &account.CreatedOn, &account.Region, &account.NewRegion, &account.Shard, &account.HasAvatar, &account.Email, &account.Language, &account.Theme, &account.NewEmail, &account.activationCode, &account.activatedOn, &account.newEmailConfirmationCode, &account.resetPwdCode)) {
+	if err.IsSqlErrNoRowsElsePanicIf(row.Scan(&account.Id, &account.Name, &account.DisplayName, &account.CreatedOn, &account.Region, &account.NewRegion, &account.Shard, &account.HasAvatar, &account.Email, &account.Language, &account.Theme, &account.NewEmail, &account.activationCode, &account.activatedOn, &account.newEmailConfirmationCode, &account.resetPwdCode, &account.NotificationDelivery)) {
 		return nil
 	}
 	return &account
 }
 
 func dbGetPersonalAccountById(ctx ctx.Ctx, id id.Id) *fullPersonalAccountInfo {
-	row := ctx.AccountQueryRow(`SELECT a.id, a.name, a.displayName, a.createdOn, a.region, a.newRegion, a.shard, a.hasAvatar, p.email, p.language, p.theme, p.newEmail, p.activationCode, p.activatedOn, p.newEmailConfirmationCode, p.resetPwdCode FROM accounts a, personalAccounts p WHERE a.id = ? AND p.id = ?`, id, id)
+	row := ctx.AccountQueryRow(`SELECT a.id, a.name, a.displayName, a.createdOn, a.region, a.newRegion, a.shard, a.hasAvatar, p.email, p.language, p.theme, p.newEmail, p.activationCode, p.activatedOn, p.newEmailConfirmationCode, p.resetPwdCode, p.notificationDelivery FROM accounts a, personalAccounts p WHERE a.id = ? AND p.id = ?`, id, id)
 	account := fullPersonalAccountInfo{}
 	account.IsPersonal = true
-	if err.IsSqlErrNoRowsElsePanicIf(row.Scan(&account.Id, &account.Name, &account.DisplayName, &account.CreatedOn, &account.Region, &account.NewRegion, &account.Shard, &account.HasAvatar, &account.Email, &account.Language, &account.Theme, &account.NewEmail, &account.activationCode, &account.activatedOn, &account.newEmailConfirmationCode, &account.resetPwdCode)) {
+	if err.IsSqlErrNoRowsElsePanicIf(row.Scan(&account.Id, &account.Name, &account.DisplayName, &account.CreatedOn, &account.Region, &account.NewRegion, &account.Shard, &account.HasAvatar, &account.Email, &account.Language, &account.Theme, &account.NewEmail, &account.activationCode, &account.activatedOn, &account.newEmailConfirmationCode, &account.resetPwdCode, &account.NotificationDelivery)) {
 		return nil
 	}
 	return &account
@@ -163,30 +166,35 @@ func dbSearchPersonalAccounts(ctx ctx.Ctx, nameOrDisplayNamePrefix string) []*Ac
 	return res
 }
 
-func dbGetPersonalAccounts(ctx ctx.Ctx, ids []id.Id) []*Account {
+func dbGetPersonalAccounts(ctx ctx.Ctx, ids []id.Id) []*Me {
 	args := make([]interface{}, 0, len(ids))
 	args = append(args, ids[0])
-	query := bytes.NewBufferString(` SELECT id, name, displayName, createdOn, region, newRegion, shard, hasAvatar FROM accounts WHERE id IN (SELECT id FROM personalAccounts WHERE id IN (?`)
+	query := bytes.NewBufferString(`SELECT a.id, a.name, a.displayName, a.createdOn, a.region, a.newRegion, a.shard, a.hasAvatar, p.email, p.language, p.theme, p.newEmail, p.notificationDelivery FROM accounts a, personalAccounts p WHERE a.id = p.id AND p.activatedOn IS NOT NULL AND p.id IN (?`)
 	for _, i := range ids[1:] {
 		query.WriteString(`,?`)
 		args = append(args, i)
 	}
-	query.WriteString(`) AND activatedOn IS NOT NULL)`)
+	query.WriteString(`)`)
 	rows, e := ctx.AccountQuery(query.String(), args...)
 	if rows != nil {
 		defer rows.Close()
 	}
 	panic.IfNotNil(e)
-	res := make([]*Account, 0, len(ids))
+	res := make([]*Me, 0, len(ids))
 	for rows.Next() {
-		acc := Account{}
+		acc := Me{}
 		acc.IsPersonal = true
-		panic.IfNotNil(rows.Scan(&acc.Id, &acc.Name, &acc.DisplayName, &acc.CreatedOn, &acc.Region, &acc.NewRegion, &acc.Shard, &acc.HasAvatar))
+		panic.IfNotNil(rows.Scan(&acc.Id, &acc.Name, &acc.DisplayName, &acc.CreatedOn, &acc.Region, &acc.NewRegion, &acc.Shard, &acc.HasAvatar, &acc.Email, &acc.Language, &acc.Theme, &acc.NewEmail, &acc.NotificationDelivery))
 		res = append(res, &acc)
 	}
 	return res
 }
 
+func dbSetNotificationDelivery(ctx ctx.Ctx, me id.Id, notificationDelivery cnst.NotificationDelivery) {
+	_, e := ctx.AccountExec(`UPDATE personalAccounts SET notificationDelivery=? WHERE id=?`, notificationDelivery, me)
+	panic.IfNotNil(e)
+}
+
 func dbCreateGroupAccountAndMembership(ctx ctx.Ctx, account *Account, member id.Id) {
 	_, e := ctx.AccountExec(`CALL  createGroupAccountAndMembership(?, ?, ?, ?, ?, ?, ?, ?, ?)`, account.Id, account.Name, account.DisplayName, account.CreatedOn, account.Region, account.NewRegion, account.Shard, account.HasAvatar, member)
 	panic.IfNotNil(e)
@@ -243,3 +251,52 @@ func dbDeleteMemberships(ctx ctx.Ctx, account id.Id, members []id.Id) {
 	_, e := ctx.AccountExec(query.String(), args...)
 	panic.IfNotNil(e)
 }
+
+func dbEnqueueEmail(ctx ctx.Ctx, sendTo []string, content string) {
+	sendToBytes, e := json.Marshal(sendTo)
+	panic.IfNotNil(e)
+	now := t.Now()
+	_, e = ctx.AccountExec(`INSERT INTO emailOutbox (id, sendTo, content, createdOn, attemptCount, nextAttemptOn, lastError, isDeadLettered) VALUES (?, ?, ?, ?, 0, ?, NULL, FALSE)`, id.New(), string(sendToBytes), content, now, now)
+	panic.IfNotNil(e)
+}
+
+func dbGetDeadLetteredEmails(ctx ctx.Ctx, after *id.Id, limit int) ([]*OutboxEmail, bool) {
+	args := make([]interface{}, 0, 3)
+	query := bytes.NewBufferString(`SELECT id, sendTo, content, createdOn, attemptCount, lastError FROM emailOutbox WHERE isDeadLettered=TRUE`)
+	if after != nil {
+		query.WriteString(` AND (createdOn, id) > (SELECT createdOn, id FROM emailOutbox WHERE id=?)`)
+		args = append(args, *after)
+	}
+	query.WriteString(` ORDER BY createdOn ASC, id ASC LIMIT ?`)
+	args = append(args, limit+1)
+	rows, e := ctx.AccountQuery(query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	res := make([]*OutboxEmail, 0, limit+1)
+	for rows.Next() {
+		oe := OutboxEmail{}
+		sendTo := ""
+		panic.IfNotNil(rows.Scan(&oe.Id, &sendTo, &oe.Content, &oe.CreatedOn, &oe.AttemptCount, &oe.LastError))
+		panic.IfNotNil(json.Unmarshal([]byte(sendTo), &oe.SendTo))
+		res = append(res, &oe)
+	}
+	if len(res) == limit+1 {
+		return res[:limit], true
+	}
+	return res, false
+}
+
+func dbResendDeadLetteredEmails(ctx ctx.Ctx, emails []id.Id) {
+	args := make([]interface{}, 0, len(emails)+1)
+	args = append(args, t.Now(), emails[0])
+	query := bytes.NewBufferString(`UPDATE emailOutbox SET attemptCount=0, nextAttemptOn=?, isDeadLettered=FALSE WHERE isDeadLettered=TRUE AND id IN (?`)
+	for _, email := range emails[1:] {
+		query.WriteString(`,?`)
+		args = append(args, email)
+	}
+	query.WriteString(`)`)
+	_, e := ctx.AccountExec(query.String(), args...)
+	panic.IfNotNil(e)
+}
@@ -91,6 +91,7 @@ var register = &endpoint.Endpoint{
 		var e error
 		acc.Shard, e = ctx.RegionalV1PrivateClient().CreateAccount(acc.Region, acc.Id, acc.Id, acc.Name, acc.DisplayName, acc.HasAvatar)
 		panic.IfNotNil(e)
+		panic.IfNotNil(ctx.RegionalV1PrivateClient().SetMemberNotificationSettings(acc.Region, acc.Shard, acc.Id, acc.Id, args.Email, acc.NotificationDelivery))
 		acc.IsPersonal = true
 		acc.Email = args.Email
 		acc.Language = args.Language
@@ -244,6 +245,7 @@ var confirmNewEmail = &endpoint.Endpoint{
 		acc.NewEmail = nil
 		acc.newEmailConfirmationCode = nil
 		dbUpdatePersonalAccount(ctx, acc)
+		setMemberNotificationSettingsInAllAccounts(ctx, &acc.Me)
 		return nil
 	},
 }
@@ -478,6 +480,32 @@ var resendMyNewEmailConfirmationEmail = &endpoint.Endpoint{
 	},
 }
 
+type setMyNotificationDeliveryArgs struct {
+	NotificationDelivery cnst.NotificationDelivery `json:"notificationDelivery"`
+}
+
+var setMyNotificationDelivery = &endpoint.Endpoint{
+	Path:            "/api/v1/centralAccount/setMyNotificationDelivery",
+	Note:            "sets how notifications for watched tasks are emailed to me in every account I am a member of, 0 immediately, 1 as a daily digest or 2 not at all",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &setMyNotificationDeliveryArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*setMyNotificationDeliveryArgs)
+		args.NotificationDelivery.Validate()
+		acc := dbGetPersonalAccountById(ctx, ctx.Me())
+		ctx.ReturnNowIf(acc == nil, http.StatusNotFound, "no such account")
+		if acc.NotificationDelivery == args.NotificationDelivery {
+			return nil //if there is no change, dont do any redundant work
+		}
+		dbSetNotificationDelivery(ctx, ctx.Me(), args.NotificationDelivery)
+		acc.NotificationDelivery = args.NotificationDelivery
+		setMemberNotificationSettingsInAllAccounts(ctx, &acc.Me)
+		return nil
+	},
+}
+
 type setAccountNameArgs struct {
 	Account id.Id  `json:"account"`
 	NewName string `json:"newName"`
@@ -765,6 +793,7 @@ var createAccount = &endpoint.Endpoint{
 		}()
 		shard, e := ctx.RegionalV1PrivateClient().CreateAccount(args.Region, account.Id, ctx.Me(), owner.Name, owner.DisplayName, owner.HasAvatar)
 		panic.IfNotNil(e)
+		panic.IfNotNil(ctx.RegionalV1PrivateClient().SetMemberNotificationSettings(args.Region, shard, account.Id, ctx.Me(), owner.Email, owner.NotificationDelivery))
 
 		account.Shard = shard
 		dbUpdateAccount(ctx, account)
@@ -903,6 +932,8 @@ var addMembers = &endpoint.Endpoint{
 			ami.Name = acc.Name
 			ami.DisplayName = acc.DisplayName
 			ami.HasAvatar = acc.HasAvatar
+			ami.Email = acc.Email
+			ami.NotificationDelivery = acc.NotificationDelivery
 			members = append(members, ami)
 		}
 
@@ -937,6 +968,44 @@ var removeMembers = &endpoint.Endpoint{
 	},
 }
 
+type getDeadLetteredEmailsArgs struct {
+	After *id.Id `json:"after"`
+	Limit int    `json:"limit"`
+}
+
+var getDeadLetteredEmails = &endpoint.Endpoint{
+	Path:      "/api/v1/centralAccount/getDeadLetteredEmails",
+	IsPrivate: true,
+	GetArgsStruct: func() interface{} {
+		return &getDeadLetteredEmailsArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getDeadLetteredEmailsArgs)
+		res := &GetDeadLetteredEmailsResult{}
+		res.Emails, res.More = dbGetDeadLetteredEmails(ctx, args.After, validate.Limit(args.Limit, ctx.MaxProcessEntityCount()))
+		return res
+	},
+}
+
+type resendDeadLetteredEmailsArgs struct {
+	Emails []id.Id `json:"emails"`
+}
+
+var resendDeadLetteredEmails = &endpoint.Endpoint{
+	Path:      "/api/v1/centralAccount/resendDeadLetteredEmails",
+	IsPrivate: true,
+	GetArgsStruct: func() interface{} {
+		return &resendDeadLetteredEmailsArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*resendDeadLetteredEmailsArgs)
+		ctx.ReturnBadRequestNowIf(len(args.Emails) == 0, "no emails given")
+		validate.EntityCount(len(args.Emails), ctx.MaxProcessEntityCount())
+		dbResendDeadLetteredEmails(ctx, args.Emails)
+		return nil
+	},
+}
+
 var Endpoints = []*endpoint.Endpoint{
 	register,
 	resendActivationEmail,
@@ -953,6 +1022,7 @@ var Endpoints = []*endpoint.Endpoint{
 	setMyPwd,
 	setMyEmail,
 	resendMyNewEmailConfirmationEmail,
+	setMyNotificationDelivery,
 	setAccountName,
 	setAccountDisplayName,
 	setAccountAvatar,
@@ -962,6 +1032,8 @@ var Endpoints = []*endpoint.Endpoint{
 	deleteAccount,
 	addMembers,
 	removeMembers,
+	getDeadLetteredEmails,
+	resendDeadLetteredEmails,
 }
 
 //structs
@@ -983,10 +1055,25 @@ func (a *Account) isMigrating() bool {
 
 type Me struct {
 	Account
-	Email    string     `json:"email"`
-	Language string     `json:"language"`
-	Theme    cnst.Theme `json:"theme"`
-	NewEmail *string    `json:"newEmail,omitempty"`
+	Email                string                    `json:"email"`
+	Language             string                    `json:"language"`
+	Theme                cnst.Theme                `json:"theme"`
+	NewEmail             *string                   `json:"newEmail,omitempty"`
+	NotificationDelivery cnst.NotificationDelivery `json:"notificationDelivery"`
+}
+
+type OutboxEmail struct {
+	Id           id.Id     `json:"id"`
+	SendTo       []string  `json:"sendTo"`
+	Content      string    `json:"content"`
+	CreatedOn    time.Time `json:"createdOn"`
+	AttemptCount int       `json:"attemptCount"`
+	LastError    *string   `json:"lastError"`
+}
+
+type GetDeadLetteredEmailsResult struct {
+	Emails []*OutboxEmail `json:"emails"`
+	More   bool           `json:"more"`
 }
 
 type fullPersonalAccountInfo struct {
@@ -1014,6 +1101,34 @@ func pwdsMatch(a, b []byte) bool {
 	return bytes.Compare(a, b) == 0
 }
 
+// regions only know a members email and notification delivery so they can email them notifications, so they are
+// copied to every account the member is in whenever either changes
+func setMemberNotificationSettingsInAllAccounts(ctx ctx.Ctx, me *Me) {
+	var after *id.Id
+	privateClientCallBatch := make([]func(), 0, 10)
+	privateClientCallBatch = append(privateClientCallBatch, func(a *Account) func() {
+		return func() {
+			panic.IfNotNil(ctx.RegionalV1PrivateClient().SetMemberNotificationSettings(a.Region, a.Shard, a.Id, me.Id, me.Email, me.NotificationDelivery))
+		}
+	}(&me.Account))
+	for {
+		accs, more := dbGetGroupAccounts(ctx, me.Id, after, 100)
+		for _, acc := range accs {
+			privateClientCallBatch = append(privateClientCallBatch, func(a *Account) func() {
+				return func() {
+					panic.IfNotNil(ctx.RegionalV1PrivateClient().SetMemberNotificationSettings(a.Region, a.Shard, a.Id, me.Id, me.Email, me.NotificationDelivery))
+				}
+			}(acc))
+		}
+		if more {
+			after = &accs[len(accs)-1].Id
+		} else {
+			break
+		}
+	}
+	panic.IfNotNil(panic.SafeGoGroup(privateClientCallBatch...))
+}
+
 type AddMember struct {
 	Id   id.Id            `json:"id"`
 	Role cnst.AccountRole `json:"role"`
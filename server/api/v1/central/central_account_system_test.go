@@ -230,6 +230,35 @@ func Test_system(t *testing.T) {
 	assert.Equal(t, catDisplayName, *accs[0].DisplayName)
 	assert.Equal(t, true, accs[0].IsPersonal)
 
+	for SR.EmailOutbox.Process() > 0 {
+	}
+	queuedEmailCount := 0
+	SR.AccountDb.QueryRowContext(context.TODO(), `SELECT COUNT(*) FROM emailOutbox WHERE sendTo LIKE ?`, "%"+aliName+"%").Scan(&queuedEmailCount)
+	assert.Equal(t, 0, queuedEmailCount)
+
+	deadEmailId := id.New()
+	SR.AccountDb.ExecContext(context.TODO(), `INSERT INTO emailOutbox (id, sendTo, content, createdOn, attemptCount, nextAttemptOn, lastError, isDeadLettered) VALUES (?, ?, ?, ?, ?, ?, ?, TRUE)`, deadEmailId, `["`+aliEmail+`"]`, "dead", time.Now().UTC(), 8, time.Now().UTC(), "provider error")
+	deadEmails, _ := client.GetDeadLetteredEmails(nil, 100)
+	found := false
+	for _, deadEmail := range deadEmails.Emails {
+		if deadEmail.Id.Equal(deadEmailId) {
+			found = true
+			assert.Equal(t, []string{aliEmail}, deadEmail.SendTo)
+			assert.Equal(t, "dead", deadEmail.Content)
+			assert.Equal(t, 8, deadEmail.AttemptCount)
+			assert.Equal(t, "provider error", *deadEmail.LastError)
+		}
+	}
+	assert.True(t, found)
+	err := client.ResendDeadLetteredEmails([]id.Id{})
+	assert.NotNil(t, err)
+	err = client.ResendDeadLetteredEmails([]id.Id{deadEmailId})
+	assert.Nil(t, err)
+	for SR.EmailOutbox.Process() > 0 {
+	}
+	SR.AccountDb.QueryRowContext(context.TODO(), `SELECT COUNT(*) FROM emailOutbox WHERE id=?`, deadEmailId).Scan(&queuedEmailCount)
+	assert.Equal(t, 0, queuedEmailCount)
+
 	SR.AvatarClient.DeleteAll()
 	client.DeleteAccount(aliCss, org.Id)
 	client.DeleteAccount(aliCss, org2.Id)
@@ -0,0 +1,75 @@
+package comment
+
+import (
+	"github.com/0xor1/trees/server/util/clientsession"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/id"
+)
+
+type Client interface {
+	Create(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, body string) (*Comment, error)
+	Edit(css *clientsession.Store, region cnst.Region, shard int, account, project, comment id.Id, body string) error
+	Delete(css *clientsession.Store, region cnst.Region, shard int, account, project, comment id.Id) error
+	Get(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, sortAsc bool, after *id.Id, limit int) (*GetResp, error)
+}
+
+func NewClient(host string) Client {
+	return &client{
+		host: host,
+	}
+}
+
+type client struct {
+	host string
+}
+
+func (c *client) Create(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, body string) (*Comment, error) {
+	val, e := create.DoRequest(css, c.host, region, &createArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Task:    task,
+		Body:    body,
+	}, nil, &Comment{})
+	if val != nil {
+		return val.(*Comment), e
+	}
+	return nil, e
+}
+
+func (c *client) Edit(css *clientsession.Store, region cnst.Region, shard int, account, project, comment id.Id, body string) error {
+	_, e := edit.DoRequest(css, c.host, region, &editArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Comment: comment,
+		Body:    body,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) Delete(css *clientsession.Store, region cnst.Region, shard int, account, project, comment id.Id) error {
+	_, e := delete.DoRequest(css, c.host, region, &deleteArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Comment: comment,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) Get(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, sortAsc bool, after *id.Id, limit int) (*GetResp, error) {
+	val, e := get.DoRequest(css, c.host, region, &getArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Task:    task,
+		SortAsc: sortAsc,
+		After:   after,
+		Limit:   limit,
+	}, nil, &GetResp{})
+	if val != nil {
+		return val.(*GetResp), e
+	}
+	return nil, e
+}
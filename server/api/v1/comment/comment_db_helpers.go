@@ -0,0 +1,143 @@
+package comment
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/cachekey"
+	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/db"
+	"github.com/0xor1/trees/server/util/err"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/sortdir"
+	"regexp"
+	"strings"
+)
+
+// matches the same member names the project.getAtMentions endpoint suggests to clients
+var atMentionRegex = regexp.MustCompile(`@([0-9a-zA-Z_]+)`)
+
+func dbResolveAtMentions(ctx ctx.Ctx, shard int, account, project id.Id, body string) []id.Id {
+	res := make([]id.Id, 0, 10)
+	names := make([]interface{}, 0, 10)
+	seen := map[string]bool{}
+	for _, match := range atMentionRegex.FindAllStringSubmatch(body, -1) {
+		name := strings.ToLower(match[1])
+		if !seen[name] && len(names) < ctx.MaxProcessEntityCount() {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return res
+	}
+	query := bytes.NewBufferString(`SELECT id FROM projectMembers WHERE account=? AND project=? AND isActive=true AND name IN (?`)
+	query.WriteString(strings.Repeat(`,?`, len(names)-1))
+	query.WriteString(`)`)
+	rows, e := ctx.TreeQuery(shard, query.String(), append([]interface{}{account, project}, names...)...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var i id.Id
+		panic.IfNotNil(rows.Scan(&i))
+		res = append(res, i)
+	}
+	return res
+}
+
+func dbCreateComment(ctx ctx.Ctx, shard int, account, project id.Id, comment *Comment) {
+	db.MakeChangeHelper(ctx, shard, `CALL createComment(?, ?, ?, ?, ?, ?, ?, ?)`, account, project, comment.Task, ctx.Me(), comment.Id, comment.CreatedOn, comment.Body, id.ToHexString(comment.Mentions))
+	ctx.TouchDlms(cachekey.NewSetDlms().Comment(account, project, comment.Id, &comment.Task).Task(account, project, comment.Task).ProjectActivities(account, project))
+}
+
+func dbEditComment(ctx ctx.Ctx, shard int, account, project, task, comment id.Id, body string, mentions []id.Id) {
+	db.MakeChangeHelper(ctx, shard, `CALL editComment(?, ?, ?, ?, ?, ?)`, account, project, comment, ctx.Me(), body, id.ToHexString(mentions))
+	ctx.TouchDlms(cachekey.NewSetDlms().Comment(account, project, comment, &task).ProjectActivities(account, project))
+}
+
+func dbDeleteComment(ctx ctx.Ctx, shard int, account, project, task, comment id.Id) {
+	db.MakeChangeHelper(ctx, shard, `CALL deleteComment(?, ?, ?, ?)`, account, project, comment, ctx.Me())
+	ctx.TouchDlms(cachekey.NewSetDlms().Comment(account, project, comment, &task).Task(account, project, task).ProjectActivities(account, project))
+}
+
+func dbGetComment(ctx ctx.Ctx, shard int, account, project, comment id.Id) *Comment {
+	cacheKey := cachekey.NewGet("comment.dbGetComment", shard, account, project, comment).Comment(account, project, comment, nil)
+	c := Comment{}
+	if ctx.GetCacheValue(&c, cacheKey) {
+		return &c
+	}
+	row := ctx.TreeQueryRow(shard, `SELECT task, id, member, createdOn, editedOn, body FROM comments WHERE account=? AND project=? AND id=?`, account, project, comment)
+	ctx.ReturnBadRequestNowIf(err.IsSqlErrNoRowsElsePanicIf(row.Scan(&c.Task, &c.Id, &c.Member, &c.CreatedOn, &c.EditedOn, &c.Body)), "no such comment")
+	dbPopulateMentions(ctx, shard, account, project, []*Comment{&c})
+	ctx.SetCacheValue(c, cacheKey)
+	return &c
+}
+
+func dbGetComments(ctx ctx.Ctx, shard int, account, project, task id.Id, sortAsc bool, after *id.Id, limit int) *GetResp {
+	cacheKey := cachekey.NewGet("comment.dbGetComments", shard, account, project, task, sortAsc, after, limit).TaskCommentSet(account, project, task)
+	res := GetResp{}
+	if ctx.GetCacheValue(&res, cacheKey) {
+		return &res
+	}
+	query := bytes.NewBufferString(`SELECT task, id, member, createdOn, editedOn, body FROM comments WHERE account=? AND project=? AND task=?`)
+	args := make([]interface{}, 0, 8)
+	args = append(args, account, project, task)
+	if after != nil {
+		query.WriteString(fmt.Sprintf(` AND createdOn %s= (SELECT createdOn FROM comments WHERE account=? AND project=? AND id=?) AND id %s ?`, sortdir.GtLtSymbol(sortAsc), sortdir.GtLtSymbol(sortAsc)))
+		args = append(args, account, project, *after, *after)
+	}
+	query.WriteString(fmt.Sprintf(` ORDER BY createdOn %s, id %s LIMIT ?`, sortdir.String(sortAsc), sortdir.String(sortAsc)))
+	args = append(args, limit+1)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	commentSet := make([]*Comment, 0, limit+1)
+	for rows.Next() {
+		c := Comment{}
+		panic.IfNotNil(rows.Scan(&c.Task, &c.Id, &c.Member, &c.CreatedOn, &c.EditedOn, &c.Body))
+		commentSet = append(commentSet, &c)
+	}
+	if len(commentSet) == limit+1 {
+		res.Comments = commentSet[:limit]
+		res.More = true
+	} else {
+		res.Comments = commentSet
+		res.More = false
+	}
+	dbPopulateMentions(ctx, shard, account, project, res.Comments)
+	ctx.SetCacheValue(res, cacheKey)
+	return &res
+}
+
+func dbPopulateMentions(ctx ctx.Ctx, shard int, account, project id.Id, comments []*Comment) {
+	if len(comments) == 0 {
+		return
+	}
+	byId := make(map[string]*Comment, len(comments))
+	args := make([]interface{}, 0, len(comments)+2)
+	args = append(args, account, project)
+	for _, c := range comments {
+		c.Mentions = make([]id.Id, 0, 5)
+		byId[c.Id.String()] = c
+		args = append(args, c.Id)
+	}
+	query := bytes.NewBufferString(`SELECT comment, member FROM commentMentions WHERE account=? AND project=? AND comment IN (?`)
+	query.WriteString(strings.Repeat(`,?`, len(comments)-1))
+	query.WriteString(`)`)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var comment, member id.Id
+		panic.IfNotNil(rows.Scan(&comment, &member))
+		if c := byId[comment.String()]; c != nil {
+			c.Mentions = append(c.Mentions, member)
+		}
+	}
+}
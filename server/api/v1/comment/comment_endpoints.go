@@ -0,0 +1,150 @@
+package comment
+
+import (
+	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/db"
+	"github.com/0xor1/trees/server/util/endpoint"
+	"github.com/0xor1/trees/server/util/id"
+	t "github.com/0xor1/trees/server/util/time"
+	"github.com/0xor1/trees/server/util/validate"
+	"time"
+)
+
+const (
+	bodyMinRuneCount = 1
+	bodyMaxRuneCount = 5000
+)
+
+type createArgs struct {
+	Shard   int    `json:"shard"`
+	Account id.Id  `json:"account"`
+	Project id.Id  `json:"project"`
+	Task    id.Id  `json:"task"`
+	Body    string `json:"body"`
+}
+
+var create = &endpoint.Endpoint{
+	Path:                     "/api/v1/comment/create",
+	RequiresSession:          true,
+	ExampleResponseStructure: &Comment{Mentions: []id.Id{id.New()}},
+	GetArgsStruct: func() interface{} {
+		return &createArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*createArgs)
+		validate.StringArg("body", args.Body, bodyMinRuneCount, bodyMaxRuneCount, nil)
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		newComment := &Comment{
+			Id:        id.New(),
+			Task:      args.Task,
+			Member:    ctx.Me(),
+			CreatedOn: t.Now(),
+			Body:      args.Body,
+			Mentions:  dbResolveAtMentions(ctx, args.Shard, args.Account, args.Project, args.Body),
+		}
+		dbCreateComment(ctx, args.Shard, args.Account, args.Project, newComment)
+		return newComment
+	},
+}
+
+type editArgs struct {
+	Shard   int    `json:"shard"`
+	Account id.Id  `json:"account"`
+	Project id.Id  `json:"project"`
+	Comment id.Id  `json:"comment"`
+	Body    string `json:"body"`
+}
+
+var edit = &endpoint.Endpoint{
+	Path:            "/api/v1/comment/edit",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &editArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*editArgs)
+		validate.StringArg("body", args.Body, bodyMinRuneCount, bodyMaxRuneCount, nil)
+		c := dbGetComment(ctx, args.Shard, args.Account, args.Project, args.Comment)
+		ctx.ReturnUnauthorizedNowIf(!c.Member.Equal(ctx.Me())) //only the author can edit a comment
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		if c.Body != args.Body {
+			dbEditComment(ctx, args.Shard, args.Account, args.Project, c.Task, c.Id, args.Body, dbResolveAtMentions(ctx, args.Shard, args.Account, args.Project, args.Body))
+		}
+		return nil
+	},
+}
+
+type deleteArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Project id.Id `json:"project"`
+	Comment id.Id `json:"comment"`
+}
+
+var delete = &endpoint.Endpoint{
+	Path:            "/api/v1/comment/delete",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &deleteArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*deleteArgs)
+		c := dbGetComment(ctx, args.Shard, args.Account, args.Project, args.Comment)
+		if c.Member.Equal(ctx.Me()) {
+			validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		} else {
+			validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		}
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		dbDeleteComment(ctx, args.Shard, args.Account, args.Project, c.Task, c.Id)
+		return nil
+	},
+}
+
+type getArgs struct {
+	Shard   int    `json:"shard"`
+	Account id.Id  `json:"account"`
+	Project id.Id  `json:"project"`
+	Task    id.Id  `json:"task"`
+	SortAsc bool   `json:"sortAsc"`
+	After   *id.Id `json:"after,omitempty"`
+	Limit   int    `json:"limit"`
+}
+
+type GetResp struct {
+	Comments []*Comment `json:"comments"`
+	More     bool       `json:"more"`
+}
+
+var get = &endpoint.Endpoint{
+	Path:                     "/api/v1/comment/get",
+	RequiresSession:          false,
+	ExampleResponseStructure: &GetResp{Comments: []*Comment{{Mentions: []id.Id{id.New()}}}},
+	GetArgsStruct: func() interface{} {
+		return &getArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		return dbGetComments(ctx, args.Shard, args.Account, args.Project, args.Task, args.SortAsc, args.After, validate.Limit(args.Limit, ctx.MaxProcessEntityCount()))
+	},
+}
+
+var Endpoints = []*endpoint.Endpoint{
+	create,
+	edit,
+	delete,
+	get,
+}
+
+type Comment struct {
+	Id        id.Id      `json:"id"`
+	Task      id.Id      `json:"task"`
+	Member    id.Id      `json:"member"`
+	CreatedOn time.Time  `json:"createdOn"`
+	EditedOn  *time.Time `json:"editedOn,omitempty"`
+	Body      string     `json:"body"`
+	Mentions  []id.Id    `json:"mentions"`
+}
@@ -0,0 +1,98 @@
+package comment
+
+import (
+	"github.com/0xor1/trees/server/api/v1/account"
+	"github.com/0xor1/trees/server/api/v1/project"
+	"github.com/0xor1/trees/server/api/v1/task"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/field"
+	"github.com/0xor1/trees/server/util/systemtest"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_system(t *testing.T) {
+	systemtest.Run(t, func(base *systemtest.Base) {
+		projectClient := project.NewClient(base.TestServerURL)
+		taskClient := task.NewClient(base.TestServerURL)
+		client := NewClient(base.TestServerURL)
+
+		start := time.Now()
+		end := start.Add(5 * 24 * time.Hour)
+		desc := "desc"
+		proj, err := projectClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, "proj", &desc, 8, 5, &start, &end, true, false, []*project.AddProjectMember{{Id: base.Ali.Info.Me.Id, Role: cnst.ProjectAdmin}, {Id: base.Bob.Info.Me.Id, Role: cnst.ProjectAdmin}, {Id: base.Cat.Info.Me.Id, Role: cnst.ProjectWriter}, {Id: base.Dan.Info.Me.Id, Role: cnst.ProjectReader}})
+		oneVal := uint64(1)
+		taskA, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, "A", &desc, false, nil, nil, &oneVal)
+
+		atMentions, err := projectClient.GetAtMentions(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, base.Bob.Info.Me.Name[:3])
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(atMentions))
+
+		commentA, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, "hi @"+base.Bob.Info.Me.Name+" and @"+strings.ToLower(base.Cat.Info.Me.Name)+", @nobody")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(commentA.Mentions))
+		commentB, err := client.Create(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, "on the project node")
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(commentB.Mentions))
+		commentC, err := client.Create(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, "second")
+		assert.Nil(t, err)
+		_, err = client.Create(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, "readers can't comment")
+		assert.NotNil(t, err)
+		_, err = client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, "")
+		assert.NotNil(t, err)
+
+		taskARes, err := taskClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id)
+		assert.Equal(t, uint64(2), taskARes.ChatCount)
+
+		res, err := client.Get(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, true, nil, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(res.Comments))
+		assert.True(t, res.More)
+		assert.True(t, commentA.Id.Equal(res.Comments[0].Id))
+		assert.Equal(t, 2, len(res.Comments[0].Mentions))
+		res, err = client.Get(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, true, &commentA.Id, 100)
+		assert.Equal(t, 1, len(res.Comments))
+		assert.False(t, res.More)
+		assert.True(t, commentC.Id.Equal(res.Comments[0].Id))
+
+		err = projectClient.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, project.Fields{IsArchived: &field.Bool{true}})
+		assert.Nil(t, err)
+		_, err = client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, "archived")
+		assert.NotNil(t, err)
+		err = client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, commentA.Id, "archived")
+		assert.NotNil(t, err)
+		err = client.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, commentA.Id)
+		assert.NotNil(t, err)
+		err = projectClient.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, project.Fields{IsArchived: &field.Bool{false}})
+		assert.Nil(t, err)
+
+		err = client.Edit(base.Bob.CSS, base.Region, 0, base.Org.Id, proj.Id, commentA.Id, "not mine to edit")
+		assert.NotNil(t, err)
+		err = client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, commentA.Id, "just @"+base.Dan.Info.Me.Name)
+		assert.Nil(t, err)
+		res, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, false, nil, 100)
+		assert.Equal(t, 2, len(res.Comments))
+		assert.True(t, commentA.Id.Equal(res.Comments[1].Id))
+		assert.Equal(t, "just @"+base.Dan.Info.Me.Name, res.Comments[1].Body)
+		assert.NotNil(t, res.Comments[1].EditedOn)
+		assert.Equal(t, 1, len(res.Comments[1].Mentions))
+		assert.True(t, base.Dan.Info.Me.Id.Equal(res.Comments[1].Mentions[0]))
+
+		err = client.Delete(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, commentC.Id)
+		assert.NotNil(t, err)
+		err = client.Delete(base.Bob.CSS, base.Region, 0, base.Org.Id, proj.Id, commentC.Id)
+		assert.Nil(t, err)
+		taskARes, err = taskClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id)
+		assert.Equal(t, uint64(1), taskARes.ChatCount)
+
+		//comments are kept while their task is in the trash
+		taskClient.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id)
+		err = client.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, commentA.Id)
+		assert.Nil(t, err)
+		res, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, true, nil, 100)
+		assert.Equal(t, 1, len(res.Comments))
+		assert.True(t, commentB.Id.Equal(res.Comments[0].Id))
+	}, account.Endpoints, project.Endpoints, task.Endpoints, Endpoints)
+}
@@ -0,0 +1,109 @@
+package file
+
+import (
+	"bytes"
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/clientsession"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/id"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+type Client interface {
+	Upload(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, name, mimeType string, data io.ReadCloser) (*File, error)
+	Download(css *clientsession.Store, region cnst.Region, shard int, account, project, file id.Id) (*DownloadResp, error)
+	Delete(css *clientsession.Store, region cnst.Region, shard int, account, project, file id.Id) error
+	Get(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, sortAsc bool, after *id.Id, limit int) (*GetResp, error)
+}
+
+func NewClient(host string) Client {
+	return &client{
+		host: host,
+	}
+}
+
+type client struct {
+	host string
+}
+
+func (c *client) Upload(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, name, mimeType string, data io.ReadCloser) (*File, error) {
+	defer data.Close()
+	val, e := upload.DoRequest(css, c.host, region, &uploadArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Task:    task,
+		File:    nil,
+	}, func() (io.ReadCloser, string) {
+		body := bytes.NewBuffer([]byte{})
+		writer := multipart.NewWriter(body)
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="file"; filename="`+escapeQuotes(name)+`"`)
+		header.Set("Content-Type", mimeType)
+		part, e := writer.CreatePart(header)
+		panic.IfNotNil(e)
+		_, e = io.Copy(part, data)
+		panic.IfNotNil(e)
+		panic.IfNotNil(writer.WriteField("shard", strconv.Itoa(shard)))
+		panic.IfNotNil(writer.WriteField("account", account.String()))
+		panic.IfNotNil(writer.WriteField("project", project.String()))
+		panic.IfNotNil(writer.WriteField("task", task.String()))
+		panic.IfNotNil(writer.Close())
+		return ioutil.NopCloser(body), writer.FormDataContentType()
+	}, &File{})
+	if val != nil {
+		return val.(*File), e
+	}
+	return nil, e
+}
+
+func (c *client) Download(css *clientsession.Store, region cnst.Region, shard int, account, project, file id.Id) (*DownloadResp, error) {
+	val, e := download.DoRequest(css, c.host, region, &downloadArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		File:    file,
+	}, nil, &DownloadResp{})
+	if val != nil {
+		return val.(*DownloadResp), e
+	}
+	return nil, e
+}
+
+func (c *client) Delete(css *clientsession.Store, region cnst.Region, shard int, account, project, file id.Id) error {
+	_, e := delete.DoRequest(css, c.host, region, &deleteArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		File:    file,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) Get(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, sortAsc bool, after *id.Id, limit int) (*GetResp, error) {
+	val, e := get.DoRequest(css, c.host, region, &getArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Task:    task,
+		SortAsc: sortAsc,
+		After:   after,
+		Limit:   limit,
+	}, nil, &GetResp{})
+	if val != nil {
+		return val.(*GetResp), e
+	}
+	return nil, e
+}
+
+// same escaping multipart.Writer.CreateFormFile uses, which doesn't let the content type be set
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,86 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/cachekey"
+	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/db"
+	"github.com/0xor1/trees/server/util/err"
+	"github.com/0xor1/trees/server/util/filestore"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/sortdir"
+)
+
+func dbGetAccountFileSize(ctx ctx.Ctx, shard int, account id.Id) uint64 {
+	row := ctx.TreeQueryRow(shard, `SELECT COALESCE(SUM(fileSize), 0) FROM projects WHERE account=?`, account)
+	size := uint64(0)
+	panic.IfNotNil(row.Scan(&size))
+	return size
+}
+
+func dbCreateFile(ctx ctx.Ctx, shard int, account, project id.Id, file *File) {
+	row := ctx.TreeQueryRow(shard, `CALL createFile(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, account, project, file.Task, ctx.Me(), file.Id, file.CreatedOn, file.Name, file.MimeType, file.Size, ctx.FileClient().AccountQuota())
+	changeMade := false
+	panic.IfNotNil(row.Scan(&changeMade))
+	if !changeMade { //the data has already been stored so clean it up
+		ctx.FileClient().Delete(filestore.Key(account, project, file.Id))
+	}
+	ctx.ReturnBadRequestNowIf(!changeMade, "no change made")
+	ctx.TouchDlms(cachekey.NewSetDlms().File(account, project, file.Id, &file.Task).Task(account, project, file.Task).Project(account, project).ProjectActivities(account, project))
+}
+
+func dbDeleteFile(ctx ctx.Ctx, shard int, account, project, task, file id.Id) {
+	db.MakeChangeHelper(ctx, shard, `CALL deleteFile(?, ?, ?, ?)`, account, project, file, ctx.Me())
+	ctx.TouchDlms(cachekey.NewSetDlms().File(account, project, file, &task).Task(account, project, task).Project(account, project).ProjectActivities(account, project))
+}
+
+func dbGetFile(ctx ctx.Ctx, shard int, account, project, file id.Id) *File {
+	cacheKey := cachekey.NewGet("file.dbGetFile", shard, account, project, file).File(account, project, file, nil)
+	f := File{}
+	if ctx.GetCacheValue(&f, cacheKey) {
+		return &f
+	}
+	row := ctx.TreeQueryRow(shard, `SELECT task, id, member, createdOn, name, mimeType, size FROM files WHERE account=? AND project=? AND id=?`, account, project, file)
+	ctx.ReturnBadRequestNowIf(err.IsSqlErrNoRowsElsePanicIf(row.Scan(&f.Task, &f.Id, &f.Member, &f.CreatedOn, &f.Name, &f.MimeType, &f.Size)), "no such file")
+	ctx.SetCacheValue(f, cacheKey)
+	return &f
+}
+
+func dbGetFiles(ctx ctx.Ctx, shard int, account, project, task id.Id, sortAsc bool, after *id.Id, limit int) *GetResp {
+	cacheKey := cachekey.NewGet("file.dbGetFiles", shard, account, project, task, sortAsc, after, limit).TaskFileSet(account, project, task)
+	res := GetResp{}
+	if ctx.GetCacheValue(&res, cacheKey) {
+		return &res
+	}
+	query := bytes.NewBufferString(`SELECT task, id, member, createdOn, name, mimeType, size FROM files WHERE account=? AND project=? AND task=?`)
+	args := make([]interface{}, 0, 8)
+	args = append(args, account, project, task)
+	if after != nil {
+		query.WriteString(fmt.Sprintf(` AND createdOn %s= (SELECT createdOn FROM files WHERE account=? AND project=? AND id=?) AND id %s ?`, sortdir.GtLtSymbol(sortAsc), sortdir.GtLtSymbol(sortAsc)))
+		args = append(args, account, project, *after, *after)
+	}
+	query.WriteString(fmt.Sprintf(` ORDER BY createdOn %s, id %s LIMIT ?`, sortdir.String(sortAsc), sortdir.String(sortAsc)))
+	args = append(args, limit+1)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	fileSet := make([]*File, 0, limit+1)
+	for rows.Next() {
+		f := File{}
+		panic.IfNotNil(rows.Scan(&f.Task, &f.Id, &f.Member, &f.CreatedOn, &f.Name, &f.MimeType, &f.Size))
+		fileSet = append(fileSet, &f)
+	}
+	if len(fileSet) == limit+1 {
+		res.Files = fileSet[:limit]
+		res.More = true
+	} else {
+		res.Files = fileSet
+		res.More = false
+	}
+	ctx.SetCacheValue(res, cacheKey)
+	return &res
+}
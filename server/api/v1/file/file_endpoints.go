@@ -0,0 +1,198 @@
+package file
+
+import (
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/db"
+	"github.com/0xor1/trees/server/util/endpoint"
+	"github.com/0xor1/trees/server/util/err"
+	"github.com/0xor1/trees/server/util/filestore"
+	"github.com/0xor1/trees/server/util/id"
+	t "github.com/0xor1/trees/server/util/time"
+	"github.com/0xor1/trees/server/util/validate"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	nameMinRuneCount      = 1
+	nameMaxRuneCount      = 250
+	mimeTypeMaxRuneCount  = 250
+	maxUploadRequestBytes = 100000000 //hard limit on the request body, the configured max file size is checked in the handler
+	defaultMimeType       = "application/octet-stream"
+)
+
+type uploadArgs struct {
+	Shard   int
+	Account id.Id
+	Project id.Id
+	Task    id.Id
+	File    multipart.File
+	Header  *multipart.FileHeader
+}
+
+var upload = &endpoint.Endpoint{
+	Path:                     "/api/v1/file/upload",
+	RequiresSession:          true,
+	ExampleResponseStructure: &File{},
+	FormStruct: map[string]string{
+		"shard":   "int",
+		"account": "Id",
+		"project": "Id",
+		"task":    "Id",
+		"file":    "file",
+	},
+	ProcessForm: func(w http.ResponseWriter, r *http.Request) interface{} {
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadRequestBytes)
+		f, header, e := r.FormFile("file")
+		err.HttpPanicf(e != nil, http.StatusBadRequest, "missing file")
+		shard, e := strconv.Atoi(r.FormValue("shard"))
+		err.HttpPanicf(e != nil, http.StatusBadRequest, "invalid shard")
+		return &uploadArgs{
+			Shard:   shard,
+			Account: id.Parse(r.FormValue("account")),
+			Project: id.Parse(r.FormValue("project")),
+			Task:    id.Parse(r.FormValue("task")),
+			File:    f,
+			Header:  header,
+		}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*uploadArgs)
+		defer args.File.Close()
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		validate.StringArg("name", args.Header.Filename, nameMinRuneCount, nameMaxRuneCount, nil)
+		mimeType := args.Header.Header.Get("Content-Type")
+		if mimeType == "" {
+			mimeType = defaultMimeType
+		}
+		validate.StringArg("mimeType", mimeType, 1, mimeTypeMaxRuneCount, nil)
+		ctx.ReturnBadRequestNowIf(args.Header.Size > ctx.FileClient().MaxFileSize(), "file too large, max size is %d bytes", ctx.FileClient().MaxFileSize())
+		ctx.ReturnBadRequestNowIf(dbGetAccountFileSize(ctx, args.Shard, args.Account)+uint64(args.Header.Size) > uint64(ctx.FileClient().AccountQuota()), "account file quota exceeded")
+		newFile := &File{
+			Id:        id.New(),
+			Task:      args.Task,
+			Member:    ctx.Me(),
+			CreatedOn: t.Now(),
+			Name:      args.Header.Filename,
+			MimeType:  mimeType,
+			Size:      uint64(args.Header.Size),
+		}
+		ctx.FileClient().Save(filestore.Key(args.Account, args.Project, newFile.Id), newFile.MimeType, args.File)
+		dbCreateFile(ctx, args.Shard, args.Account, args.Project, newFile)
+		return newFile
+	},
+}
+
+type downloadArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Project id.Id `json:"project"`
+	File    id.Id `json:"file"`
+}
+
+type DownloadResp struct {
+	File *File  `json:"file"`
+	Data []byte `json:"data"`
+}
+
+var download = &endpoint.Endpoint{
+	Path:                     "/api/v1/file/download",
+	Note:                     "data is the base64 encoded file content",
+	RequiresSession:          false,
+	ExampleResponseStructure: &DownloadResp{File: &File{}, Data: []byte{}},
+	GetArgsStruct: func() interface{} {
+		return &downloadArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*downloadArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		f := dbGetFile(ctx, args.Shard, args.Account, args.Project, args.File)
+		reader := ctx.FileClient().Load(filestore.Key(args.Account, args.Project, f.Id))
+		defer reader.Close()
+		data, e := ioutil.ReadAll(reader)
+		panic.IfNotNil(e)
+		return &DownloadResp{
+			File: f,
+			Data: data,
+		}
+	},
+}
+
+type deleteArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Project id.Id `json:"project"`
+	File    id.Id `json:"file"`
+}
+
+var delete = &endpoint.Endpoint{
+	Path:            "/api/v1/file/delete",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &deleteArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*deleteArgs)
+		f := dbGetFile(ctx, args.Shard, args.Account, args.Project, args.File)
+		if f.Member.Equal(ctx.Me()) {
+			validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		} else {
+			validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		}
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		dbDeleteFile(ctx, args.Shard, args.Account, args.Project, f.Task, f.Id)
+		ctx.FileClient().Delete(filestore.Key(args.Account, args.Project, f.Id))
+		return nil
+	},
+}
+
+type getArgs struct {
+	Shard   int    `json:"shard"`
+	Account id.Id  `json:"account"`
+	Project id.Id  `json:"project"`
+	Task    id.Id  `json:"task"`
+	SortAsc bool   `json:"sortAsc"`
+	After   *id.Id `json:"after,omitempty"`
+	Limit   int    `json:"limit"`
+}
+
+type GetResp struct {
+	Files []*File `json:"files"`
+	More  bool    `json:"more"`
+}
+
+var get = &endpoint.Endpoint{
+	Path:                     "/api/v1/file/get",
+	RequiresSession:          false,
+	ExampleResponseStructure: &GetResp{Files: []*File{{}}},
+	GetArgsStruct: func() interface{} {
+		return &getArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		return dbGetFiles(ctx, args.Shard, args.Account, args.Project, args.Task, args.SortAsc, args.After, validate.Limit(args.Limit, ctx.MaxProcessEntityCount()))
+	},
+}
+
+var Endpoints = []*endpoint.Endpoint{
+	upload,
+	download,
+	delete,
+	get,
+}
+
+type File struct {
+	Id        id.Id     `json:"id"`
+	Task      id.Id     `json:"task"`
+	Member    id.Id     `json:"member"`
+	CreatedOn time.Time `json:"createdOn"`
+	Name      string    `json:"name"`
+	MimeType  string    `json:"mimeType"`
+	Size      uint64    `json:"size"`
+}
@@ -0,0 +1,88 @@
+package file
+
+import (
+	"bytes"
+	"github.com/0xor1/trees/server/api/v1/project"
+	"github.com/0xor1/trees/server/api/v1/task"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/systemtest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func Test_system(t *testing.T) {
+	systemtest.Run(t, func(base *systemtest.Base) {
+		projectClient := project.NewClient(base.TestServerURL)
+		taskClient := task.NewClient(base.TestServerURL)
+		client := NewClient(base.TestServerURL)
+
+		start := time.Now()
+		end := start.Add(5 * 24 * time.Hour)
+		desc := "desc"
+		proj, err := projectClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, "proj", &desc, 8, 5, &start, &end, true, false, []*project.AddProjectMember{{Id: base.Ali.Info.Me.Id, Role: cnst.ProjectAdmin}, {Id: base.Bob.Info.Me.Id, Role: cnst.ProjectAdmin}, {Id: base.Cat.Info.Me.Id, Role: cnst.ProjectWriter}, {Id: base.Dan.Info.Me.Id, Role: cnst.ProjectReader}})
+		oneVal := uint64(1)
+		taskA, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, "A", &desc, false, nil, nil, &oneVal)
+
+		dataA := []byte("hello world")
+		fileA, err := client.Upload(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, "a.txt", "text/plain", ioutil.NopCloser(bytes.NewReader(dataA)))
+		assert.Nil(t, err)
+		assert.Equal(t, "a.txt", fileA.Name)
+		assert.Equal(t, "text/plain", fileA.MimeType)
+		assert.Equal(t, uint64(len(dataA)), fileA.Size)
+		dataB := []byte("on the project node")
+		fileB, err := client.Upload(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, "b.txt", "text/plain", ioutil.NopCloser(bytes.NewReader(dataB)))
+		assert.Nil(t, err)
+		fileC, err := client.Upload(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, "c.bin", "", ioutil.NopCloser(bytes.NewReader([]byte{1, 2, 3})))
+		assert.Nil(t, err)
+		_, err = client.Upload(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, "d.txt", "text/plain", ioutil.NopCloser(bytes.NewReader(dataA)))
+		assert.NotNil(t, err)
+		_, err = client.Upload(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, "too-big.bin", "", ioutil.NopCloser(bytes.NewReader(make([]byte, base.SR.FileClient.MaxFileSize()+1))))
+		assert.NotNil(t, err)
+
+		projRes, err := projectClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Equal(t, uint64(3), projRes.FileCount)
+		assert.Equal(t, uint64(len(dataA)+len(dataB)+3), projRes.FileSize)
+		assert.Equal(t, uint64(1), projRes.LinkedFileCount)
+		taskARes, err := taskClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id)
+		assert.Equal(t, uint64(2), taskARes.LinkedFileCount)
+
+		res, err := client.Get(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, true, nil, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(res.Files))
+		assert.True(t, res.More)
+		assert.True(t, fileA.Id.Equal(res.Files[0].Id))
+		res, err = client.Get(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, true, &fileA.Id, 100)
+		assert.Equal(t, 1, len(res.Files))
+		assert.False(t, res.More)
+		assert.True(t, fileC.Id.Equal(res.Files[0].Id))
+		assert.Equal(t, "application/octet-stream", res.Files[0].MimeType)
+
+		downloadRes, err := client.Download(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, fileA.Id)
+		assert.Nil(t, err)
+		assert.True(t, fileA.Id.Equal(downloadRes.File.Id))
+		assert.Equal(t, dataA, downloadRes.Data)
+
+		err = client.Delete(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, fileB.Id)
+		assert.NotNil(t, err)
+		err = client.Delete(base.Bob.CSS, base.Region, 0, base.Org.Id, proj.Id, fileB.Id)
+		assert.Nil(t, err)
+		_, err = client.Download(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, fileB.Id)
+		assert.NotNil(t, err)
+		projRes, err = projectClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Equal(t, uint64(2), projRes.FileCount)
+		assert.Equal(t, uint64(len(dataA)+3), projRes.FileSize)
+		assert.Equal(t, uint64(0), projRes.LinkedFileCount)
+
+		//files are kept while their task is in the trash
+		taskClient.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id)
+		_, err = client.Download(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, fileA.Id)
+		assert.Nil(t, err)
+		projRes, err = projectClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Equal(t, uint64(2), projRes.FileCount)
+		assert.Equal(t, uint64(len(dataA)+3), projRes.FileSize)
+
+		projectClient.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
+	})
+}
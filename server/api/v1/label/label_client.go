@@ -0,0 +1,69 @@
+package label
+
+import (
+	"github.com/0xor1/trees/server/util/clientsession"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/id"
+)
+
+type Client interface {
+	Create(css *clientsession.Store, region cnst.Region, shard int, account id.Id, name, colour string) (*Label, error)
+	Edit(css *clientsession.Store, region cnst.Region, shard int, account, label id.Id, name, colour *string) error
+	Delete(css *clientsession.Store, region cnst.Region, shard int, account, label id.Id) error
+	Get(css *clientsession.Store, region cnst.Region, shard int, account id.Id, project *id.Id) ([]*Label, error)
+}
+
+func NewClient(host string) Client {
+	return &client{
+		host: host,
+	}
+}
+
+type client struct {
+	host string
+}
+
+func (c *client) Create(css *clientsession.Store, region cnst.Region, shard int, account id.Id, name, colour string) (*Label, error) {
+	val, e := create.DoRequest(css, c.host, region, &createArgs{
+		Shard:   shard,
+		Account: account,
+		Name:    name,
+		Colour:  colour,
+	}, nil, &Label{})
+	if val != nil {
+		return val.(*Label), e
+	}
+	return nil, e
+}
+
+func (c *client) Edit(css *clientsession.Store, region cnst.Region, shard int, account, label id.Id, name, colour *string) error {
+	_, e := edit.DoRequest(css, c.host, region, &editArgs{
+		Shard:   shard,
+		Account: account,
+		Label:   label,
+		Name:    name,
+		Colour:  colour,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) Delete(css *clientsession.Store, region cnst.Region, shard int, account, label id.Id) error {
+	_, e := delete.DoRequest(css, c.host, region, &deleteArgs{
+		Shard:   shard,
+		Account: account,
+		Label:   label,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) Get(css *clientsession.Store, region cnst.Region, shard int, account id.Id, project *id.Id) ([]*Label, error) {
+	val, e := get.DoRequest(css, c.host, region, &getArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+	}, nil, &[]*Label{})
+	if val != nil {
+		return *val.(*[]*Label), e
+	}
+	return nil, e
+}
@@ -0,0 +1,82 @@
+package label
+
+import (
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/cachekey"
+	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/db"
+	"github.com/0xor1/trees/server/util/err"
+	"github.com/0xor1/trees/server/util/id"
+)
+
+func dbCreateLabel(ctx ctx.Ctx, shard int, account id.Id, label *Label) {
+	_, e := ctx.TreeExec(shard, `CALL createLabel(?, ?, ?, ?, ?)`, account, label.Id, ctx.Me(), label.Name, label.Colour)
+	panic.IfNotNil(e)
+	ctx.TouchDlms(cachekey.NewSetDlms().Label(account, label.Id))
+}
+
+func dbEditLabel(ctx ctx.Ctx, shard int, account id.Id, label *Label) {
+	db.MakeChangeHelper(ctx, shard, `CALL editLabel(?, ?, ?, ?, ?)`, account, label.Id, ctx.Me(), label.Name, label.Colour)
+	ctx.TouchDlms(cachekey.NewSetDlms().Label(account, label.Id))
+}
+
+func dbDeleteLabel(ctx ctx.Ctx, shard int, account, label id.Id) {
+	rows, e := ctx.TreeQuery(shard, `CALL deleteLabel(?, ?, ?)`, account, label, ctx.Me())
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	cacheKey := cachekey.NewSetDlms().Label(account, label)
+	for rows.Next() {
+		var project, task id.Id
+		var parent *id.Id
+		panic.IfNotNil(rows.Scan(&project, &task, &parent))
+		cacheKey.Task(account, project, task).ProjectLabelledTaskSet(account, project)
+		if parent != nil {
+			cacheKey.TaskChildrenSet(account, project, *parent)
+		}
+	}
+	ctx.TouchDlms(cacheKey)
+}
+
+func dbLabelNameInUse(ctx ctx.Ctx, shard int, account id.Id, exclude *id.Id, name string) bool {
+	inUse := false
+	if exclude == nil {
+		panic.IfNotNil(ctx.TreeQueryRow(shard, `SELECT COUNT(*) > 0 FROM labels WHERE account=? AND name=?`, account, name).Scan(&inUse))
+	} else {
+		panic.IfNotNil(ctx.TreeQueryRow(shard, `SELECT COUNT(*) > 0 FROM labels WHERE account=? AND name=? AND id<>?`, account, name, *exclude).Scan(&inUse))
+	}
+	return inUse
+}
+
+func dbGetLabel(ctx ctx.Ctx, shard int, account, label id.Id) *Label {
+	cacheKey := cachekey.NewGet("label.dbGetLabel", shard, account, label).Label(account, label)
+	l := Label{}
+	if ctx.GetCacheValue(&l, cacheKey) {
+		return &l
+	}
+	row := ctx.TreeQueryRow(shard, `SELECT id, name, colour FROM labels WHERE account=? AND id=?`, account, label)
+	ctx.ReturnBadRequestNowIf(err.IsSqlErrNoRowsElsePanicIf(row.Scan(&l.Id, &l.Name, &l.Colour)), "no such label")
+	ctx.SetCacheValue(l, cacheKey)
+	return &l
+}
+
+func dbGetLabels(ctx ctx.Ctx, shard int, account id.Id) []*Label {
+	cacheKey := cachekey.NewGet("label.dbGetLabels", shard, account).AccountLabelSet(account)
+	res := make([]*Label, 0, 20)
+	if ctx.GetCacheValue(&res, cacheKey) {
+		return res
+	}
+	rows, e := ctx.TreeQuery(shard, `SELECT id, name, colour FROM labels WHERE account=? ORDER BY name ASC`, account)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		l := Label{}
+		panic.IfNotNil(rows.Scan(&l.Id, &l.Name, &l.Colour))
+		res = append(res, &l)
+	}
+	ctx.SetCacheValue(res, cacheKey)
+	return res
+}
@@ -0,0 +1,142 @@
+package label
+
+import (
+	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/db"
+	"github.com/0xor1/trees/server/util/endpoint"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/validate"
+	"regexp"
+	"strings"
+)
+
+const (
+	nameMinRuneCount = 1
+	nameMaxRuneCount = 50
+)
+
+var colourRegex = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+type createArgs struct {
+	Shard   int    `json:"shard"`
+	Account id.Id  `json:"account"`
+	Name    string `json:"name"`
+	Colour  string `json:"colour"`
+}
+
+var create = &endpoint.Endpoint{
+	Path:                     "/api/v1/label/create",
+	RequiresSession:          true,
+	ExampleResponseStructure: &Label{},
+	GetArgsStruct: func() interface{} {
+		return &createArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*createArgs)
+		validate.MemberHasAccountAdminAccess(db.GetAccountRole(ctx, args.Shard, args.Account, ctx.Me()))
+		newLabel := &Label{
+			Id:     id.New(),
+			Name:   args.Name,
+			Colour: strings.ToLower(args.Colour),
+		}
+		validateLabel(newLabel)
+		ctx.ReturnBadRequestNowIf(dbLabelNameInUse(ctx, args.Shard, args.Account, nil, newLabel.Name), "label name already in use")
+		dbCreateLabel(ctx, args.Shard, args.Account, newLabel)
+		return newLabel
+	},
+}
+
+type editArgs struct {
+	Shard   int     `json:"shard"`
+	Account id.Id   `json:"account"`
+	Label   id.Id   `json:"label"`
+	Name    *string `json:"name,omitempty"`
+	Colour  *string `json:"colour,omitempty"`
+}
+
+var edit = &endpoint.Endpoint{
+	Path:            "/api/v1/label/edit",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &editArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*editArgs)
+		validate.MemberHasAccountAdminAccess(db.GetAccountRole(ctx, args.Shard, args.Account, ctx.Me()))
+		l := dbGetLabel(ctx, args.Shard, args.Account, args.Label)
+		if args.Name != nil {
+			l.Name = *args.Name
+		}
+		if args.Colour != nil {
+			l.Colour = strings.ToLower(*args.Colour)
+		}
+		validateLabel(l)
+		ctx.ReturnBadRequestNowIf(dbLabelNameInUse(ctx, args.Shard, args.Account, &l.Id, l.Name), "label name already in use")
+		dbEditLabel(ctx, args.Shard, args.Account, l)
+		return nil
+	},
+}
+
+type deleteArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Label   id.Id `json:"label"`
+}
+
+var delete = &endpoint.Endpoint{
+	Path:            "/api/v1/label/delete",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &deleteArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*deleteArgs)
+		validate.MemberHasAccountAdminAccess(db.GetAccountRole(ctx, args.Shard, args.Account, ctx.Me()))
+		l := dbGetLabel(ctx, args.Shard, args.Account, args.Label)
+		dbDeleteLabel(ctx, args.Shard, args.Account, l.Id)
+		return nil
+	},
+}
+
+type getArgs struct {
+	Shard   int    `json:"shard"`
+	Account id.Id  `json:"account"`
+	Project *id.Id `json:"project,omitempty"`
+}
+
+var get = &endpoint.Endpoint{
+	Path:                     "/api/v1/label/get",
+	Note:                     "pass a project to read the accounts labels with access to that project, e.g. when viewing a public project",
+	RequiresSession:          false,
+	ExampleResponseStructure: []*Label{{}},
+	GetArgsStruct: func() interface{} {
+		return &getArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getArgs)
+		if args.Project != nil {
+			validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, *args.Project, ctx.TryMe()))
+		} else {
+			ctx.ReturnUnauthorizedNowIf(db.GetAccountRole(ctx, args.Shard, args.Account, ctx.Me()) == nil)
+		}
+		return dbGetLabels(ctx, args.Shard, args.Account)
+	},
+}
+
+var Endpoints = []*endpoint.Endpoint{
+	create,
+	edit,
+	delete,
+	get,
+}
+
+type Label struct {
+	Id     id.Id  `json:"id"`
+	Name   string `json:"name"`
+	Colour string `json:"colour"` //#rrggbb
+}
+
+func validateLabel(l *Label) {
+	validate.StringArg("name", l.Name, nameMinRuneCount, nameMaxRuneCount, nil)
+	validate.StringArg("colour", l.Colour, 7, 7, []*regexp.Regexp{colourRegex})
+}
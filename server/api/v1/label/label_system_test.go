@@ -0,0 +1,112 @@
+package label
+
+import (
+	"github.com/0xor1/trees/server/api/v1/project"
+	"github.com/0xor1/trees/server/api/v1/task"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/systemtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_system(t *testing.T) {
+	systemtest.Run(t, func(base *systemtest.Base) {
+		projectClient := project.NewClient(base.TestServerURL)
+		taskClient := task.NewClient(base.TestServerURL)
+		client := NewClient(base.TestServerURL)
+
+		start := time.Now()
+		end := start.Add(5 * 24 * time.Hour)
+		desc := "desc"
+		proj, err := projectClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, "proj", &desc, 8, 5, &start, &end, true, false, []*project.AddProjectMember{{Id: base.Ali.Info.Me.Id, Role: cnst.ProjectAdmin}, {Id: base.Bob.Info.Me.Id, Role: cnst.ProjectAdmin}, {Id: base.Cat.Info.Me.Id, Role: cnst.ProjectWriter}, {Id: base.Dan.Info.Me.Id, Role: cnst.ProjectReader}})
+		oneVal := uint64(1)
+		taskA, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, "A", &desc, false, nil, nil, &oneVal)
+		taskB, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, &taskA.Id, "B", &desc, false, nil, nil, &oneVal)
+		taskC, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, &taskB.Id, "C", &desc, false, nil, nil, &oneVal)
+
+		bug, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, "bug", "#FF0000")
+		assert.Nil(t, err)
+		assert.Equal(t, "#ff0000", bug.Colour)
+		urgent, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, "urgent", "#00ff00")
+		assert.Nil(t, err)
+		_, err = client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, "bug", "#0000ff")
+		assert.NotNil(t, err)
+		_, err = client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, "blue", "blue")
+		assert.NotNil(t, err)
+		_, err = client.Create(base.Cat.CSS, base.Region, 0, base.Org.Id, "cats", "#0000ff")
+		assert.NotNil(t, err)
+
+		newColour := "#0000ff"
+		err = client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, urgent.Id, nil, &newColour)
+		assert.Nil(t, err)
+		labels, err := client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(labels))
+		assert.Equal(t, "bug", labels[0].Name)
+		assert.Equal(t, "urgent", labels[1].Name)
+		assert.Equal(t, newColour, labels[1].Colour)
+		labels, err = client.Get(base.Dan.CSS, base.Region, 0, base.Org.Id, &proj.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(labels))
+
+		err = taskClient.AddLabels(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, []id.Id{bug.Id, urgent.Id})
+		assert.Nil(t, err)
+		err = taskClient.AddLabels(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, []id.Id{bug.Id})
+		assert.Nil(t, err)
+		err = taskClient.AddLabels(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, []id.Id{bug.Id})
+		assert.NotNil(t, err)
+		err = taskClient.AddLabels(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, taskB.Id, []id.Id{bug.Id})
+		assert.NotNil(t, err)
+		err = taskClient.AddLabels(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskB.Id, []id.Id{id.New()})
+		assert.NotNil(t, err)
+
+		taskARes, err := taskClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id)
+		assert.Equal(t, 2, len(taskARes.Labels))
+
+		children, err := taskClient.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, 100, []id.Id{bug.Id}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(children.Children))
+		assert.True(t, taskA.Id.Equal(children.Children[0].Id))
+		assert.True(t, taskC.Id.Equal(children.Children[1].Id))
+		children, err = taskClient.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, 1, []id.Id{bug.Id}, nil)
+		assert.Equal(t, 1, len(children.Children))
+		assert.True(t, children.More)
+		children, err = taskClient.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, &taskA.Id, 100, []id.Id{bug.Id}, nil)
+		assert.Equal(t, 1, len(children.Children))
+		assert.True(t, taskC.Id.Equal(children.Children[0].Id))
+		children, err = taskClient.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, 100, []id.Id{bug.Id, urgent.Id}, nil)
+		assert.Equal(t, 1, len(children.Children))
+		assert.True(t, taskA.Id.Equal(children.Children[0].Id))
+
+		byLabels, err := taskClient.GetByLabels(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, []id.Id{bug.Id}, nil, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(byLabels.Children))
+		assert.True(t, byLabels.More)
+		assert.True(t, taskA.Id.Equal(byLabels.Children[0].Id))
+		byLabels, err = taskClient.GetByLabels(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, []id.Id{bug.Id}, &taskA.Id, 100)
+		assert.Equal(t, 1, len(byLabels.Children))
+		assert.False(t, byLabels.More)
+		assert.True(t, taskC.Id.Equal(byLabels.Children[0].Id))
+
+		err = taskClient.RemoveLabels(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, []id.Id{bug.Id})
+		assert.Nil(t, err)
+		byLabels, err = taskClient.GetByLabels(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, []id.Id{bug.Id}, nil, 100)
+		assert.Equal(t, 1, len(byLabels.Children))
+		assert.True(t, taskC.Id.Equal(byLabels.Children[0].Id))
+
+		err = client.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, urgent.Id)
+		assert.Nil(t, err)
+		taskARes, err = taskClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id)
+		assert.Equal(t, 0, len(taskARes.Labels))
+		labels, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, nil)
+		assert.Equal(t, 1, len(labels))
+
+		taskClient.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id)
+		byLabels, err = taskClient.GetByLabels(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, []id.Id{bug.Id}, nil, 100)
+		assert.Equal(t, 0, len(byLabels.Children))
+
+		projectClient.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
+	})
+}
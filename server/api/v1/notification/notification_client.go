@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"github.com/0xor1/trees/server/util/clientsession"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/id"
+	"time"
+)
+
+type Client interface {
+	Watch(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, includeDescendants bool) error
+	Unwatch(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id) error
+	GetWatches(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) ([]*Watch, error)
+	Get(css *clientsession.Store, region cnst.Region, shard int, account id.Id, unreadOnly bool, occurredBefore *time.Time, limit int) ([]*Notification, error)
+	MarkRead(css *clientsession.Store, region cnst.Region, shard int, account id.Id, occurredOn time.Time) error
+}
+
+func NewClient(host string) Client {
+	return &client{
+		host: host,
+	}
+}
+
+type client struct {
+	host string
+}
+
+func (c *client) Watch(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, includeDescendants bool) error {
+	_, e := watch.DoRequest(css, c.host, region, &watchArgs{
+		Shard:              shard,
+		Account:            account,
+		Project:            project,
+		Task:               task,
+		IncludeDescendants: includeDescendants,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) Unwatch(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id) error {
+	_, e := unwatch.DoRequest(css, c.host, region, &unwatchArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Task:    task,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) GetWatches(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) ([]*Watch, error) {
+	val, e := getWatches.DoRequest(css, c.host, region, &getWatchesArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+	}, nil, &[]*Watch{})
+	if val != nil {
+		return *val.(*[]*Watch), e
+	}
+	return nil, e
+}
+
+func (c *client) Get(css *clientsession.Store, region cnst.Region, shard int, account id.Id, unreadOnly bool, occurredBefore *time.Time, limit int) ([]*Notification, error) {
+	val, e := get.DoRequest(css, c.host, region, &getArgs{
+		Shard:          shard,
+		Account:        account,
+		UnreadOnly:     unreadOnly,
+		OccurredBefore: occurredBefore,
+		Limit:          limit,
+	}, nil, &[]*Notification{})
+	if val != nil {
+		return *val.(*[]*Notification), e
+	}
+	return nil, e
+}
+
+func (c *client) MarkRead(css *clientsession.Store, region cnst.Region, shard int, account id.Id, occurredOn time.Time) error {
+	_, e := markRead.DoRequest(css, c.host, region, &markReadArgs{
+		Shard:      shard,
+		Account:    account,
+		OccurredOn: occurredOn,
+	}, nil, nil)
+	return e
+}
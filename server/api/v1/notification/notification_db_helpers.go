@@ -0,0 +1,69 @@
+package notification
+
+import (
+	"bytes"
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/id"
+	"time"
+)
+
+//notifications are written by the notifier which has no ctx to touch dlms with, so nothing in this package is cached
+
+func dbWatchTask(ctx ctx.Ctx, shard int, account, project, task id.Id, includeDescendants bool) bool {
+	taskExists := false
+	panic.IfNotNil(ctx.TreeQueryRow(shard, `CALL watchTask(?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), includeDescendants).Scan(&taskExists))
+	return taskExists
+}
+
+func dbUnwatchTask(ctx ctx.Ctx, shard int, account, project, task id.Id) {
+	_, e := ctx.TreeExec(shard, `CALL unwatchTask(?, ?, ?, ?)`, account, project, task, ctx.Me())
+	panic.IfNotNil(e)
+}
+
+func dbGetWatches(ctx ctx.Ctx, shard int, account, project id.Id) []*Watch {
+	rows, e := ctx.TreeQuery(shard, `SELECT task, includeDescendants, watchedOn FROM taskWatchers WHERE account=? AND member=? AND project=? ORDER BY task ASC`, account, ctx.Me(), project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	res := make([]*Watch, 0, 10)
+	for rows.Next() {
+		w := Watch{}
+		panic.IfNotNil(rows.Scan(&w.Task, &w.IncludeDescendants, &w.WatchedOn))
+		res = append(res, &w)
+	}
+	return res
+}
+
+func dbGetNotifications(ctx ctx.Ctx, shard int, account id.Id, unreadOnly bool, occurredBefore *time.Time, limit int) []*Notification {
+	query := bytes.NewBufferString(`SELECT project, task, occurredOn, actor, item, itemType, action, itemName, extraInfo, isRead FROM notifications WHERE account=? AND member=?`)
+	args := make([]interface{}, 0, 4)
+	args = append(args, account, ctx.Me())
+	if unreadOnly {
+		query.WriteString(` AND isRead=FALSE`)
+	}
+	if occurredBefore != nil {
+		query.WriteString(` AND occurredOn<?`)
+		args = append(args, occurredBefore)
+	}
+	query.WriteString(` ORDER BY occurredOn DESC LIMIT ?`)
+	args = append(args, limit)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	res := make([]*Notification, 0, limit)
+	for rows.Next() {
+		n := Notification{}
+		panic.IfNotNil(rows.Scan(&n.Project, &n.Task, &n.OccurredOn, &n.Member, &n.Item, &n.ItemType, &n.Action, &n.ItemName, &n.ExtraInfo, &n.IsRead))
+		res = append(res, &n)
+	}
+	return res
+}
+
+func dbMarkRead(ctx ctx.Ctx, shard int, account id.Id, occurredOn time.Time) {
+	_, e := ctx.TreeExec(shard, `UPDATE notifications SET isRead=TRUE WHERE account=? AND member=? AND isRead=FALSE AND occurredOn<=?`, account, ctx.Me(), occurredOn)
+	panic.IfNotNil(e)
+}
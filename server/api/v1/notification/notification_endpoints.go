@@ -0,0 +1,144 @@
+package notification
+
+import (
+	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/db"
+	"github.com/0xor1/trees/server/util/endpoint"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/validate"
+	"time"
+)
+
+type watchArgs struct {
+	Shard              int   `json:"shard"`
+	Account            id.Id `json:"account"`
+	Project            id.Id `json:"project"`
+	Task               id.Id `json:"task"`
+	IncludeDescendants bool  `json:"includeDescendants"`
+}
+
+var watch = &endpoint.Endpoint{
+	Path:            "/api/v1/notification/watch",
+	Note:            "watching a task notifies me of changes to it, includeDescendants also notifies me of changes to every task under it, watching a task again replaces includeDescendants",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &watchArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*watchArgs)
+		me := ctx.Me()
+		accountRole, projectRole, projectIsPublic := db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, &me)
+		validate.MemberHasProjectReadAccess(accountRole, projectRole, projectIsPublic)
+		ctx.ReturnBadRequestNowIf(accountRole == nil, "only account members can watch tasks")
+		ctx.ReturnBadRequestNowIf(!dbWatchTask(ctx, args.Shard, args.Account, args.Project, args.Task, args.IncludeDescendants), "no such task")
+		return nil
+	},
+}
+
+type unwatchArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Project id.Id `json:"project"`
+	Task    id.Id `json:"task"`
+}
+
+var unwatch = &endpoint.Endpoint{
+	Path:            "/api/v1/notification/unwatch",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &unwatchArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*unwatchArgs)
+		dbUnwatchTask(ctx, args.Shard, args.Account, args.Project, args.Task)
+		return nil
+	},
+}
+
+type getWatchesArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Project id.Id `json:"project"`
+}
+
+var getWatches = &endpoint.Endpoint{
+	Path:                     "/api/v1/notification/getWatches",
+	RequiresSession:          true,
+	ExampleResponseStructure: []*Watch{{}},
+	GetArgsStruct: func() interface{} {
+		return &getWatchesArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getWatchesArgs)
+		return dbGetWatches(ctx, args.Shard, args.Account, args.Project)
+	},
+}
+
+type getArgs struct {
+	Shard          int        `json:"shard"`
+	Account        id.Id      `json:"account"`
+	UnreadOnly     bool       `json:"unreadOnly"`
+	OccurredBefore *time.Time `json:"occurredBefore,omitempty"`
+	Limit          int        `json:"limit"`
+}
+
+var get = &endpoint.Endpoint{
+	Path:                     "/api/v1/notification/get",
+	Note:                     "returns my notifications in the account newest first",
+	RequiresSession:          true,
+	ExampleResponseStructure: []*Notification{{}},
+	GetArgsStruct: func() interface{} {
+		return &getArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getArgs)
+		return dbGetNotifications(ctx, args.Shard, args.Account, args.UnreadOnly, args.OccurredBefore, validate.Limit(args.Limit, ctx.MaxProcessEntityCount()))
+	},
+}
+
+type markReadArgs struct {
+	Shard      int       `json:"shard"`
+	Account    id.Id     `json:"account"`
+	OccurredOn time.Time `json:"occurredOn"`
+}
+
+var markRead = &endpoint.Endpoint{
+	Path:            "/api/v1/notification/markRead",
+	Note:            "marks all my notifications in the account that occurred on or before occurredOn as read",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &markReadArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*markReadArgs)
+		dbMarkRead(ctx, args.Shard, args.Account, args.OccurredOn)
+		return nil
+	},
+}
+
+var Endpoints = []*endpoint.Endpoint{
+	watch,
+	unwatch,
+	getWatches,
+	get,
+	markRead,
+}
+
+type Watch struct {
+	Task               id.Id     `json:"task"`
+	IncludeDescendants bool      `json:"includeDescendants"`
+	WatchedOn          time.Time `json:"watchedOn"`
+}
+
+type Notification struct {
+	Project    id.Id     `json:"project"`
+	Task       id.Id     `json:"task"`
+	OccurredOn time.Time `json:"occurredOn"`
+	Member     id.Id     `json:"member"` //who made the change
+	Item       id.Id     `json:"item"`
+	ItemType   string    `json:"itemType"`
+	Action     string    `json:"action"`
+	ItemName   *string   `json:"itemName,omitempty"`
+	ExtraInfo  *string   `json:"extraInfo,omitempty"`
+	IsRead     bool      `json:"isRead"`
+}
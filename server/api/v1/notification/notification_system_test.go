@@ -0,0 +1,83 @@
+package notification
+
+import (
+	"github.com/0xor1/trees/server/api/v1/project"
+	"github.com/0xor1/trees/server/api/v1/task"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/field"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/systemtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_system(t *testing.T) {
+	systemtest.Run(t, func(base *systemtest.Base) {
+		projectClient := project.NewClient(base.TestServerURL)
+		taskClient := task.NewClient(base.TestServerURL)
+		client := NewClient(base.TestServerURL)
+
+		start := time.Now()
+		end := start.Add(5 * 24 * time.Hour)
+		desc := "desc"
+		proj, err := projectClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, "proj", &desc, 8, 5, &start, &end, false, false, []*project.AddProjectMember{{Id: base.Ali.Info.Me.Id, Role: cnst.ProjectAdmin}, {Id: base.Bob.Info.Me.Id, Role: cnst.ProjectAdmin}, {Id: base.Cat.Info.Me.Id, Role: cnst.ProjectWriter}})
+		oneVal := uint64(1)
+		falseVal := false
+		taskA, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, "A", &desc, true, &falseVal, nil, nil)
+		taskB, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, nil, "B", &desc, false, nil, nil, &oneVal)
+		taskC, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, &taskA.Id, "C", &desc, false, nil, nil, &oneVal)
+
+		err = client.Watch(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, true)
+		assert.Nil(t, err)
+		err = client.Watch(base.Bob.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, false)
+		assert.Nil(t, err)
+		err = client.Watch(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, true)
+		assert.NotNil(t, err)
+		err = client.Watch(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, id.New(), true)
+		assert.NotNil(t, err)
+		watches, err := client.GetWatches(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(watches))
+		assert.True(t, taskA.Id.Equal(watches[0].Task))
+		assert.True(t, watches[0].IncludeDescendants)
+
+		time.Sleep(10 * time.Millisecond)
+		taskClient.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskB.Id, task.Fields{Name: &field.String{"BBB"}})
+		taskClient.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, task.Fields{Name: &field.String{"CCC"}})
+		taskClient.Edit(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id, task.Fields{Name: &field.String{"AAA"}})
+		base.SR.Notifier.Process(0, time.Now().UTC())
+
+		notifications, err := client.Get(base.Cat.CSS, base.Region, 0, base.Org.Id, false, nil, 100)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(notifications))
+		assert.True(t, taskB.Id.Equal(notifications[0].Task))
+		assert.True(t, base.Ali.Info.Me.Id.Equal(notifications[0].Member))
+		assert.False(t, notifications[0].IsRead)
+		notifications, err = client.Get(base.Bob.CSS, base.Region, 0, base.Org.Id, false, nil, 100)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(notifications))
+		assert.True(t, taskA.Id.Equal(notifications[0].Task))
+		assert.True(t, base.Cat.Info.Me.Id.Equal(notifications[0].Member))
+
+		err = client.MarkRead(base.Cat.CSS, base.Region, 0, base.Org.Id, time.Now().UTC())
+		assert.Nil(t, err)
+		notifications, err = client.Get(base.Cat.CSS, base.Region, 0, base.Org.Id, true, nil, 100)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(notifications))
+
+		err = client.Unwatch(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskA.Id)
+		assert.Nil(t, err)
+		watches, err = client.GetWatches(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(watches))
+		time.Sleep(10 * time.Millisecond)
+		taskClient.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskB.Id, task.Fields{Name: &field.String{"B"}})
+		base.SR.Notifier.Process(0, time.Now().UTC())
+		notifications, err = client.Get(base.Cat.CSS, base.Region, 0, base.Org.Id, true, nil, 100)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(notifications))
+
+		projectClient.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
+	})
+}
@@ -53,6 +53,14 @@ func (c *testClient) MemberIsAccountOwner(region cnst.Region, shard int, account
 	return _memberIsAccountOwner(c.testServerBaseUrl, region, shard, account, me)
 }
 
+func (c *testClient) SetMemberNotificationSettings(region cnst.Region, shard int, account, me id.Id, email string, notificationDelivery cnst.NotificationDelivery) error {
+	return _setMemberNotificationSettings(c.testServerBaseUrl, region, shard, account, me, email, notificationDelivery)
+}
+
+func (c *testClient) VerifyTree(region cnst.Region, shard int, account, project *id.Id, repair bool) ([]*private.TreeDiscrepancy, error) {
+	return _verifyTree(c.testServerBaseUrl, region, shard, account, project, repair)
+}
+
 func NewClient(env cnst.Env, scheme, nakedHost string) private.V1Client {
 	return &client{
 		env:       env,
@@ -107,6 +115,14 @@ func (c *client) MemberIsAccountOwner(region cnst.Region, shard int, account, me
 	return _memberIsAccountOwner(c.getBaseUrl(region), region, shard, account, me)
 }
 
+func (c *client) SetMemberNotificationSettings(region cnst.Region, shard int, account, me id.Id, email string, notificationDelivery cnst.NotificationDelivery) error {
+	return _setMemberNotificationSettings(c.getBaseUrl(region), region, shard, account, me, email, notificationDelivery)
+}
+
+func (c *client) VerifyTree(region cnst.Region, shard int, account, project *id.Id, repair bool) ([]*private.TreeDiscrepancy, error) {
+	return _verifyTree(c.getBaseUrl(region), region, shard, account, project, repair)
+}
+
 func _createAccount(baseUrl string, region cnst.Region, account, me id.Id, myName string, myDisplayName *string, hasAvatar bool) (int, error) {
 	respVal := 0
 	val, e := createAccount.DoRequest(nil, baseUrl, region, &createAccountArgs{
@@ -206,3 +222,27 @@ func _memberIsAccountOwner(baseUrl string, region cnst.Region, shard int, accoun
 	}
 	return false, e
 }
+
+func _setMemberNotificationSettings(baseUrl string, region cnst.Region, shard int, account, me id.Id, email string, notificationDelivery cnst.NotificationDelivery) error {
+	_, e := setMemberNotificationSettings.DoRequest(nil, baseUrl, region, &setMemberNotificationSettingsArgs{
+		Shard:                shard,
+		Account:              account,
+		Me:                   me,
+		Email:                email,
+		NotificationDelivery: notificationDelivery,
+	}, nil, nil)
+	return e
+}
+
+func _verifyTree(baseUrl string, region cnst.Region, shard int, account, project *id.Id, repair bool) ([]*private.TreeDiscrepancy, error) {
+	val, e := verifyTree.DoRequest(nil, baseUrl, region, &verifyTreeArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Repair:  repair,
+	}, nil, &[]*private.TreeDiscrepancy{})
+	if val != nil {
+		return *val.(*[]*private.TreeDiscrepancy), e
+	}
+	return nil, e
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"github.com/0xor1/panic"
 	"github.com/0xor1/trees/server/util/cachekey"
+	"github.com/0xor1/trees/server/util/cnst"
 	"github.com/0xor1/trees/server/util/ctx"
 	"github.com/0xor1/trees/server/util/err"
 	"github.com/0xor1/trees/server/util/id"
@@ -55,11 +56,11 @@ func dbGetAllInactiveMembersFromInputSet(ctx ctx.Ctx, shard int, account id.Id,
 
 func dbAddMembers(ctx ctx.Ctx, shard int, account id.Id, members []*private.AddMember) {
 	queryArgs := make([]interface{}, 0, 3*len(members))
-	queryArgs = append(queryArgs, account, members[0].Id, members[0].Name, members[0].DisplayName, members[0].HasAvatar, members[0].Role)
-	query := bytes.NewBufferString(`INSERT INTO accountMembers (account, id, name, displayName, hasAvatar, role) VALUES (?,?,?,?,?,?)`)
+	queryArgs = append(queryArgs, account, members[0].Id, members[0].Name, members[0].DisplayName, members[0].HasAvatar, members[0].Role, members[0].Email, members[0].NotificationDelivery)
+	query := bytes.NewBufferString(`INSERT INTO accountMembers (account, id, name, displayName, hasAvatar, role, email, notificationDelivery) VALUES (?,?,?,?,?,?,?,?)`)
 	for _, mem := range members[1:] {
-		query.WriteString(`,(?,?,?,?,?,?)`)
-		queryArgs = append(queryArgs, account, mem.Id, mem.Name, mem.DisplayName, mem.HasAvatar, mem.Role)
+		query.WriteString(`,(?,?,?,?,?,?,?,?)`)
+		queryArgs = append(queryArgs, account, mem.Id, mem.Name, mem.DisplayName, mem.HasAvatar, mem.Role, mem.Email, mem.NotificationDelivery)
 	}
 	_, e := ctx.TreeExec(shard, query.String(), queryArgs...)
 	panic.IfNotNil(e)
@@ -70,7 +71,7 @@ func dbUpdateMembersAndSetActive(ctx ctx.Ctx, shard int, account id.Id, members
 	memberIds := make([]id.Id, 0, len(members))
 	for _, mem := range members {
 		memberIds = append(memberIds, mem.Id)
-		_, e := ctx.TreeExec(shard, `CALL updateMembersAndSetActive(?, ?, ?, ?, ?, ?)`, account, mem.Id, mem.Name, mem.DisplayName, mem.HasAvatar, mem.Role)
+		_, e := ctx.TreeExec(shard, `CALL updateMembersAndSetActive(?, ?, ?, ?, ?, ?, ?, ?)`, account, mem.Id, mem.Name, mem.DisplayName, mem.HasAvatar, mem.Role, mem.Email, mem.NotificationDelivery)
 		panic.IfNotNil(e)
 	}
 	ctx.TouchDlms(cachekey.NewSetDlms().AccountMembers(account, memberIds))
@@ -142,6 +143,12 @@ func dbSetMemberHasAvatar(ctx ctx.Ctx, shard int, account, member id.Id, hasAvat
 	ctx.TouchDlms(cachekey.NewSetDlms().AccountMember(account, member))
 }
 
+func dbSetMemberNotificationSettings(ctx ctx.Ctx, shard int, account, member id.Id, email string, notificationDelivery cnst.NotificationDelivery) {
+	//notification settings aren't part of any cached member value so there are no dlms to touch
+	_, e := ctx.TreeExec(shard, `CALL setMemberNotificationSettings(?, ?, ?, ?)`, account, member, email, notificationDelivery)
+	panic.IfNotNil(e)
+}
+
 func dbLogAccountBatchAddOrRemoveMembersActivity(ctx ctx.Ctx, shard int, account, member id.Id, members []id.Id, action string) {
 	query := bytes.NewBufferString(`INSERT INTO accountActivities (account, occurredOn, member, item, itemType, action, itemName, extraInfo) VALUES (?,?,?,?,?,?,?,?)`)
 	args := make([]interface{}, 0, len(members)*8)
@@ -155,3 +162,52 @@ func dbLogAccountBatchAddOrRemoveMembersActivity(ctx ctx.Ctx, shard int, account
 	panic.IfNotNil(e)
 	ctx.TouchDlms(cachekey.NewSetDlms().AccountActivities(account))
 }
+
+type accountProject struct {
+	account id.Id
+	project id.Id
+}
+
+func dbGetProjectsToVerify(ctx ctx.Ctx, shard int, account, project *id.Id) []*accountProject {
+	query := bytes.NewBufferString(`SELECT account, id FROM projects`)
+	args := make([]interface{}, 0, 2)
+	if account != nil {
+		query.WriteString(` WHERE account=?`)
+		args = append(args, *account)
+		if project != nil {
+			query.WriteString(` AND id=?`)
+			args = append(args, *project)
+		}
+	}
+	query.WriteString(` ORDER BY account ASC, id ASC`)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	res := make([]*accountProject, 0, 100)
+	for rows.Next() {
+		ap := accountProject{}
+		panic.IfNotNil(rows.Scan(&ap.account, &ap.project))
+		res = append(res, &ap)
+	}
+	return res
+}
+
+func dbVerifyProjectTree(ctx ctx.Ctx, shard int, account, project id.Id, repair bool) []*private.TreeDiscrepancy {
+	rows, e := ctx.TreeQuery(shard, `CALL verifyProjectTree(?, ?, ?)`, account, project, repair)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	res := make([]*private.TreeDiscrepancy, 0, 10)
+	for rows.Next() {
+		d := private.TreeDiscrepancy{Account: account, Project: project}
+		panic.IfNotNil(rows.Scan(&d.Task, &d.Field, &d.Stored, &d.Computed))
+		res = append(res, &d)
+	}
+	if repair && len(res) > 0 {
+		ctx.TouchDlms(cachekey.NewSetDlms().ProjectMaster(account, project))
+	}
+	return res
+}
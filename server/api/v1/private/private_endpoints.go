@@ -5,6 +5,7 @@ import (
 	"github.com/0xor1/trees/server/util/ctx"
 	"github.com/0xor1/trees/server/util/db"
 	"github.com/0xor1/trees/server/util/endpoint"
+	"github.com/0xor1/trees/server/util/filestore"
 	"github.com/0xor1/trees/server/util/id"
 	"github.com/0xor1/trees/server/util/private"
 	"github.com/0xor1/trees/server/util/validate"
@@ -48,7 +49,7 @@ var deleteAccount = &endpoint.Endpoint{
 			validate.MemberHasAccountOwnerAccess(db.GetAccountRole(ctx, args.Shard, args.Account, args.Me))
 		}
 		dbDeleteAccount(ctx, args.Shard, args.Account)
-		//TODO delete s3 data, uploaded files etc
+		ctx.FileClient().DeletePrefix(filestore.AccountPrefix(args.Account))
 		return nil
 	},
 }
@@ -256,6 +257,53 @@ var memberIsAccountOwner = &endpoint.Endpoint{
 	},
 }
 
+type setMemberNotificationSettingsArgs struct {
+	Shard                int                       `json:"shard"`
+	Account              id.Id                     `json:"account"`
+	Me                   id.Id                     `json:"me"`
+	Email                string                    `json:"email"`
+	NotificationDelivery cnst.NotificationDelivery `json:"notificationDelivery"`
+}
+
+var setMemberNotificationSettings = &endpoint.Endpoint{
+	Path:      "/api/v1/private/setMemberNotificationSettings",
+	IsPrivate: true,
+	GetArgsStruct: func() interface{} {
+		return &setMemberNotificationSettingsArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*setMemberNotificationSettingsArgs)
+		args.NotificationDelivery.Validate()
+		dbSetMemberNotificationSettings(ctx, args.Shard, args.Account, args.Me, args.Email, args.NotificationDelivery)
+		return nil
+	},
+}
+
+type verifyTreeArgs struct {
+	Shard   int    `json:"shard"`
+	Account *id.Id `json:"account,omitempty"`
+	Project *id.Id `json:"project,omitempty"`
+	Repair  bool   `json:"repair"`
+}
+
+var verifyTree = &endpoint.Endpoint{
+	Path:      "/api/v1/private/verifyTree",
+	Note:      "recomputes the task aggregates of a project, every project in an account or every project on the shard and returns any that don't match the stored values, pass repair to save the recomputed values",
+	IsPrivate: true,
+	GetArgsStruct: func() interface{} {
+		return &verifyTreeArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*verifyTreeArgs)
+		ctx.ReturnBadRequestNowIf(args.Project != nil && args.Account == nil, "project requires account")
+		res := make([]*private.TreeDiscrepancy, 0, 20)
+		for _, ap := range dbGetProjectsToVerify(ctx, args.Shard, args.Account, args.Project) {
+			res = append(res, dbVerifyProjectTree(ctx, args.Shard, ap.account, ap.project, args.Repair)...)
+		}
+		return res
+	},
+}
+
 var Endpoints = []*endpoint.Endpoint{
 	createAccount,
 	deleteAccount,
@@ -266,4 +314,6 @@ var Endpoints = []*endpoint.Endpoint{
 	setMemberDisplayName,
 	setMemberHasAvatar,
 	memberIsAccountOwner,
+	setMemberNotificationSettings,
+	verifyTree,
 }
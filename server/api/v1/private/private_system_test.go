@@ -42,6 +42,11 @@ func Test_system(t *testing.T) {
 	val, err = client.MemberIsAccountOwner(region, 0, orgId, aliId)
 	assert.Nil(t, err)
 	assert.True(t, val)
+	discrepancies, err := client.VerifyTree(region, 0, &orgId, nil, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(discrepancies))
+	_, err = client.VerifyTree(region, 0, nil, &orgId, false)
+	assert.NotNil(t, err)
 	client.RemoveMembers(region, 0, orgId, aliId, []id.Id{bob.Id})
 	client.DeleteAccount(region, 0, orgId, aliId)
 	client.DeleteAccount(region, 0, aliId, aliId)
@@ -4,6 +4,7 @@ import (
 	"github.com/0xor1/trees/server/util/activity"
 	"github.com/0xor1/trees/server/util/clientsession"
 	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/customfield"
 	"github.com/0xor1/trees/server/util/id"
 	"time"
 )
@@ -23,6 +24,8 @@ type Client interface {
 	AddMembers(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, members []*AddProjectMember) error
 	//must be account owner/admin or project admin
 	SetMemberRole(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, member id.Id, role cnst.ProjectRole) error
+	//must be account owner/admin or project admin, nil hourlyRate falls back to the members account hourly rate
+	SetMemberHourlyRate(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, member id.Id, hourlyRate *uint64) error
 	//must be account owner/admin or project admin
 	RemoveMembers(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, members []id.Id) error
 	//pointers are optional filters, anyone who can see a project can see all the member info for that project
@@ -33,6 +36,34 @@ type Client interface {
 	GetMe(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) (*Member, error)
 	//either one or both of OccurredAfter/Before must be nil
 	GetActivities(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, item, member *id.Id, occurredAfter, occurredBefore *time.Time, limit int) ([]*activity.Activity, error)
+	//must be account owner/admin or project admin
+	CreateState(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, name string, isDone bool) (*State, error)
+	//must be account owner/admin or project admin
+	EditState(css *clientsession.Store, region cnst.Region, shard int, account, project, state id.Id, name *string, isDone *bool) error
+	//must be account owner/admin or project admin
+	MoveState(css *clientsession.Store, region cnst.Region, shard int, account, project, state id.Id, newPosition int) error
+	//must be account owner/admin or project admin
+	DeleteState(css *clientsession.Store, region cnst.Region, shard int, account, project, state, replacement id.Id) error
+	//check project access permission per user
+	GetStates(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) ([]*State, error)
+	//must be account owner/admin or project admin
+	CreateCustomField(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, name string, fieldType cnst.CustomFieldType, options []string) (*customfield.Field, error)
+	//must be account owner/admin or project admin
+	EditCustomField(css *clientsession.Store, region cnst.Region, shard int, account, project, customField id.Id, name *string, options []string) error
+	//must be account owner/admin or project admin
+	DeleteCustomField(css *clientsession.Store, region cnst.Region, shard int, account, project, customField id.Id) error
+	//check project access permission per user
+	GetCustomFields(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) ([]*customfield.Field, error)
+	//must be account owner/admin
+	SaveAsTemplate(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, name string, copyEstimates bool) (*Project, error)
+	//must be account owner/admin
+	CreateFromTemplate(css *clientsession.Store, region cnst.Region, shard int, account, template id.Id, name string, description *string, startOn, dueOn *time.Time, isPublic bool, members []*AddProjectMember) (*Project, error)
+	//must be account owner/admin
+	GetTemplates(css *clientsession.Store, region cnst.Region, shard int, account id.Id, nameContains *string, after *id.Id, limit int) (*GetSetResult, error)
+	//must be account owner/admin
+	Export(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) (*Export, error)
+	//must be account owner/admin
+	Import(css *clientsession.Store, region cnst.Region, shard int, account id.Id, export *Export) (*Project, error)
 }
 
 func NewClient(host string) Client {
@@ -140,6 +171,17 @@ func (c *client) SetMemberRole(css *clientsession.Store, region cnst.Region, sha
 	return e
 }
 
+func (c *client) SetMemberHourlyRate(css *clientsession.Store, region cnst.Region, shard int, account, project, member id.Id, hourlyRate *uint64) error {
+	_, e := setMemberHourlyRate.DoRequest(css, c.host, region, &setMemberHourlyRateArgs{
+		Shard:      shard,
+		Account:    account,
+		Project:    project,
+		Member:     member,
+		HourlyRate: hourlyRate,
+	}, nil, nil)
+	return e
+}
+
 func (c *client) RemoveMembers(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, members []id.Id) error {
 	_, e := removeMembers.DoRequest(css, c.host, region, &removeMembersArgs{
 		Shard:   shard,
@@ -167,7 +209,7 @@ func (c *client) GetMembers(css *clientsession.Store, region cnst.Region, shard
 }
 
 func (c *client) GetAtMentions(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, nameOrDisplayNamePrefix string) ([]*Member, error) {
-	val, e := getMembers.DoRequest(css, c.host, region, &getAtMentionsArgs{
+	val, e := getAtMentions.DoRequest(css, c.host, region, &getAtMentionsArgs{
 		Shard:                   shard,
 		Account:                 account,
 		Project:                 project,
@@ -207,3 +249,182 @@ func (c *client) GetActivities(css *clientsession.Store, region cnst.Region, sha
 	}
 	return nil, e
 }
+
+func (c *client) CreateState(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, name string, isDone bool) (*State, error) {
+	val, e := createState.DoRequest(css, c.host, region, &createStateArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Name:    name,
+		IsDone:  isDone,
+	}, nil, &State{})
+	if val != nil {
+		return val.(*State), e
+	}
+	return nil, e
+}
+
+func (c *client) EditState(css *clientsession.Store, region cnst.Region, shard int, account, project, state id.Id, name *string, isDone *bool) error {
+	_, e := editState.DoRequest(css, c.host, region, &editStateArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		State:   state,
+		Name:    name,
+		IsDone:  isDone,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) MoveState(css *clientsession.Store, region cnst.Region, shard int, account, project, state id.Id, newPosition int) error {
+	_, e := moveState.DoRequest(css, c.host, region, &moveStateArgs{
+		Shard:       shard,
+		Account:     account,
+		Project:     project,
+		State:       state,
+		NewPosition: newPosition,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) DeleteState(css *clientsession.Store, region cnst.Region, shard int, account, project, state, replacement id.Id) error {
+	_, e := deleteState.DoRequest(css, c.host, region, &deleteStateArgs{
+		Shard:       shard,
+		Account:     account,
+		Project:     project,
+		State:       state,
+		Replacement: replacement,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) GetStates(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) ([]*State, error) {
+	val, e := getStates.DoRequest(css, c.host, region, &getStatesArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+	}, nil, &[]*State{})
+	if val != nil {
+		return *val.(*[]*State), e
+	}
+	return nil, e
+}
+
+func (c *client) CreateCustomField(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, name string, fieldType cnst.CustomFieldType, options []string) (*customfield.Field, error) {
+	val, e := createCustomField.DoRequest(css, c.host, region, &createCustomFieldArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Name:    name,
+		Type:    fieldType,
+		Options: options,
+	}, nil, &customfield.Field{})
+	if val != nil {
+		return val.(*customfield.Field), e
+	}
+	return nil, e
+}
+
+func (c *client) EditCustomField(css *clientsession.Store, region cnst.Region, shard int, account, project, customField id.Id, name *string, options []string) error {
+	_, e := editCustomField.DoRequest(css, c.host, region, &editCustomFieldArgs{
+		Shard:       shard,
+		Account:     account,
+		Project:     project,
+		CustomField: customField,
+		Name:        name,
+		Options:     options,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) DeleteCustomField(css *clientsession.Store, region cnst.Region, shard int, account, project, customField id.Id) error {
+	_, e := deleteCustomField.DoRequest(css, c.host, region, &deleteCustomFieldArgs{
+		Shard:       shard,
+		Account:     account,
+		Project:     project,
+		CustomField: customField,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) GetCustomFields(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) ([]*customfield.Field, error) {
+	val, e := getCustomFields.DoRequest(css, c.host, region, &getCustomFieldsArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+	}, nil, &[]*customfield.Field{})
+	if val != nil {
+		return *val.(*[]*customfield.Field), e
+	}
+	return nil, e
+}
+
+func (c *client) SaveAsTemplate(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, name string, copyEstimates bool) (*Project, error) {
+	val, e := saveAsTemplate.DoRequest(css, c.host, region, &saveAsTemplateArgs{
+		Shard:         shard,
+		Account:       account,
+		Project:       project,
+		Name:          name,
+		CopyEstimates: copyEstimates,
+	}, nil, &Project{})
+	if val != nil {
+		return val.(*Project), e
+	}
+	return nil, e
+}
+
+func (c *client) CreateFromTemplate(css *clientsession.Store, region cnst.Region, shard int, account, template id.Id, name string, description *string, startOn, dueOn *time.Time, isPublic bool, members []*AddProjectMember) (*Project, error) {
+	val, e := createFromTemplate.DoRequest(css, c.host, region, &createFromTemplateArgs{
+		Shard:       shard,
+		Account:     account,
+		Template:    template,
+		Name:        name,
+		Description: description,
+		StartOn:     startOn,
+		DueOn:       dueOn,
+		IsPublic:    isPublic,
+		Members:     members,
+	}, nil, &Project{})
+	if val != nil {
+		return val.(*Project), e
+	}
+	return nil, e
+}
+
+func (c *client) GetTemplates(css *clientsession.Store, region cnst.Region, shard int, account id.Id, nameContains *string, after *id.Id, limit int) (*GetSetResult, error) {
+	val, e := getTemplates.DoRequest(css, c.host, region, &getTemplatesArgs{
+		Shard:        shard,
+		Account:      account,
+		NameContains: nameContains,
+		After:        after,
+		Limit:        limit,
+	}, nil, &GetSetResult{})
+	if val != nil {
+		return val.(*GetSetResult), e
+	}
+	return nil, e
+}
+
+func (c *client) Export(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) (*Export, error) {
+	val, e := export.DoRequest(css, c.host, region, &exportArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+	}, nil, &Export{})
+	if val != nil {
+		return val.(*Export), e
+	}
+	return nil, e
+}
+
+func (c *client) Import(css *clientsession.Store, region cnst.Region, shard int, account id.Id, export *Export) (*Project, error) {
+	val, e := importProject.DoRequest(css, c.host, region, &importArgs{
+		Shard:   shard,
+		Account: account,
+		Export:  export,
+	}, nil, &Project{})
+	if val != nil {
+		return val.(*Project), e
+	}
+	return nil, e
+}
@@ -8,11 +8,14 @@ import (
 	"github.com/0xor1/trees/server/util/cachekey"
 	"github.com/0xor1/trees/server/util/cnst"
 	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/customfield"
 	"github.com/0xor1/trees/server/util/db"
 	"github.com/0xor1/trees/server/util/err"
 	"github.com/0xor1/trees/server/util/field"
 	"github.com/0xor1/trees/server/util/id"
 	"github.com/0xor1/trees/server/util/sortdir"
+	t "github.com/0xor1/trees/server/util/time"
+	"github.com/0xor1/trees/server/util/timelog"
 	"github.com/0xor1/trees/server/util/validate"
 	"strings"
 	"time"
@@ -31,7 +34,8 @@ func dbGetProjectExists(ctx ctx.Ctx, shard int, account, project id.Id) bool {
 }
 
 func dbCreateProject(ctx ctx.Ctx, shard int, account id.Id, project *Project) {
-	_, e := ctx.TreeExec(shard, `CALL createProject(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, account, project.Id, ctx.Me(), project.Name, project.Description, project.HoursPerDay, project.DaysPerWeek, project.CreatedOn, project.StartOn, project.DueOn, project.IsParallel, project.IsPublic)
+	//the default todo, doing, review and done states are created along with the project
+	_, e := ctx.TreeExec(shard, `CALL createProject(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, account, project.Id, ctx.Me(), project.Name, project.Description, project.HoursPerDay, project.DaysPerWeek, project.CreatedOn, project.StartOn, project.DueOn, project.IsParallel, project.IsPublic, project.IsTemplate, id.New(), id.New(), id.New(), id.New())
 	panic.IfNotNil(e)
 	ctx.TouchDlms(cachekey.NewSetDlms().AccountActivities(account).AccountProjectsSet(account))
 }
@@ -61,10 +65,18 @@ func dbEdit(ctx ctx.Ctx, shard int, account, project id.Id, fields Fields) {
 	if !setDueOn {
 		fields.DueOn = &field.TimePtr{}
 	}
-	if !setIsPublic && !setIsArchived && !setHoursPerDay && !setDaysPerWeek && !setStartOn && !setDueOn {
+	setTimeLogMaxAgeDays := fields.TimeLogMaxAgeDays != nil
+	if !setTimeLogMaxAgeDays {
+		fields.TimeLogMaxAgeDays = &field.UInt16Ptr{}
+	}
+	setBudget := fields.Budget != nil
+	if !setBudget {
+		fields.Budget = &field.UInt64Ptr{}
+	}
+	if !setIsPublic && !setIsArchived && !setHoursPerDay && !setDaysPerWeek && !setStartOn && !setDueOn && !setTimeLogMaxAgeDays && !setBudget {
 		return
 	}
-	_, e := ctx.TreeExec(shard, `CALL editProject(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, account, project, ctx.Me(), setIsPublic, fields.IsPublic.Val, setIsArchived, fields.IsArchived.Val, setHoursPerDay, fields.HoursPerDay.Val, setDaysPerWeek, fields.DaysPerWeek.Val, setStartOn, fields.StartOn.Val, setDueOn, fields.DueOn.Val)
+	_, e := ctx.TreeExec(shard, `CALL editProject(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, account, project, ctx.Me(), setIsPublic, fields.IsPublic.Val, setIsArchived, fields.IsArchived.Val, setHoursPerDay, fields.HoursPerDay.Val, setDaysPerWeek, fields.DaysPerWeek.Val, setStartOn, fields.StartOn.Val, setDueOn, fields.DueOn.Val, setTimeLogMaxAgeDays, fields.TimeLogMaxAgeDays.Val, setBudget, fields.Budget.Val)
 	panic.IfNotNil(e)
 	ctx.TouchDlms(cachekey.NewSetDlms().AccountActivities(account).Project(account, project).ProjectActivities(account, project))
 }
@@ -75,24 +87,29 @@ func dbGetProject(ctx ctx.Ctx, shard int, account, proj id.Id) *Project {
 	if ctx.GetCacheValue(&res, cacheKey) {
 		return &res
 	}
-	row := ctx.TreeQueryRow(shard, `SELECT p.id, p.isArchived, p.name, p.hoursPerDay, p.daysPerWeek, p.createdOn, p.startOn, p.dueOn, p.fileCount, p.fileSize, p.isPublic, t.description, t.totalRemainingTime, t.totalLoggedTime, t.minimumRemainingTime, t.linkedFileCount, t.chatCount, t.childCount, t.descendantCount, t.isParallel FROM projects p, tasks t WHERE p.account=? AND p.id=? AND t.account=? AND t.project=? AND t.id=?`, account, proj, account, proj, proj)
-	if err.IsSqlErrNoRowsElsePanicIf(row.Scan(&res.Id, &res.IsArchived, &res.Name, &res.HoursPerDay, &res.DaysPerWeek, &res.CreatedOn, &res.StartOn, &res.DueOn, &res.FileCount, &res.FileSize, &res.IsPublic, &res.Description, &res.TotalRemainingTime, &res.TotalLoggedTime, &res.MinimumRemainingTime, &res.LinkedFileCount, &res.ChatCount, &res.ChildCount, &res.DescendantCount, &res.IsParallel)) {
+	row := ctx.TreeQueryRow(shard, `SELECT p.id, p.isArchived, p.name, p.hoursPerDay, p.daysPerWeek, p.createdOn, p.startOn, p.dueOn, p.fileCount, p.fileSize, p.isPublic, p.isTemplate, p.timeLogMaxAgeDays, p.budget, t.description, t.totalRemainingTime, t.totalLoggedTime, t.totalCost, t.totalRevenue, t.minimumRemainingTime, t.linkedFileCount, t.chatCount, t.childCount, t.descendantCount, t.isParallel FROM projects p, tasks t WHERE p.account=? AND p.id=? AND t.account=? AND t.project=? AND t.id=?`, account, proj, account, proj, proj)
+	if err.IsSqlErrNoRowsElsePanicIf(row.Scan(&res.Id, &res.IsArchived, &res.Name, &res.HoursPerDay, &res.DaysPerWeek, &res.CreatedOn, &res.StartOn, &res.DueOn, &res.FileCount, &res.FileSize, &res.IsPublic, &res.IsTemplate, &res.TimeLogMaxAgeDays, &res.Budget, &res.Description, &res.TotalRemainingTime, &res.TotalLoggedTime, &res.TotalCost, &res.TotalRevenue, &res.MinimumRemainingTime, &res.LinkedFileCount, &res.ChatCount, &res.ChildCount, &res.DescendantCount, &res.IsParallel)) {
 		return nil
 	}
+	res.IsOverBudget = res.Budget != nil && res.TotalCost > *res.Budget
 	ctx.SetCacheValue(res, cacheKey)
 	return &res
 }
 
 func dbGetPublicProjects(ctx ctx.Ctx, shard int, account id.Id, nameContains *string, createdOnAfter, createdOnBefore, startOnAfter, startOnBefore, dueOnAfter, dueOnBefore *time.Time, isArchived bool, sortBy cnst.SortBy, sortAsc bool, after *id.Id, limit int) *GetSetResult {
-	return dbGetProjects(ctx, shard, `AND isPublic=true`, account, nil, nameContains, createdOnAfter, createdOnBefore, startOnAfter, startOnBefore, dueOnAfter, dueOnBefore, isArchived, sortBy, sortAsc, after, limit)
+	return dbGetProjects(ctx, shard, `AND isTemplate=false AND isPublic=true`, account, nil, nameContains, createdOnAfter, createdOnBefore, startOnAfter, startOnBefore, dueOnAfter, dueOnBefore, isArchived, sortBy, sortAsc, after, limit)
 }
 
 func dbGetPublicAndSpecificAccessProjects(ctx ctx.Ctx, shard int, account, me id.Id, nameContains *string, createdOnAfter, createdOnBefore, startOnAfter, startOnBefore, dueOnAfter, dueOnBefore *time.Time, isArchived bool, sortBy cnst.SortBy, sortAsc bool, after *id.Id, limit int) *GetSetResult {
-	return dbGetProjects(ctx, shard, `AND (isPublic=true OR id IN (SELECT project FROM projectMembers WHERE account=? AND isActive=true AND id=?))`, account, &me, nameContains, createdOnAfter, createdOnBefore, startOnAfter, startOnBefore, dueOnAfter, dueOnBefore, isArchived, sortBy, sortAsc, after, limit)
+	return dbGetProjects(ctx, shard, `AND isTemplate=false AND (isPublic=true OR id IN (SELECT project FROM projectMembers WHERE account=? AND isActive=true AND id=?))`, account, &me, nameContains, createdOnAfter, createdOnBefore, startOnAfter, startOnBefore, dueOnAfter, dueOnBefore, isArchived, sortBy, sortAsc, after, limit)
 }
 
 func dbGetAllProjects(ctx ctx.Ctx, shard int, account id.Id, nameContains *string, createdOnAfter, createdOnBefore, startOnAfter, startOnBefore, dueOnAfter, dueOnBefore *time.Time, isArchived bool, sortBy cnst.SortBy, sortAsc bool, after *id.Id, limit int) *GetSetResult {
-	return dbGetProjects(ctx, shard, ``, account, nil, nameContains, createdOnAfter, createdOnBefore, startOnAfter, startOnBefore, dueOnAfter, dueOnBefore, isArchived, sortBy, sortAsc, after, limit)
+	return dbGetProjects(ctx, shard, `AND isTemplate=false`, account, nil, nameContains, createdOnAfter, createdOnBefore, startOnAfter, startOnBefore, dueOnAfter, dueOnBefore, isArchived, sortBy, sortAsc, after, limit)
+}
+
+func dbGetTemplates(ctx ctx.Ctx, shard int, account id.Id, nameContains *string, after *id.Id, limit int) *GetSetResult {
+	return dbGetProjects(ctx, shard, `AND isTemplate=true`, account, nil, nameContains, nil, nil, nil, nil, nil, nil, false, cnst.SortByName, true, after, limit)
 }
 
 func dbDeleteProject(ctx ctx.Ctx, shard int, account, project id.Id) {
@@ -111,6 +128,11 @@ func dbSetMemberRole(ctx ctx.Ctx, shard int, account, project, member id.Id, rol
 	ctx.TouchDlms(cachekey.NewSetDlms().ProjectMember(account, project, member).ProjectActivities(account, project))
 }
 
+func dbSetMemberHourlyRate(ctx ctx.Ctx, shard int, account, project, member id.Id, hourlyRate *uint64) {
+	db.MakeChangeHelper(ctx, shard, `CALL setProjectMemberHourlyRate(?, ?, ?, ?, ?)`, account, project, ctx.Me(), member, hourlyRate)
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectMember(account, project, member).ProjectActivities(account, project))
+}
+
 func dbSetMemberInactive(ctx ctx.Ctx, shard int, account, project id.Id, member id.Id) {
 	db.MakeChangeHelper(ctx, shard, `CALL setProjectMemberInactive(?, ?, ?, ?)`, account, project, ctx.Me(), member)
 	ctx.TouchDlms(cachekey.NewSetDlms().ProjectMember(account, project, member).ProjectActivities(account, project))
@@ -125,7 +147,7 @@ func dbGetMembers(ctx ctx.Ctx, shard int, account, project id.Id, role *cnst.Pro
 	if ctx.GetCacheValue(&res, cacheKey) {
 		return &res
 	}
-	query := bytes.NewBufferString(`SELECT p1.id, p1.isActive, p1.totalRemainingTime, p1.totalLoggedTime, p1.role FROM projectMembers p1`)
+	query := bytes.NewBufferString(`SELECT p1.id, p1.isActive, p1.totalRemainingTime, p1.totalLoggedTime, p1.role, p1.hourlyRate FROM projectMembers p1`)
 	args := make([]interface{}, 0, 9)
 	if after != nil {
 		query.WriteString(`, projectMembers p2`)
@@ -165,7 +187,7 @@ func dbGetMembers(ctx ctx.Ctx, shard int, account, project id.Id, role *cnst.Pro
 	memSet := make([]*Member, 0, limit+1)
 	for rows.Next() {
 		mem := Member{}
-		panic.IfNotNil(rows.Scan(&mem.Id, &mem.IsActive, &mem.TotalRemainingTime, &mem.TotalLoggedTime, &mem.Role))
+		panic.IfNotNil(rows.Scan(&mem.Id, &mem.IsActive, &mem.TotalRemainingTime, &mem.TotalLoggedTime, &mem.Role, &mem.HourlyRate))
 		memSet = append(memSet, &mem)
 	}
 	if len(memSet) == limit+1 {
@@ -185,12 +207,371 @@ func dbGetMember(ctx ctx.Ctx, shard int, account, project, mem id.Id) *Member {
 	if ctx.GetCacheValue(&res, cacheKey) {
 		return &res
 	}
-	row := ctx.TreeQueryRow(shard, `SELECT id, isActive, role FROM projectMembers WHERE account=? AND project=? AND id=?`, account, project, mem)
-	panic.IfNotNil(row.Scan(&res.Id, &res.IsActive, &res.Role))
+	row := ctx.TreeQueryRow(shard, `SELECT id, isActive, role, hourlyRate FROM projectMembers WHERE account=? AND project=? AND id=?`, account, project, mem)
+	panic.IfNotNil(row.Scan(&res.Id, &res.IsActive, &res.Role, &res.HourlyRate))
 	ctx.SetCacheValue(&res, cacheKey)
 	return &res
 }
 
+func dbCreateState(ctx ctx.Ctx, shard int, account, project id.Id, state *State) {
+	db.MakeChangeHelper(ctx, shard, `CALL createProjectState(?, ?, ?, ?, ?, ?)`, account, project, state.Id, ctx.Me(), state.Name, state.IsDone)
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectStateSet(account, project).ProjectActivities(account, project))
+}
+
+func dbEditState(ctx ctx.Ctx, shard int, account, project, state id.Id, name *string, setIsDone bool) {
+	db.MakeChangeHelper(ctx, shard, `CALL editProjectState(?, ?, ?, ?, ?, ?)`, account, project, state, ctx.Me(), name, setIsDone)
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectStateSet(account, project).ProjectActivities(account, project))
+}
+
+func dbMoveState(ctx ctx.Ctx, shard int, account, project, state id.Id, newPosition int) {
+	db.MakeChangeHelper(ctx, shard, `CALL moveProjectState(?, ?, ?, ?, ?)`, account, project, state, ctx.Me(), newPosition)
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectStateSet(account, project).ProjectActivities(account, project))
+}
+
+func dbDeleteState(ctx ctx.Ctx, shard int, account, project, state, replacement id.Id) {
+	db.MakeChangeHelper(ctx, shard, `CALL deleteProjectState(?, ?, ?, ?, ?)`, account, project, state, ctx.Me(), replacement)
+	//any task in the project may have had its state or state counts changed
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectMaster(account, project))
+}
+
+func dbCopyStates(ctx ctx.Ctx, shard int, account, sourceProject, project id.Id) {
+	newIds := make([]id.Id, 0, maxStateCount)
+	for range dbGetStates(ctx, shard, account, sourceProject) {
+		newIds = append(newIds, id.New())
+	}
+	db.MakeChangeHelper(ctx, shard, `CALL copyProjectStates(?, ?, ?, ?)`, account, sourceProject, project, id.ToHexString(newIds))
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectStateSet(account, project))
+}
+
+func dbGetStates(ctx ctx.Ctx, shard int, account, project id.Id) []*State {
+	res := make([]*State, 0, maxStateCount)
+	cacheKey := cachekey.NewGet("project.dbGetStates", shard, account, project).ProjectStateSet(account, project)
+	if ctx.GetCacheValue(&res, cacheKey) {
+		return res
+	}
+	rows, e := ctx.TreeQuery(shard, `SELECT id, name, isDone FROM projectStates WHERE account=? AND project=? ORDER BY position ASC`, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		s := State{}
+		panic.IfNotNil(rows.Scan(&s.Id, &s.Name, &s.IsDone))
+		res = append(res, &s)
+	}
+	ctx.SetCacheValue(res, cacheKey)
+	return res
+}
+
+func dbCreateCustomField(ctx ctx.Ctx, shard int, account, project id.Id, f *customfield.Field) {
+	db.MakeChangeHelper(ctx, shard, `CALL createCustomField(?, ?, ?, ?, ?, ?, ?, ?)`, account, project, f.Id, ctx.Me(), t.Now(), f.Name, f.Type, f.OptionsStr())
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectCustomFieldSet(account, project).ProjectActivities(account, project))
+}
+
+func dbEditCustomField(ctx ctx.Ctx, shard int, account, project id.Id, f *customfield.Field) {
+	db.MakeChangeHelper(ctx, shard, `CALL editCustomField(?, ?, ?, ?, ?, ?)`, account, project, f.Id, ctx.Me(), f.Name, f.OptionsStr())
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectCustomFieldSet(account, project).ProjectActivities(account, project))
+}
+
+func dbDeleteCustomField(ctx ctx.Ctx, shard int, account, project, customField id.Id) {
+	rows, e := ctx.TreeQuery(shard, `CALL deleteCustomField(?, ?, ?, ?)`, account, project, customField, ctx.Me())
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	cacheKey := cachekey.NewSetDlms().ProjectCustomFieldSet(account, project).ProjectActivities(account, project)
+	for rows.Next() {
+		var task id.Id
+		var parent *id.Id
+		panic.IfNotNil(rows.Scan(&task, &parent))
+		cacheKey.Task(account, project, task)
+		if parent != nil {
+			cacheKey.TaskChildrenSet(account, project, *parent)
+		}
+	}
+	ctx.TouchDlms(cacheKey)
+}
+
+// dbCustomFieldOptionsInUse returns the options of a select field which are held by at least one task.
+func dbCustomFieldOptionsInUse(ctx ctx.Ctx, shard int, account, project, customField id.Id) []string {
+	rows, e := ctx.TreeQuery(shard, `SELECT DISTINCT textValue FROM taskCustomFieldValues WHERE account=? AND project=? AND customField=?`, account, project, customField)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	res := make([]string, 0, customfield.MaxOptions)
+	for rows.Next() {
+		var option string
+		panic.IfNotNil(rows.Scan(&option))
+		res = append(res, option)
+	}
+	return res
+}
+
+func dbGetExport(ctx ctx.Ctx, shard int, account, project id.Id) *Export {
+	proj := dbGetProject(ctx, shard, account, project)
+	ctx.ReturnBadRequestNowIf(proj == nil, "no such project")
+	res := &Export{
+		Version:      exportVersion,
+		ExportedOn:   t.Now(),
+		Project:      proj,
+		States:       dbGetStates(ctx, shard, account, project),
+		Members:      make([]*Member, 0, 100),
+		CustomFields: db.GetProjectCustomFields(ctx, shard, account, project),
+		Labels:       make([]*ExportLabel, 0, 20),
+		TimeLogs:     make([]*timelog.TimeLog, 0, 100),
+		Activities:   make([]*activity.Activity, 0, 100),
+	}
+
+	rows, e := ctx.TreeQuery(shard, `SELECT id, isActive, totalRemainingTime, totalLoggedTime, role FROM projectMembers WHERE account=? AND project=?`, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		mem := Member{}
+		panic.IfNotNil(rows.Scan(&mem.Id, &mem.IsActive, &mem.TotalRemainingTime, &mem.TotalLoggedTime, &mem.Role))
+		res.Members = append(res.Members, &mem)
+	}
+
+	type exportRow struct {
+		task        *ExportTask
+		firstChild  *id.Id
+		nextSibling *id.Id
+	}
+	taskRows := map[string]*exportRow{}
+	rows, e = ctx.TreeQuery(shard, `SELECT id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, isParallel, state FROM tasks WHERE account=? AND project=?`, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		row := &exportRow{task: &ExportTask{}}
+		var parent *id.Id
+		panic.IfNotNil(rows.Scan(&row.task.Id, &parent, &row.firstChild, &row.nextSibling, &row.task.IsAbstract, &row.task.Name, &row.task.Description, &row.task.CreatedOn, &row.task.RemainingTime, &row.task.IsParallel, &row.task.State))
+		if parent != nil {
+			row.task.Parent = *parent
+		}
+		if row.task.IsAbstract {
+			row.task.RemainingTime = 0
+		}
+		taskRows[row.task.Id.String()] = row
+	}
+	rows, e = ctx.TreeQuery(shard, `SELECT task, member, remainingTime FROM taskMembers WHERE account=? AND project=? ORDER BY task, member`, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var task id.Id
+		tm := ExportTaskMember{}
+		panic.IfNotNil(rows.Scan(&task, &tm.Id, &tm.RemainingTime))
+		if row := taskRows[task.String()]; row != nil {
+			row.task.Members = append(row.task.Members, &tm)
+		}
+	}
+	rows, e = ctx.TreeQuery(shard, `SELECT task, label FROM taskLabels WHERE account=? AND project=? ORDER BY task, label`, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var task, label id.Id
+		panic.IfNotNil(rows.Scan(&task, &label))
+		if row := taskRows[task.String()]; row != nil {
+			row.task.Labels = append(row.task.Labels, label)
+		}
+	}
+	rows, e = ctx.TreeQuery(shard, `SELECT task, customField, textValue, numberValue, dateValue FROM taskCustomFieldValues WHERE account=? AND project=? ORDER BY task, customField`, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var task id.Id
+		v := customfield.Value{}
+		panic.IfNotNil(rows.Scan(&task, &v.Field, &v.Text, &v.Number, &v.Date))
+		if row := taskRows[task.String()]; row != nil {
+			row.task.CustomFieldValues = append(row.task.CustomFieldValues, &v)
+		}
+	}
+	rows, e = ctx.TreeQuery(shard, `SELECT task, dependsOn FROM taskDependencies WHERE account=? AND project=? ORDER BY task, dependsOn`, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var task, dependsOn id.Id
+		panic.IfNotNil(rows.Scan(&task, &dependsOn))
+		if row := taskRows[task.String()]; row != nil {
+			row.task.DependsOn = append(row.task.DependsOn, dependsOn)
+		}
+	}
+	//only the labels of tasks still in the project, not of trashed ones
+	rows, e = ctx.TreeQuery(shard, `SELECT id, name, colour FROM labels WHERE account=? AND id IN (SELECT l.label FROM taskLabels l INNER JOIN tasks t ON t.account=l.account AND t.project=l.project AND t.id=l.task WHERE l.account=? AND l.project=?) ORDER BY name`, account, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		l := ExportLabel{}
+		panic.IfNotNil(rows.Scan(&l.Id, &l.Name, &l.Colour))
+		res.Labels = append(res.Labels, &l)
+	}
+	//walk the tree depth first following the sibling links so the document keeps the task order
+	res.Tasks = make([]*ExportTask, 0, len(taskRows))
+	stack := []*id.Id{taskRows[project.String()].firstChild}
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		if next == nil {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		row := taskRows[next.String()]
+		panic.If(row == nil, "broken task tree in project export")
+		res.Tasks = append(res.Tasks, row.task)
+		stack[len(stack)-1] = row.nextSibling
+		if row.task.IsAbstract {
+			stack = append(stack, row.firstChild)
+		}
+	}
+
+	rows, e = ctx.TreeQuery(shard, `SELECT task, id, member, loggedOn, taskHasBeenDeleted, taskName, duration, note, isBillable, hourlyRate, cost FROM timeLogs WHERE account=? AND project=? ORDER BY loggedOn ASC`, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		tl := timelog.TimeLog{Project: project}
+		panic.IfNotNil(rows.Scan(&tl.Task, &tl.Id, &tl.Member, &tl.LoggedOn, &tl.TaskHasBeenDeleted, &tl.TaskName, &tl.Duration, &tl.Note, &tl.IsBillable, &tl.HourlyRate, &tl.Cost))
+		res.TimeLogs = append(res.TimeLogs, &tl)
+	}
+
+	rows, e = ctx.TreeQuery(shard, `SELECT occurredOn, item, member, itemType, itemHasBeenDeleted, action, itemName, extraInfo FROM projectActivities WHERE account=? AND project=? ORDER BY occurredOn ASC`, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		act := activity.Activity{}
+		panic.IfNotNil(rows.Scan(&act.OccurredOn, &act.Item, &act.Member, &act.ItemType, &act.ItemHasBeenDeleted, &act.Action, &act.ItemName, &act.ExtraInfo))
+		res.Activities = append(res.Activities, &act)
+	}
+	return res
+}
+
+func dbGetLabelIdsByName(ctx ctx.Ctx, shard int, account id.Id) map[string]id.Id {
+	res := map[string]id.Id{}
+	rows, e := ctx.TreeQuery(shard, `SELECT id, name FROM labels WHERE account=?`, account)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var label id.Id
+		name := ""
+		panic.IfNotNil(rows.Scan(&label, &name))
+		res[name] = label
+	}
+	return res
+}
+
+func dbImport(ctx ctx.Ctx, shard int, account id.Id, plan *importPlan) {
+	project := plan.project.Id
+	//the rows are staged under batch, which no project has, and only moved on to the project by importProject in one transaction, so a failed import leaves nothing behind
+	batch := id.New()
+	defer func() {
+		r := recover()
+		if r != nil {
+			for _, table := range []string{`projectStates`, `taskStateCounts`, `tasks`, `taskMembers`, `projectMembers`, `customFields`, `taskCustomFieldValues`, `taskLabels`, `taskDependencies`, `timeLogs`, `remainingTimeChanges`, `projectActivities`} {
+				_, e := ctx.TreeExec(shard, fmt.Sprintf(`DELETE FROM %s WHERE account=? AND project=?`, table), account, batch)
+				ctx.LogIf(e)
+			}
+			if e, ok := r.(error); ok {
+				panic.IfNotNil(e)
+			}
+			panic.If(true, "%v", r)
+		}
+	}()
+
+	rows := make([][]interface{}, 0, len(plan.states))
+	for i, s := range plan.states {
+		rows = append(rows, []interface{}{account, batch, s.Id, i, s.Name, s.IsDone})
+	}
+	db.BulkInsert(ctx, shard, `INSERT INTO projectStates (account, project, id, position, name, isDone) VALUES `, rows)
+
+	now := t.Now()
+	rows = make([][]interface{}, 0, len(plan.customFields))
+	for i, f := range plan.customFields {
+		//keep the documents field order, fields are listed in createdOn order
+		rows = append(rows, []interface{}{account, batch, f.Id, now.Add(time.Duration(i) * time.Microsecond), f.Name, f.Type, f.OptionsStr()})
+	}
+	db.BulkInsert(ctx, shard, `INSERT INTO customFields (account, project, id, createdOn, name, type, options) VALUES `, rows)
+
+	rows = make([][]interface{}, 0, len(plan.tasks))
+	stateCountRows := make([][]interface{}, 0, len(plan.tasks))
+	remainingTimeRows := make([][]interface{}, 0, len(plan.tasks))
+	taskMemberRows := make([][]interface{}, 0, len(plan.tasks))
+	labelRows := make([][]interface{}, 0, len(plan.tasks))
+	customFieldValueRows := make([][]interface{}, 0, len(plan.tasks))
+	for _, it := range plan.tasks {
+		var parent *id.Id
+		if it.parent != nil {
+			parent = &it.parent.id
+		}
+		rows = append(rows, []interface{}{account, batch, it.id, parent, it.firstChild, it.nextSibling, it.isAbstract, it.name, it.description, it.createdOn, it.totalRemainingTime, it.totalLoggedTime, it.totalCost, it.totalRevenue, it.minimumRemainingTime, 0, 0, it.childCount, it.descendantCount, it.isParallel, it.state})
+		for state, count := range it.stateCounts {
+			stateCountRows = append(stateCountRows, []interface{}{account, batch, it.id, id.Parse(state), count})
+		}
+		for _, tm := range it.members {
+			taskMemberRows = append(taskMemberRows, []interface{}{account, batch, it.id, tm.Id, tm.RemainingTime})
+		}
+		for _, label := range it.labels {
+			labelRows = append(labelRows, []interface{}{account, batch, it.id, label})
+		}
+		for _, v := range it.customFieldValues {
+			customFieldValueRows = append(customFieldValueRows, []interface{}{account, batch, it.id, v.Field, v.Text, v.Number, v.Date})
+		}
+		if !it.isAbstract && it.totalRemainingTime > 0 {
+			remainingTimeRows = append(remainingTimeRows, []interface{}{account, batch, it.id, now, ctx.Me(), 0, it.totalRemainingTime})
+		}
+	}
+	db.BulkInsert(ctx, shard, `INSERT INTO tasks (account, project, id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state) VALUES `, rows)
+	db.BulkInsert(ctx, shard, `INSERT INTO taskMembers (account, project, task, member, remainingTime) VALUES `, taskMemberRows)
+	db.BulkInsert(ctx, shard, `INSERT INTO taskStateCounts (account, project, task, state, count) VALUES `, stateCountRows)
+	db.BulkInsert(ctx, shard, `INSERT INTO remainingTimeChanges (account, project, task, occurredOn, member, oldValue, newValue) VALUES `, remainingTimeRows)
+	db.BulkInsert(ctx, shard, `INSERT INTO taskLabels (account, project, task, label) VALUES `, labelRows)
+	db.BulkInsert(ctx, shard, `INSERT INTO taskCustomFieldValues (account, project, task, customField, textValue, numberValue, dateValue) VALUES `, customFieldValueRows)
+
+	rows = make([][]interface{}, 0, len(plan.dependencies))
+	for _, d := range plan.dependencies {
+		rows = append(rows, []interface{}{account, batch, d.task, d.dependsOn, d.commonAncestor})
+	}
+	db.BulkInsert(ctx, shard, `INSERT INTO taskDependencies (account, project, task, dependsOn, commonAncestor) VALUES `, rows)
+
+	for _, mem := range plan.members {
+		_, e := ctx.TreeExec(shard, `INSERT INTO projectMembers (account, project, id, name, displayName, isActive, totalRemainingTime, totalLoggedTime, role) SELECT account, ?, id, name, displayName, ?, ?, ?, ? FROM accountMembers WHERE account=? AND id=?`, batch, mem.isActive, mem.totalRemainingTime, mem.totalLoggedTime, mem.role, account, mem.id)
+		panic.IfNotNil(e)
+	}
+
+	rows = make([][]interface{}, 0, len(plan.timeLogs))
+	for _, tl := range plan.timeLogs {
+		rows = append(rows, []interface{}{account, batch, tl.Task, tl.Id, tl.Member, tl.LoggedOn, tl.TaskHasBeenDeleted, tl.TaskName, tl.Duration, tl.Note, tl.IsBillable, tl.HourlyRate, tl.Cost})
+	}
+	db.BulkInsert(ctx, shard, `INSERT INTO timeLogs (account, project, task, id, member, loggedOn, taskHasBeenDeleted, taskName, duration, note, isBillable, hourlyRate, cost) VALUES `, rows)
+
+	rows = make([][]interface{}, 0, len(plan.activities))
+	for _, act := range plan.activities {
+		rows = append(rows, []interface{}{account, batch, act.OccurredOn, act.Member, act.Item, act.ItemType, act.ItemHasBeenDeleted, act.Action, act.ItemName, act.ExtraInfo})
+	}
+	db.BulkInsert(ctx, shard, `INSERT INTO projectActivities (account, project, occurredOn, member, item, itemType, itemHasBeenDeleted, action, itemName, extraInfo) VALUES `, rows)
+
+	p := plan.project
+	_, e := ctx.TreeExec(shard, `CALL importProject(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, account, project, batch, ctx.Me(), p.Name, p.HoursPerDay, p.DaysPerWeek, p.CreatedOn, p.StartOn, p.DueOn, p.IsPublic)
+	panic.IfNotNil(e)
+	ctx.TouchDlms(cachekey.NewSetDlms().AccountActivities(account).AccountProjectsSet(account))
+}
+
 func dbGetActivities(ctx ctx.Ctx, shard int, account, project id.Id, item, member *id.Id, occurredAfter, occurredBefore *time.Time, limit int) []*activity.Activity {
 	ctx.ReturnBadRequestNowIf(occurredAfter != nil && occurredBefore != nil, "only one of occurredAfter or occurredBefore can be set")
 	res := make([]*activity.Activity, 0, limit)
@@ -242,7 +623,7 @@ func dbGetProjects(ctx ctx.Ctx, shard int, specificSqlFilterTxt string, account
 	if ctx.GetCacheValue(&res, cacheKey) {
 		return &res
 	}
-	query := bytes.NewBufferString(`SELECT id, isArchived, name, hoursPerDay, daysPerWeek, createdOn, startOn, dueOn, fileCount, fileSize, isPublic FROM projects WHERE account=? AND isArchived=? %s`)
+	query := bytes.NewBufferString(`SELECT id, isArchived, name, hoursPerDay, daysPerWeek, createdOn, startOn, dueOn, fileCount, fileSize, isPublic, isTemplate, timeLogMaxAgeDays, budget FROM projects WHERE account=? AND isArchived=? %s`)
 	args := make([]interface{}, 0, 14)
 	args = append(args, account, isArchived)
 	if me != nil {
@@ -292,7 +673,7 @@ func dbGetProjects(ctx ctx.Ctx, shard int, specificSqlFilterTxt string, account
 	resIdx := map[string]int{}
 	for rows.Next() {
 		proj := Project{}
-		panic.IfNotNil(rows.Scan(&proj.Id, &proj.IsArchived, &proj.Name, &proj.HoursPerDay, &proj.DaysPerWeek, &proj.CreatedOn, &proj.StartOn, &proj.DueOn, &proj.FileCount, &proj.FileSize, &proj.IsPublic))
+		panic.IfNotNil(rows.Scan(&proj.Id, &proj.IsArchived, &proj.Name, &proj.HoursPerDay, &proj.DaysPerWeek, &proj.CreatedOn, &proj.StartOn, &proj.DueOn, &proj.FileCount, &proj.FileSize, &proj.IsPublic, &proj.IsTemplate, &proj.TimeLogMaxAgeDays, &proj.Budget))
 		projSet = append(projSet, &proj)
 		resIdx[proj.Id.String()] = idx
 		idx++
@@ -300,12 +681,12 @@ func dbGetProjects(ctx ctx.Ctx, shard int, specificSqlFilterTxt string, account
 	if len(projSet) > 0 { //populate task properties
 		var i id.Id
 		var description *string
-		var totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount uint64
+		var totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount uint64
 		var isParallel bool
 		query.Reset()
 		args = make([]interface{}, 0, len(projSet)+1)
 		args = append(args, account, projSet[0].Id)
-		query.WriteString(`SELECT id, description, totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel FROM tasks WHERE account=? AND project=id AND project IN (?`)
+		query.WriteString(`SELECT t.id, t.description, t.totalRemainingTime, t.totalLoggedTime, t.totalCost, t.totalRevenue, t.minimumRemainingTime, t.linkedFileCount, t.chatCount, t.childCount, t.descendantCount, t.isParallel FROM tasks t WHERE t.account=? AND t.project=t.id AND t.project IN (?`)
 		for _, proj := range projSet[1:] {
 			query.WriteString(`,?`)
 			args = append(args, proj.Id)
@@ -317,11 +698,14 @@ func dbGetProjects(ctx ctx.Ctx, shard int, specificSqlFilterTxt string, account
 		}
 		panic.IfNotNil(e)
 		for rows.Next() {
-			rows.Scan(&i, &description, &totalRemainingTime, &totalLoggedTime, &minimumRemainingTime, &linkedFileCount, &chatCount, &childCount, &descendantCount, &isParallel)
+			rows.Scan(&i, &description, &totalRemainingTime, &totalLoggedTime, &totalCost, &totalRevenue, &minimumRemainingTime, &linkedFileCount, &chatCount, &childCount, &descendantCount, &isParallel)
 			proj := projSet[resIdx[i.String()]]
 			proj.Description = description
 			proj.TotalRemainingTime = totalRemainingTime
 			proj.TotalLoggedTime = totalLoggedTime
+			proj.TotalCost = totalCost
+			proj.TotalRevenue = totalRevenue
+			proj.IsOverBudget = proj.Budget != nil && totalCost > *proj.Budget
 			proj.MinimumRemainingTime = minimumRemainingTime
 			proj.LinkedFileCount = linkedFileCount
 			proj.ChatCount = chatCount
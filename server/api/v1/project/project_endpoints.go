@@ -4,16 +4,25 @@ import (
 	"github.com/0xor1/trees/server/util/activity"
 	"github.com/0xor1/trees/server/util/cnst"
 	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/customfield"
 	"github.com/0xor1/trees/server/util/db"
 	"github.com/0xor1/trees/server/util/endpoint"
 	"github.com/0xor1/trees/server/util/field"
+	"github.com/0xor1/trees/server/util/filestore"
 	"github.com/0xor1/trees/server/util/id"
 	t "github.com/0xor1/trees/server/util/time"
+	"github.com/0xor1/trees/server/util/timelog"
 	"github.com/0xor1/trees/server/util/validate"
 	"net/http"
 	"time"
 )
 
+const (
+	stateNameMinRuneCount = 1
+	stateNameMaxRuneCount = 50
+	maxStateCount         = 20
+)
+
 type createArgs struct {
 	Shard       int                 `json:"shard"`
 	Account     id.Id               `json:"account"`
@@ -98,6 +107,10 @@ var edit = &endpoint.Endpoint{
 			//other fields only require project admin access
 			validate.MemberHasProjectAdminAccess(accRole, projRole)
 		}
+		if args.Fields.IsArchived == nil || args.Fields.IsArchived.Val {
+			//unarchiving is the only edit an archived project accepts
+			db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		}
 		if args.Fields.HoursPerDay != nil {
 			validate.HoursPerDay(args.Fields.HoursPerDay.Val)
 		}
@@ -192,8 +205,9 @@ var delete = &endpoint.Endpoint{
 	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
 		args := a.(*deleteArgs)
 		validate.MemberHasAccountAdminAccess(db.GetAccountRole(ctx, args.Shard, args.Account, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
 		dbDeleteProject(ctx, args.Shard, args.Account, args.Project)
-		//TODO delete s3 data, uploaded files etc
+		ctx.FileClient().DeletePrefix(filestore.ProjectPrefix(args.Account, args.Project))
 		return nil
 	},
 }
@@ -217,6 +231,7 @@ var addMembers = &endpoint.Endpoint{
 		ctx.ReturnBadRequestNowIf(args.Account.Equal(ctx.Me()), "can't add/remove members to/from personal accounts")
 
 		validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
 		ctx.ReturnNowIf(!dbGetProjectExists(ctx, args.Shard, args.Account, args.Project), http.StatusBadRequest, "no such project")
 
 		for _, mem := range args.Members {
@@ -252,6 +267,7 @@ var setMemberRole = &endpoint.Endpoint{
 		args.Role.Validate()
 
 		validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
 
 		accRole, projectRole := db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, args.Member)
 		ctx.ReturnBadRequestNowIf(projectRole == nil, "user is not a member of this project")
@@ -263,6 +279,31 @@ var setMemberRole = &endpoint.Endpoint{
 	},
 }
 
+type setMemberHourlyRateArgs struct {
+	Shard      int     `json:"shard"`
+	Account    id.Id   `json:"account"`
+	Project    id.Id   `json:"project"`
+	Member     id.Id   `json:"member"`
+	HourlyRate *uint64 `json:"hourlyRate,omitempty"`
+}
+
+var setMemberHourlyRate = &endpoint.Endpoint{
+	Path:            "/api/v1/project/setMemberHourlyRate",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &setMemberHourlyRateArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*setMemberHourlyRateArgs)
+		validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		_, projectRole := db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, args.Member)
+		ctx.ReturnBadRequestNowIf(projectRole == nil, "user is not a member of this project")
+		dbSetMemberHourlyRate(ctx, args.Shard, args.Account, args.Project, args.Member, args.HourlyRate)
+		return nil
+	},
+}
+
 type removeMembersArgs struct {
 	Shard   int     `json:"shard"`
 	Account id.Id   `json:"account"`
@@ -281,6 +322,7 @@ var removeMembers = &endpoint.Endpoint{
 		validate.EntityCount(len(args.Members), ctx.MaxProcessEntityCount())
 		ctx.ReturnBadRequestNowIf(args.Account.Equal(ctx.Me()), "can't add/remove members to/from personal accounts")
 		validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
 
 		for _, mem := range args.Members {
 			dbSetMemberInactive(ctx, args.Shard, args.Account, args.Project, mem)
@@ -313,8 +355,14 @@ var getMembers = &endpoint.Endpoint{
 	},
 	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
 		args := a.(*getMembersArgs)
-		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
-		return dbGetMembers(ctx, args.Shard, args.Account, args.Project, args.Role, args.NameOrDisplayNameContains, false, args.After, validate.Limit(args.Limit, ctx.MaxProcessEntityCount()))
+		accRole, projRole, isPublic := db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe())
+		validate.MemberHasProjectReadAccess(accRole, projRole, isPublic)
+		res := dbGetMembers(ctx, args.Shard, args.Account, args.Project, args.Role, args.NameOrDisplayNameContains, false, args.After, validate.Limit(args.Limit, ctx.MaxProcessEntityCount()))
+		if accRole != nil && (*accRole == cnst.AccountOwner || *accRole == cnst.AccountAdmin) || projRole != nil && *projRole == cnst.ProjectAdmin {
+			return res
+		}
+		//hourly rates are only shown to admins
+		return &GetMembersResult{Members: withoutHourlyRates(res.Members), More: res.More}
 	},
 }
 
@@ -330,13 +378,13 @@ var getAtMentions = &endpoint.Endpoint{
 	RequiresSession:          false,
 	ExampleResponseStructure: []*Member{{}},
 	GetArgsStruct: func() interface{} {
-		return &getMembersArgs{}
+		return &getAtMentionsArgs{}
 	},
 	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
 		args := a.(*getAtMentionsArgs)
 		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
 		res := dbGetMembers(ctx, args.Shard, args.Account, args.Project, nil, &args.NameOrDisplayNamePrefix, true, nil, 10)
-		return res.Members
+		return withoutHourlyRates(res.Members)
 	},
 }
 
@@ -385,6 +433,445 @@ var getActivities = &endpoint.Endpoint{
 	},
 }
 
+type createStateArgs struct {
+	Shard   int    `json:"shard"`
+	Account id.Id  `json:"account"`
+	Project id.Id  `json:"project"`
+	Name    string `json:"name"`
+	IsDone  bool   `json:"isDone"`
+}
+
+var createState = &endpoint.Endpoint{
+	Path:                     "/api/v1/project/createState",
+	Note:                     "new states are added after the existing ones, creating a done state unsets isDone on the previous done state",
+	RequiresSession:          true,
+	ExampleResponseStructure: &State{},
+	GetArgsStruct: func() interface{} {
+		return &createStateArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*createStateArgs)
+		validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		validate.StringArg("name", args.Name, stateNameMinRuneCount, stateNameMaxRuneCount, nil)
+		states := dbGetStates(ctx, args.Shard, args.Account, args.Project)
+		ctx.ReturnBadRequestNowIf(len(states) >= maxStateCount, "a project can have at most %d states", maxStateCount)
+		ctx.ReturnBadRequestNowIf(stateNameInUse(states, nil, args.Name), "state name already in use")
+		newState := &State{
+			Id:     id.New(),
+			Name:   args.Name,
+			IsDone: args.IsDone,
+		}
+		dbCreateState(ctx, args.Shard, args.Account, args.Project, newState)
+		return newState
+	},
+}
+
+type editStateArgs struct {
+	Shard   int     `json:"shard"`
+	Account id.Id   `json:"account"`
+	Project id.Id   `json:"project"`
+	State   id.Id   `json:"state"`
+	Name    *string `json:"name,omitempty"`
+	IsDone  *bool   `json:"isDone,omitempty"`
+}
+
+var editState = &endpoint.Endpoint{
+	Path:            "/api/v1/project/editState",
+	Note:            "isDone can only be set to true, which unsets it on the previous done state",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &editStateArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*editStateArgs)
+		validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		ctx.ReturnBadRequestNowIf(args.IsDone != nil && !*args.IsDone, "a project must always have a done state, set isDone on another state instead")
+		states := dbGetStates(ctx, args.Shard, args.Account, args.Project)
+		ctx.ReturnBadRequestNowIf(getState(states, args.State) == nil, "no such state")
+		if args.Name != nil {
+			validate.StringArg("name", *args.Name, stateNameMinRuneCount, stateNameMaxRuneCount, nil)
+			ctx.ReturnBadRequestNowIf(stateNameInUse(states, &args.State, *args.Name), "state name already in use")
+		}
+		dbEditState(ctx, args.Shard, args.Account, args.Project, args.State, args.Name, args.IsDone != nil)
+		return nil
+	},
+}
+
+type moveStateArgs struct {
+	Shard       int   `json:"shard"`
+	Account     id.Id `json:"account"`
+	Project     id.Id `json:"project"`
+	State       id.Id `json:"state"`
+	NewPosition int   `json:"newPosition"`
+}
+
+var moveState = &endpoint.Endpoint{
+	Path:            "/api/v1/project/moveState",
+	Note:            "newPosition is zero based, new concrete tasks start in the state at position zero",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &moveStateArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*moveStateArgs)
+		validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		ctx.ReturnBadRequestNowIf(args.NewPosition < 0, "newPosition must not be negative")
+		dbMoveState(ctx, args.Shard, args.Account, args.Project, args.State, args.NewPosition)
+		return nil
+	},
+}
+
+type deleteStateArgs struct {
+	Shard       int   `json:"shard"`
+	Account     id.Id `json:"account"`
+	Project     id.Id `json:"project"`
+	State       id.Id `json:"state"`
+	Replacement id.Id `json:"replacement"`
+}
+
+var deleteState = &endpoint.Endpoint{
+	Path:            "/api/v1/project/deleteState",
+	Note:            "tasks in the deleted state are moved to the replacement state, the done state can not be deleted",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &deleteStateArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*deleteStateArgs)
+		validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		ctx.ReturnBadRequestNowIf(args.State.Equal(args.Replacement), "a state can not be its own replacement")
+		states := dbGetStates(ctx, args.Shard, args.Account, args.Project)
+		state := getState(states, args.State)
+		ctx.ReturnBadRequestNowIf(state == nil, "no such state")
+		ctx.ReturnBadRequestNowIf(state.IsDone, "the done state can not be deleted")
+		ctx.ReturnBadRequestNowIf(getState(states, args.Replacement) == nil, "no such replacement state")
+		dbDeleteState(ctx, args.Shard, args.Account, args.Project, args.State, args.Replacement)
+		return nil
+	},
+}
+
+type getStatesArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Project id.Id `json:"project"`
+}
+
+var getStates = &endpoint.Endpoint{
+	Path:                     "/api/v1/project/getStates",
+	RequiresSession:          false,
+	ExampleResponseStructure: []*State{{}},
+	GetArgsStruct: func() interface{} {
+		return &getStatesArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getStatesArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		return dbGetStates(ctx, args.Shard, args.Account, args.Project)
+	},
+}
+
+type createCustomFieldArgs struct {
+	Shard   int                  `json:"shard"`
+	Account id.Id                `json:"account"`
+	Project id.Id                `json:"project"`
+	Name    string               `json:"name"`
+	Type    cnst.CustomFieldType `json:"type"`
+	Options []string             `json:"options,omitempty"`
+}
+
+var createCustomField = &endpoint.Endpoint{
+	Path:                     "/api/v1/project/createCustomField",
+	Note:                     "options are required for select fields and not allowed for any other type",
+	RequiresSession:          true,
+	ExampleResponseStructure: &customfield.Field{Options: []string{""}},
+	GetArgsStruct: func() interface{} {
+		return &createCustomFieldArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*createCustomFieldArgs)
+		validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		newField := &customfield.Field{
+			Id:      id.New(),
+			Name:    args.Name,
+			Type:    args.Type,
+			Options: args.Options,
+		}
+		newField.Validate()
+		fields := db.GetProjectCustomFields(ctx, args.Shard, args.Account, args.Project)
+		ctx.ReturnBadRequestNowIf(len(fields) >= customfield.MaxFields, "a project can have at most %d custom fields", customfield.MaxFields)
+		ctx.ReturnBadRequestNowIf(customFieldNameInUse(fields, nil, args.Name), "custom field name already in use")
+		dbCreateCustomField(ctx, args.Shard, args.Account, args.Project, newField)
+		return newField
+	},
+}
+
+type editCustomFieldArgs struct {
+	Shard       int      `json:"shard"`
+	Account     id.Id    `json:"account"`
+	Project     id.Id    `json:"project"`
+	CustomField id.Id    `json:"customField"`
+	Name        *string  `json:"name,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+var editCustomField = &endpoint.Endpoint{
+	Path:            "/api/v1/project/editCustomField",
+	Note:            "a custom fields type can not be changed, options replace the existing options of a select field and must still include every option held by a task",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &editCustomFieldArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*editCustomFieldArgs)
+		validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		fields := db.GetProjectCustomFields(ctx, args.Shard, args.Account, args.Project)
+		existing := customfield.Get(fields, args.CustomField)
+		ctx.ReturnBadRequestNowIf(existing == nil, "no such custom field")
+		edited := &customfield.Field{
+			Id:      existing.Id,
+			Name:    existing.Name,
+			Type:    existing.Type,
+			Options: existing.Options,
+		}
+		if args.Name != nil {
+			edited.Name = *args.Name
+			ctx.ReturnBadRequestNowIf(customFieldNameInUse(fields, &args.CustomField, *args.Name), "custom field name already in use")
+		}
+		if args.Options != nil {
+			edited.Options = args.Options
+		}
+		edited.Validate()
+		if args.Options != nil {
+			for _, inUse := range dbCustomFieldOptionsInUse(ctx, args.Shard, args.Account, args.Project, args.CustomField) {
+				ctx.ReturnBadRequestNowIf(!optionsContain(edited.Options, inUse), "option %q is held by at least one task and can not be removed", inUse)
+			}
+		}
+		dbEditCustomField(ctx, args.Shard, args.Account, args.Project, edited)
+		return nil
+	},
+}
+
+type deleteCustomFieldArgs struct {
+	Shard       int   `json:"shard"`
+	Account     id.Id `json:"account"`
+	Project     id.Id `json:"project"`
+	CustomField id.Id `json:"customField"`
+}
+
+var deleteCustomField = &endpoint.Endpoint{
+	Path:            "/api/v1/project/deleteCustomField",
+	Note:            "the custom fields value is removed from every task",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &deleteCustomFieldArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*deleteCustomFieldArgs)
+		validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		ctx.ReturnBadRequestNowIf(customfield.Get(db.GetProjectCustomFields(ctx, args.Shard, args.Account, args.Project), args.CustomField) == nil, "no such custom field")
+		dbDeleteCustomField(ctx, args.Shard, args.Account, args.Project, args.CustomField)
+		return nil
+	},
+}
+
+type getCustomFieldsArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Project id.Id `json:"project"`
+}
+
+var getCustomFields = &endpoint.Endpoint{
+	Path:                     "/api/v1/project/getCustomFields",
+	RequiresSession:          false,
+	ExampleResponseStructure: []*customfield.Field{{Options: []string{""}}},
+	GetArgsStruct: func() interface{} {
+		return &getCustomFieldsArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getCustomFieldsArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		return db.GetProjectCustomFields(ctx, args.Shard, args.Account, args.Project)
+	},
+}
+
+type saveAsTemplateArgs struct {
+	Shard         int    `json:"shard"`
+	Account       id.Id  `json:"account"`
+	Project       id.Id  `json:"project"`
+	Name          string `json:"name"`
+	CopyEstimates bool   `json:"copyEstimates"`
+}
+
+var saveAsTemplate = &endpoint.Endpoint{
+	Path:                     "/api/v1/project/saveAsTemplate",
+	RequiresSession:          true,
+	ExampleResponseStructure: &Project{},
+	GetArgsStruct: func() interface{} {
+		return &saveAsTemplateArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*saveAsTemplateArgs)
+		validate.MemberHasAccountAdminAccess(db.GetAccountRole(ctx, args.Shard, args.Account, ctx.Me()))
+		source := dbGetProject(ctx, args.Shard, args.Account, args.Project)
+		ctx.ReturnBadRequestNowIf(source == nil, "no such project")
+
+		template := &Project{}
+		template.Id = id.New()
+		template.Name = args.Name
+		template.HoursPerDay = source.HoursPerDay
+		template.DaysPerWeek = source.DaysPerWeek
+		template.Description = source.Description
+		template.CreatedOn = t.Now()
+		template.IsParallel = source.IsParallel
+		template.IsTemplate = true
+		dbCreateProject(ctx, args.Shard, args.Account, template)
+		dbCopyStates(ctx, args.Shard, args.Account, args.Project, template.Id)
+		db.CopyTask(ctx, args.Shard, args.Account, args.Project, args.Project, template.Id, template.Id, nil, args.CopyEstimates, false)
+		return dbGetProject(ctx, args.Shard, args.Account, template.Id)
+	},
+}
+
+type createFromTemplateArgs struct {
+	Shard       int                 `json:"shard"`
+	Account     id.Id               `json:"account"`
+	Template    id.Id               `json:"template"`
+	Name        string              `json:"name"`
+	Description *string             `json:"description"`
+	StartOn     *time.Time          `json:"startOn"`
+	DueOn       *time.Time          `json:"dueOn"`
+	IsPublic    bool                `json:"isPublic"`
+	Members     []*AddProjectMember `json:"members"`
+}
+
+var createFromTemplate = &endpoint.Endpoint{
+	Path:                     "/api/v1/project/createFromTemplate",
+	RequiresSession:          true,
+	ExampleResponseStructure: &Project{},
+	GetArgsStruct: func() interface{} {
+		return &createFromTemplateArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*createFromTemplateArgs)
+		validate.MemberHasAccountAdminAccess(db.GetAccountRole(ctx, args.Shard, args.Account, ctx.Me()))
+		template := dbGetProject(ctx, args.Shard, args.Account, args.Template)
+		ctx.ReturnBadRequestNowIf(template == nil || !template.IsTemplate, "no such template")
+		if args.Description == nil {
+			args.Description = template.Description
+		}
+
+		project := create.CtxHandler(ctx, &createArgs{
+			Shard:       args.Shard,
+			Account:     args.Account,
+			Name:        args.Name,
+			Description: args.Description,
+			HoursPerDay: template.HoursPerDay,
+			DaysPerWeek: template.DaysPerWeek,
+			StartOn:     args.StartOn,
+			DueOn:       args.DueOn,
+			IsParallel:  template.IsParallel,
+			IsPublic:    args.IsPublic,
+			Members:     args.Members,
+		}).(*Project)
+		dbCopyStates(ctx, args.Shard, args.Account, args.Template, project.Id)
+		db.CopyTask(ctx, args.Shard, args.Account, args.Template, args.Template, project.Id, project.Id, nil, true, false)
+		return dbGetProject(ctx, args.Shard, args.Account, project.Id)
+	},
+}
+
+type getTemplatesArgs struct {
+	Shard        int     `json:"shard"`
+	Account      id.Id   `json:"account"`
+	NameContains *string `json:"nameContains"`
+	After        *id.Id  `json:"after"`
+	Limit        int     `json:"limit"`
+}
+
+var getTemplates = &endpoint.Endpoint{
+	Path:                     "/api/v1/project/getTemplates",
+	RequiresSession:          true,
+	ExampleResponseStructure: &GetSetResult{Projects: []*Project{{}}},
+	GetArgsStruct: func() interface{} {
+		return &getTemplatesArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getTemplatesArgs)
+		validate.MemberHasAccountAdminAccess(db.GetAccountRole(ctx, args.Shard, args.Account, ctx.Me()))
+		args.Limit = validate.Limit(args.Limit, ctx.MaxProcessEntityCount())
+		return dbGetTemplates(ctx, args.Shard, args.Account, args.NameContains, args.After, args.Limit)
+	},
+}
+
+type exportArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Project id.Id `json:"project"`
+}
+
+var export = &endpoint.Endpoint{
+	Path:                     "/api/v1/project/export",
+	RequiresSession:          true,
+	ExampleResponseStructure: &Export{Project: &Project{}, States: []*State{{}}, Members: []*Member{{}}, CustomFields: []*customfield.Field{{}}, Labels: []*ExportLabel{{}}, Tasks: []*ExportTask{{}}, TimeLogs: []*timelog.TimeLog{{}}, Activities: []*activity.Activity{{}}},
+	GetArgsStruct: func() interface{} {
+		return &exportArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*exportArgs)
+		validate.MemberHasAccountAdminAccess(db.GetAccountRole(ctx, args.Shard, args.Account, ctx.Me()))
+		return dbGetExport(ctx, args.Shard, args.Account, args.Project)
+	},
+}
+
+type importArgs struct {
+	Shard   int     `json:"shard"`
+	Account id.Id   `json:"account"`
+	Export  *Export `json:"export"`
+}
+
+var importProject = &endpoint.Endpoint{
+	Path:                     "/api/v1/project/import",
+	RequiresSession:          true,
+	ExampleResponseStructure: &Project{},
+	GetArgsStruct: func() interface{} {
+		return &importArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*importArgs)
+		validate.MemberHasAccountAdminAccess(db.GetAccountRole(ctx, args.Shard, args.Account, ctx.Me()))
+		//only members of the target account are kept, account owners and admins are always project admins
+		members := map[string]cnst.ProjectRole{}
+		if args.Export != nil {
+			for _, mem := range args.Export.Members {
+				if mem == nil {
+					continue
+				}
+				mem.Role.Validate()
+				accRole := db.GetAccountRole(ctx, args.Shard, args.Account, mem.Id)
+				if accRole == nil {
+					continue
+				}
+				members[mem.Id.String()] = mem.Role
+				if *accRole == cnst.AccountOwner || *accRole == cnst.AccountAdmin {
+					members[mem.Id.String()] = cnst.ProjectAdmin
+				}
+			}
+		}
+		plan := newImportPlan(ctx, args.Export, members, dbGetLabelIdsByName(ctx, args.Shard, args.Account))
+		ctx.ReturnBadRequestNowIf(plan.project.IsPublic && !db.GetAccount(ctx, args.Shard, args.Account).PublicProjectsEnabled, "public projects are not enabled on this account")
+		if _, exists := members[ctx.Me().String()]; !exists && args.Account.Equal(ctx.Me()) {
+			plan.members = append(plan.members, &importMember{id: ctx.Me(), isActive: true, role: cnst.ProjectAdmin})
+		}
+		dbImport(ctx, args.Shard, args.Account, plan)
+		return dbGetProject(ctx, args.Shard, args.Account, plan.project.Id)
+	},
+}
+
 var Endpoints = []*endpoint.Endpoint{
 	create,
 	edit,
@@ -393,10 +880,25 @@ var Endpoints = []*endpoint.Endpoint{
 	delete,
 	addMembers,
 	setMemberRole,
+	setMemberHourlyRate,
 	removeMembers,
 	getMembers,
 	getMe,
 	getActivities,
+	createState,
+	editState,
+	moveState,
+	deleteState,
+	getStates,
+	createCustomField,
+	editCustomField,
+	deleteCustomField,
+	getCustomFields,
+	saveAsTemplate,
+	createFromTemplate,
+	getTemplates,
+	export,
+	importProject,
 }
 
 type Member struct {
@@ -405,6 +907,17 @@ type Member struct {
 	TotalLoggedTime    uint64           `json:"totalLoggedTime"`
 	IsActive           bool             `json:"isActive"`
 	Role               cnst.ProjectRole `json:"role"`
+	HourlyRate         *uint64          `json:"hourlyRate,omitempty"` //overrides the account hourly rate on this project, only shown to admins
+}
+
+func withoutHourlyRates(members []*Member) []*Member {
+	res := make([]*Member, 0, len(members))
+	for _, mem := range members {
+		m := *mem
+		m.HourlyRate = nil
+		res = append(res, &m)
+	}
+	return res
 }
 
 type Project struct {
@@ -428,6 +941,12 @@ type Project struct {
 	DescendantCount      uint64     `json:"descendantCount"`
 	IsParallel           bool       `json:"isParallel"`
 	IsPublic             bool       `json:"isPublic"`
+	IsTemplate           bool       `json:"isTemplate"`
+	TimeLogMaxAgeDays    *uint16    `json:"timeLogMaxAgeDays,omitempty"` //nil for no limit, project admins can always log further back
+	TotalCost            uint64     `json:"totalCost"`
+	TotalRevenue         uint64     `json:"totalRevenue"` //the cost of the billable time
+	Budget               *uint64    `json:"budget,omitempty"`
+	IsOverBudget         bool       `json:"isOverBudget"` //totalCost has passed budget
 }
 
 type Fields struct {
@@ -443,9 +962,55 @@ type Fields struct {
 	StartOn *field.TimePtr `json:"startOn,omitempty"`
 	// account owner/admin or project admin
 	DueOn *field.TimePtr `json:"dueOn,omitempty"`
+	// account owner/admin or project admin
+	TimeLogMaxAgeDays *field.UInt16Ptr `json:"timeLogMaxAgeDays,omitempty"`
+	// account owner/admin or project admin
+	Budget *field.UInt64Ptr `json:"budget,omitempty"`
+}
+
+type State struct {
+	Id     id.Id  `json:"id"`
+	Name   string `json:"name"`
+	IsDone bool   `json:"isDone"`
 }
 
 type AddProjectMember struct {
 	Id   id.Id            `json:"id"`
 	Role cnst.ProjectRole `json:"role"`
 }
+
+func getState(states []*State, state id.Id) *State {
+	for _, s := range states {
+		if s.Id.Equal(state) {
+			return s
+		}
+	}
+	return nil
+}
+
+func stateNameInUse(states []*State, exclude *id.Id, name string) bool {
+	for _, s := range states {
+		if s.Name == name && (exclude == nil || !s.Id.Equal(*exclude)) {
+			return true
+		}
+	}
+	return false
+}
+
+func customFieldNameInUse(fields []*customfield.Field, exclude *id.Id, name string) bool {
+	for _, f := range fields {
+		if f.Name == name && (exclude == nil || !f.Id.Equal(*exclude)) {
+			return true
+		}
+	}
+	return false
+}
+
+func optionsContain(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
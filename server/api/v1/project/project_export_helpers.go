@@ -0,0 +1,430 @@
+package project
+
+import (
+	"github.com/0xor1/trees/server/util/activity"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/customfield"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/timelog"
+	"github.com/0xor1/trees/server/util/validate"
+	"time"
+)
+
+const (
+	exportVersion               = 4 //version 1 documents, with a single member per task, version 2 documents, without time log billing, and version 3 documents, without custom fields, labels or dependencies, are still accepted
+	maxImportTaskCount          = 10000
+	taskNameMinRuneCount        = 1
+	taskNameMaxRuneCount        = 250
+	taskDescriptionMaxRuneCount = 1250
+)
+
+// Export is a portable copy of a project, Tasks lists every task bar the project node depth first with siblings in
+// order, so every tasks parent, and its previous sibling, comes before it.
+type Export struct {
+	Version      int                  `json:"version"`
+	ExportedOn   time.Time            `json:"exportedOn"`
+	Project      *Project             `json:"project"`
+	States       []*State             `json:"states"`
+	Members      []*Member            `json:"members"`
+	CustomFields []*customfield.Field `json:"customFields"`
+	Labels       []*ExportLabel       `json:"labels"`
+	Tasks        []*ExportTask        `json:"tasks"`
+	TimeLogs     []*timelog.TimeLog   `json:"timeLogs"`
+	Activities   []*activity.Activity `json:"activities"`
+}
+
+type ExportTask struct {
+	Id                id.Id                `json:"id"`
+	Parent            id.Id                `json:"parent"`
+	IsAbstract        bool                 `json:"isAbstract"`
+	Name              string               `json:"name"`
+	Description       *string              `json:"description,omitempty"`
+	CreatedOn         time.Time            `json:"createdOn"`
+	RemainingTime     uint64               `json:"remainingTime"`
+	IsParallel        bool                 `json:"isParallel"`
+	Member            *id.Id               `json:"member,omitempty"` //only in version 1 documents
+	Members           []*ExportTaskMember  `json:"members,omitempty"`
+	State             *id.Id               `json:"state,omitempty"`
+	Labels            []id.Id              `json:"labels,omitempty"`
+	CustomFieldValues []*customfield.Value `json:"customFieldValues,omitempty"`
+	DependsOn         []id.Id              `json:"dependsOn,omitempty"` //the tasks this task can not start until they have finished
+}
+
+// ExportLabel is an account label used by the exported tasks, labels are matched on to the importing accounts labels
+// by name.
+type ExportLabel struct {
+	Id     id.Id  `json:"id"`
+	Name   string `json:"name"`
+	Colour string `json:"colour"`
+}
+
+type ExportTaskMember struct {
+	Id            id.Id  `json:"id"`
+	RemainingTime uint64 `json:"remainingTime"`
+}
+
+type importTask struct {
+	id                   id.Id
+	parent               *importTask
+	firstChild           *id.Id
+	nextSibling          *id.Id
+	isAbstract           bool
+	name                 string
+	description          *string
+	createdOn            time.Time
+	totalRemainingTime   uint64
+	totalLoggedTime      uint64
+	totalCost            uint64
+	totalRevenue         uint64
+	minimumRemainingTime uint64
+	childCount           uint64
+	descendantCount      uint64
+	isParallel           bool
+	members              []*ExportTaskMember
+	state                *id.Id
+	stateCounts          map[string]uint64
+	labels               []id.Id
+	customFieldValues    []*customfield.Value
+}
+
+type importDependency struct {
+	task           id.Id
+	dependsOn      id.Id
+	commonAncestor id.Id
+}
+
+type importMember struct {
+	id                 id.Id
+	isActive           bool
+	role               cnst.ProjectRole
+	totalRemainingTime uint64
+	totalLoggedTime    uint64
+}
+
+// importPlan is an export remapped on to fresh ids with every aggregate value recalculated from the document rather
+// than trusted, tasks starts with the project node and keeps the documents depth first order.
+type importPlan struct {
+	project      *Project
+	states       []*State
+	members      []*importMember
+	customFields []*customfield.Field
+	tasks        []*importTask
+	dependencies []*importDependency
+	timeLogs     []*timelog.TimeLog
+	activities   []*activity.Activity
+}
+
+// newImportPlan validates doc and remaps it on to a new project, members maps the documents member ids to the role
+// they will have in the new project, any member not in it is dropped from the project and unassigned from its tasks.
+// labels maps the importing accounts label names to their ids, task labels with no match are dropped.
+func newImportPlan(ctx ctx.Ctx, doc *Export, members map[string]cnst.ProjectRole, labels map[string]id.Id) *importPlan {
+	ctx.ReturnBadRequestNowIf(doc == nil || doc.Project == nil, "export document has no project")
+	ctx.ReturnBadRequestNowIf(doc.Version < 1 || doc.Version > exportVersion, "unsupported export version %d", doc.Version)
+	validate.HoursPerDay(doc.Project.HoursPerDay)
+	validate.DaysPerWeek(doc.Project.DaysPerWeek)
+	validate.StringArg("project name", doc.Project.Name, taskNameMinRuneCount, taskNameMaxRuneCount, nil)
+	ctx.ReturnBadRequestNowIf(len(doc.Tasks) > maxImportTaskCount, "can not import more than %d tasks", maxImportTaskCount)
+
+	plan := &importPlan{}
+	newIds := map[string]id.Id{}
+	plan.project = &Project{
+		Id:          id.New(),
+		Name:        doc.Project.Name,
+		Description: doc.Project.Description,
+		HoursPerDay: doc.Project.HoursPerDay,
+		DaysPerWeek: doc.Project.DaysPerWeek,
+		CreatedOn:   doc.Project.CreatedOn,
+		StartOn:     doc.Project.StartOn,
+		DueOn:       doc.Project.DueOn,
+		IsParallel:  doc.Project.IsParallel,
+		IsPublic:    doc.Project.IsPublic,
+	}
+	newIds[doc.Project.Id.String()] = plan.project.Id
+
+	ctx.ReturnBadRequestNowIf(len(doc.States) == 0 || len(doc.States) > maxStateCount, "export document must have between 1 and %d states", maxStateCount)
+	stateNames := map[string]bool{}
+	oldStates := map[string]id.Id{}
+	doneCount := 0
+	for _, s := range doc.States {
+		ctx.ReturnBadRequestNowIf(s == nil, "export document has a null state")
+		validate.StringArg("state name", s.Name, stateNameMinRuneCount, stateNameMaxRuneCount, nil)
+		_, exists := newIds[s.Id.String()]
+		ctx.ReturnBadRequestNowIf(exists || stateNames[s.Name], "export document has duplicate states")
+		stateNames[s.Name] = true
+		if s.IsDone {
+			doneCount++
+		}
+		newIds[s.Id.String()] = id.New()
+		oldStates[s.Id.String()] = newIds[s.Id.String()]
+		plan.states = append(plan.states, &State{Id: newIds[s.Id.String()], Name: s.Name, IsDone: s.IsDone})
+	}
+	ctx.ReturnBadRequestNowIf(doneCount != 1, "export document must have exactly one done state")
+
+	ctx.ReturnBadRequestNowIf(len(doc.CustomFields) > customfield.MaxFields, "export document can not have more than %d custom fields", customfield.MaxFields)
+	fieldNames := map[string]bool{}
+	for _, f := range doc.CustomFields {
+		ctx.ReturnBadRequestNowIf(f == nil, "export document has a null custom field")
+		f.Validate()
+		_, exists := newIds[f.Id.String()]
+		ctx.ReturnBadRequestNowIf(exists || fieldNames[f.Name], "export document has duplicate custom fields")
+		fieldNames[f.Name] = true
+		newIds[f.Id.String()] = id.New()
+		plan.customFields = append(plan.customFields, &customfield.Field{Id: newIds[f.Id.String()], Name: f.Name, Type: f.Type, Options: f.Options})
+	}
+
+	newLabels := map[string]id.Id{}
+	for _, l := range doc.Labels {
+		ctx.ReturnBadRequestNowIf(l == nil, "export document has a null label")
+		if label, exists := labels[l.Name]; exists {
+			newLabels[l.Id.String()] = label
+		}
+	}
+
+	root := &importTask{
+		id:          plan.project.Id,
+		isAbstract:  true,
+		name:        plan.project.Name,
+		description: plan.project.Description,
+		createdOn:   plan.project.CreatedOn,
+		isParallel:  plan.project.IsParallel,
+		stateCounts: map[string]uint64{},
+	}
+	plan.tasks = append(make([]*importTask, 0, len(doc.Tasks)+1), root)
+	oldTasks := map[string]*importTask{doc.Project.Id.String(): root}
+	lastChild := map[*importTask]*importTask{}
+	//the stack holds the current tasks ancestors, a depth first list can only step down to a new child or back up the stack
+	stack := []*importTask{root}
+	stackOldIds := []string{doc.Project.Id.String()}
+	for _, et := range doc.Tasks {
+		ctx.ReturnBadRequestNowIf(et == nil, "export document has a null task")
+		_, exists := newIds[et.Id.String()]
+		ctx.ReturnBadRequestNowIf(exists, "export document has duplicate ids")
+		for len(stack) > 0 && stackOldIds[len(stackOldIds)-1] != et.Parent.String() {
+			stack = stack[:len(stack)-1]
+			stackOldIds = stackOldIds[:len(stackOldIds)-1]
+		}
+		ctx.ReturnBadRequestNowIf(len(stack) == 0, "export document tasks are not in depth first order")
+		validate.StringArg("task name", et.Name, taskNameMinRuneCount, taskNameMaxRuneCount, nil)
+		if et.Description != nil {
+			validate.StringArg("task description", *et.Description, 0, taskDescriptionMaxRuneCount, nil)
+		}
+		it := &importTask{
+			id:          id.New(),
+			parent:      stack[len(stack)-1],
+			isAbstract:  et.IsAbstract,
+			name:        et.Name,
+			description: et.Description,
+			createdOn:   et.CreatedOn,
+		}
+		if et.IsAbstract {
+			ctx.ReturnBadRequestNowIf(et.State != nil || et.Member != nil || len(et.Members) > 0 || et.RemainingTime != 0, "abstract tasks can not have a state, members or remainingTime")
+			it.isParallel = et.IsParallel
+			it.stateCounts = map[string]uint64{}
+		} else {
+			ctx.ReturnBadRequestNowIf(et.State == nil, "concrete tasks must have a state")
+			state, exists := oldStates[et.State.String()]
+			ctx.ReturnBadRequestNowIf(!exists, "concrete task has an unknown state")
+			it.state = &state
+			it.totalRemainingTime = et.RemainingTime
+			it.minimumRemainingTime = et.RemainingTime
+			taskMembers := et.Members
+			if doc.Version == 1 {
+				ctx.ReturnBadRequestNowIf(len(et.Members) > 0, "version 1 documents can not have task members")
+				if et.Member != nil {
+					taskMembers = []*ExportTaskMember{{Id: *et.Member, RemainingTime: et.RemainingTime}}
+				}
+			} else {
+				ctx.ReturnBadRequestNowIf(et.Member != nil, "only version 1 documents can have a task member")
+			}
+			//members who can't be assigned tasks in the new project are dropped and their shares left unassigned
+			seenMembers := map[string]bool{}
+			assignedTime := uint64(0)
+			for _, tm := range taskMembers {
+				ctx.ReturnBadRequestNowIf(tm == nil || seenMembers[tm.Id.String()], "export document has a null or duplicate task member")
+				seenMembers[tm.Id.String()] = true
+				assignedTime += tm.RemainingTime
+				if role, exists := members[tm.Id.String()]; exists && role != cnst.ProjectReader {
+					it.members = append(it.members, tm)
+				}
+			}
+			ctx.ReturnBadRequestNowIf(assignedTime > et.RemainingTime, "task members remainingTimes can not add up to more than the tasks remainingTime")
+		}
+		seenLabels := map[string]bool{}
+		for _, l := range et.Labels {
+			if label, exists := newLabels[l.String()]; exists && !seenLabels[label.String()] {
+				seenLabels[label.String()] = true
+				it.labels = append(it.labels, label)
+			}
+		}
+		seenFields := map[string]bool{}
+		for _, v := range et.CustomFieldValues {
+			ctx.ReturnBadRequestNowIf(v == nil || seenFields[v.Field.String()], "export document has a null or duplicate custom field value")
+			seenFields[v.Field.String()] = true
+			field, exists := newIds[v.Field.String()]
+			ctx.ReturnBadRequestNowIf(!exists || customfield.Get(plan.customFields, field) == nil, "custom field value has an unknown custom field")
+			nv := *v
+			nv.Field = field
+			customfield.ValidateValue(plan.customFields, &nv)
+			if !nv.IsEmpty() {
+				it.customFieldValues = append(it.customFieldValues, &nv)
+			}
+		}
+		if prev := lastChild[it.parent]; prev != nil {
+			prev.nextSibling = &it.id
+		} else {
+			it.parent.firstChild = &it.id
+		}
+		lastChild[it.parent] = it
+		newIds[et.Id.String()] = it.id
+		oldTasks[et.Id.String()] = it
+		plan.tasks = append(plan.tasks, it)
+		if it.isAbstract {
+			stack = append(stack, it)
+			stackOldIds = append(stackOldIds, et.Id.String())
+		}
+	}
+
+	//dependencies are only between concrete tasks and can not form a cycle
+	dependsOn := map[*importTask][]*importTask{}
+	for _, et := range doc.Tasks {
+		it := oldTasks[et.Id.String()]
+		seenDependencies := map[string]bool{}
+		for _, d := range et.DependsOn {
+			dt := oldTasks[d.String()]
+			ctx.ReturnBadRequestNowIf(dt == nil || dt == it || dt.isAbstract || it.isAbstract || seenDependencies[d.String()], "export document has an invalid dependency")
+			seenDependencies[d.String()] = true
+			dependsOn[it] = append(dependsOn[it], dt)
+			plan.dependencies = append(plan.dependencies, &importDependency{task: it.id, dependsOn: dt.id, commonAncestor: lowestCommonAncestor(it, dt).id})
+		}
+	}
+	//a depth first walk of the dependencies that reaches a task still on its own path has found a cycle
+	onPath := map[*importTask]bool{}
+	walked := map[*importTask]bool{}
+	var walk func(it *importTask)
+	walk = func(it *importTask) {
+		ctx.ReturnBadRequestNowIf(onPath[it], "export document dependencies can not form a cycle")
+		if walked[it] {
+			return
+		}
+		onPath[it] = true
+		for _, dt := range dependsOn[it] {
+			walk(dt)
+		}
+		onPath[it] = false
+		walked[it] = true
+	}
+	for it := range dependsOn {
+		walk(it)
+	}
+
+	loggedTime := map[string]uint64{}
+	for _, tl := range doc.TimeLogs {
+		ctx.ReturnBadRequestNowIf(tl == nil || tl.Duration == 0, "export document time logs must have a duration")
+		_, exists := newIds[tl.Id.String()]
+		ctx.ReturnBadRequestNowIf(exists, "export document has duplicate ids")
+		newIds[tl.Id.String()] = id.New()
+		ntl := &timelog.TimeLog{
+			Id:                 newIds[tl.Id.String()],
+			Project:            plan.project.Id,
+			Task:               tl.Task,
+			Member:             tl.Member,
+			LoggedOn:           tl.LoggedOn,
+			TaskHasBeenDeleted: true,
+			TaskName:           tl.TaskName,
+			Duration:           tl.Duration,
+			IsBillable:         tl.IsBillable,
+			HourlyRate:         tl.HourlyRate,
+			Cost:               tl.Cost,
+			Note:               tl.Note,
+		}
+		if doc.Version < 3 {
+			ntl.IsBillable = true
+			ntl.HourlyRate = 0
+			ntl.Cost = 0
+		}
+		//logs against tasks that are no longer in the project are kept for the record but not counted in any task
+		if it := oldTasks[tl.Task.String()]; it != nil && !tl.TaskHasBeenDeleted {
+			ctx.ReturnBadRequestNowIf(it.isAbstract, "time logs can only be against concrete tasks")
+			it.totalLoggedTime += tl.Duration
+			it.totalCost += ntl.Cost
+			if ntl.IsBillable {
+				it.totalRevenue += ntl.Cost
+			}
+			ntl.Task = it.id
+			ntl.TaskHasBeenDeleted = false
+		}
+		loggedTime[tl.Member.String()] += tl.Duration
+		plan.timeLogs = append(plan.timeLogs, ntl)
+	}
+
+	remainingTime := map[string]uint64{}
+	//children always come after their parent so walking backwards completes every task before its parent is reached,
+	//minimumRemainingTime ignores dependencies here, importProject recalculates every chain they change
+	for i := len(plan.tasks) - 1; i > 0; i-- {
+		it := plan.tasks[i]
+		p := it.parent
+		if !it.isAbstract {
+			for _, tm := range it.members {
+				remainingTime[tm.Id.String()] += tm.RemainingTime
+			}
+			p.stateCounts[it.state.String()]++
+		} else {
+			for state, count := range it.stateCounts {
+				p.stateCounts[state] += count
+			}
+		}
+		p.totalRemainingTime += it.totalRemainingTime
+		p.totalLoggedTime += it.totalLoggedTime
+		p.totalCost += it.totalCost
+		p.totalRevenue += it.totalRevenue
+		p.childCount++
+		p.descendantCount += it.descendantCount + 1
+		if !p.isParallel {
+			p.minimumRemainingTime += it.minimumRemainingTime
+		} else if it.minimumRemainingTime > p.minimumRemainingTime {
+			p.minimumRemainingTime = it.minimumRemainingTime
+		}
+	}
+
+	for _, mem := range doc.Members {
+		ctx.ReturnBadRequestNowIf(mem == nil, "export document has a null member")
+		role, exists := members[mem.Id.String()]
+		if !exists {
+			continue
+		}
+		plan.members = append(plan.members, &importMember{
+			id:                 mem.Id,
+			isActive:           mem.IsActive,
+			role:               role,
+			totalRemainingTime: remainingTime[mem.Id.String()],
+			totalLoggedTime:    loggedTime[mem.Id.String()],
+		})
+	}
+
+	for _, act := range doc.Activities {
+		ctx.ReturnBadRequestNowIf(act == nil, "export document has a null activity")
+		nact := *act
+		if item, exists := newIds[act.Item.String()]; exists {
+			nact.Item = item
+		} else {
+			//comments, files and deleted tasks are not exported
+			nact.ItemHasBeenDeleted = true
+		}
+		plan.activities = append(plan.activities, &nact)
+	}
+	return plan
+}
+
+// lowestCommonAncestor returns the lowest task with both a and b beneath it, neither can be the ancestor of the other.
+func lowestCommonAncestor(a, b *importTask) *importTask {
+	ancestors := map[*importTask]bool{}
+	for p := a.parent; p != nil; p = p.parent {
+		ancestors[p] = true
+	}
+	p := b.parent
+	for !ancestors[p] {
+		p = p.parent
+	}
+	return p
+}
@@ -47,7 +47,16 @@ func Test_system(t *testing.T) {
 		projRes, err = client.GetSet(base.Ali.CSS, base.Region, 0, base.Org.Id, nil, nil, nil, nil, nil, nil, nil, true, cnst.SortByCreatedOn, true, nil, 100)
 		assert.Equal(t, 1, len(projRes.Projects))
 		assert.False(t, projRes.More)
-		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, Fields{IsArchived: &field.Bool{false}})
+		err = client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, Fields{HoursPerDay: &field.UInt8{7}})
+		assert.NotNil(t, err)
+		_, err = client.CreateState(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, "nope", false)
+		assert.NotNil(t, err)
+		err = client.AddMembers(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, []*AddProjectMember{{Id: base.Cat.Info.Me.Id, Role: cnst.ProjectReader}})
+		assert.NotNil(t, err)
+		err = client.Edit(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, Fields{IsArchived: &field.Bool{false}})
+		assert.NotNil(t, err)
+		err = client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, Fields{IsArchived: &field.Bool{false}})
+		assert.Nil(t, err)
 		aliP := &AddProjectMember{}
 		aliP.Id = base.Ali.Info.Me.Id
 		aliP.Role = cnst.ProjectAdmin
@@ -70,6 +79,101 @@ func Test_system(t *testing.T) {
 		assert.True(t, bobMe.Id.Equal(base.Bob.Info.Me.Id))
 		activities, err := client.GetActivities(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, nil, nil, nil, 100)
 		assert.Equal(t, 10, len(activities))
+		states, err := client.GetStates(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, 4, len(states))
+		assert.Equal(t, "todo", states[0].Name)
+		assert.True(t, states[3].IsDone)
+		blocked, err := client.CreateState(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, "blocked", false)
+		assert.Nil(t, err)
+		_, err = client.CreateState(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, "blocked", false)
+		assert.NotNil(t, err)
+		_, err = client.CreateState(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, "nope", false)
+		assert.NotNil(t, err)
+		err = client.MoveState(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, blocked.Id, 1)
+		assert.Nil(t, err)
+		newName := "stuck"
+		trueVal := true
+		falseVal := false
+		err = client.EditState(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, blocked.Id, &newName, nil)
+		assert.Nil(t, err)
+		err = client.EditState(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, states[3].Id, nil, &falseVal)
+		assert.NotNil(t, err)
+		err = client.EditState(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, states[2].Id, nil, &trueVal)
+		assert.Nil(t, err)
+		err = client.DeleteState(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, states[2].Id, states[0].Id)
+		assert.NotNil(t, err)
+		err = client.DeleteState(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, states[3].Id, states[0].Id)
+		assert.Nil(t, err)
+		states, err = client.GetStates(base.Bob.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Equal(t, 4, len(states))
+		assert.Equal(t, "stuck", states[1].Name)
+		assert.True(t, states[3].IsDone)
+		risk, err := client.CreateCustomField(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, "risk", cnst.CustomFieldSelect, []string{"low", "high"})
+		assert.Nil(t, err)
+		_, err = client.CreateCustomField(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, "risk", cnst.CustomFieldText, nil)
+		assert.NotNil(t, err)
+		_, err = client.CreateCustomField(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, "customer", cnst.CustomFieldText, []string{"acme"})
+		assert.NotNil(t, err)
+		_, err = client.CreateCustomField(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, "nope", cnst.CustomFieldNumber, nil)
+		assert.NotNil(t, err)
+		estimate, err := client.CreateCustomField(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, "estimate", cnst.CustomFieldNumber, nil)
+		assert.Nil(t, err)
+		err = client.EditCustomField(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, risk.Id, nil, []string{"low", "medium", "high"})
+		assert.Nil(t, err)
+		err = client.EditCustomField(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, estimate.Id, nil, []string{"1"})
+		assert.NotNil(t, err)
+		err = client.DeleteCustomField(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, estimate.Id)
+		assert.Nil(t, err)
+		customFields, err := client.GetCustomFields(base.Bob.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(customFields))
+		assert.Equal(t, cnst.CustomFieldSelect, customFields[0].Type)
+		assert.Equal(t, []string{"low", "medium", "high"}, customFields[0].Options)
+		tpl, err := client.SaveAsTemplate(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, "tpl", true)
+		assert.Nil(t, err)
+		assert.True(t, tpl.IsTemplate)
+		_, err = client.SaveAsTemplate(base.Bob.CSS, base.Region, 0, base.Org.Id, proj.Id, "nope", true)
+		assert.NotNil(t, err)
+		tplRes, err := client.GetTemplates(base.Ali.CSS, base.Region, 0, base.Org.Id, nil, nil, 100)
+		assert.Equal(t, 1, len(tplRes.Projects))
+		assert.True(t, tplRes.Projects[0].Id.Equal(tpl.Id))
+		projRes, err = client.GetSet(base.Ali.CSS, base.Region, 0, base.Org.Id, nil, nil, nil, nil, nil, nil, nil, false, cnst.SortByCreatedOn, true, nil, 100)
+		assert.Equal(t, 1, len(projRes.Projects))
+		_, err = client.CreateFromTemplate(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, "nope", nil, nil, nil, false, nil)
+		assert.NotNil(t, err)
+		fromTpl, err := client.CreateFromTemplate(base.Ali.CSS, base.Region, 0, base.Org.Id, tpl.Id, "fromTpl", nil, nil, nil, false, nil)
+		assert.Nil(t, err)
+		assert.False(t, fromTpl.IsTemplate)
+		assert.Equal(t, proj.HoursPerDay, fromTpl.HoursPerDay)
+		states, err = client.GetStates(base.Ali.CSS, base.Region, 0, base.Org.Id, fromTpl.Id)
+		assert.Equal(t, 4, len(states))
+		assert.Equal(t, "stuck", states[1].Name)
+		client.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, fromTpl.Id)
+		_, err = client.Export(base.Bob.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.NotNil(t, err)
+		exp, err := client.Export(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, proj.Name, exp.Project.Name)
+		assert.Equal(t, 4, len(exp.States))
+		assert.Equal(t, 1, len(exp.CustomFields))
+		imported, err := client.Import(base.Ali.CSS, base.Region, 0, base.Org.Id, exp)
+		assert.Nil(t, err)
+		assert.False(t, imported.Id.Equal(proj.Id))
+		assert.Equal(t, proj.Name, imported.Name)
+		states, err = client.GetStates(base.Ali.CSS, base.Region, 0, base.Org.Id, imported.Id)
+		assert.Equal(t, 4, len(states))
+		assert.Equal(t, "stuck", states[1].Name)
+		customFields, err = client.GetCustomFields(base.Ali.CSS, base.Region, 0, base.Org.Id, imported.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(customFields))
+		assert.False(t, customFields[0].Id.Equal(risk.Id))
+		assert.Equal(t, []string{"low", "medium", "high"}, customFields[0].Options)
+		exp.Version = 0
+		_, err = client.Import(base.Ali.CSS, base.Region, 0, base.Org.Id, exp)
+		assert.NotNil(t, err)
+		client.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, imported.Id)
+		client.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, tpl.Id)
 		client.RemoveMembers(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, []id.Id{base.Bob.Info.Me.Id, base.Cat.Info.Me.Id})
 		client.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
 	}, account.Endpoints, Endpoints)
@@ -0,0 +1,79 @@
+package recurrence
+
+import (
+	"github.com/0xor1/trees/server/util/clientsession"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/recur"
+	"time"
+)
+
+type Client interface {
+	Create(css *clientsession.Store, region cnst.Region, shard int, account, project, parent id.Id, name string, description *string, totalRemainingTime uint64, members []*Member, rule recur.Rule, startOn *time.Time) (*Recurrence, error)
+	Edit(css *clientsession.Store, region cnst.Region, shard int, account, project, recurrence id.Id, fields Fields) error
+	Delete(css *clientsession.Store, region cnst.Region, shard int, account, project, recurrence id.Id) error
+	Get(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, parent *id.Id) ([]*Recurrence, error)
+}
+
+func NewClient(host string) Client {
+	return &client{
+		host: host,
+	}
+}
+
+type client struct {
+	host string
+}
+
+func (c *client) Create(css *clientsession.Store, region cnst.Region, shard int, account, project, parent id.Id, name string, description *string, totalRemainingTime uint64, members []*Member, rule recur.Rule, startOn *time.Time) (*Recurrence, error) {
+	val, e := create.DoRequest(css, c.host, region, &createArgs{
+		Shard:              shard,
+		Account:            account,
+		Project:            project,
+		Parent:             parent,
+		Name:               name,
+		Description:        description,
+		TotalRemainingTime: totalRemainingTime,
+		Members:            members,
+		Rule:               rule,
+		StartOn:            startOn,
+	}, nil, &Recurrence{})
+	if val != nil {
+		return val.(*Recurrence), e
+	}
+	return nil, e
+}
+
+func (c *client) Edit(css *clientsession.Store, region cnst.Region, shard int, account, project, recurrence id.Id, fields Fields) error {
+	_, e := edit.DoRequest(css, c.host, region, &editArgs{
+		Shard:      shard,
+		Account:    account,
+		Project:    project,
+		Recurrence: recurrence,
+		Fields:     fields,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) Delete(css *clientsession.Store, region cnst.Region, shard int, account, project, recurrence id.Id) error {
+	_, e := delete.DoRequest(css, c.host, region, &deleteArgs{
+		Shard:      shard,
+		Account:    account,
+		Project:    project,
+		Recurrence: recurrence,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) Get(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, parent *id.Id) ([]*Recurrence, error) {
+	val, e := get.DoRequest(css, c.host, region, &getArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Parent:  parent,
+	}, nil, &[]*Recurrence{})
+	if val != nil {
+		return *val.(*[]*Recurrence), e
+	}
+	return nil, e
+}
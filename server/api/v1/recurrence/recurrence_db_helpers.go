@@ -0,0 +1,116 @@
+package recurrence
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/cachekey"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/db"
+	"github.com/0xor1/trees/server/util/err"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/recur"
+)
+
+//recurrences are moved on by the scheduler which has no ctx to touch dlms with, so nothing in this package is cached
+
+func dbGetDaysPerWeek(ctx ctx.Ctx, shard int, account, project id.Id) uint8 {
+	daysPerWeek := uint8(0)
+	row := ctx.TreeQueryRow(shard, `SELECT daysPerWeek FROM projects WHERE account=? AND id=?`, account, project)
+	ctx.ReturnBadRequestNowIf(err.IsSqlErrNoRowsElsePanicIf(row.Scan(&daysPerWeek)), "no such project")
+	return daysPerWeek
+}
+
+func dbCreateRecurrence(ctx ctx.Ctx, shard int, account, project id.Id, r *Recurrence) {
+	db.MakeChangeHelper(ctx, shard, `CALL createRecurrence(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, account, project, r.Id, r.Parent, ctx.Me(), r.Name, r.Description, r.TotalRemainingTime, r.Rule.Frequency.String(), r.Rule.WeekDaysMask(), r.Rule.MonthDay, r.nextOccurrenceOn, r.NextRunOn, r.CreatedOn, membersStr(r.Members))
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectActivities(account, project))
+}
+
+func dbEditRecurrence(ctx ctx.Ctx, shard int, account, project id.Id, r *Recurrence) {
+	db.MakeChangeHelper(ctx, shard, `CALL editRecurrence(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, account, project, r.Id, ctx.Me(), r.Name, r.Description, r.TotalRemainingTime, r.Rule.Frequency.String(), r.Rule.WeekDaysMask(), r.Rule.MonthDay, r.IsActive, r.nextOccurrenceOn, r.NextRunOn, membersStr(r.Members))
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectActivities(account, project))
+}
+
+func dbDeleteRecurrence(ctx ctx.Ctx, shard int, account, project, recurrence id.Id) {
+	db.MakeChangeHelper(ctx, shard, `CALL deleteRecurrence(?, ?, ?, ?)`, account, project, recurrence, ctx.Me())
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectActivities(account, project))
+}
+
+func dbGetRecurrence(ctx ctx.Ctx, shard int, account, project, recurrence id.Id) *Recurrence {
+	row := ctx.TreeQueryRow(shard, `SELECT id, parent, name, description, totalRemainingTime, frequency, weekDays, monthDay, isActive, nextOccurrenceOn, nextRunOn, createdBy, createdOn FROM recurrences WHERE account=? AND project=? AND id=?`, account, project, recurrence)
+	r, e := scanRecurrence(row)
+	ctx.ReturnBadRequestNowIf(err.IsSqlErrNoRowsElsePanicIf(e), "no such recurrence")
+	dbPopulateMembers(ctx, shard, account, project, []*Recurrence{r})
+	return r
+}
+
+func dbGetRecurrences(ctx ctx.Ctx, shard int, account, project id.Id, parent *id.Id) []*Recurrence {
+	query := bytes.NewBufferString(`SELECT id, parent, name, description, totalRemainingTime, frequency, weekDays, monthDay, isActive, nextOccurrenceOn, nextRunOn, createdBy, createdOn FROM recurrences WHERE account=? AND project=?`)
+	args := make([]interface{}, 0, 3)
+	args = append(args, account, project)
+	if parent != nil {
+		query.WriteString(` AND parent=?`)
+		args = append(args, *parent)
+	}
+	query.WriteString(` ORDER BY createdOn ASC, id ASC`)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	res := make([]*Recurrence, 0, 20)
+	for rows.Next() {
+		r, e := scanRecurrence(rows)
+		panic.IfNotNil(e)
+		res = append(res, r)
+	}
+	dbPopulateMembers(ctx, shard, account, project, res)
+	return res
+}
+
+func dbPopulateMembers(ctx ctx.Ctx, shard int, account, project id.Id, recurrences []*Recurrence) {
+	if len(recurrences) == 0 {
+		return
+	}
+	idx := map[string]*Recurrence{}
+	for _, r := range recurrences {
+		idx[r.Id.String()] = r
+	}
+	rows, e := ctx.TreeQuery(shard, `SELECT recurrence, member, remainingTime FROM recurrenceMembers WHERE account=? AND project=? ORDER BY remainingTime DESC, member ASC`, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var recurrence id.Id
+		m := Member{}
+		panic.IfNotNil(rows.Scan(&recurrence, &m.Id, &m.RemainingTime))
+		if r := idx[recurrence.String()]; r != nil {
+			r.Members = append(r.Members, &m)
+		}
+	}
+}
+
+func scanRecurrence(row interface {
+	Scan(...interface{}) error
+}) (*Recurrence, error) {
+	r := Recurrence{Members: []*Member{}}
+	var frequency cnst.RecurrenceFrequency
+	var weekDays, monthDay uint8
+	if e := row.Scan(&r.Id, &r.Parent, &r.Name, &r.Description, &r.TotalRemainingTime, &frequency, &weekDays, &monthDay, &r.IsActive, &r.nextOccurrenceOn, &r.NextRunOn, &r.CreatedBy, &r.CreatedOn); e != nil {
+		return nil, e
+	}
+	r.Rule = *recur.NewRule(frequency, weekDays, monthDay)
+	return &r, nil
+}
+
+// each members 32 hex char id followed by their 16 hex char remaining time, the format _setRecurrenceMembers takes
+func membersStr(members []*Member) string {
+	buf := bytes.NewBufferString(``)
+	for _, m := range members {
+		buf.WriteString(id.ToHexString([]id.Id{m.Id}))
+		buf.WriteString(fmt.Sprintf("%016x", m.RemainingTime))
+	}
+	return buf.String()
+}
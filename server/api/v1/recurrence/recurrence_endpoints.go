@@ -0,0 +1,258 @@
+package recurrence
+
+import (
+	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/db"
+	"github.com/0xor1/trees/server/util/endpoint"
+	"github.com/0xor1/trees/server/util/field"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/recur"
+	t "github.com/0xor1/trees/server/util/time"
+	"github.com/0xor1/trees/server/util/validate"
+	"time"
+)
+
+const (
+	nameMinRuneCount        = 1
+	nameMaxRuneCount        = 250
+	descriptionMaxRuneCount = 1250
+	maxMembers              = 60
+)
+
+type createArgs struct {
+	Shard              int        `json:"shard"`
+	Account            id.Id      `json:"account"`
+	Project            id.Id      `json:"project"`
+	Parent             id.Id      `json:"parent"`
+	Name               string     `json:"name"`
+	Description        *string    `json:"description,omitempty"`
+	TotalRemainingTime uint64     `json:"totalRemainingTime"`
+	Members            []*Member  `json:"members,omitempty"`
+	Rule               recur.Rule `json:"rule"`
+	StartOn            *time.Time `json:"startOn,omitempty"`
+}
+
+var create = &endpoint.Endpoint{
+	Path:                     "/api/v1/recurrence/create",
+	Note:                     "creates a concrete task under parent on each occurrence of rule, starting from startOn or today, occurrences that fall outside the projects working days are created on the next working day",
+	RequiresSession:          true,
+	ExampleResponseStructure: &Recurrence{},
+	GetArgsStruct: func() interface{} {
+		return &createArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*createArgs)
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		if args.Members == nil {
+			args.Members = []*Member{}
+		}
+		r := &Recurrence{
+			Id:                 id.New(),
+			Parent:             args.Parent,
+			Name:               args.Name,
+			Description:        args.Description,
+			TotalRemainingTime: args.TotalRemainingTime,
+			Members:            args.Members,
+			Rule:               args.Rule,
+			IsActive:           true,
+			CreatedBy:          ctx.Me(),
+			CreatedOn:          t.Now(),
+		}
+		validateRecurrence(ctx, args.Shard, args.Account, args.Project, r)
+		schedule(r, startFrom(args.StartOn, false), dbGetDaysPerWeek(ctx, args.Shard, args.Account, args.Project))
+		dbCreateRecurrence(ctx, args.Shard, args.Account, args.Project, r)
+		return r
+	},
+}
+
+type editArgs struct {
+	Shard      int    `json:"shard"`
+	Account    id.Id  `json:"account"`
+	Project    id.Id  `json:"project"`
+	Recurrence id.Id  `json:"recurrence"`
+	Fields     Fields `json:"fields"`
+}
+
+var edit = &endpoint.Endpoint{
+	Path:            "/api/v1/recurrence/edit",
+	Note:            "setting isActive false stops the recurrence, changing its rule, startOn or restarting it reschedules it from startOn or else tomorrow",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &editArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*editArgs)
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		r := dbGetRecurrence(ctx, args.Shard, args.Account, args.Project, args.Recurrence)
+		reschedule := false
+		if args.Fields.Name != nil {
+			r.Name = args.Fields.Name.Val
+		}
+		if args.Fields.Description != nil {
+			r.Description = args.Fields.Description.Val
+		}
+		if args.Fields.TotalRemainingTime != nil {
+			r.TotalRemainingTime = args.Fields.TotalRemainingTime.Val
+		}
+		if args.Fields.Members != nil {
+			r.Members = args.Fields.Members.Val
+			if r.Members == nil {
+				r.Members = []*Member{}
+			}
+		}
+		if args.Fields.Rule != nil {
+			r.Rule = args.Fields.Rule.Val
+			reschedule = true
+		}
+		if args.Fields.StartOn != nil {
+			reschedule = true
+		}
+		if args.Fields.IsActive != nil {
+			reschedule = reschedule || (args.Fields.IsActive.Val && !r.IsActive)
+			r.IsActive = args.Fields.IsActive.Val
+		}
+		validateRecurrence(ctx, args.Shard, args.Account, args.Project, r)
+		if !r.IsActive {
+			r.nextOccurrenceOn = nil
+			r.NextRunOn = nil
+		} else if reschedule {
+			var startOn *time.Time
+			if args.Fields.StartOn != nil {
+				startOn = &args.Fields.StartOn.Val
+			}
+			schedule(r, startFrom(startOn, true), dbGetDaysPerWeek(ctx, args.Shard, args.Account, args.Project))
+		}
+		dbEditRecurrence(ctx, args.Shard, args.Account, args.Project, r)
+		return nil
+	},
+}
+
+type deleteArgs struct {
+	Shard      int   `json:"shard"`
+	Account    id.Id `json:"account"`
+	Project    id.Id `json:"project"`
+	Recurrence id.Id `json:"recurrence"`
+}
+
+var delete = &endpoint.Endpoint{
+	Path:            "/api/v1/recurrence/delete",
+	Note:            "tasks already created by the recurrence are left in place",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &deleteArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*deleteArgs)
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		dbDeleteRecurrence(ctx, args.Shard, args.Account, args.Project, args.Recurrence)
+		return nil
+	},
+}
+
+type getArgs struct {
+	Shard   int    `json:"shard"`
+	Account id.Id  `json:"account"`
+	Project id.Id  `json:"project"`
+	Parent  *id.Id `json:"parent,omitempty"`
+}
+
+var get = &endpoint.Endpoint{
+	Path:                     "/api/v1/recurrence/get",
+	Note:                     "pass a parent to get only the recurrences that create tasks under it",
+	RequiresSession:          false,
+	ExampleResponseStructure: []*Recurrence{{}},
+	GetArgsStruct: func() interface{} {
+		return &getArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		return dbGetRecurrences(ctx, args.Shard, args.Account, args.Project, args.Parent)
+	},
+}
+
+var Endpoints = []*endpoint.Endpoint{
+	create,
+	edit,
+	delete,
+	get,
+}
+
+type Recurrence struct {
+	Id                 id.Id      `json:"id"`
+	Parent             id.Id      `json:"parent"`
+	Name               string     `json:"name"`
+	Description        *string    `json:"description"`
+	TotalRemainingTime uint64     `json:"totalRemainingTime"`
+	Members            []*Member  `json:"members"` //each members share of totalRemainingTime on the created tasks, any remainder is unassigned
+	Rule               recur.Rule `json:"rule"`
+	IsActive           bool       `json:"isActive"`
+	NextRunOn          *time.Time `json:"nextRunOn,omitempty"` //only active recurrences have a next run
+	CreatedBy          id.Id      `json:"createdBy"`
+	CreatedOn          time.Time  `json:"createdOn"`
+	nextOccurrenceOn   *time.Time
+}
+
+type Member struct {
+	Id            id.Id  `json:"id"`
+	RemainingTime uint64 `json:"remainingTime"`
+}
+
+type Fields struct {
+	Name               *field.String    `json:"name,omitempty"`
+	Description        *field.StringPtr `json:"description,omitempty"`
+	TotalRemainingTime *field.UInt64    `json:"totalRemainingTime,omitempty"`
+	Members            *MembersField    `json:"members,omitempty"` //replaces all of the members
+	Rule               *RuleField       `json:"rule,omitempty"`
+	StartOn            *field.Time      `json:"startOn,omitempty"`
+	IsActive           *field.Bool      `json:"isActive,omitempty"`
+}
+
+type MembersField struct {
+	Val []*Member `json:"val"`
+}
+
+type RuleField struct {
+	Val recur.Rule `json:"val"`
+}
+
+func validateRecurrence(ctx ctx.Ctx, shard int, account, project id.Id, r *Recurrence) {
+	validate.StringArg("name", r.Name, nameMinRuneCount, nameMaxRuneCount, nil)
+	if r.Description != nil {
+		validate.StringArg("description", *r.Description, 0, descriptionMaxRuneCount, nil)
+	}
+	r.Rule.Validate()
+	ctx.ReturnBadRequestNowIf(len(r.Members) > maxMembers, "too many members")
+	assignedTime := uint64(0)
+	seen := map[string]bool{}
+	for _, m := range r.Members {
+		ctx.ReturnBadRequestNowIf(seen[m.Id.String()], "duplicate member")
+		seen[m.Id.String()] = true
+		validate.MemberIsAProjectMemberWithWriteAccess(db.GetProjectRole(ctx, shard, account, project, m.Id))
+		assignedTime += m.RemainingTime
+	}
+	ctx.ReturnBadRequestNowIf(assignedTime > r.TotalRemainingTime, "members remaining time is greater than totalRemainingTime")
+}
+
+// startFrom returns the day a recurrence is scheduled from, never earlier than today, or tomorrow when rescheduling so a
+// run that has already happened today is not repeated.
+func startFrom(startOn *time.Time, rescheduling bool) time.Time {
+	earliest := t.Now()
+	if rescheduling {
+		earliest = earliest.AddDate(0, 0, 1)
+	}
+	if startOn != nil && startOn.After(earliest) {
+		return *startOn
+	}
+	return earliest
+}
+
+func schedule(r *Recurrence, from time.Time, daysPerWeek uint8) {
+	occurrence := r.Rule.First(from)
+	runOn := recur.RunOn(occurrence, daysPerWeek)
+	r.nextOccurrenceOn = &occurrence
+	r.NextRunOn = &runOn
+}
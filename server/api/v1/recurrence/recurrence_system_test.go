@@ -0,0 +1,113 @@
+package recurrence
+
+import (
+	"github.com/0xor1/trees/server/api/v1/project"
+	"github.com/0xor1/trees/server/api/v1/task"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/field"
+	"github.com/0xor1/trees/server/util/recur"
+	"github.com/0xor1/trees/server/util/systemtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_system(t *testing.T) {
+	systemtest.Run(t, func(base *systemtest.Base) {
+		projectClient := project.NewClient(base.TestServerURL)
+		taskClient := task.NewClient(base.TestServerURL)
+		client := NewClient(base.TestServerURL)
+
+		start := time.Now()
+		end := start.Add(5 * 24 * time.Hour)
+		desc := "desc"
+		proj, err := projectClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, "proj", &desc, 8, 7, &start, &end, false, false, []*project.AddProjectMember{{Id: base.Ali.Info.Me.Id, Role: cnst.ProjectAdmin}, {Id: base.Cat.Info.Me.Id, Role: cnst.ProjectWriter}, {Id: base.Dan.Info.Me.Id, Role: cnst.ProjectReader}})
+		falseVal := false
+		oneVal := uint64(1)
+		chores, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, "chores", &desc, true, &falseVal, nil, nil)
+		concrete, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, &chores.Id, "concrete", &desc, false, nil, nil, &oneVal)
+
+		daily := recur.Rule{Frequency: cnst.RecurrenceDaily}
+		members := []*Member{{Id: base.Ali.Info.Me.Id, RemainingTime: 2}, {Id: base.Cat.Info.Me.Id, RemainingTime: 1}}
+		_, err = client.Create(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, chores.Id, "backups", nil, 4, nil, daily, nil)
+		assert.NotNil(t, err)
+		_, err = client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, concrete.Id, "backups", nil, 4, nil, daily, nil)
+		assert.NotNil(t, err)
+		_, err = client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, chores.Id, "backups", nil, 2, members, daily, nil)
+		assert.NotNil(t, err)
+		_, err = client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, chores.Id, "backups", nil, 4, []*Member{{Id: base.Dan.Info.Me.Id, RemainingTime: 1}}, daily, nil)
+		assert.NotNil(t, err)
+		_, err = client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, chores.Id, "backups", nil, 4, nil, recur.Rule{Frequency: cnst.RecurrenceWeekly}, nil)
+		assert.NotNil(t, err)
+
+		backups, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, chores.Id, "backups", &desc, 4, members, daily, nil)
+		assert.Nil(t, err)
+		assert.True(t, backups.IsActive)
+		assert.Equal(t, 2, len(backups.Members))
+		today := recur.RunOn(time.Now(), 7)
+		assert.True(t, today.Equal(*backups.NextRunOn))
+		monthly, err := client.Create(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, "invoices", nil, 0, nil, recur.Rule{Frequency: cnst.RecurrenceMonthly, MonthDay: 31}, nil)
+		assert.Nil(t, err)
+		recurrences, err := client.Get(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(recurrences))
+		recurrences, err = client.Get(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, &chores.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(recurrences))
+		assert.True(t, backups.Id.Equal(recurrences[0].Id))
+		assert.Equal(t, 2, len(recurrences[0].Members))
+
+		base.SR.Scheduler.Process(0, time.Now().UTC())
+		children, err := taskClient.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, chores.Id, nil, 100, nil, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(children.Children))
+		assert.Equal(t, "backups", children.Children[0].Name)
+		assert.Equal(t, uint64(4), children.Children[0].TotalRemainingTime)
+		assert.Equal(t, 2, len(children.Children[0].Members))
+		recurrences, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, &chores.Id)
+		assert.True(t, today.AddDate(0, 0, 1).Equal(*recurrences[0].NextRunOn))
+
+		//nothing else is due until tomorrow, and runs missed before now are skipped
+		assert.Equal(t, 0, base.SR.Scheduler.Process(0, time.Now().UTC()))
+		base.SR.Scheduler.Process(0, time.Now().UTC().AddDate(0, 0, 3))
+		children, err = taskClient.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, chores.Id, nil, 100, nil, nil)
+		assert.Equal(t, 2, len(children.Children))
+		recurrences, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, &chores.Id)
+		assert.True(t, today.AddDate(0, 0, 4).Equal(*recurrences[0].NextRunOn))
+
+		newName := "nightly backups"
+		err = client.Edit(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, backups.Id, Fields{Name: &field.String{newName}, Members: &MembersField{[]*Member{{Id: base.Cat.Info.Me.Id, RemainingTime: 4}}}})
+		assert.Nil(t, err)
+		err = client.Edit(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, backups.Id, Fields{TotalRemainingTime: &field.UInt64{3}})
+		assert.NotNil(t, err)
+		err = client.Edit(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, backups.Id, Fields{IsActive: &field.Bool{false}})
+		assert.NotNil(t, err)
+		err = client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, backups.Id, Fields{IsActive: &field.Bool{false}})
+		assert.Nil(t, err)
+		recurrences, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, &chores.Id)
+		assert.Equal(t, newName, recurrences[0].Name)
+		assert.False(t, recurrences[0].IsActive)
+		assert.Nil(t, recurrences[0].NextRunOn)
+		assert.Equal(t, 1, len(recurrences[0].Members))
+		base.SR.Scheduler.Process(0, time.Now().UTC().AddDate(0, 0, 10))
+		children, err = taskClient.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, chores.Id, nil, 100, nil, nil)
+		assert.Equal(t, 2, len(children.Children))
+
+		//deleting the parent stops its recurrences on their next run
+		err = client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, backups.Id, Fields{IsActive: &field.Bool{true}})
+		assert.Nil(t, err)
+		err = taskClient.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, chores.Id)
+		assert.Nil(t, err)
+		base.SR.Scheduler.Process(0, time.Now().UTC().AddDate(0, 0, 2))
+		recurrences, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, &chores.Id)
+		assert.False(t, recurrences[0].IsActive)
+
+		err = client.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, backups.Id)
+		assert.Nil(t, err)
+		err = client.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, monthly.Id)
+		assert.Nil(t, err)
+		recurrences, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(recurrences))
+	})
+}
@@ -3,17 +3,40 @@ package task
 import (
 	"github.com/0xor1/trees/server/util/clientsession"
 	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/customfield"
 	"github.com/0xor1/trees/server/util/id"
+	"time"
 )
 
 type Client interface {
 	Create(css *clientsession.Store, region cnst.Region, shard int, account, project, parent id.Id, previousSibling *id.Id, name string, description *string, isAbstract bool, isParallel *bool, member *id.Id, remainingTime *uint64) (*Task, error)
 	Edit(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, fields Fields) error
+	AddMember(css *clientsession.Store, region cnst.Region, shard int, account, project, task, member id.Id, remainingTime *uint64) error
+	RemoveMember(css *clientsession.Store, region cnst.Region, shard int, account, project, task, member id.Id) error
+	SetMemberRemainingTime(css *clientsession.Store, region cnst.Region, shard int, account, project, task, member id.Id, remainingTime uint64) error
+	BulkSetMember(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, tasks []id.Id, member *id.Id) error
 	Move(css *clientsession.Store, region cnst.Region, shard int, account, project, task, parent id.Id, nextSibling *id.Id) error
+	BulkMove(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, tasks []id.Id, newParent id.Id, newPreviousSibling *id.Id) error
+	Copy(css *clientsession.Store, region cnst.Region, shard int, account, sourceProject, task, project, newParent id.Id, newPreviousSibling *id.Id, copyEstimates, copyMembers bool) (*Task, error)
+	Import(css *clientsession.Store, region cnst.Region, shard int, account, project, parent id.Id, previousSibling *id.Id, format cnst.ImportFormat, data string) (*ImportResp, error)
 	Delete(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id) error
+	BulkDelete(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, tasks []id.Id) error
+	GetTrash(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, after *id.Id, limit int) (*GetTrashResp, error)
+	Restore(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, newParent, newPreviousSibling *id.Id) error
 	Get(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, task id.Id) (*Task, error)
-	GetChildren(css *clientsession.Store, region cnst.Region, shard int, account, project, parent id.Id, fromSibling *id.Id, limit int) (*GetChildrenResp, error)
+	GetChildren(css *clientsession.Store, region cnst.Region, shard int, account, project, parent id.Id, fromSibling *id.Id, limit int, labels []id.Id, customFieldFilters []*customfield.Value) (*GetChildrenResp, error)
 	GetAncestors(css *clientsession.Store, region cnst.Region, shard int, account, project, child id.Id, limit int) (*GetAncestorsResp, error)
+	GetSubtree(css *clientsession.Store, region cnst.Region, shard int, account, project, root id.Id, depth int, after *id.Id, limit int, customFieldFilters []*customfield.Value) (*GetSubtreeResp, error)
+	GetByLabels(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, labels []id.Id, after *id.Id, limit int) (*GetChildrenResp, error)
+	Search(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, query string, offset, limit int) (*SearchResp, error)
+	AddLabels(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, labels []id.Id) error
+	RemoveLabels(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, labels []id.Id) error
+	AddDependency(css *clientsession.Store, region cnst.Region, shard int, account, project, task, dependsOn id.Id) error
+	RemoveDependency(css *clientsession.Store, region cnst.Region, shard int, account, project, task, dependsOn id.Id) error
+	GetDependencies(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id) (*GetDependenciesResp, error)
+	GetCriticalPath(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) (*GetCriticalPathResp, error)
+	GetForecast(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) (*GetForecastResp, error)
+	GetBurndown(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, from, to time.Time) (*GetBurndownResp, error)
 }
 
 func NewClient(host string) Client {
@@ -57,6 +80,52 @@ func (c *client) Edit(css *clientsession.Store, region cnst.Region, shard int, a
 	return e
 }
 
+func (c *client) AddMember(css *clientsession.Store, region cnst.Region, shard int, account, project, task, member id.Id, remainingTime *uint64) error {
+	_, e := addMember.DoRequest(css, c.host, region, &addMemberArgs{
+		Shard:         shard,
+		Account:       account,
+		Project:       project,
+		Task:          task,
+		Member:        member,
+		RemainingTime: remainingTime,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) RemoveMember(css *clientsession.Store, region cnst.Region, shard int, account, project, task, member id.Id) error {
+	_, e := removeMember.DoRequest(css, c.host, region, &removeMemberArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Task:    task,
+		Member:  member,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) SetMemberRemainingTime(css *clientsession.Store, region cnst.Region, shard int, account, project, task, member id.Id, remainingTime uint64) error {
+	_, e := setMemberRemainingTime.DoRequest(css, c.host, region, &setMemberRemainingTimeArgs{
+		Shard:         shard,
+		Account:       account,
+		Project:       project,
+		Task:          task,
+		Member:        member,
+		RemainingTime: remainingTime,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) BulkSetMember(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, tasks []id.Id, member *id.Id) error {
+	_, e := bulkSetMember.DoRequest(css, c.host, region, &bulkSetMemberArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Tasks:   tasks,
+		Member:  member,
+	}, nil, nil)
+	return e
+}
+
 func (c *client) Move(css *clientsession.Store, region cnst.Region, shard int, account, project, task, newParent id.Id, newPreviousSibling *id.Id) error {
 	_, e := move.DoRequest(css, c.host, region, &moveArgs{
 		Shard:              shard,
@@ -69,6 +138,52 @@ func (c *client) Move(css *clientsession.Store, region cnst.Region, shard int, a
 	return e
 }
 
+func (c *client) BulkMove(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, tasks []id.Id, newParent id.Id, newPreviousSibling *id.Id) error {
+	_, e := bulkMove.DoRequest(css, c.host, region, &bulkMoveArgs{
+		Shard:              shard,
+		Account:            account,
+		Project:            project,
+		Tasks:              tasks,
+		NewParent:          newParent,
+		NewPreviousSibling: newPreviousSibling,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) Copy(css *clientsession.Store, region cnst.Region, shard int, account, sourceProject, task, project, newParent id.Id, newPreviousSibling *id.Id, copyEstimates, copyMembers bool) (*Task, error) {
+	val, e := copyTask.DoRequest(css, c.host, region, &copyArgs{
+		Shard:              shard,
+		Account:            account,
+		SourceProject:      sourceProject,
+		Task:               task,
+		Project:            project,
+		NewParent:          newParent,
+		NewPreviousSibling: newPreviousSibling,
+		CopyEstimates:      copyEstimates,
+		CopyMembers:        copyMembers,
+	}, nil, &Task{})
+	if val != nil {
+		return val.(*Task), e
+	}
+	return nil, e
+}
+
+func (c *client) Import(css *clientsession.Store, region cnst.Region, shard int, account, project, parent id.Id, previousSibling *id.Id, format cnst.ImportFormat, data string) (*ImportResp, error) {
+	val, e := importTasks.DoRequest(css, c.host, region, &importArgs{
+		Shard:           shard,
+		Account:         account,
+		Project:         project,
+		Parent:          parent,
+		PreviousSibling: previousSibling,
+		Format:          format,
+		Data:            data,
+	}, nil, &ImportResp{})
+	if val != nil {
+		return val.(*ImportResp), e
+	}
+	return nil, e
+}
+
 func (c *client) Delete(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id) error {
 	_, e := delete.DoRequest(css, c.host, region, &deleteArgs{
 		Shard:   shard,
@@ -79,6 +194,42 @@ func (c *client) Delete(css *clientsession.Store, region cnst.Region, shard int,
 	return e
 }
 
+func (c *client) BulkDelete(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, tasks []id.Id) error {
+	_, e := bulkDelete.DoRequest(css, c.host, region, &bulkDeleteArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Tasks:   tasks,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) GetTrash(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, after *id.Id, limit int) (*GetTrashResp, error) {
+	val, e := getTrash.DoRequest(css, c.host, region, &getTrashArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		After:   after,
+		Limit:   limit,
+	}, nil, &GetTrashResp{})
+	if val != nil {
+		return val.(*GetTrashResp), e
+	}
+	return nil, e
+}
+
+func (c *client) Restore(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, newParent, newPreviousSibling *id.Id) error {
+	_, e := restore.DoRequest(css, c.host, region, &restoreArgs{
+		Shard:              shard,
+		Account:            account,
+		Project:            project,
+		Task:               task,
+		NewParent:          newParent,
+		NewPreviousSibling: newPreviousSibling,
+	}, nil, nil)
+	return e
+}
+
 func (c *client) Get(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, task id.Id) (*Task, error) {
 	val, e := get.DoRequest(css, c.host, region, &getArgs{
 		Shard:   shard,
@@ -92,14 +243,16 @@ func (c *client) Get(css *clientsession.Store, region cnst.Region, shard int, ac
 	return nil, e
 }
 
-func (c *client) GetChildren(css *clientsession.Store, region cnst.Region, shard int, account, project, parent id.Id, fromSibling *id.Id, limit int) (*GetChildrenResp, error) {
+func (c *client) GetChildren(css *clientsession.Store, region cnst.Region, shard int, account, project, parent id.Id, fromSibling *id.Id, limit int, labels []id.Id, customFieldFilters []*customfield.Value) (*GetChildrenResp, error) {
 	val, e := getChildren.DoRequest(css, c.host, region, &getChildrenArgs{
-		Shard:       shard,
-		Account:     account,
-		Project:     project,
-		Parent:      parent,
-		FromSibling: fromSibling,
-		Limit:       limit,
+		Shard:              shard,
+		Account:            account,
+		Project:            project,
+		Parent:             parent,
+		FromSibling:        fromSibling,
+		Limit:              limit,
+		Labels:             labels,
+		CustomFieldFilters: customFieldFilters,
 	}, nil, &GetChildrenResp{})
 	if val != nil {
 		return val.(*GetChildrenResp), e
@@ -120,3 +273,146 @@ func (c *client) GetAncestors(css *clientsession.Store, region cnst.Region, shar
 	}
 	return nil, e
 }
+
+func (c *client) GetSubtree(css *clientsession.Store, region cnst.Region, shard int, account, project, root id.Id, depth int, after *id.Id, limit int, customFieldFilters []*customfield.Value) (*GetSubtreeResp, error) {
+	val, e := getSubtree.DoRequest(css, c.host, region, &getSubtreeArgs{
+		Shard:              shard,
+		Account:            account,
+		Project:            project,
+		Root:               root,
+		Depth:              depth,
+		After:              after,
+		Limit:              limit,
+		CustomFieldFilters: customFieldFilters,
+	}, nil, &GetSubtreeResp{})
+	if val != nil {
+		return val.(*GetSubtreeResp), e
+	}
+	return nil, e
+}
+
+func (c *client) GetByLabels(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, labels []id.Id, after *id.Id, limit int) (*GetChildrenResp, error) {
+	val, e := getByLabels.DoRequest(css, c.host, region, &getByLabelsArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Labels:  labels,
+		After:   after,
+		Limit:   limit,
+	}, nil, &GetChildrenResp{})
+	if val != nil {
+		return val.(*GetChildrenResp), e
+	}
+	return nil, e
+}
+
+func (c *client) Search(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, query string, offset, limit int) (*SearchResp, error) {
+	val, e := search.DoRequest(css, c.host, region, &searchArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Query:   query,
+		Offset:  offset,
+		Limit:   limit,
+	}, nil, &SearchResp{})
+	if val != nil {
+		return val.(*SearchResp), e
+	}
+	return nil, e
+}
+
+func (c *client) AddLabels(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, labels []id.Id) error {
+	_, e := addLabels.DoRequest(css, c.host, region, &setLabelsArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Task:    task,
+		Labels:  labels,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) RemoveLabels(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, labels []id.Id) error {
+	_, e := removeLabels.DoRequest(css, c.host, region, &setLabelsArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Task:    task,
+		Labels:  labels,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) AddDependency(css *clientsession.Store, region cnst.Region, shard int, account, project, task, dependsOn id.Id) error {
+	_, e := addDependency.DoRequest(css, c.host, region, &addDependencyArgs{
+		Shard:     shard,
+		Account:   account,
+		Project:   project,
+		Task:      task,
+		DependsOn: dependsOn,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) RemoveDependency(css *clientsession.Store, region cnst.Region, shard int, account, project, task, dependsOn id.Id) error {
+	_, e := removeDependency.DoRequest(css, c.host, region, &removeDependencyArgs{
+		Shard:     shard,
+		Account:   account,
+		Project:   project,
+		Task:      task,
+		DependsOn: dependsOn,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) GetDependencies(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id) (*GetDependenciesResp, error) {
+	val, e := getDependencies.DoRequest(css, c.host, region, &getDependenciesArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Task:    task,
+	}, nil, &GetDependenciesResp{})
+	if val != nil {
+		return val.(*GetDependenciesResp), e
+	}
+	return nil, e
+}
+
+func (c *client) GetCriticalPath(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) (*GetCriticalPathResp, error) {
+	val, e := getCriticalPath.DoRequest(css, c.host, region, &getCriticalPathArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+	}, nil, &GetCriticalPathResp{})
+	if val != nil {
+		return val.(*GetCriticalPathResp), e
+	}
+	return nil, e
+}
+
+func (c *client) GetForecast(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id) (*GetForecastResp, error) {
+	val, e := getForecast.DoRequest(css, c.host, region, &getForecastArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+	}, nil, &GetForecastResp{})
+	if val != nil {
+		return val.(*GetForecastResp), e
+	}
+	return nil, e
+}
+
+func (c *client) GetBurndown(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, from, to time.Time) (*GetBurndownResp, error) {
+	val, e := getBurndown.DoRequest(css, c.host, region, &getBurndownArgs{
+		Shard:   shard,
+		Account: account,
+		Project: project,
+		Task:    task,
+		From:    from,
+		To:      to,
+	}, nil, &GetBurndownResp{})
+	if val != nil {
+		return val.(*GetBurndownResp), e
+	}
+	return nil, e
+}
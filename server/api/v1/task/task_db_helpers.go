@@ -1,11 +1,19 @@
 package task
 
 import (
+	"bytes"
 	"github.com/0xor1/panic"
 	"github.com/0xor1/trees/server/util/cachekey"
 	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/customfield"
 	"github.com/0xor1/trees/server/util/db"
+	"github.com/0xor1/trees/server/util/err"
+	"github.com/0xor1/trees/server/util/filestore"
 	"github.com/0xor1/trees/server/util/id"
+	t "github.com/0xor1/trees/server/util/time"
+	"sort"
+	"strings"
+	"time"
 )
 
 func dbCreateTask(ctx ctx.Ctx, shard int, account, project, parent id.Id, nextSibling *id.Id, newTask *Task) {
@@ -23,12 +31,96 @@ func dbCreateTask(ctx ctx.Ctx, shard int, account, project, parent id.Id, nextSi
 	args = append(args, newTask.CreatedOn)
 	args = append(args, newTask.TotalRemainingTime)
 	args = append(args, newTask.IsParallel)
-	if newTask.Member != nil {
-		args = append(args, *newTask.Member)
+	if len(newTask.Members) > 0 {
+		args = append(args, newTask.Members[0].Id)
 	} else {
 		args = append(args, nil)
 	}
-	ctx.TouchDlms(cachekey.NewSetDlms().ProjectActivities(account, project).TaskChildrenSet(account, project, parent).CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL createTask(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, args...)))
+	if newTask.State != nil {
+		args = append(args, *newTask.State)
+	} else {
+		args = append(args, nil)
+	}
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectActivities(account, project).TaskChildrenSet(account, project, parent).CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL createTask(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, args...)))
+}
+
+func dbGetImportAssignees(ctx ctx.Ctx, shard int, account, project id.Id, names []string) map[string]id.Id {
+	res := make(map[string]id.Id, len(names))
+	if len(names) == 0 {
+		return res
+	}
+	args := make([]interface{}, 0, len(names)+2)
+	args = append(args, account, project)
+	for _, name := range names {
+		args = append(args, name)
+	}
+	query := bytes.NewBufferString(`SELECT id, name FROM projectMembers WHERE account=? AND project=? AND isActive=true AND role<2 AND name IN (?`)
+	query.WriteString(strings.Repeat(`,?`, len(names)-1))
+	query.WriteString(`)`)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var member id.Id
+		name := ""
+		panic.IfNotNil(rows.Scan(&member, &name))
+		res[name] = member
+	}
+	return res
+}
+
+func dbImportTasks(ctx ctx.Ctx, shard int, account, project, parent id.Id, previousSibling *id.Id, tasks []*importRow) {
+	//the rows are staged in taskImportRows and only moved in to tasks by importTasks under the project lock, so a failed import leaves nothing behind
+	batch := id.New()
+	defer func() {
+		r := recover()
+		if r != nil {
+			_, e := ctx.TreeExec(shard, `DELETE FROM taskImportRows WHERE account=? AND project=? AND batch=?`, account, project, batch)
+			ctx.LogIf(e)
+			if e, ok := r.(error); ok {
+				panic.IfNotNil(e)
+			}
+			panic.If(true, "%v", r)
+		}
+	}()
+	stagedRows := make([][]interface{}, 0, len(tasks))
+	for i, ir := range tasks {
+		var taskParent *id.Id
+		if ir.parent != nil {
+			taskParent = &ir.parent.id
+		}
+		remainingTime := uint64(0)
+		if ir.estimate != nil {
+			remainingTime = *ir.estimate
+		}
+		stagedRows = append(stagedRows, []interface{}{account, project, batch, i + 1, ir.id, taskParent, ir.firstChild, ir.nextSibling, len(ir.children) > 0, ir.name, ir.description, remainingTime, ir.member})
+	}
+	db.BulkInsert(ctx, shard, `INSERT INTO taskImportRows (account, project, batch, idx, id, parent, firstChild, nextSibling, isAbstract, name, description, totalRemainingTime, member) VALUES `, stagedRows)
+
+	rows, e := ctx.TreeQuery(shard, `CALL importTasks(?, ?, ?, ?, ?, ?, ?)`, account, project, parent, ctx.Me(), previousSibling, batch, t.Now())
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	affectedTasks := make([]id.Id, 0, len(tasks)+10)
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project)
+	for rows.Next() {
+		var i id.Id
+		key := ""
+		rows.Scan(&i, &key)
+		switch key {
+		case "t":
+			affectedTasks = append(affectedTasks, i)
+		case "m":
+			cacheKey.ProjectMember(account, project, i)
+		default:
+			panic.If(true, "unknown key value in import tasks rows")
+		}
+	}
+	ctx.ReturnBadRequestNowIf(len(affectedTasks) == 0, "no change made")
+	ctx.TouchDlms(cacheKey.CombinedTaskAndTaskChildrenSets(account, project, affectedTasks))
 }
 
 func dbSetName(ctx ctx.Ctx, shard int, account, project, task id.Id, name string) {
@@ -65,15 +157,25 @@ func dbSetDescription(ctx ctx.Ctx, shard int, account, project, task id.Id, desc
 }
 
 func dbSetIsAbstract(ctx ctx.Ctx, shard int, account, project id.Id, task id.Id, isAbstract bool) {
-	row := ctx.TreeQueryRow(shard, `CALL setTaskIsAbstract(?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), isAbstract)
-	var parent *id.Id
-	panic.IfNotNil(row.Scan(&parent))
-	panic.If(parent == nil, "no change made")
-	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project).Task(account, project, task)
-	if parent != nil {
-		cacheKey.TaskChildrenSet(account, project, *parent)
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project)
+	ctx.TouchDlms(cacheKey.CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL setTaskIsAbstract(?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), isAbstract)))
+}
+
+func dbSetState(ctx ctx.Ctx, shard int, account, project id.Id, task id.Id, state id.Id) {
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project)
+	ctx.TouchDlms(cacheKey.CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL setTaskState(?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), state)))
+}
+
+func dbGetFirstState(ctx ctx.Ctx, shard int, account, project id.Id) id.Id {
+	var state id.Id
+	cacheKey := cachekey.NewGet("task.dbGetFirstState", shard, account, project).ProjectStateSet(account, project)
+	if ctx.GetCacheValue(&state, cacheKey) {
+		return state
 	}
-	ctx.TouchDlms(cacheKey)
+	row := ctx.TreeQueryRow(shard, `SELECT id FROM projectStates WHERE account=? AND project=? ORDER BY position ASC LIMIT 1`, account, project)
+	ctx.ReturnBadRequestNowIf(err.IsSqlErrNoRowsElsePanicIf(row.Scan(&state)), "no such project")
+	ctx.SetCacheValue(state, cacheKey)
+	return state
 }
 
 func dbSetIsParallel(ctx ctx.Ctx, shard int, account, project id.Id, task id.Id, isParallel bool) {
@@ -86,34 +188,100 @@ func dbSetMember(ctx ctx.Ctx, shard int, account, project, task id.Id, member *i
 	if member != nil {
 		memArg = *member
 	}
+	rows, e := ctx.TreeQuery(shard, `CALL setTaskMember(?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), memArg)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
 	changeMade := false
-	var parent id.Id
-	var existingMember *id.Id
-	panic.IfNotNil(ctx.TreeQueryRow(shard, `CALL setTaskMember(?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), memArg).Scan(&changeMade, &parent, &existingMember))
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project)
+	for rows.Next() {
+		var i id.Id
+		key := ""
+		panic.IfNotNil(rows.Scan(&i, &key))
+		switch key {
+		case "t":
+			changeMade = true
+			if i.Equal(task) {
+				cacheKey.Task(account, project, i)
+			} else {
+				cacheKey.TaskChildrenSet(account, project, i)
+			}
+		case "m":
+			cacheKey.ProjectMember(account, project, i)
+		default:
+			panic.If(true, "unknown key value in set task member rows")
+		}
+	}
 	ctx.ReturnBadRequestNowIf(!changeMade, "no change made")
-	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project).TaskChildrenSet(account, project, parent).Task(account, project, task)
+	ctx.TouchDlms(cacheKey)
+}
+
+func dbAddMember(ctx ctx.Ctx, shard int, account, project, task, member id.Id, remainingTime *uint64) {
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project).ProjectMember(account, project, member)
+	ctx.TouchDlms(cacheKey.CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL addTaskMember(?, ?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), member, remainingTime)))
+}
+
+func dbRemoveMember(ctx ctx.Ctx, shard int, account, project, task, member id.Id) {
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project).ProjectMember(account, project, member)
+	ctx.TouchDlms(cacheKey.CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL removeTaskMember(?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), member)))
+}
+
+func dbBulkSetMember(ctx ctx.Ctx, shard int, account, project id.Id, tasks []id.Id, member *id.Id) {
+	var memArg []byte
 	if member != nil {
-		cacheKey.ProjectMember(account, project, *member)
+		memArg = *member
 	}
-	if existingMember != nil {
-		cacheKey.ProjectMember(account, project, *existingMember)
+	rows, e := ctx.TreeQuery(shard, `CALL setTasksMember(?, ?, ?, ?, ?)`, account, project, id.ToHexString(tasks), ctx.Me(), memArg)
+	if rows != nil {
+		defer rows.Close()
 	}
-	ctx.TouchDlms(cacheKey)
+	panic.IfNotNil(e)
+	affectedTasks := make([]id.Id, 0, len(tasks)*2)
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project)
+	for rows.Next() {
+		var i id.Id
+		key := ""
+		rows.Scan(&i, &key)
+		switch key {
+		case "t":
+			affectedTasks = append(affectedTasks, i)
+		case "m":
+			cacheKey.ProjectMember(account, project, i)
+		default:
+			panic.If(true, "unknown key value in set tasks member rows")
+		}
+	}
+	ctx.ReturnBadRequestNowIf(len(affectedTasks) == 0, "no change made")
+	ctx.TouchDlms(cacheKey.CombinedTaskAndTaskChildrenSets(account, project, affectedTasks))
 }
 
 func dbMoveTask(ctx ctx.Ctx, shard int, account, project, task, newParent id.Id, newPreviousSibling *id.Id) {
 	ctx.TouchDlms(cachekey.NewSetDlms().ProjectActivities(account, project).CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL moveTask(?, ?, ?, ?, ?, ?)`, account, project, task, newParent, ctx.Me(), newPreviousSibling)))
 }
 
+func dbBulkMoveTasks(ctx ctx.Ctx, shard int, account, project id.Id, tasks []id.Id, newParent id.Id, newPreviousSibling *id.Id) {
+	ctx.TouchDlms(cachekey.NewSetDlms().ProjectActivities(account, project).CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL moveTasks(?, ?, ?, ?, ?, ?)`, account, project, id.ToHexString(tasks), newParent, ctx.Me(), newPreviousSibling)))
+}
+
 func dbDeleteTask(ctx ctx.Ctx, shard int, account, project, task id.Id) {
-	rows, e := ctx.TreeQuery(shard, `CALL deleteTask(?, ?, ?, ?)`, account, project, task, ctx.Me())
+	dbDeleteTasksHelper(ctx, shard, account, project, `CALL deleteTask(?, ?, ?, ?)`, account, project, task, ctx.Me())
+}
+
+func dbBulkDeleteTasks(ctx ctx.Ctx, shard int, account, project id.Id, tasks []id.Id) {
+	dbDeleteTasksHelper(ctx, shard, account, project, `CALL deleteTasks(?, ?, ?, ?)`, account, project, id.ToHexString(tasks), ctx.Me())
+}
+
+func dbDeleteTasksHelper(ctx ctx.Ctx, shard int, account, project id.Id, sql string, args ...interface{}) {
+	rows, e := ctx.TreeQuery(shard, sql, args...)
 	if rows != nil {
 		defer rows.Close()
 	}
 	panic.IfNotNil(e)
 	affectedTasks := make([]id.Id, 0, 100)
 	updatedProjectMembers := make([]id.Id, 0, 100)
-	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project)
+	deletedFiles := make([]id.Id, 0, 100)
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project).ProjectLabelledTaskSet(account, project).ProjectTrashSet(account, project)
 	for rows.Next() {
 		var i id.Id
 		var j id.Id
@@ -127,53 +295,660 @@ func dbDeleteTask(ctx ctx.Ctx, shard int, account, project, task id.Id) {
 			updatedProjectMembers = append(updatedProjectMembers, i)
 		case "tl":
 			cacheKey.TimeLog(account, project, i, &j, &k)
+		case "d":
+			cacheKey.TaskDependencySet(account, project, i)
+		case "f":
+			deletedFiles = append(deletedFiles, i)
+			cacheKey.File(account, project, i, &j).Project(account, project)
 		default:
 			panic.If(true, "unknown key value in delete task rows")
 		}
 	}
+	for _, file := range deletedFiles {
+		ctx.FileClient().Delete(filestore.Key(account, project, file))
+	}
 	ctx.TouchDlms(cacheKey.CombinedTaskAndTaskChildrenSets(account, project, affectedTasks).ProjectMembers(account, project, updatedProjectMembers))
 }
 
+func dbGetTrash(ctx ctx.Ctx, shard int, account, project id.Id, after *id.Id, limit int) *GetTrashResp {
+	res := GetTrashResp{}
+	cacheKey := cachekey.NewGet("task.dbGetTrash", shard, account, project, after, limit).ProjectTrashSet(account, project)
+	if ctx.GetCacheValue(&res, cacheKey) {
+		return &res
+	}
+	query := bytes.NewBufferString(`SELECT id, deletedOn, member, parent, previousSibling, isAbstract, name, totalRemainingTime, totalLoggedTime, descendantCount FROM trash WHERE account=? AND project=?`)
+	args := make([]interface{}, 0, 7)
+	args = append(args, account, project)
+	if after != nil {
+		query.WriteString(` AND deletedOn <= (SELECT deletedOn FROM trash WHERE account=? AND project=? AND id=?) AND id < ?`)
+		args = append(args, account, project, *after, *after)
+	}
+	query.WriteString(` ORDER BY deletedOn DESC, id DESC LIMIT ?`)
+	args = append(args, limit+1)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	items := make([]*TrashItem, 0, limit+1)
+	for rows.Next() {
+		ti := TrashItem{}
+		panic.IfNotNil(rows.Scan(&ti.Id, &ti.DeletedOn, &ti.DeletedBy, &ti.Parent, &ti.PreviousSibling, &ti.IsAbstract, &ti.Name, &ti.TotalRemainingTime, &ti.TotalLoggedTime, &ti.DescendantCount))
+		ti.PurgeOn = ti.DeletedOn.AddDate(0, 0, ctx.TrashRetentionDays())
+		items = append(items, &ti)
+	}
+	if len(items) == limit+1 {
+		res.Items = items[:limit]
+		res.More = true
+	} else {
+		res.Items = items
+		res.More = false
+	}
+	ctx.SetCacheValue(res, cacheKey)
+	return &res
+}
+
+func dbRestoreTask(ctx ctx.Ctx, shard int, account, project, task id.Id, newParent, newPreviousSibling *id.Id) {
+	var parentArg, previousSiblingArg []byte
+	if newParent != nil {
+		parentArg = *newParent
+	}
+	if newPreviousSibling != nil {
+		previousSiblingArg = *newPreviousSibling
+	}
+	rows, e := ctx.TreeQuery(shard, `CALL restoreTask(?, ?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), parentArg, previousSiblingArg)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	affectedTasks := make([]id.Id, 0, 100)
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project).ProjectTrashSet(account, project).ProjectLabelledTaskSet(account, project)
+	for rows.Next() {
+		var i id.Id
+		var j id.Id
+		var k id.Id
+		key := ""
+		rows.Scan(&i, &j, &k, &key)
+		switch key {
+		case "t":
+			affectedTasks = append(affectedTasks, i)
+		case "m":
+			cacheKey.ProjectMember(account, project, i)
+		case "tl":
+			cacheKey.TimeLog(account, project, i, &j, &k)
+		default:
+			panic.If(true, "unknown key value in restore task rows")
+		}
+	}
+	ctx.ReturnBadRequestNowIf(len(affectedTasks) == 0, "no change made, the task may not be in the trash or the position it is being restored to may no longer exist")
+	ctx.TouchDlms(cacheKey.CombinedTaskAndTaskChildrenSets(account, project, affectedTasks))
+}
+
+func dbAddLabels(ctx ctx.Ctx, shard int, account, project, task id.Id, labels []id.Id) {
+	dbSetLabelsHelper(ctx, shard, `CALL addTaskLabels(?, ?, ?, ?, ?)`, account, project, task, labels)
+}
+
+func dbRemoveLabels(ctx ctx.Ctx, shard int, account, project, task id.Id, labels []id.Id) {
+	dbSetLabelsHelper(ctx, shard, `CALL removeTaskLabels(?, ?, ?, ?, ?)`, account, project, task, labels)
+}
+
+func dbSetLabelsHelper(ctx ctx.Ctx, shard int, sql string, account, project, task id.Id, labels []id.Id) {
+	row := ctx.TreeQueryRow(shard, sql, account, project, task, ctx.Me(), id.ToHexString(labels))
+	changeMade := false
+	var parent *id.Id
+	panic.IfNotNil(row.Scan(&changeMade, &parent))
+	ctx.ReturnBadRequestNowIf(!changeMade, "no change made")
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project).ProjectLabelledTaskSet(account, project).Task(account, project, task)
+	if parent != nil {
+		cacheKey.TaskChildrenSet(account, project, *parent)
+	}
+	ctx.TouchDlms(cacheKey)
+}
+
+func dbSetCustomFieldValue(ctx ctx.Ctx, shard int, account, project, task id.Id, v *customfield.Value) {
+	row := ctx.TreeQueryRow(shard, `CALL setTaskCustomFieldValue(?, ?, ?, ?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), v.Field, v.Text, v.Number, v.Date)
+	changeMade := false
+	var parent *id.Id
+	panic.IfNotNil(row.Scan(&changeMade, &parent))
+	ctx.ReturnBadRequestNowIf(!changeMade, "no change made")
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project).Task(account, project, task)
+	if parent != nil {
+		cacheKey.TaskChildrenSet(account, project, *parent)
+	}
+	ctx.TouchDlms(cacheKey)
+}
+
+// dbValidateDependency gives a specific bad request for the cases add/removeTaskDependency would otherwise only report as no
+// change made, the procs still check everything again under the project lock.
+func dbValidateDependency(ctx ctx.Ctx, shard int, account, project, task, dependsOn id.Id, shouldExist bool) {
+	rows, e := ctx.TreeQuery(shard, `SELECT isAbstract FROM tasks WHERE account=? AND project=? AND id IN (?, ?)`, account, project, task, dependsOn)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	found, anyAbstract := 0, false
+	for rows.Next() {
+		var isAbstract bool
+		panic.IfNotNil(rows.Scan(&isAbstract))
+		found++
+		anyAbstract = anyAbstract || isAbstract
+	}
+	ctx.ReturnBadRequestNowIf(found != 2, "no such task")
+	exists := false
+	panic.IfNotNil(ctx.TreeQueryRow(shard, `SELECT COUNT(*)=1 FROM taskDependencies WHERE account=? AND project=? AND task=? AND dependsOn=?`, account, project, task, dependsOn).Scan(&exists))
+	if shouldExist {
+		ctx.ReturnBadRequestNowIf(!exists, "no such dependency")
+	} else {
+		ctx.ReturnBadRequestNowIf(anyAbstract, "dependencies can only be between concrete tasks")
+		ctx.ReturnBadRequestNowIf(exists, "dependency already exists")
+	}
+}
+
+func dbAddDependency(ctx ctx.Ctx, shard int, account, project, task, dependsOn id.Id) {
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project).TaskDependencySet(account, project, task).TaskDependencySet(account, project, dependsOn)
+	ctx.TouchDlms(cacheKey.CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL addTaskDependency(?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), dependsOn)))
+}
+
+func dbRemoveDependency(ctx ctx.Ctx, shard int, account, project, task, dependsOn id.Id) {
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project).TaskDependencySet(account, project, task).TaskDependencySet(account, project, dependsOn)
+	ctx.TouchDlms(cacheKey.CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL removeTaskDependency(?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), dependsOn)))
+}
+
+func dbGetDependencies(ctx ctx.Ctx, shard int, account, project, task id.Id) *GetDependenciesResp {
+	res := GetDependenciesResp{}
+	cacheKey := cachekey.NewGet("project.dbGetDependencies", shard, account, project, task).TaskDependencySet(account, project, task)
+	if ctx.GetCacheValue(&res, cacheKey) {
+		return &res
+	}
+	rows, e := ctx.TreeQuery(shard, `SELECT task, dependsOn FROM taskDependencies WHERE account = ? AND project = ? AND (task = ? OR dependsOn = ?)`, account, project, task, task)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	res.DependsOn = make([]id.Id, 0, 10)
+	res.Dependents = make([]id.Id, 0, 10)
+	for rows.Next() {
+		var dependent id.Id
+		var dependsOn id.Id
+		panic.IfNotNil(rows.Scan(&dependent, &dependsOn))
+		if dependent.Equal(task) {
+			res.DependsOn = append(res.DependsOn, dependsOn)
+		} else {
+			res.Dependents = append(res.Dependents, dependent)
+		}
+	}
+	ctx.SetCacheValue(res, cacheKey)
+	return &res
+}
+
+func dbGetScheduleTasks(ctx ctx.Ctx, shard int, account, project id.Id) ([]*scheduleTask, []*scheduleDependency) {
+	rows, e := ctx.TreeQuery(shard, `SELECT id, parent, nextSibling, isAbstract, isParallel, totalRemainingTime, name FROM tasks WHERE account = ? AND project = ?`, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	tasks := make([]*scheduleTask, 0, 100)
+	byId := make(map[string]*scheduleTask, 100)
+	for rows.Next() {
+		st := scheduleTask{}
+		panic.IfNotNil(rows.Scan(&st.id, &st.parent, &st.nextSibling, &st.isAbstract, &st.isParallel, &st.remainingTime, &st.name))
+		tasks = append(tasks, &st)
+		byId[st.id.String()] = &st
+	}
+	memberRows, e := ctx.TreeQuery(shard, `SELECT task, member, remainingTime FROM taskMembers WHERE account = ? AND project = ? ORDER BY task, member`, account, project)
+	if memberRows != nil {
+		defer memberRows.Close()
+	}
+	panic.IfNotNil(e)
+	for memberRows.Next() {
+		var task id.Id
+		sm := scheduleMember{}
+		panic.IfNotNil(memberRows.Scan(&task, &sm.id, &sm.remainingTime))
+		if st := byId[task.String()]; st != nil {
+			st.members = append(st.members, &sm)
+		}
+	}
+	depRows, e := ctx.TreeQuery(shard, `SELECT task, dependsOn FROM taskDependencies WHERE account = ? AND project = ?`, account, project)
+	if depRows != nil {
+		defer depRows.Close()
+	}
+	panic.IfNotNil(e)
+	deps := make([]*scheduleDependency, 0, 100)
+	for depRows.Next() {
+		sd := scheduleDependency{}
+		panic.IfNotNil(depRows.Scan(&sd.task, &sd.dependsOn))
+		deps = append(deps, &sd)
+	}
+	return tasks, deps
+}
+
+type projectCalendar struct {
+	hoursPerDay uint8
+	daysPerWeek uint8
+	startOn     *time.Time
+	dueOn       *time.Time
+}
+
+func dbGetProjectCalendar(ctx ctx.Ctx, shard int, account, project id.Id) *projectCalendar {
+	res := projectCalendar{}
+	row := ctx.TreeQueryRow(shard, `SELECT hoursPerDay, daysPerWeek, startOn, dueOn FROM projects WHERE account = ? AND id = ?`, account, project)
+	panic.IfNotNil(row.Scan(&res.hoursPerDay, &res.daysPerWeek, &res.startOn, &res.dueOn))
+	return &res
+}
+
+type timeHistoryEvent struct {
+	isTimeLog  bool
+	task       string
+	occurredOn time.Time
+	value      uint64
+}
+
+func dbGetBurndown(ctx ctx.Ctx, shard int, account, project, task id.Id, from time.Time, dayCount int) *GetBurndownResp {
+	taskExists := false
+	panic.IfNotNil(ctx.TreeQueryRow(shard, `SELECT COUNT(*)=1 FROM tasks WHERE account=? AND project=? AND id=?`, account, project, task).Scan(&taskExists))
+	ctx.ReturnBadRequestNowIf(!taskExists, "no such task")
+	rows, e := ctx.TreeQuery(shard, `CALL getTaskTimeHistory(?, ?, ?, ?)`, account, project, task, from.AddDate(0, 0, dayCount))
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	events := make([]*timeHistoryEvent, 0, 100)
+	for rows.Next() {
+		key := ""
+		var eventTask id.Id
+		ev := timeHistoryEvent{}
+		panic.IfNotNil(rows.Scan(&key, &eventTask, &ev.occurredOn, &ev.value))
+		ev.isTimeLog = key == "l"
+		ev.task = eventTask.String()
+		events = append(events, &ev)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].occurredOn.Before(events[j].occurredOn)
+	})
+	//replay the history keeping each tasks latest remaining time, so the total is right even for tasks whose history
+	//doesn't start from zero
+	taskRemainingTimes := map[string]uint64{}
+	remainingTime := uint64(0)
+	loggedTime := uint64(0)
+	res := GetBurndownResp{
+		Days: make([]*BurndownDay, 0, dayCount),
+	}
+	for day := 0; day < dayCount; day++ {
+		dayStart := from.AddDate(0, 0, day)
+		dayEnd := dayStart.AddDate(0, 0, 1)
+		for len(events) > 0 && events[0].occurredOn.Before(dayEnd) {
+			ev := events[0]
+			events = events[1:]
+			if ev.isTimeLog {
+				loggedTime += ev.value
+			} else {
+				remainingTime = remainingTime - taskRemainingTimes[ev.task] + ev.value
+				taskRemainingTimes[ev.task] = ev.value
+			}
+		}
+		res.Days = append(res.Days, &BurndownDay{
+			Day:           dayStart,
+			RemainingTime: remainingTime,
+			LoggedTime:    loggedTime,
+			TotalTime:     remainingTime + loggedTime,
+		})
+	}
+	return &res
+}
+
 func dbGetTask(ctx ctx.Ctx, shard int, account, project id.Id, task id.Id) *Task {
 	cacheKey := cachekey.NewGet("project.dbGetTask", shard, account, project, task).Task(account, project, task)
 	res := Task{}
 	if ctx.GetCacheValue(&res, cacheKey) {
 		return &res
 	}
-	panic.IfNotNil(ctx.TreeQueryRow(shard, `SELECT id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, member FROM tasks WHERE account = ? AND project = ? AND id = ?`, account, project, task).Scan(&res.Id, &res.Parent, &res.FirstChild, &res.NextSibling, &res.IsAbstract, &res.Name, &res.Description, &res.CreatedOn, &res.TotalRemainingTime, &res.TotalLoggedTime, &res.MinimumRemainingTime, &res.LinkedFileCount, &res.ChatCount, &res.ChildCount, &res.DescendantCount, &res.IsParallel, &res.Member))
+	panic.IfNotNil(ctx.TreeQueryRow(shard, `SELECT id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state FROM tasks WHERE account = ? AND project = ? AND id = ?`, account, project, task).Scan(&res.Id, &res.Parent, &res.FirstChild, &res.NextSibling, &res.IsAbstract, &res.Name, &res.Description, &res.CreatedOn, &res.TotalRemainingTime, &res.TotalLoggedTime, &res.TotalCost, &res.TotalRevenue, &res.MinimumRemainingTime, &res.LinkedFileCount, &res.ChatCount, &res.ChildCount, &res.DescendantCount, &res.IsParallel, &res.State))
+	dbPopulateLabels(ctx, shard, account, project, []*Task{&res})
+	dbPopulateCustomFieldValues(ctx, shard, account, project, []*Task{&res})
+	dbPopulateMembers(ctx, shard, account, project, []*Task{&res})
+	dbPopulateStateCounts(ctx, shard, account, project, []*Task{&res})
 	ctx.SetCacheValue(res, cacheKey)
 	return &res
 }
 
-func dbGetChildTasks(ctx ctx.Ctx, shard int, account, project, parent id.Id, fromSibling *id.Id, limit int) *GetChildrenResp {
+func dbGetChildTasks(ctx ctx.Ctx, shard int, account, project, parent id.Id, fromSibling *id.Id, limit int, labels []id.Id, customFieldFilters []*customfield.Value) *GetChildrenResp {
 	res := GetChildrenResp{}
-	cacheKey := cachekey.NewGet("project.dbGetChildTasks", shard, account, project, parent, fromSibling, limit).TaskChildrenSet(account, project, parent)
+	cacheKey := cachekey.NewGet("project.dbGetChildTasks", shard, account, project, parent, fromSibling, limit, labels, customFieldFilters).TaskChildrenSet(account, project, parent)
 	if ctx.GetCacheValue(&res, cacheKey) {
 		return &res
 	}
-	rows, e := ctx.TreeQuery(shard, `CALL getChildTasks(?, ?, ?, ?, ?)`, account, project, parent, fromSibling, limit+1)
+	childSet := make([]*Task, 0, limit+1)
+	for { //custom field values are filtered here rather than in getChildTasks, so keep reading pages until there are enough matches
+		page := dbQueryChildTasks(ctx, shard, account, project, parent, fromSibling, limit+1, labels)
+		dbPopulateCustomFieldValues(ctx, shard, account, project, page)
+		for _, ta := range page {
+			if len(childSet) < limit+1 && customfield.MatchesAll(ta.CustomFieldValues, customFieldFilters) {
+				childSet = append(childSet, ta)
+			}
+		}
+		if len(childSet) == limit+1 || len(page) < limit+1 {
+			break
+		}
+		fromSibling = &page[len(page)-1].Id
+	}
+	if len(childSet) == limit+1 {
+		res.Children = childSet[:limit]
+		res.More = true
+	} else {
+		res.Children = childSet
+		res.More = false
+	}
+	dbPopulateLabels(ctx, shard, account, project, res.Children)
+	dbPopulateMembers(ctx, shard, account, project, res.Children)
+	dbPopulateStateCounts(ctx, shard, account, project, res.Children)
+	ctx.SetCacheValue(res, cacheKey)
+	return &res
+}
+
+func dbQueryChildTasks(ctx ctx.Ctx, shard int, account, project, parent id.Id, fromSibling *id.Id, limit int, labels []id.Id) []*Task {
+	rows, e := ctx.TreeQuery(shard, `CALL getChildTasks(?, ?, ?, ?, ?, ?)`, account, project, parent, fromSibling, limit, id.ToHexString(labels))
 	if rows != nil {
 		defer rows.Close()
 	}
 	panic.IfNotNil(e)
-	childSet := make([]*Task, 0, limit+1)
+	res := make([]*Task, 0, limit)
 	for rows.Next() {
 		ta := Task{}
-		panic.IfNotNil(rows.Scan(&ta.Id, &ta.Parent, &ta.FirstChild, &ta.NextSibling, &ta.IsAbstract, &ta.Name, &ta.Description, &ta.CreatedOn, &ta.TotalRemainingTime, &ta.TotalLoggedTime, &ta.MinimumRemainingTime, &ta.LinkedFileCount, &ta.ChatCount, &ta.ChildCount, &ta.DescendantCount, &ta.IsParallel, &ta.Member))
+		panic.IfNotNil(rows.Scan(&ta.Id, &ta.Parent, &ta.FirstChild, &ta.NextSibling, &ta.IsAbstract, &ta.Name, &ta.Description, &ta.CreatedOn, &ta.TotalRemainingTime, &ta.TotalLoggedTime, &ta.TotalCost, &ta.TotalRevenue, &ta.MinimumRemainingTime, &ta.LinkedFileCount, &ta.ChatCount, &ta.ChildCount, &ta.DescendantCount, &ta.IsParallel, &ta.State))
 		nilOutPropertiesThatAreNotNilInTheDb(&ta)
-		childSet = append(childSet, &ta)
+		res = append(res, &ta)
 	}
-	if len(childSet) == limit+1 {
-		res.Children = childSet[:limit]
+	return res
+}
+
+// subtreeCacheValue holds every task read to build a subtree page, not just the ones returned, as with filters a value
+// changing on a task which wasn't returned can still change the page.
+type subtreeCacheValue struct {
+	Resp GetSubtreeResp `json:"resp"`
+	Read []id.Id        `json:"read"`
+}
+
+func dbGetSubtreeTasks(ctx ctx.Ctx, shard int, account, project, root id.Id, depth int, after *id.Id, limit int, customFieldFilters []*customfield.Value) *GetSubtreeResp {
+	cached := subtreeCacheValue{}
+	cacheKey := cachekey.NewGet("project.dbGetSubtreeTasks", shard, account, project, root, depth, after, limit, customFieldFilters).TaskChildrenSet(account, project, root)
+	innerCacheKey := cachekey.NewGet("project.dbGetSubtreeTasks-inner", shard, account, project, root, depth, after, limit, customFieldFilters)
+	innerRes := true
+	if ctx.GetCacheValue(&cached, cacheKey) {
+		for _, ta := range cached.Read { //we have to check the children set dlm of every read task here to ensure no part of the subtree has changed since the result was cached
+			innerCacheKey.TaskChildrenSet(account, project, ta)
+		}
+		if ctx.GetCacheValue(&innerRes, innerCacheKey) {
+			return &cached.Resp
+		}
+		innerCacheKey.DlmKeys = map[string]bool{}
+	}
+	res := GetSubtreeResp{}
+	read := make([]id.Id, 0, limit+1)
+	taskSet := make([]*Task, 0, limit+1)
+	for { //custom field values are filtered here rather than in getSubtreeTasks, so keep reading pages until there are enough matches
+		page := dbQuerySubtreeTasks(ctx, shard, account, project, root, depth, after, limit+1)
+		dbPopulateCustomFieldValues(ctx, shard, account, project, page)
+		for _, ta := range page {
+			if len(taskSet) == limit+1 {
+				break
+			}
+			read = append(read, ta.Id)
+			if customfield.MatchesAll(ta.CustomFieldValues, customFieldFilters) {
+				taskSet = append(taskSet, ta)
+			}
+		}
+		if len(taskSet) == limit+1 || len(page) < limit+1 {
+			break
+		}
+		after = &page[len(page)-1].Id
+	}
+	if len(taskSet) == limit+1 {
+		res.Tasks = taskSet[:limit]
 		res.More = true
 	} else {
-		res.Children = childSet
+		res.Tasks = taskSet
+		res.More = false
+	}
+	for _, ta := range read {
+		innerCacheKey.TaskChildrenSet(account, project, ta)
+	}
+	dbPopulateLabels(ctx, shard, account, project, res.Tasks)
+	dbPopulateMembers(ctx, shard, account, project, res.Tasks)
+	dbPopulateStateCounts(ctx, shard, account, project, res.Tasks)
+	ctx.SetCacheValue(&subtreeCacheValue{Resp: res, Read: read}, cacheKey)
+	ctx.SetCacheValue(true, innerCacheKey)
+	return &res
+}
+
+func dbQuerySubtreeTasks(ctx ctx.Ctx, shard int, account, project, root id.Id, depth int, after *id.Id, limit int) []*Task {
+	rows, e := ctx.TreeQuery(shard, `CALL getSubtreeTasks(?, ?, ?, ?, ?, ?)`, account, project, root, after, depth, limit)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	res := make([]*Task, 0, limit)
+	for rows.Next() {
+		ta := Task{}
+		panic.IfNotNil(rows.Scan(&ta.Id, &ta.Parent, &ta.FirstChild, &ta.NextSibling, &ta.IsAbstract, &ta.Name, &ta.Description, &ta.CreatedOn, &ta.TotalRemainingTime, &ta.TotalLoggedTime, &ta.TotalCost, &ta.TotalRevenue, &ta.MinimumRemainingTime, &ta.LinkedFileCount, &ta.ChatCount, &ta.ChildCount, &ta.DescendantCount, &ta.IsParallel, &ta.State))
+		nilOutPropertiesThatAreNotNilInTheDb(&ta)
+		res = append(res, &ta)
+	}
+	return res
+}
+
+func dbGetTasksByLabels(ctx ctx.Ctx, shard int, account, project id.Id, labels []id.Id, after *id.Id, limit int) *GetChildrenResp {
+	res := GetChildrenResp{}
+	cacheKey := cachekey.NewGet("project.dbGetTasksByLabels", shard, account, project, labels, after, limit).ProjectLabelledTaskSet(account, project)
+	innerCacheKey := cachekey.NewGet("project.dbGetTasksByLabels-inner", shard, account, project, labels, after, limit)
+	innerRes := true
+	if ctx.GetCacheValue(&res, cacheKey) {
+		for _, ta := range res.Children { //we have to check each task dlm here to ensure the tasks haven't been edited or deleted since the result was cached
+			innerCacheKey.Task(account, project, ta.Id)
+		}
+		if ctx.GetCacheValue(&innerRes, innerCacheKey) {
+			return &res
+		}
+		innerCacheKey.DlmKeys = map[string]bool{}
+	}
+	query := bytes.NewBufferString(`SELECT id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state FROM tasks WHERE account=? AND project=? AND id IN (SELECT task FROM taskLabels WHERE account=? AND project=? AND label IN (?`)
+	query.WriteString(strings.Repeat(`,?`, len(labels)-1))
+	query.WriteString(`) GROUP BY task HAVING COUNT(*)=?)`)
+	args := make([]interface{}, 0, len(labels)+10)
+	args = append(args, account, project, account, project)
+	for _, label := range labels {
+		args = append(args, label)
+	}
+	args = append(args, len(labels))
+	if after != nil {
+		query.WriteString(` AND createdOn >= (SELECT createdOn FROM tasks WHERE account=? AND project=? AND id=?) AND id > ?`)
+		args = append(args, account, project, *after, *after)
+	}
+	query.WriteString(` ORDER BY createdOn ASC, id ASC LIMIT ?`)
+	args = append(args, limit+1)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	taskSet := make([]*Task, 0, limit+1)
+	for rows.Next() {
+		ta := Task{}
+		panic.IfNotNil(rows.Scan(&ta.Id, &ta.Parent, &ta.FirstChild, &ta.NextSibling, &ta.IsAbstract, &ta.Name, &ta.Description, &ta.CreatedOn, &ta.TotalRemainingTime, &ta.TotalLoggedTime, &ta.TotalCost, &ta.TotalRevenue, &ta.MinimumRemainingTime, &ta.LinkedFileCount, &ta.ChatCount, &ta.ChildCount, &ta.DescendantCount, &ta.IsParallel, &ta.State))
+		nilOutPropertiesThatAreNotNilInTheDb(&ta)
+		taskSet = append(taskSet, &ta)
+	}
+	if len(taskSet) == limit+1 {
+		res.Children = taskSet[:limit]
+		res.More = true
+	} else {
+		res.Children = taskSet
 		res.More = false
 	}
+	dbPopulateLabels(ctx, shard, account, project, res.Children)
+	dbPopulateCustomFieldValues(ctx, shard, account, project, res.Children)
+	dbPopulateMembers(ctx, shard, account, project, res.Children)
+	dbPopulateStateCounts(ctx, shard, account, project, res.Children)
 	ctx.SetCacheValue(res, cacheKey)
+	for _, ta := range res.Children {
+		innerCacheKey.Task(account, project, ta.Id)
+	}
+	ctx.SetCacheValue(true, innerCacheKey)
+	return &res
+}
+
+func dbSearchTasks(ctx ctx.Ctx, shard int, account, project id.Id, query string, offset, limit int) *SearchResp {
+	rows, e := ctx.TreeQuery(shard, `SELECT id, parent, firstChild, nextSibling, isAbstract, name, description, createdOn, totalRemainingTime, totalLoggedTime, totalCost, totalRevenue, minimumRemainingTime, linkedFileCount, chatCount, childCount, descendantCount, isParallel, state, MATCH(name, description) AGAINST(? IN NATURAL LANGUAGE MODE) AS relevance FROM tasks WHERE account=? AND project=? AND id<>? AND MATCH(name, description) AGAINST(? IN NATURAL LANGUAGE MODE) ORDER BY relevance DESC, id ASC LIMIT ? OFFSET ?`, query, account, project, project, query, limit+1, offset)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	taskSet := make([]*Task, 0, limit+1)
+	for rows.Next() {
+		ta := Task{}
+		relevance := float64(0)
+		panic.IfNotNil(rows.Scan(&ta.Id, &ta.Parent, &ta.FirstChild, &ta.NextSibling, &ta.IsAbstract, &ta.Name, &ta.Description, &ta.CreatedOn, &ta.TotalRemainingTime, &ta.TotalLoggedTime, &ta.TotalCost, &ta.TotalRevenue, &ta.MinimumRemainingTime, &ta.LinkedFileCount, &ta.ChatCount, &ta.ChildCount, &ta.DescendantCount, &ta.IsParallel, &ta.State, &relevance))
+		nilOutPropertiesThatAreNotNilInTheDb(&ta)
+		taskSet = append(taskSet, &ta)
+	}
+	res := SearchResp{}
+	if len(taskSet) == limit+1 {
+		taskSet = taskSet[:limit]
+		res.More = true
+	}
+	dbPopulateLabels(ctx, shard, account, project, taskSet)
+	dbPopulateCustomFieldValues(ctx, shard, account, project, taskSet)
+	dbPopulateMembers(ctx, shard, account, project, taskSet)
+	dbPopulateStateCounts(ctx, shard, account, project, taskSet)
+	res.Results = make([]*SearchResult, 0, len(taskSet))
+	for _, ta := range taskSet {
+		res.Results = append(res.Results, &SearchResult{Task: ta})
+	}
 	return &res
 }
 
+func dbPopulateLabels(ctx ctx.Ctx, shard int, account, project id.Id, tasks []*Task) {
+	if len(tasks) == 0 {
+		return
+	}
+	byId := make(map[string]*Task, len(tasks))
+	args := make([]interface{}, 0, len(tasks)+2)
+	args = append(args, account, project)
+	for _, ta := range tasks {
+		ta.Labels = make([]id.Id, 0, 5)
+		byId[ta.Id.String()] = ta
+		args = append(args, ta.Id)
+	}
+	query := bytes.NewBufferString(`SELECT task, label FROM taskLabels WHERE account=? AND project=? AND task IN (?`)
+	query.WriteString(strings.Repeat(`,?`, len(tasks)-1))
+	query.WriteString(`)`)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var task, label id.Id
+		panic.IfNotNil(rows.Scan(&task, &label))
+		if ta := byId[task.String()]; ta != nil {
+			ta.Labels = append(ta.Labels, label)
+		}
+	}
+}
+
+func dbPopulateCustomFieldValues(ctx ctx.Ctx, shard int, account, project id.Id, tasks []*Task) {
+	if len(tasks) == 0 {
+		return
+	}
+	byId := make(map[string]*Task, len(tasks))
+	args := make([]interface{}, 0, len(tasks)+2)
+	args = append(args, account, project)
+	for _, ta := range tasks {
+		ta.CustomFieldValues = make([]*customfield.Value, 0, 5)
+		byId[ta.Id.String()] = ta
+		args = append(args, ta.Id)
+	}
+	query := bytes.NewBufferString(`SELECT task, customField, textValue, numberValue, dateValue FROM taskCustomFieldValues WHERE account=? AND project=? AND task IN (?`)
+	query.WriteString(strings.Repeat(`,?`, len(tasks)-1))
+	query.WriteString(`)`)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var task id.Id
+		v := customfield.Value{}
+		panic.IfNotNil(rows.Scan(&task, &v.Field, &v.Text, &v.Number, &v.Date))
+		if ta := byId[task.String()]; ta != nil {
+			ta.CustomFieldValues = append(ta.CustomFieldValues, &v)
+		}
+	}
+}
+
+func dbPopulateMembers(ctx ctx.Ctx, shard int, account, project id.Id, tasks []*Task) {
+	byId := make(map[string]*Task, len(tasks))
+	args := make([]interface{}, 0, len(tasks)+2)
+	args = append(args, account, project)
+	for _, ta := range tasks {
+		if !ta.IsAbstract {
+			ta.Members = make([]*TaskMember, 0, 2)
+			byId[ta.Id.String()] = ta
+			args = append(args, ta.Id)
+		}
+	}
+	if len(byId) == 0 {
+		return
+	}
+	query := bytes.NewBufferString(`SELECT task, member, remainingTime FROM taskMembers WHERE account=? AND project=? AND task IN (?`)
+	query.WriteString(strings.Repeat(`,?`, len(byId)-1))
+	query.WriteString(`)`)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var task id.Id
+		tm := TaskMember{}
+		panic.IfNotNil(rows.Scan(&task, &tm.Id, &tm.RemainingTime))
+		if ta := byId[task.String()]; ta != nil {
+			ta.Members = append(ta.Members, &tm)
+		}
+	}
+}
+
+func dbPopulateStateCounts(ctx ctx.Ctx, shard int, account, project id.Id, tasks []*Task) {
+	byId := make(map[string]*Task, len(tasks))
+	args := make([]interface{}, 0, len(tasks)+2)
+	args = append(args, account, project)
+	for _, ta := range tasks {
+		if ta.IsAbstract {
+			ta.StateCounts = make([]*StateCount, 0, 5)
+			byId[ta.Id.String()] = ta
+			args = append(args, ta.Id)
+		}
+	}
+	if len(byId) == 0 {
+		return
+	}
+	query := bytes.NewBufferString(`SELECT task, state, count FROM taskStateCounts WHERE account=? AND project=? AND task IN (?`)
+	query.WriteString(strings.Repeat(`,?`, len(byId)-1))
+	query.WriteString(`)`)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		var task id.Id
+		sc := StateCount{}
+		panic.IfNotNil(rows.Scan(&task, &sc.State, &sc.Count))
+		if ta := byId[task.String()]; ta != nil {
+			ta.StateCounts = append(ta.StateCounts, &sc)
+		}
+	}
+}
+
 func dbGetAncestorTasks(ctx ctx.Ctx, shard int, account, project, child id.Id, limit int) *GetAncestorsResp {
 	res := GetAncestorsResp{}
 	cacheKey := cachekey.NewGet("project.dbGetAncestorTasks", shard, account, project, child, limit).Task(account, project, child)
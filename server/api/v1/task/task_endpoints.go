@@ -1,16 +1,25 @@
 package task
 
 import (
+	"github.com/0xor1/trees/server/util/cnst"
 	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/customfield"
 	"github.com/0xor1/trees/server/util/db"
 	"github.com/0xor1/trees/server/util/endpoint"
 	"github.com/0xor1/trees/server/util/field"
 	"github.com/0xor1/trees/server/util/id"
 	t "github.com/0xor1/trees/server/util/time"
 	"github.com/0xor1/trees/server/util/validate"
+	"sort"
 	"time"
 )
 
+const (
+	searchQueryMinRuneCount = 1
+	searchQueryMaxRuneCount = 250
+	searchAncestorLimit     = 20
+)
+
 type createArgs struct {
 	Shard              int     `json:"shard"`
 	Account            id.Id   `json:"account"`
@@ -35,6 +44,7 @@ var create = &endpoint.Endpoint{
 	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
 		args := a.(*createArgs)
 		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
 		if args.IsAbstract {
 			ctx.ReturnBadRequestNowIf(args.IsParallel == nil, "abstract tasks must have isParallel set")
 			ctx.ReturnBadRequestNowIf(args.Member != nil, "abstract tasks do not accept a member arg")
@@ -64,7 +74,16 @@ var create = &endpoint.Endpoint{
 			ChildCount:           zeroPtr,
 			DescendantCount:      zeroPtr,
 			IsParallel:           args.IsParallel,
-			Member:               args.Member,
+		}
+		if !args.IsAbstract { //concrete tasks start in the projects first state
+			state := dbGetFirstState(ctx, args.Shard, args.Account, args.Project)
+			newTask.State = &state
+			newTask.Members = []*TaskMember{}
+			if args.Member != nil { //the initial member takes all of the tasks remaining time
+				newTask.Members = append(newTask.Members, &TaskMember{Id: *args.Member, RemainingTime: newTask.TotalRemainingTime})
+			}
+		} else {
+			newTask.StateCounts = []*StateCount{}
 		}
 		dbCreateTask(ctx, args.Shard, args.Account, args.Project, args.Parent, args.PreviousSibling, newTask)
 		return newTask
@@ -92,6 +111,7 @@ var edit = &endpoint.Endpoint{
 		} else {
 			validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
 		}
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
 		if args.Fields.Name != nil {
 			dbSetName(ctx, args.Shard, args.Account, args.Project, args.Task, args.Fields.Name.Val)
 		}
@@ -111,13 +131,122 @@ var edit = &endpoint.Endpoint{
 			}
 			dbSetMember(ctx, args.Shard, args.Account, args.Project, args.Task, args.Fields.Member.Val)
 		}
+		if args.Fields.State != nil {
+			dbSetState(ctx, args.Shard, args.Account, args.Project, args.Task, args.Fields.State.Val)
+		}
+		if args.Fields.CustomFieldValues != nil {
+			validateCustomFieldValues(ctx, args.Shard, args.Account, args.Project, args.Fields.CustomFieldValues.Val)
+			for _, v := range args.Fields.CustomFieldValues.Val {
+				dbSetCustomFieldValue(ctx, args.Shard, args.Account, args.Project, args.Task, v)
+			}
+		}
 		if args.Fields.RemainingTime != nil {
-			db.SetRemainingTimeAndOrLogTime(ctx, args.Shard, args.Account, args.Project, args.Task, &args.Fields.RemainingTime.Val, nil, nil)
+			db.SetRemainingTimeAndOrLogTime(ctx, args.Shard, args.Account, args.Project, args.Task, nil, &args.Fields.RemainingTime.Val, nil, nil, nil, nil)
 		}
 		return nil
 	},
 }
 
+type addMemberArgs struct {
+	Shard         int     `json:"shard"`
+	Account       id.Id   `json:"account"`
+	Project       id.Id   `json:"project"`
+	Task          id.Id   `json:"task"`
+	Member        id.Id   `json:"member"`
+	RemainingTime *uint64 `json:"remainingTime,omitempty"` //nil gives the member all of the tasks unassigned remaining time, otherwise it is added on to the tasks totalRemainingTime
+}
+
+var addMember = &endpoint.Endpoint{
+	Path:            "/api/v1/task/addMember",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &addMemberArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*addMemberArgs)
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		validate.MemberIsAProjectMemberWithWriteAccess(db.GetProjectRole(ctx, args.Shard, args.Account, args.Project, args.Member))
+		dbAddMember(ctx, args.Shard, args.Account, args.Project, args.Task, args.Member, args.RemainingTime)
+		return nil
+	},
+}
+
+type removeMemberArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Project id.Id `json:"project"`
+	Task    id.Id `json:"task"`
+	Member  id.Id `json:"member"`
+}
+
+var removeMember = &endpoint.Endpoint{
+	Path:            "/api/v1/task/removeMember",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &removeMemberArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*removeMemberArgs)
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		dbRemoveMember(ctx, args.Shard, args.Account, args.Project, args.Task, args.Member)
+		return nil
+	},
+}
+
+type setMemberRemainingTimeArgs struct {
+	Shard         int    `json:"shard"`
+	Account       id.Id  `json:"account"`
+	Project       id.Id  `json:"project"`
+	Task          id.Id  `json:"task"`
+	Member        id.Id  `json:"member"`
+	RemainingTime uint64 `json:"remainingTime"`
+}
+
+var setMemberRemainingTime = &endpoint.Endpoint{
+	Path:            "/api/v1/task/setMemberRemainingTime",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &setMemberRemainingTimeArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*setMemberRemainingTimeArgs)
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		db.SetRemainingTimeAndOrLogTime(ctx, args.Shard, args.Account, args.Project, args.Task, &args.Member, &args.RemainingTime, nil, nil, nil, nil)
+		return nil
+	},
+}
+
+type bulkSetMemberArgs struct {
+	Shard   int     `json:"shard"`
+	Account id.Id   `json:"account"`
+	Project id.Id   `json:"project"`
+	Tasks   []id.Id `json:"tasks"`
+	Member  *id.Id  `json:"member,omitempty"` //replaces all of the tasks members, nil unassigns the tasks
+}
+
+var bulkSetMember = &endpoint.Endpoint{
+	Path:            "/api/v1/task/bulkSetMember",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &bulkSetMemberArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*bulkSetMemberArgs)
+		validateBulkTasks(ctx, args.Project, args.Tasks)
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		if args.Member != nil {
+			validate.MemberIsAProjectMemberWithWriteAccess(db.GetProjectRole(ctx, args.Shard, args.Account, args.Project, *args.Member))
+		}
+
+		dbBulkSetMember(ctx, args.Shard, args.Account, args.Project, args.Tasks, args.Member)
+		return nil
+	},
+}
+
 type moveArgs struct {
 	Shard              int    `json:"shard"`
 	Account            id.Id  `json:"account"`
@@ -136,12 +265,125 @@ var move = &endpoint.Endpoint{
 	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
 		args := a.(*moveArgs)
 		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
 
 		dbMoveTask(ctx, args.Shard, args.Account, args.Project, args.Task, args.NewParent, args.NewPreviousSibling)
 		return nil
 	},
 }
 
+type bulkMoveArgs struct {
+	Shard              int     `json:"shard"`
+	Account            id.Id   `json:"account"`
+	Project            id.Id   `json:"project"`
+	Tasks              []id.Id `json:"tasks"`
+	NewParent          id.Id   `json:"newParent"`
+	NewPreviousSibling *id.Id  `json:"newPreviousSibling"`
+}
+
+var bulkMove = &endpoint.Endpoint{
+	Path:            "/api/v1/task/bulkMove",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &bulkMoveArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*bulkMoveArgs)
+		validateBulkTasks(ctx, args.Project, args.Tasks)
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+
+		dbBulkMoveTasks(ctx, args.Shard, args.Account, args.Project, args.Tasks, args.NewParent, args.NewPreviousSibling)
+		return nil
+	},
+}
+
+type copyArgs struct {
+	Shard              int    `json:"shard"`
+	Account            id.Id  `json:"account"`
+	SourceProject      id.Id  `json:"sourceProject"`
+	Task               id.Id  `json:"task"`
+	Project            id.Id  `json:"project"`
+	NewParent          id.Id  `json:"newParent"`
+	NewPreviousSibling *id.Id `json:"newPreviousSibling"`
+	CopyEstimates      bool   `json:"copyEstimates"`
+	CopyMembers        bool   `json:"copyMembers"`
+}
+
+var copyTask = &endpoint.Endpoint{
+	Path:                     "/api/v1/task/copy",
+	RequiresSession:          true,
+	ExampleResponseStructure: &Task{},
+	GetArgsStruct: func() interface{} {
+		return &copyArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*copyArgs)
+		ctx.ReturnBadRequestNowIf(args.SourceProject.Equal(args.Task), "use project saveAsTemplate endpoint to copy the project node")
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.SourceProject, ctx.TryMe()))
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+
+		newTask := db.CopyTask(ctx, args.Shard, args.Account, args.SourceProject, args.Task, args.Project, args.NewParent, args.NewPreviousSibling, args.CopyEstimates, args.CopyMembers)
+		return dbGetTask(ctx, args.Shard, args.Account, args.Project, newTask)
+	},
+}
+
+type importArgs struct {
+	Shard           int               `json:"shard"`
+	Account         id.Id             `json:"account"`
+	Project         id.Id             `json:"project"`
+	Parent          id.Id             `json:"parent"`
+	PreviousSibling *id.Id            `json:"previousSibling,omitempty"`
+	Format          cnst.ImportFormat `json:"format"`
+	Data            string            `json:"data"`
+}
+
+type ImportResp struct {
+	Tasks  []id.Id        `json:"tasks"`  //the imported top level tasks in order
+	Errors []*ImportError `json:"errors"` //if there are any errors nothing is imported
+}
+
+var importTasks = &endpoint.Endpoint{
+	Path:                     "/api/v1/task/import",
+	RequiresSession:          true,
+	ExampleResponseStructure: &ImportResp{Tasks: []id.Id{}, Errors: []*ImportError{{}}},
+	GetArgsStruct: func() interface{} {
+		return &importArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*importArgs)
+		args.Format.Validate()
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+
+		var roots []*importRow
+		var errs importErrors
+		if args.Format == cnst.ImportFormatCsv {
+			roots, errs = parseCsvImport(args.Data)
+		} else {
+			roots, errs = parseMarkdownImport(args.Data)
+		}
+		tasks := flattenImportRows(roots)
+		ctx.ReturnBadRequestNowIf(len(tasks) == 0 && len(errs) == 0, "no tasks to import")
+		ctx.ReturnBadRequestNowIf(len(tasks) > maxImportTaskCount, "can not import more than %d tasks at once", maxImportTaskCount)
+		errs = append(errs, validateImportRows(roots, dbGetImportAssignees(ctx, args.Shard, args.Account, args.Project, importRowAssignees(roots)))...)
+		if len(errs) > 0 {
+			sort.SliceStable(errs, func(i, j int) bool {
+				return errs[i].Row < errs[j].Row
+			})
+			return &ImportResp{Tasks: []id.Id{}, Errors: errs}
+		}
+
+		dbImportTasks(ctx, args.Shard, args.Account, args.Project, args.Parent, args.PreviousSibling, tasks)
+		res := &ImportResp{Tasks: make([]id.Id, 0, len(roots)), Errors: []*ImportError{}}
+		for _, ir := range roots {
+			res.Tasks = append(res.Tasks, ir.id)
+		}
+		return res
+	},
+}
+
 type deleteArgs struct {
 	Shard   int   `json:"shard"`
 	Account id.Id `json:"account"`
@@ -151,6 +393,7 @@ type deleteArgs struct {
 
 var delete = &endpoint.Endpoint{
 	Path:            "/api/v1/task/delete",
+	Note:            "moves the task and its descendants in to the project trash, they can be restored until they are purged after the trash retention period",
 	RequiresSession: true,
 	GetArgsStruct: func() interface{} {
 		return &deleteArgs{}
@@ -159,12 +402,92 @@ var delete = &endpoint.Endpoint{
 		args := a.(*deleteArgs)
 		ctx.ReturnBadRequestNowIf(args.Project.Equal(args.Task), "use project delete endpoint to delete the project node")
 		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
 
 		dbDeleteTask(ctx, args.Shard, args.Account, args.Project, args.Task)
 		return nil
 	},
 }
 
+type bulkDeleteArgs struct {
+	Shard   int     `json:"shard"`
+	Account id.Id   `json:"account"`
+	Project id.Id   `json:"project"`
+	Tasks   []id.Id `json:"tasks"`
+}
+
+var bulkDelete = &endpoint.Endpoint{
+	Path:            "/api/v1/task/bulkDelete",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &bulkDeleteArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*bulkDeleteArgs)
+		validateBulkTasks(ctx, args.Project, args.Tasks)
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+
+		dbBulkDeleteTasks(ctx, args.Shard, args.Account, args.Project, args.Tasks)
+		return nil
+	},
+}
+
+type getTrashArgs struct {
+	Shard   int    `json:"shard"`
+	Account id.Id  `json:"account"`
+	Project id.Id  `json:"project"`
+	After   *id.Id `json:"after,omitempty"`
+	Limit   int    `json:"limit"`
+}
+
+type GetTrashResp struct {
+	Items []*TrashItem `json:"items"`
+	More  bool         `json:"more"`
+}
+
+var getTrash = &endpoint.Endpoint{
+	Path:                     "/api/v1/task/getTrash",
+	Note:                     "returns the subtrees deleted from the project that can still be restored, most recently deleted first",
+	RequiresSession:          false,
+	ExampleResponseStructure: &GetTrashResp{Items: []*TrashItem{{}}},
+	GetArgsStruct: func() interface{} {
+		return &getTrashArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getTrashArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		return dbGetTrash(ctx, args.Shard, args.Account, args.Project, args.After, validate.Limit(args.Limit, ctx.MaxProcessEntityCount()))
+	},
+}
+
+type restoreArgs struct {
+	Shard              int    `json:"shard"`
+	Account            id.Id  `json:"account"`
+	Project            id.Id  `json:"project"`
+	Task               id.Id  `json:"task"`
+	NewParent          *id.Id `json:"newParent,omitempty"`
+	NewPreviousSibling *id.Id `json:"newPreviousSibling,omitempty"`
+}
+
+var restore = &endpoint.Endpoint{
+	Path:            "/api/v1/task/restore",
+	Note:            "puts a deleted subtree back where it was deleted from, or under newParent after newPreviousSibling when newParent is given",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &restoreArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*restoreArgs)
+		ctx.ReturnBadRequestNowIf(args.NewParent == nil && args.NewPreviousSibling != nil, "newPreviousSibling can only be given along with newParent")
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+
+		dbRestoreTask(ctx, args.Shard, args.Account, args.Project, args.Task, args.NewParent, args.NewPreviousSibling)
+		return nil
+	},
+}
+
 type getArgs struct {
 	Shard   int   `json:"shard"`
 	Account id.Id `json:"account"`
@@ -188,12 +511,14 @@ var get = &endpoint.Endpoint{
 }
 
 type getChildrenArgs struct {
-	Shard       int    `json:"shard"`
-	Account     id.Id  `json:"account"`
-	Project     id.Id  `json:"project"`
-	Parent      id.Id  `json:"parent"`
-	FromSibling *id.Id `json:"fromSibling,omitempty"`
-	Limit       int    `json:"limit"`
+	Shard              int                  `json:"shard"`
+	Account            id.Id                `json:"account"`
+	Project            id.Id                `json:"project"`
+	Parent             id.Id                `json:"parent"`
+	FromSibling        *id.Id               `json:"fromSibling,omitempty"`
+	Limit              int                  `json:"limit"`
+	Labels             []id.Id              `json:"labels,omitempty"`             //only return children carrying all of these labels
+	CustomFieldFilters []*customfield.Value `json:"customFieldFilters,omitempty"` //only return children matching all of these values, a filter with no value matches children without one
 }
 
 type GetChildrenResp struct {
@@ -212,7 +537,11 @@ var getChildren = &endpoint.Endpoint{
 		args := a.(*getChildrenArgs)
 		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
 		validate.Limit(args.Limit, ctx.MaxProcessEntityCount())
-		return dbGetChildTasks(ctx, args.Shard, args.Account, args.Project, args.Parent, args.FromSibling, args.Limit)
+		if len(args.Labels) > 0 {
+			validate.EntityCount(len(args.Labels), ctx.MaxProcessEntityCount())
+		}
+		validateCustomFieldValues(ctx, args.Shard, args.Account, args.Project, args.CustomFieldFilters)
+		return dbGetChildTasks(ctx, args.Shard, args.Account, args.Project, args.Parent, args.FromSibling, args.Limit, args.Labels, args.CustomFieldFilters)
 	},
 }
 
@@ -244,34 +573,434 @@ var getAncestors = &endpoint.Endpoint{
 	},
 }
 
+type getSubtreeArgs struct {
+	Shard              int                  `json:"shard"`
+	Account            id.Id                `json:"account"`
+	Project            id.Id                `json:"project"`
+	Root               id.Id                `json:"root"`
+	Depth              int                  `json:"depth"`
+	After              *id.Id               `json:"after,omitempty"`
+	Limit              int                  `json:"limit"`
+	CustomFieldFilters []*customfield.Value `json:"customFieldFilters,omitempty"` //only return tasks matching all of these values, their descendants are still searched when they don't match
+}
+
+type GetSubtreeResp struct {
+	Tasks []*Task `json:"tasks"`
+	More  bool    `json:"more"`
+}
+
+var getSubtree = &endpoint.Endpoint{
+	Path:                     "/api/v1/task/getSubtree",
+	Note:                     "returns the descendants of root down to depth levels below it, depth first in sibling order so every task follows its parent, pass the last returned task as after to get the next page",
+	RequiresSession:          false,
+	ExampleResponseStructure: &GetSubtreeResp{Tasks: []*Task{{}}},
+	GetArgsStruct: func() interface{} {
+		return &getSubtreeArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getSubtreeArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		ctx.ReturnBadRequestNowIf(args.Depth < 1, "depth must be at least 1")
+		validate.Limit(args.Limit, ctx.MaxProcessEntityCount())
+		validateCustomFieldValues(ctx, args.Shard, args.Account, args.Project, args.CustomFieldFilters)
+		return dbGetSubtreeTasks(ctx, args.Shard, args.Account, args.Project, args.Root, args.Depth, args.After, args.Limit, args.CustomFieldFilters)
+	},
+}
+
+type getByLabelsArgs struct {
+	Shard   int     `json:"shard"`
+	Account id.Id   `json:"account"`
+	Project id.Id   `json:"project"`
+	Labels  []id.Id `json:"labels"`
+	After   *id.Id  `json:"after,omitempty"`
+	Limit   int     `json:"limit"`
+}
+
+var getByLabels = &endpoint.Endpoint{
+	Path:                     "/api/v1/task/getByLabels",
+	Note:                     "returns every task in the project carrying all of the given labels, oldest first",
+	RequiresSession:          false,
+	ExampleResponseStructure: &GetChildrenResp{Children: []*Task{{Labels: []id.Id{id.New()}}}},
+	GetArgsStruct: func() interface{} {
+		return &getByLabelsArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getByLabelsArgs)
+		ctx.ReturnBadRequestNowIf(len(args.Labels) == 0, "no labels given")
+		validate.EntityCount(len(args.Labels), ctx.MaxProcessEntityCount())
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		return dbGetTasksByLabels(ctx, args.Shard, args.Account, args.Project, args.Labels, args.After, validate.Limit(args.Limit, ctx.MaxProcessEntityCount()))
+	},
+}
+
+type searchArgs struct {
+	Shard   int    `json:"shard"`
+	Account id.Id  `json:"account"`
+	Project id.Id  `json:"project"`
+	Query   string `json:"query"`
+	Offset  int    `json:"offset"`
+	Limit   int    `json:"limit"`
+}
+
+type SearchResp struct {
+	Results []*SearchResult `json:"results"`
+	More    bool            `json:"more"`
+}
+
+var search = &endpoint.Endpoint{
+	Path:                     "/api/v1/task/search",
+	Note:                     "full text search over the names and descriptions of the tasks in a project, most relevant first, each result includes its ancestors for breadcrumbs",
+	RequiresSession:          false,
+	ExampleResponseStructure: &SearchResp{Results: []*SearchResult{{Task: &Task{}, Ancestors: &GetAncestorsResp{Ancestors: []*Ancestor{{}}}}}},
+	GetArgsStruct: func() interface{} {
+		return &searchArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*searchArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		validate.StringArg("query", args.Query, searchQueryMinRuneCount, searchQueryMaxRuneCount, nil)
+		ctx.ReturnBadRequestNowIf(args.Offset < 0, "offset must not be negative")
+		res := dbSearchTasks(ctx, args.Shard, args.Account, args.Project, args.Query, args.Offset, validate.Limit(args.Limit, ctx.MaxProcessEntityCount()))
+		for _, r := range res.Results {
+			r.Ancestors = dbGetAncestorTasks(ctx, args.Shard, args.Account, args.Project, r.Task.Id, searchAncestorLimit)
+		}
+		return res
+	},
+}
+
+type setLabelsArgs struct {
+	Shard   int     `json:"shard"`
+	Account id.Id   `json:"account"`
+	Project id.Id   `json:"project"`
+	Task    id.Id   `json:"task"`
+	Labels  []id.Id `json:"labels"`
+}
+
+var addLabels = &endpoint.Endpoint{
+	Path:            "/api/v1/task/addLabels",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &setLabelsArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*setLabelsArgs)
+		ctx.ReturnBadRequestNowIf(len(args.Labels) == 0, "no labels given")
+		validate.EntityCount(len(args.Labels), ctx.MaxProcessEntityCount())
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		dbAddLabels(ctx, args.Shard, args.Account, args.Project, args.Task, args.Labels)
+		return nil
+	},
+}
+
+var removeLabels = &endpoint.Endpoint{
+	Path:            "/api/v1/task/removeLabels",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &setLabelsArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*setLabelsArgs)
+		ctx.ReturnBadRequestNowIf(len(args.Labels) == 0, "no labels given")
+		validate.EntityCount(len(args.Labels), ctx.MaxProcessEntityCount())
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		dbRemoveLabels(ctx, args.Shard, args.Account, args.Project, args.Task, args.Labels)
+		return nil
+	},
+}
+
+type addDependencyArgs struct {
+	Shard     int   `json:"shard"`
+	Account   id.Id `json:"account"`
+	Project   id.Id `json:"project"`
+	Task      id.Id `json:"task"`
+	DependsOn id.Id `json:"dependsOn"`
+}
+
+var addDependency = &endpoint.Endpoint{
+	Path:            "/api/v1/task/addDependency",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &addDependencyArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*addDependencyArgs)
+		ctx.ReturnBadRequestNowIf(args.Task.Equal(args.DependsOn), "a task can not depend on itself")
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+
+		dbValidateDependency(ctx, args.Shard, args.Account, args.Project, args.Task, args.DependsOn, false)
+		dbAddDependency(ctx, args.Shard, args.Account, args.Project, args.Task, args.DependsOn)
+		return nil
+	},
+}
+
+type removeDependencyArgs struct {
+	Shard     int   `json:"shard"`
+	Account   id.Id `json:"account"`
+	Project   id.Id `json:"project"`
+	Task      id.Id `json:"task"`
+	DependsOn id.Id `json:"dependsOn"`
+}
+
+var removeDependency = &endpoint.Endpoint{
+	Path:            "/api/v1/task/removeDependency",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &removeDependencyArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*removeDependencyArgs)
+		validate.MemberHasProjectWriteAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+
+		dbValidateDependency(ctx, args.Shard, args.Account, args.Project, args.Task, args.DependsOn, true)
+		dbRemoveDependency(ctx, args.Shard, args.Account, args.Project, args.Task, args.DependsOn)
+		return nil
+	},
+}
+
+type getDependenciesArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Project id.Id `json:"project"`
+	Task    id.Id `json:"task"`
+}
+
+type GetDependenciesResp struct {
+	DependsOn  []id.Id `json:"dependsOn"`
+	Dependents []id.Id `json:"dependents"`
+}
+
+var getDependencies = &endpoint.Endpoint{
+	Path:                     "/api/v1/task/getDependencies",
+	RequiresSession:          false,
+	ExampleResponseStructure: &GetDependenciesResp{DependsOn: []id.Id{id.New()}, Dependents: []id.Id{id.New()}},
+	GetArgsStruct: func() interface{} {
+		return &getDependenciesArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getDependenciesArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		return dbGetDependencies(ctx, args.Shard, args.Account, args.Project, args.Task)
+	},
+}
+
+type getCriticalPathArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Project id.Id `json:"project"`
+}
+
+type GetCriticalPathResp struct {
+	Tasks     []*CriticalPathTask `json:"tasks"`
+	TotalTime uint64              `json:"totalTime"`
+}
+
+var getCriticalPath = &endpoint.Endpoint{
+	Path:                     "/api/v1/task/getCriticalPath",
+	RequiresSession:          false,
+	ExampleResponseStructure: &GetCriticalPathResp{Tasks: []*CriticalPathTask{{}}},
+	GetArgsStruct: func() interface{} {
+		return &getCriticalPathArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getCriticalPathArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		tasks, deps := dbGetScheduleTasks(ctx, args.Shard, args.Account, args.Project)
+		s := newSchedule(args.Project, tasks, deps, false)
+		res := &GetCriticalPathResp{
+			Tasks:     make([]*CriticalPathTask, 0, 20),
+			TotalTime: s.project.finish,
+		}
+		for _, st := range s.criticalPath() {
+			res.Tasks = append(res.Tasks, &CriticalPathTask{
+				Id:      st.id,
+				Name:    st.name,
+				Members: st.memberIds(),
+				Start:   st.start,
+				Finish:  st.finish,
+			})
+		}
+		return res
+	},
+}
+
+type getForecastArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+	Project id.Id `json:"project"`
+}
+
+type GetForecastResp struct {
+	Start  time.Time       `json:"start"`
+	Finish time.Time       `json:"finish"`
+	DueOn  *time.Time      `json:"dueOn,omitempty"`
+	IsLate bool            `json:"isLate"`
+	Tasks  []*ForecastTask `json:"tasks"`
+}
+
+var getForecast = &endpoint.Endpoint{
+	Path:                     "/api/v1/task/getForecast",
+	RequiresSession:          false,
+	ExampleResponseStructure: &GetForecastResp{Tasks: []*ForecastTask{{}}},
+	GetArgsStruct: func() interface{} {
+		return &getForecastArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getForecastArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		calendar := dbGetProjectCalendar(ctx, args.Shard, args.Account, args.Project)
+		ctx.ReturnBadRequestNowIf(calendar.hoursPerDay == 0 || calendar.daysPerWeek == 0, "project hoursPerDay and daysPerWeek must be set to forecast a schedule")
+		//remaining work can't be started in the past so the forecast runs from now or the project start, whichever is later
+		base := t.Now()
+		if calendar.startOn != nil && calendar.startOn.After(base) {
+			base = *calendar.startOn
+		}
+		tasks, deps := dbGetScheduleTasks(ctx, args.Shard, args.Account, args.Project)
+		s := newSchedule(args.Project, tasks, deps, true)
+		res := &GetForecastResp{
+			Start:  workTimeToDate(base, s.project.start, calendar.hoursPerDay, calendar.daysPerWeek),
+			Finish: workTimeToDate(base, s.project.finish, calendar.hoursPerDay, calendar.daysPerWeek),
+			DueOn:  calendar.dueOn,
+			Tasks:  make([]*ForecastTask, 0, len(tasks)),
+		}
+		res.IsLate = res.DueOn != nil && res.Finish.After(*res.DueOn)
+		for _, st := range tasks {
+			if st != s.project {
+				res.Tasks = append(res.Tasks, &ForecastTask{
+					Id:     st.id,
+					Start:  workTimeToDate(base, st.start, calendar.hoursPerDay, calendar.daysPerWeek),
+					Finish: workTimeToDate(base, st.finish, calendar.hoursPerDay, calendar.daysPerWeek),
+				})
+			}
+		}
+		return res
+	},
+}
+
+type getBurndownArgs struct {
+	Shard   int       `json:"shard"`
+	Account id.Id     `json:"account"`
+	Project id.Id     `json:"project"`
+	Task    id.Id     `json:"task"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+}
+
+type GetBurndownResp struct {
+	Days []*BurndownDay `json:"days"`
+}
+
+var getBurndown = &endpoint.Endpoint{
+	Path:                     "/api/v1/task/getBurndown",
+	RequiresSession:          false,
+	ExampleResponseStructure: &GetBurndownResp{Days: []*BurndownDay{{}}},
+	GetArgsStruct: func() interface{} {
+		return &getBurndownArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getBurndownArgs)
+		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
+		from := args.From.UTC().Truncate(24 * time.Hour)
+		to := args.To.UTC().Truncate(24 * time.Hour)
+		ctx.ReturnBadRequestNowIf(to.Before(from), "to must not be before from")
+		dayCount := int(to.Sub(from)/(24*time.Hour)) + 1
+		validate.EntityCount(dayCount, ctx.MaxProcessEntityCount())
+		return dbGetBurndown(ctx, args.Shard, args.Account, args.Project, args.Task, from, dayCount)
+	},
+}
+
+func validateBulkTasks(ctx ctx.Ctx, project id.Id, tasks []id.Id) {
+	validate.EntityCount(len(tasks), ctx.MaxProcessEntityCount())
+	seen := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		ctx.ReturnBadRequestNowIf(project.Equal(task), "bulk operations can not include the project node")
+		ctx.ReturnBadRequestNowIf(seen[task.String()], "duplicate task ids")
+		seen[task.String()] = true
+	}
+}
+
 var Endpoints = []*endpoint.Endpoint{
 	create,
 	edit,
+	addMember,
+	removeMember,
+	setMemberRemainingTime,
+	bulkSetMember,
 	move,
+	bulkMove,
+	copyTask,
+	importTasks,
 	delete,
+	bulkDelete,
+	getTrash,
+	restore,
 	get,
 	getChildren,
 	getAncestors,
+	getSubtree,
+	getByLabels,
+	search,
+	addLabels,
+	removeLabels,
+	addDependency,
+	removeDependency,
+	getDependencies,
+	getCriticalPath,
+	getForecast,
+	getBurndown,
 }
 
 type Task struct {
-	Id                   id.Id     `json:"id"`
-	Parent               *id.Id    `json:"parent,omitempty"`
-	FirstChild           *id.Id    `json:"firstChild,omitempty"`
-	NextSibling          *id.Id    `json:"nextSibling,omitempty"`
-	IsAbstract           bool      `json:"isAbstract"`
-	Name                 string    `json:"name"`
-	Description          *string   `json:"description"`
-	CreatedOn            time.Time `json:"createdOn"`
-	TotalRemainingTime   uint64    `json:"totalRemainingTime"`
-	TotalLoggedTime      uint64    `json:"totalLoggedTime"`
-	MinimumRemainingTime *uint64   `json:"minimumRemainingTime,omitempty"` //only abstract tasks
-	LinkedFileCount      uint64    `json:"linkedFileCount"`
-	ChatCount            uint64    `json:"chatCount"`
-	ChildCount           *uint64   `json:"childCount,omitempty"`      //only abstract tasks
-	DescendantCount      *uint64   `json:"descendantCount,omitempty"` //only abstract tasks
-	IsParallel           *bool     `json:"isParallel,omitempty"`      //only abstract tasks
-	Member               *id.Id    `json:"member,omitempty"`          //only task tasks
+	Id                   id.Id                `json:"id"`
+	Parent               *id.Id               `json:"parent,omitempty"`
+	FirstChild           *id.Id               `json:"firstChild,omitempty"`
+	NextSibling          *id.Id               `json:"nextSibling,omitempty"`
+	IsAbstract           bool                 `json:"isAbstract"`
+	Name                 string               `json:"name"`
+	Description          *string              `json:"description"`
+	CreatedOn            time.Time            `json:"createdOn"`
+	TotalRemainingTime   uint64               `json:"totalRemainingTime"`
+	TotalLoggedTime      uint64               `json:"totalLoggedTime"`
+	TotalCost            uint64               `json:"totalCost"`                      //the cost of all time logged on the task and its descendants
+	TotalRevenue         uint64               `json:"totalRevenue"`                   //the cost of the billable time logged on the task and its descendants
+	MinimumRemainingTime *uint64              `json:"minimumRemainingTime,omitempty"` //only abstract tasks
+	LinkedFileCount      uint64               `json:"linkedFileCount"`
+	ChatCount            uint64               `json:"chatCount"`
+	ChildCount           *uint64              `json:"childCount,omitempty"`      //only abstract tasks
+	DescendantCount      *uint64              `json:"descendantCount,omitempty"` //only abstract tasks
+	IsParallel           *bool                `json:"isParallel,omitempty"`      //only abstract tasks
+	Members              []*TaskMember        `json:"members,omitempty"`         //only concrete tasks
+	State                *id.Id               `json:"state,omitempty"`           //only concrete tasks
+	StateCounts          []*StateCount        `json:"stateCounts,omitempty"`     //only abstract tasks, counts of concrete descendants in each state
+	Labels               []id.Id              `json:"labels"`
+	CustomFieldValues    []*customfield.Value `json:"customFieldValues"`
+}
+
+type TaskMember struct {
+	Id            id.Id  `json:"id"`
+	RemainingTime uint64 `json:"remainingTime"` //this members share of the tasks totalRemainingTime
+}
+
+type TrashItem struct {
+	Id                 id.Id     `json:"id"`
+	DeletedOn          time.Time `json:"deletedOn"`
+	DeletedBy          id.Id     `json:"deletedBy"`
+	Parent             id.Id     `json:"parent"`
+	PreviousSibling    *id.Id    `json:"previousSibling,omitempty"`
+	IsAbstract         bool      `json:"isAbstract"`
+	Name               string    `json:"name"`
+	TotalRemainingTime uint64    `json:"totalRemainingTime"`
+	TotalLoggedTime    uint64    `json:"totalLoggedTime"`
+	DescendantCount    uint64    `json:"descendantCount"`
+	PurgeOn            time.Time `json:"purgeOn"` //when it will be permanently deleted
+}
+
+type StateCount struct {
+	State id.Id  `json:"state"`
+	Count uint64 `json:"count"`
 }
 
 type Ancestor struct {
@@ -280,11 +1009,59 @@ type Ancestor struct {
 	//may want to add on time values here to render progress bars within breadcrumb ui component
 }
 
+type SearchResult struct {
+	Task      *Task             `json:"task"`
+	Ancestors *GetAncestorsResp `json:"ancestors"`
+}
+
+type CriticalPathTask struct {
+	Id      id.Id   `json:"id"`
+	Name    string  `json:"name"`
+	Members []id.Id `json:"members,omitempty"`
+	Start   uint64  `json:"start"`  //minutes after the project starts
+	Finish  uint64  `json:"finish"` //minutes after the project starts
+}
+
+type ForecastTask struct {
+	Id     id.Id     `json:"id"`
+	Start  time.Time `json:"start"`
+	Finish time.Time `json:"finish"`
+}
+
+type BurndownDay struct {
+	Day           time.Time `json:"day"`
+	RemainingTime uint64    `json:"remainingTime"` //burndown
+	LoggedTime    uint64    `json:"loggedTime"`    //burnup, cumulative
+	TotalTime     uint64    `json:"totalTime"`     //scope line for burnup, remainingTime + loggedTime
+}
+
 type Fields struct {
-	Name          *field.String    `json:"name,omitempty"`
-	Description   *field.StringPtr `json:"description,omitempty"`
-	IsAbstract    *field.Bool      `json:"isAbstract,omitempty"`    //limit to only editable on abstract tasks which have no children and concrete tasks which have no timelogs
-	IsParallel    *field.Bool      `json:"isParallel,omitempty"`    //only relevant to abstract tasks
-	Member        *field.IdPtr     `json:"member,omitempty"`        //only relevant to concrete tasks
-	RemainingTime *field.UInt64    `json:"remainingTime,omitempty"` //only relevant to concrete tasks
+	Name              *field.String           `json:"name,omitempty"`
+	Description       *field.StringPtr        `json:"description,omitempty"`
+	IsAbstract        *field.Bool             `json:"isAbstract,omitempty"`        //limit to only editable on abstract tasks which have no children and concrete tasks which have no timelogs
+	IsParallel        *field.Bool             `json:"isParallel,omitempty"`        //only relevant to abstract tasks
+	Member            *field.IdPtr            `json:"member,omitempty"`            //only relevant to concrete tasks, replaces all of the tasks members
+	RemainingTime     *field.UInt64           `json:"remainingTime,omitempty"`     //only relevant to concrete tasks, sets my share if I'm a member, else the only members share, else the unassigned time
+	State             *field.Id               `json:"state,omitempty"`             //only relevant to concrete tasks
+	CustomFieldValues *CustomFieldValuesField `json:"customFieldValues,omitempty"` //sets each of these values, a value with nothing set clears it
+}
+
+type CustomFieldValuesField struct {
+	Val []*customfield.Value `json:"val"`
+}
+
+// validateCustomFieldValues checks values, either to set or to filter by, against the projects custom fields, each field may only appear once.
+func validateCustomFieldValues(ctx ctx.Ctx, shard int, account, project id.Id, values []*customfield.Value) {
+	if len(values) == 0 {
+		return
+	}
+	validate.EntityCount(len(values), customfield.MaxFields)
+	fields := db.GetProjectCustomFields(ctx, shard, account, project)
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		ctx.ReturnBadRequestNowIf(v == nil, "custom field values must not be null")
+		customfield.ValidateValue(fields, v)
+		ctx.ReturnBadRequestNowIf(seen[v.Field.String()], "duplicate custom field")
+		seen[v.Field.String()] = true
+	}
 }
@@ -0,0 +1,284 @@
+package task
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/0xor1/trees/server/util/id"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxImportTaskCount            = 1000
+	importNameMinRuneCount        = 1
+	importNameMaxRuneCount        = 250
+	importDescriptionMaxRuneCount = 1250
+)
+
+var (
+	markdownListItem = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+(.*)$`)
+	markdownIndent   = regexp.MustCompile(`^\s*`)
+)
+
+// ImportError is a reason a single row of an import document was rejected, Row is the 1 based line number in a
+// markdown outline or the 1 based record number, counting the header, in a csv.
+type ImportError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+type importRow struct {
+	row         int
+	indent      int
+	parent      *importRow
+	children    []*importRow
+	name        string
+	description *string
+	estimate    *uint64
+	assignee    string
+	member      *id.Id //set by validateImportRows
+	//set by flattenImportRows
+	id          id.Id
+	firstChild  *id.Id
+	nextSibling *id.Id
+}
+
+type importErrors []*ImportError
+
+func (ie *importErrors) add(row int, format string, args ...interface{}) {
+	*ie = append(*ie, &ImportError{Row: row, Message: fmt.Sprintf(format, args...)})
+}
+
+// parseMarkdownImport reads an outline of list items, each nested item is a child of the item above it with less
+// indentation, an item may end with "~<estimate>" and "@<assignee>" and any indented line that isn't a list item
+// is added to the description of the item above it.
+func parseMarkdownImport(data string) ([]*importRow, importErrors) {
+	roots := make([]*importRow, 0, 10)
+	errs := importErrors{}
+	stack := make([]*importRow, 0, 10)
+	for i, line := range strings.Split(strings.Replace(data, "\t", "    ", -1), "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" {
+			continue
+		}
+		matches := markdownListItem.FindStringSubmatch(line)
+		if matches == nil {
+			indent := len(markdownIndent.FindString(line))
+			if len(stack) == 0 || indent <= stack[len(stack)-1].indent {
+				errs.add(i+1, "expected a list item")
+				continue
+			}
+			prev := stack[len(stack)-1]
+			description := strings.TrimSpace(line)
+			if prev.description != nil {
+				description = *prev.description + "\n" + description
+			}
+			prev.description = &description
+			continue
+		}
+		ir := &importRow{row: i + 1, indent: len(matches[1])}
+		fields := strings.Fields(matches[2])
+		for len(fields) > 1 {
+			last := fields[len(fields)-1]
+			if strings.HasPrefix(last, "~") && ir.estimate == nil {
+				ir.estimate = parseImportEstimate(ir.row, last[1:], &errs)
+			} else if strings.HasPrefix(last, "@") && ir.assignee == "" {
+				ir.assignee = last[1:]
+			} else {
+				break
+			}
+			fields = fields[:len(fields)-1]
+		}
+		ir.name = strings.Join(fields, " ")
+		for len(stack) > 0 && stack[len(stack)-1].indent >= ir.indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 {
+			ir.parent = stack[len(stack)-1]
+			ir.parent.children = append(ir.parent.children, ir)
+		} else {
+			roots = append(roots, ir)
+		}
+		stack = append(stack, ir)
+	}
+	return roots, errs
+}
+
+// parseCsvImport reads a csv with a header row naming its columns, name is required and parent path, description,
+// estimate and assignee are optional, parent path is a "/" separated list of names leading from the import parent to
+// a task on an earlier row, an empty parent path puts the task directly under the import parent.
+func parseCsvImport(data string) ([]*importRow, importErrors) {
+	roots := make([]*importRow, 0, 10)
+	errs := importErrors{}
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.TrimLeadingSpace = true
+	header, e := reader.Read()
+	if e != nil {
+		errs.add(1, "missing or invalid header row")
+		return roots, errs
+	}
+	columns := map[string]int{}
+	for i, col := range header {
+		col = strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(col)))
+		if col == "parent" || col == "path" {
+			col = "parentpath"
+		}
+		switch col {
+		case "parentpath", "name", "description", "estimate", "assignee":
+			if _, exists := columns[col]; exists {
+				errs.add(1, "duplicate column %q", header[i])
+			}
+			columns[col] = i
+		default:
+			errs.add(1, "unknown column %q", header[i])
+		}
+	}
+	if _, exists := columns["name"]; !exists {
+		errs.add(1, "missing name column")
+	}
+	if len(errs) > 0 {
+		return roots, errs
+	}
+	cell := func(record []string, col string) string {
+		if i, exists := columns[col]; exists {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	byPath := map[string][]*importRow{}
+	for row := 2; ; row++ {
+		record, e := reader.Read()
+		if e == io.EOF {
+			break
+		}
+		if pe, ok := e.(*csv.ParseError); ok && pe.Err == csv.ErrFieldCount {
+			errs.add(row, "expected %d columns", len(header))
+			continue
+		} else if e != nil {
+			errs.add(row, "invalid csv: %s", e.Error())
+			break
+		}
+		ir := &importRow{row: row, name: cell(record, "name"), assignee: cell(record, "assignee")}
+		if description := cell(record, "description"); description != "" {
+			ir.description = &description
+		}
+		if estimate := cell(record, "estimate"); estimate != "" {
+			ir.estimate = parseImportEstimate(row, estimate, &errs)
+		}
+		path := make([]string, 0, 5)
+		for _, name := range strings.Split(cell(record, "parentpath"), "/") {
+			if name = strings.TrimSpace(name); name != "" {
+				path = append(path, name)
+			}
+		}
+		parentPath := strings.Join(path, "/")
+		if parentPath != "" {
+			parents := byPath[parentPath]
+			if len(parents) == 0 {
+				errs.add(row, "parent path %q does not match an earlier row", parentPath)
+				continue
+			} else if len(parents) > 1 {
+				errs.add(row, "parent path %q matches more than one earlier row", parentPath)
+				continue
+			}
+			ir.parent = parents[0]
+			ir.parent.children = append(ir.parent.children, ir)
+		} else {
+			roots = append(roots, ir)
+		}
+		ownPath := ir.name
+		if parentPath != "" {
+			ownPath = parentPath + "/" + ir.name
+		}
+		byPath[ownPath] = append(byPath[ownPath], ir)
+	}
+	return roots, errs
+}
+
+// parseImportEstimate accepts a whole number of minutes or a duration like "1h30m".
+func parseImportEstimate(row int, val string, errs *importErrors) *uint64 {
+	if minutes, e := strconv.ParseUint(val, 10, 64); e == nil {
+		return &minutes
+	}
+	if d, e := time.ParseDuration(val); e == nil && d >= 0 && d%time.Minute == 0 {
+		minutes := uint64(d / time.Minute)
+		return &minutes
+	}
+	errs.add(row, "invalid estimate %q", val)
+	return nil
+}
+
+// validateImportRows checks every row against the rules task/create applies, assignees maps the assignee names used in
+// the document to the active project writers with those names, rows with children are imported as abstract tasks.
+func validateImportRows(roots []*importRow, assignees map[string]id.Id) importErrors {
+	errs := importErrors{}
+	var validate func(rows []*importRow)
+	validate = func(rows []*importRow) {
+		for _, ir := range rows {
+			if count := utf8.RuneCountInString(ir.name); count < importNameMinRuneCount || count > importNameMaxRuneCount {
+				errs.add(ir.row, "name must be between %d and %d characters long", importNameMinRuneCount, importNameMaxRuneCount)
+			}
+			if ir.description != nil && utf8.RuneCountInString(*ir.description) > importDescriptionMaxRuneCount {
+				errs.add(ir.row, "description must be no more than %d characters long", importDescriptionMaxRuneCount)
+			}
+			if len(ir.children) > 0 && (ir.estimate != nil || ir.assignee != "") {
+				errs.add(ir.row, "tasks with children can not have an estimate or assignee")
+			}
+			if ir.assignee != "" {
+				if member, exists := assignees[ir.assignee]; exists {
+					ir.member = &member
+				} else {
+					errs.add(ir.row, "assignee %q is not an active project member with write access", ir.assignee)
+				}
+			}
+			validate(ir.children)
+		}
+	}
+	validate(roots)
+	return errs
+}
+
+// flattenImportRows gives every row a new id and links it to its first child and next sibling, the rows are returned
+// depth first so every row comes after its parent, which is the order importTasks expects.
+func flattenImportRows(roots []*importRow) []*importRow {
+	res := make([]*importRow, 0, 100)
+	var flatten func(rows []*importRow)
+	flatten = func(rows []*importRow) {
+		for _, ir := range rows {
+			ir.id = id.New()
+		}
+		for i, ir := range rows {
+			if i+1 < len(rows) {
+				ir.nextSibling = &rows[i+1].id
+			}
+			if len(ir.children) > 0 {
+				ir.firstChild = &ir.children[0].id
+			}
+			res = append(res, ir)
+			flatten(ir.children)
+		}
+	}
+	flatten(roots)
+	return res
+}
+
+func importRowAssignees(roots []*importRow) []string {
+	res := make([]string, 0, 10)
+	seen := map[string]bool{}
+	var collect func(rows []*importRow)
+	collect = func(rows []*importRow) {
+		for _, ir := range rows {
+			if ir.assignee != "" && !seen[ir.assignee] {
+				seen[ir.assignee] = true
+				res = append(res, ir.assignee)
+			}
+			collect(ir.children)
+		}
+	}
+	collect(roots)
+	return res
+}
@@ -0,0 +1,301 @@
+package task
+
+import (
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/id"
+	"time"
+)
+
+type scheduleTask struct {
+	id            id.Id
+	parent        *id.Id
+	nextSibling   *id.Id
+	isAbstract    bool
+	isParallel    bool
+	remainingTime uint64
+	name          string
+	members       []*scheduleMember
+	//populated by newSchedule
+	previousSibling *scheduleTask
+	parentTask      *scheduleTask
+	children        []*scheduleTask
+	dependsOn       []*scheduleTask
+	//populated by calcStart and calcFinish
+	state         int
+	start         uint64
+	finish        uint64
+	startDriver   *scheduleTask //the task whose finish (or start if it is the parent) determined this tasks start, nil if it starts at 0
+	finishDriver  *scheduleTask //the child whose finish determined this abstract tasks finish
+	startIsParent bool
+}
+
+type scheduleMember struct {
+	id            id.Id
+	remainingTime uint64
+}
+
+type scheduleInterval struct {
+	start  uint64
+	finish uint64
+}
+
+type scheduleDependency struct {
+	task      id.Id
+	dependsOn id.Id
+}
+
+const (
+	scheduleStateNew = iota
+	scheduleStateStarting
+	scheduleStateStarted
+	scheduleStateFinishing
+	scheduleStateFinished
+)
+
+// schedule lays out every task in a project as a time offset from the start of the project, tasks start after their
+// parent starts, after their previous sibling finishes when the parent is serial and after all of their dependencies
+// finish. A concrete tasks members work on their shares of it side by side, so it finishes after its longest share, or
+// its unassigned remaining time, and abstract tasks finish when their last child finishes. When memberAware is set a
+// member can only work on one task at a time, so each share is pushed back to the earliest gap in its members existing
+// work that it fits in.
+type schedule struct {
+	project     *scheduleTask
+	tasks       map[string]*scheduleTask
+	memberAware bool
+	members     map[string][]*scheduleInterval
+}
+
+func newSchedule(project id.Id, tasks []*scheduleTask, deps []*scheduleDependency, memberAware bool) *schedule {
+	s := &schedule{
+		tasks:       make(map[string]*scheduleTask, len(tasks)),
+		memberAware: memberAware,
+		members:     map[string][]*scheduleInterval{},
+	}
+	for _, st := range tasks {
+		s.tasks[st.id.String()] = st
+	}
+	s.project = s.tasks[project.String()]
+	panic.If(s.project == nil, "project task node not found")
+	for _, st := range tasks {
+		if st.parent != nil {
+			st.parentTask = s.tasks[st.parent.String()]
+		}
+		if st.nextSibling != nil {
+			if next := s.tasks[st.nextSibling.String()]; next != nil {
+				next.previousSibling = st
+			}
+		}
+	}
+	for _, st := range tasks {
+		if st.parentTask != nil && st.previousSibling == nil {
+			for child := st; child != nil; child = s.nextSibling(child) {
+				st.parentTask.children = append(st.parentTask.children, child)
+			}
+		}
+	}
+	for _, dep := range deps {
+		task, dependsOn := s.tasks[dep.task.String()], s.tasks[dep.dependsOn.String()]
+		if task != nil && dependsOn != nil {
+			task.dependsOn = append(task.dependsOn, dependsOn)
+		}
+	}
+	s.calcFinish(s.project)
+	return s
+}
+
+func (s *schedule) nextSibling(st *scheduleTask) *scheduleTask {
+	if st.nextSibling == nil {
+		return nil
+	}
+	return s.tasks[st.nextSibling.String()]
+}
+
+// the dependency tables cycle detection only covers direct dependencies, a dependency can still wait on a task that
+// can only start once the dependent has finished due to the tree structure, in that case the edge which would close
+// the loop is ignored.
+func (s *schedule) calcStart(st *scheduleTask) {
+	if st.state != scheduleStateNew {
+		return
+	}
+	st.state = scheduleStateStarting
+	st.start = 0
+	if st.parentTask != nil && s.canUseStart(st.parentTask) {
+		s.calcStart(st.parentTask)
+		st.start = st.parentTask.start
+		st.startDriver = st.parentTask
+		st.startIsParent = true
+		if !st.parentTask.isParallel && st.previousSibling != nil && s.canUseFinish(st.previousSibling) {
+			s.calcFinish(st.previousSibling)
+			if st.previousSibling.finish >= st.start {
+				st.start = st.previousSibling.finish
+				st.startDriver = st.previousSibling
+				st.startIsParent = false
+			}
+		}
+	}
+	for _, dep := range st.dependsOn {
+		if s.canUseFinish(dep) {
+			s.calcFinish(dep)
+			if dep.finish > st.start {
+				st.start = dep.finish
+				st.startDriver = dep
+				st.startIsParent = false
+			}
+		}
+	}
+	if st.start == 0 { //nothing is holding this task back
+		st.startDriver = nil
+		st.startIsParent = false
+	}
+	st.state = scheduleStateStarted
+}
+
+func (s *schedule) calcFinish(st *scheduleTask) {
+	if st.state == scheduleStateFinished {
+		return
+	}
+	s.calcStart(st)
+	st.state = scheduleStateFinishing
+	if st.isAbstract {
+		st.finish = st.start
+		for _, child := range st.children {
+			if s.canUseFinish(child) {
+				s.calcFinish(child)
+				if st.finishDriver == nil || child.finish > st.finish {
+					st.finish = child.finish
+					st.finishDriver = child
+				}
+			}
+		}
+	} else {
+		earliestStart := st.start
+		unassigned := st.remainingTime
+		st.finish = st.start
+		for i, m := range st.members {
+			if unassigned >= m.remainingTime {
+				unassigned -= m.remainingTime
+			} else {
+				unassigned = 0
+			}
+			start := earliestStart
+			if s.memberAware && m.remainingTime > 0 {
+				start = s.reserveMemberTime(m.id, earliestStart, m.remainingTime)
+			}
+			if i == 0 || start < st.start {
+				st.start = start
+			}
+			if start+m.remainingTime > st.finish {
+				st.finish = start + m.remainingTime
+			}
+		}
+		if unassigned > 0 || len(st.members) == 0 { //unassigned time can be picked up straight away by anyone
+			st.start = earliestStart
+			if earliestStart+unassigned > st.finish {
+				st.finish = earliestStart + unassigned
+			}
+		}
+	}
+	st.state = scheduleStateFinished
+}
+
+// reserveMemberTime books duration into the first gap in the members work at or after earliestStart and returns the
+// start of the booking.
+func (s *schedule) reserveMemberTime(member id.Id, earliestStart, duration uint64) uint64 {
+	booked := s.members[member.String()]
+	start := earliestStart
+	idx := 0
+	for ; idx < len(booked); idx++ {
+		if booked[idx].finish <= start {
+			continue
+		}
+		if booked[idx].start >= start+duration {
+			break
+		}
+		start = booked[idx].finish
+	}
+	booked = append(booked, nil)
+	copy(booked[idx+1:], booked[idx:])
+	booked[idx] = &scheduleInterval{start: start, finish: start + duration}
+	s.members[member.String()] = booked
+	return start
+}
+
+func (st *scheduleTask) memberIds() []id.Id {
+	if len(st.members) == 0 {
+		return nil
+	}
+	res := make([]id.Id, 0, len(st.members))
+	for _, m := range st.members {
+		res = append(res, m.id)
+	}
+	return res
+}
+
+func (s *schedule) canUseStart(st *scheduleTask) bool {
+	return st.state != scheduleStateStarting
+}
+
+func (s *schedule) canUseFinish(st *scheduleTask) bool {
+	return st.state != scheduleStateStarting && st.state != scheduleStateFinishing
+}
+
+// criticalPath walks back from the project finish through whatever determined each finish and start, returning the
+// chain of concrete tasks with no slack in the order they run.
+func (s *schedule) criticalPath() []*scheduleTask {
+	path := make([]*scheduleTask, 0, 20)
+	st := s.project
+	atFinish := true
+	for st != nil {
+		if atFinish {
+			if !st.isAbstract {
+				path = append(path, st)
+			} else if st.finishDriver != nil {
+				st = st.finishDriver
+				continue
+			}
+		}
+		atFinish = !st.startIsParent
+		st = st.startDriver
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+const workDayStartHour = 9 //UTC
+
+// workTimeToDate converts a schedule offset in minutes of work to a date, a working day is hoursPerDay of work starting
+// at workDayStartHour and only the first daysPerWeek days of each week, starting on Monday, are worked. Work starts at
+// base, or the start of the next working day if base is outside working hours.
+func workTimeToDate(base time.Time, offset uint64, hoursPerDay, daysPerWeek uint8) time.Time {
+	if daysPerWeek > 7 {
+		daysPerWeek = 7
+	}
+	minutesPerDay := uint64(hoursPerDay) * 60
+	base = base.UTC()
+	date := time.Date(base.Year(), base.Month(), base.Day(), workDayStartHour, 0, 0, 0, time.UTC)
+	if workDay := nextWorkDay(date, daysPerWeek); !workDay.Equal(date) {
+		date = workDay
+	} else if base.After(date) {
+		//the part of the working day already gone is counted as done so the offset carries on from base
+		done := uint64(base.Sub(date) / time.Minute)
+		if done > minutesPerDay {
+			done = minutesPerDay
+		}
+		offset += done
+	}
+	days := offset / minutesPerDay
+	date = date.AddDate(0, 0, int(days/uint64(daysPerWeek))*7) //whole weeks land on the same week day
+	for i := uint64(0); i < days%uint64(daysPerWeek); i++ {
+		date = nextWorkDay(date.AddDate(0, 0, 1), daysPerWeek)
+	}
+	return date.Add(time.Duration(offset%minutesPerDay) * time.Minute)
+}
+
+func nextWorkDay(date time.Time, daysPerWeek uint8) time.Time {
+	for (int(date.Weekday())+6)%7 >= int(daysPerWeek) {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
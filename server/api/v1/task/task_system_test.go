@@ -4,7 +4,9 @@ import (
 	"github.com/0xor1/trees/server/api/v1/account"
 	"github.com/0xor1/trees/server/api/v1/project"
 	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/customfield"
 	"github.com/0xor1/trees/server/util/field"
+	"github.com/0xor1/trees/server/util/id"
 	"github.com/0xor1/trees/server/util/systemtest"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -42,7 +44,7 @@ func Test_system(t *testing.T) {
 		assert.Equal(t, uint64(0), *taskA.ChildCount)
 		assert.Equal(t, uint64(0), *taskA.DescendantCount)
 		assert.Equal(t, false, *taskA.IsParallel)
-		assert.Nil(t, taskA.Member)
+		assert.Nil(t, taskA.Members)
 		client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, &taskA.Id, "B", &desc, true, &falseVal, nil, nil)
 		taskC, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, "C", &desc, true, &trueVal, nil, nil)
 		taskD, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, &taskA.Id, "D", &desc, false, nil, &base.Ali.Info.Me.Id, &fourVal)
@@ -62,13 +64,46 @@ func Test_system(t *testing.T) {
 		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, Fields{Member: &field.IdPtr{&base.Bob.Info.Me.Id}})
 		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, Fields{Member: &field.IdPtr{&base.Cat.Info.Me.Id}})
 		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, Fields{Member: &field.IdPtr{nil}})
-		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, Fields{Member: &field.IdPtr{&base.Cat.Info.Me.Id}})
 		client.Edit(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskG.Id, Fields{RemainingTime: &field.UInt64{1}})
 
 		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, Fields{IsAbstract: &field.Bool{true}})
 		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, Fields{IsAbstract: &field.Bool{false}})
 		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, Fields{IsAbstract: &field.Bool{true}})
 		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, Fields{IsAbstract: &field.Bool{false}})
+		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, Fields{Member: &field.IdPtr{&base.Cat.Info.Me.Id}})
+		err = client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, Fields{IsAbstract: &field.Bool{true}})
+		assert.NotNil(t, err)
+
+		//tasks can be shared between members, each with their own share of the remaining time
+		err = client.AddMember(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskK.Id, base.Bob.Info.Me.Id, &twoVal)
+		assert.Nil(t, err)
+		err = client.AddMember(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskK.Id, base.Bob.Info.Me.Id, &twoVal)
+		assert.NotNil(t, err)
+		err = client.AddMember(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskK.Id, base.Dan.Info.Me.Id, nil)
+		assert.NotNil(t, err)
+		shared, err := client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskK.Id)
+		assert.Equal(t, uint64(6), shared.TotalRemainingTime)
+		assert.Equal(t, 2, len(shared.Members))
+		err = client.SetMemberRemainingTime(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskK.Id, base.Bob.Info.Me.Id, 1)
+		assert.Nil(t, err)
+		err = client.RemoveMember(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskK.Id, base.Ali.Info.Me.Id)
+		assert.Nil(t, err)
+		shared, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskK.Id)
+		assert.Equal(t, uint64(5), shared.TotalRemainingTime)
+		assert.Equal(t, 1, len(shared.Members))
+		assert.True(t, base.Bob.Info.Me.Id.Equal(shared.Members[0].Id))
+		assert.Equal(t, uint64(1), shared.Members[0].RemainingTime)
+		err = client.AddMember(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskK.Id, base.Ali.Info.Me.Id, nil)
+		assert.Nil(t, err)
+		shared, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskK.Id)
+		assert.Equal(t, uint64(5), shared.TotalRemainingTime)
+		assert.Equal(t, 2, len(shared.Members))
+		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskK.Id, Fields{Member: &field.IdPtr{&base.Ali.Info.Me.Id}})
+		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskK.Id, Fields{RemainingTime: &field.UInt64{4}})
+		shared, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskK.Id)
+		assert.Equal(t, uint64(4), shared.TotalRemainingTime)
+		assert.Equal(t, 1, len(shared.Members))
+		assert.Equal(t, uint64(4), shared.Members[0].RemainingTime)
 
 		client.Move(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskG.Id, taskA.Id, nil)
 		client.Move(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskG.Id, taskA.Id, &taskK.Id)
@@ -81,6 +116,39 @@ func Test_system(t *testing.T) {
 		assert.True(t, taskA.Id.Equal(ancestors.Ancestors[1].Id))
 		assert.True(t, taskL.Id.Equal(ancestors.Ancestors[2].Id))
 
+		subtree, err := client.GetSubtree(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskL.Id, 1, nil, 100, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(subtree.Tasks))
+		assert.True(t, taskM.Id.Equal(subtree.Tasks[0].Id))
+		assert.True(t, taskG.Id.Equal(subtree.Tasks[1].Id))
+		subtree, err = client.GetSubtree(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, 1, nil, 100, nil)
+		assert.Equal(t, 3, len(subtree.Tasks))
+		subtree, err = client.GetSubtree(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, 100, nil, 100, nil)
+		assert.Equal(t, 13, len(subtree.Tasks))
+		assert.False(t, subtree.More)
+		seen := map[string]bool{proj.Id.String(): true}
+		for _, ta := range subtree.Tasks { //every task comes after its parent
+			assert.True(t, seen[ta.Parent.String()])
+			seen[ta.Id.String()] = true
+		}
+		page, err := client.GetSubtree(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, 100, nil, 5, nil)
+		paged := page.Tasks
+		for page.More {
+			page, err = client.GetSubtree(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, 100, &paged[len(paged)-1].Id, 5, nil)
+			paged = append(paged, page.Tasks...)
+		}
+		assert.Equal(t, len(subtree.Tasks), len(paged))
+		for i := range paged {
+			assert.True(t, subtree.Tasks[i].Id.Equal(paged[i].Id))
+		}
+		_, err = client.GetSubtree(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, 0, nil, 100, nil)
+		assert.NotNil(t, err)
+
+		//every create, edit and move so far should have left the aggregates consistent
+		discrepancies, err := base.SR.RegionalV1PrivateClient.VerifyTree(base.Region, 0, &base.Org.Id, &proj.Id, false)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(discrepancies))
+
 		//test setting project as public and try getting info without a session
 		ancestors, err = client.GetAncestors(nil, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, 100)
 		assert.Nil(t, ancestors)
@@ -102,15 +170,264 @@ func Test_system(t *testing.T) {
 
 		task, err := client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskH.Id)
 		assert.NotNil(t, 2, task)
-		res, err := client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, nil, 100)
+		res, err := client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, nil, 100, nil, nil)
 		assert.Equal(t, 3, len(res.Children))
-		res, err = client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, nil, 2)
+		res, err = client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, nil, 2, nil, nil)
 		assert.Equal(t, 2, len(res.Children))
-		res, err = client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, &taskE.Id, 100)
+		res, err = client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, &taskE.Id, 100, nil, nil)
 		assert.Equal(t, 2, len(res.Children))
-		res, err = client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, &taskH.Id, 100)
+		res, err = client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, &taskH.Id, 100, nil, nil)
+		assert.Equal(t, 1, len(res.Children))
+		res, err = client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, &taskF.Id, 100, nil, nil)
+		assert.Equal(t, 0, len(res.Children))
+
+		//custom field values are set through edit and can filter children and subtrees
+		risk, err := projectClient.CreateCustomField(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, "risk", cnst.CustomFieldSelect, []string{"low", "high"})
+		assert.Nil(t, err)
+		high := "high"
+		medium := "medium"
+		err = client.Edit(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskH.Id, Fields{CustomFieldValues: &CustomFieldValuesField{[]*customfield.Value{{Field: risk.Id, Text: &high}}}})
+		assert.Nil(t, err)
+		err = client.Edit(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, Fields{CustomFieldValues: &CustomFieldValuesField{[]*customfield.Value{{Field: risk.Id, Text: &medium}}}})
+		assert.NotNil(t, err)
+		task, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskH.Id)
+		assert.Equal(t, 1, len(task.CustomFieldValues))
+		assert.Equal(t, high, *task.CustomFieldValues[0].Text)
+		res, err = client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, nil, 100, nil, []*customfield.Value{{Field: risk.Id, Text: &high}})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(res.Children))
+		assert.True(t, taskH.Id.Equal(res.Children[0].Id))
+		res, err = client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, nil, 1, nil, []*customfield.Value{{Field: risk.Id}})
 		assert.Equal(t, 1, len(res.Children))
-		res, err = client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, &taskF.Id, 100)
+		assert.True(t, taskE.Id.Equal(res.Children[0].Id))
+		assert.True(t, res.More)
+		subtree, err = client.GetSubtree(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, 100, nil, 100, []*customfield.Value{{Field: risk.Id, Text: &high}})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(subtree.Tasks))
+		assert.True(t, taskH.Id.Equal(subtree.Tasks[0].Id))
+		err = client.Edit(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskH.Id, Fields{CustomFieldValues: &CustomFieldValuesField{[]*customfield.Value{{Field: risk.Id}}}})
+		assert.Nil(t, err)
+		res, err = client.GetChildren(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, nil, 100, nil, []*customfield.Value{{Field: risk.Id, Text: &high}})
 		assert.Equal(t, 0, len(res.Children))
+
+		//dependencies between children of a parallel task chain them together
+		err = client.AddDependency(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskH.Id, taskE.Id)
+		assert.Nil(t, err)
+		err = client.AddDependency(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, taskH.Id)
+		assert.NotNil(t, err)
+		err = client.AddDependency(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, taskE.Id)
+		assert.NotNil(t, err)
+		err = client.AddDependency(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, taskF.Id, taskE.Id)
+		assert.NotNil(t, err)
+		err = client.AddDependency(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskF.Id, id.New())
+		assert.NotNil(t, err)
+		err = client.AddDependency(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskF.Id, taskC.Id)
+		assert.NotNil(t, err)
+		err = client.AddDependency(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskH.Id, taskE.Id)
+		assert.NotNil(t, err)
+		task, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id)
+		assert.Equal(t, uint64(5), *task.MinimumRemainingTime)
+		deps, err := client.GetDependencies(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id)
+		assert.Equal(t, 0, len(deps.DependsOn))
+		assert.Equal(t, 1, len(deps.Dependents))
+		assert.True(t, taskH.Id.Equal(deps.Dependents[0]))
+		deps, err = client.GetDependencies(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskH.Id)
+		assert.Equal(t, 1, len(deps.DependsOn))
+		assert.True(t, taskE.Id.Equal(deps.DependsOn[0]))
+		assert.Equal(t, 0, len(deps.Dependents))
+		criticalPath, err := client.GetCriticalPath(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Equal(t, uint64(5), criticalPath.TotalTime)
+		assert.Equal(t, 2, len(criticalPath.Tasks))
+		assert.True(t, taskE.Id.Equal(criticalPath.Tasks[0].Id))
+		assert.Equal(t, uint64(0), criticalPath.Tasks[0].Start)
+		assert.Equal(t, uint64(2), criticalPath.Tasks[0].Finish)
+		assert.True(t, taskH.Id.Equal(criticalPath.Tasks[1].Id))
+		assert.Equal(t, uint64(2), criticalPath.Tasks[1].Start)
+		assert.Equal(t, uint64(5), criticalPath.Tasks[1].Finish)
+		err = client.RemoveDependency(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskH.Id, taskE.Id)
+		assert.Nil(t, err)
+		err = client.RemoveDependency(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskH.Id, taskE.Id)
+		assert.NotNil(t, err)
+		task, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id)
+		assert.Equal(t, uint64(3), *task.MinimumRemainingTime)
+
+		//E, F and H are all assigned to ali so they can't be worked on in parallel, starting two minutes before the end of a
+		//friday the work runs over the weekend in to the start of monday
+		friday := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 7)
+		for friday.Weekday() != time.Friday {
+			friday = friday.AddDate(0, 0, 1)
+		}
+		forecastStart := friday.Add(16*time.Hour + 58*time.Minute)
+		forecastDueOn := friday.AddDate(0, 0, 14)
+		err = projectClient.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, project.Fields{StartOn: &field.TimePtr{Val: &forecastStart}, DueOn: &field.TimePtr{Val: &forecastDueOn}})
+		assert.Nil(t, err)
+		forecast, err := client.GetForecast(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, 5, len(forecast.Tasks))
+		assert.True(t, forecastStart.Equal(forecast.Start))
+		assert.True(t, friday.AddDate(0, 0, 3).Add(9*time.Hour+4*time.Minute).Equal(forecast.Finish))
+		assert.False(t, forecast.IsLate)
+
+		burndown, err := client.GetBurndown(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, start.AddDate(0, 0, -1), start)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(burndown.Days))
+		assert.Equal(t, uint64(0), burndown.Days[0].RemainingTime)
+		assert.Equal(t, uint64(6), burndown.Days[1].RemainingTime)
+		assert.Equal(t, uint64(0), burndown.Days[1].LoggedTime)
+		assert.Equal(t, uint64(6), burndown.Days[1].TotalTime)
+		burndown, err = client.GetBurndown(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, start, start)
+		assert.Equal(t, 1, len(burndown.Days))
+		assert.Equal(t, uint64(6), burndown.Days[0].RemainingTime)
+		burndown, err = client.GetBurndown(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, start, start.AddDate(0, 0, -1))
+		assert.Nil(t, burndown)
+		assert.NotNil(t, err)
+		burndown, err = client.GetBurndown(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, id.New(), start, start)
+		assert.Nil(t, burndown)
+		assert.NotNil(t, err)
+
+		states, err := projectClient.GetStates(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Equal(t, 4, len(states))
+		taskS, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, "S", nil, true, &falseVal, nil, nil)
+		taskT, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskS.Id, nil, "T", nil, false, nil, nil, &oneVal)
+		assert.True(t, states[0].Id.Equal(*taskT.State))
+		taskU, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskS.Id, &taskT.Id, "U", nil, false, nil, nil, &oneVal)
+		err = client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskU.Id, Fields{State: &field.Id{states[3].Id}})
+		assert.Nil(t, err)
+		err = client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskU.Id, Fields{State: &field.Id{states[3].Id}})
+		assert.NotNil(t, err)
+		task, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskS.Id)
+		assert.Equal(t, 2, len(task.StateCounts))
+		for _, sc := range task.StateCounts {
+			assert.Equal(t, uint64(1), sc.Count)
+		}
+		task, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskU.Id)
+		assert.True(t, states[3].Id.Equal(*task.State))
+		err = projectClient.DeleteState(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, states[0].Id, states[3].Id)
+		assert.Nil(t, err)
+		task, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskS.Id)
+		assert.Equal(t, 1, len(task.StateCounts))
+		assert.Equal(t, uint64(2), task.StateCounts[0].Count)
+		client.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskT.Id)
+		task, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskS.Id)
+		assert.Equal(t, uint64(1), task.StateCounts[0].Count)
+
+		searchDesc := "rewrite the invoicing pipeline"
+		client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskU.Id, Fields{Description: &field.StringPtr{&searchDesc}})
+		searchRes, err := client.Search(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, "invoicing", 0, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(searchRes.Results))
+		assert.False(t, searchRes.More)
+		assert.True(t, taskU.Id.Equal(searchRes.Results[0].Task.Id))
+		assert.Equal(t, 2, len(searchRes.Results[0].Ancestors.Ancestors))
+		assert.True(t, proj.Id.Equal(searchRes.Results[0].Ancestors.Ancestors[0].Id))
+		assert.True(t, taskS.Id.Equal(searchRes.Results[0].Ancestors.Ancestors[1].Id))
+		_, err = client.Search(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, "", 0, 10)
+		assert.NotNil(t, err)
+
+		copyS, err := client.Copy(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskS.Id, proj.Id, proj.Id, &taskS.Id, true, false)
+		assert.Nil(t, err)
+		assert.Equal(t, "S", copyS.Name)
+		assert.True(t, proj.Id.Equal(*copyS.Parent))
+		assert.Equal(t, uint64(1), *copyS.ChildCount)
+		assert.Equal(t, uint64(1), copyS.TotalRemainingTime)
+		assert.Equal(t, uint64(0), copyS.TotalLoggedTime)
+		assert.Equal(t, 1, len(copyS.StateCounts))
+		assert.True(t, states[3].Id.Equal(copyS.StateCounts[0].State))
+		copyU, err := client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, *copyS.FirstChild)
+		assert.False(t, copyU.Id.Equal(taskU.Id))
+		assert.Equal(t, 0, len(copyU.Members))
+		copyS, err = client.Copy(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskS.Id, proj.Id, proj.Id, nil, false, false)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(0), copyS.TotalRemainingTime)
+		_, err = client.Copy(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, proj.Id, proj.Id, nil, false, false)
+		assert.NotNil(t, err)
+
+		importRes, err := client.Import(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, cnst.ImportFormatMarkdown, "- Imp\n  - ImpA ~1h @"+base.Cat.Info.Me.Name+"\n    first line\n  - ImpB ~30")
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(importRes.Errors))
+		assert.Equal(t, 1, len(importRes.Tasks))
+		imp, err := client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, importRes.Tasks[0])
+		assert.Equal(t, "Imp", imp.Name)
+		assert.True(t, imp.IsAbstract)
+		assert.Equal(t, uint64(2), *imp.ChildCount)
+		assert.Equal(t, uint64(90), imp.TotalRemainingTime)
+		impA, err := client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, *imp.FirstChild)
+		assert.Equal(t, "ImpA", impA.Name)
+		assert.Equal(t, "first line", *impA.Description)
+		assert.Equal(t, 1, len(impA.Members))
+		assert.True(t, base.Cat.Info.Me.Id.Equal(impA.Members[0].Id))
+		assert.Equal(t, uint64(60), impA.Members[0].RemainingTime)
+		importRes, err = client.Import(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, imp.Id, &impA.Id, cnst.ImportFormatCsv, "parent path,name,estimate,assignee\n,Csv,,\nCsv,CsvA,2h,\nNope,CsvB,,\nCsv,CsvC,,"+base.Dan.Info.Me.Name)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(importRes.Tasks))
+		assert.Equal(t, 2, len(importRes.Errors))
+		assert.Equal(t, 4, importRes.Errors[0].Row)
+		assert.Equal(t, 5, importRes.Errors[1].Row)
+		importRes, err = client.Import(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, imp.Id, &impA.Id, cnst.ImportFormatCsv, "parent path,name,estimate\n,Csv,\nCsv,CsvA,2h")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(importRes.Tasks))
+		imp, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, imp.Id)
+		assert.Equal(t, uint64(3), *imp.ChildCount)
+		assert.Equal(t, uint64(210), imp.TotalRemainingTime)
+		_, err = client.Import(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, cnst.ImportFormatMarkdown, "- Nope")
+		assert.NotNil(t, err)
+
+		impB, err := client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, *impA.NextSibling)
+		err = client.BulkSetMember(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, []id.Id{impA.Id, impB.Id}, &base.Bob.Info.Me.Id)
+		assert.Nil(t, err)
+		impB, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, impB.Id)
+		assert.Equal(t, 1, len(impB.Members))
+		assert.True(t, base.Bob.Info.Me.Id.Equal(impB.Members[0].Id))
+		err = client.BulkSetMember(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, []id.Id{impA.Id, imp.Id}, nil)
+		assert.NotNil(t, err)
+		err = client.BulkMove(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, []id.Id{impB.Id, impA.Id}, proj.Id, nil)
+		assert.Nil(t, err)
+		impB, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, impB.Id)
+		assert.True(t, proj.Id.Equal(*impB.Parent))
+		assert.True(t, impA.Id.Equal(*impB.NextSibling))
+		imp, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, imp.Id)
+		assert.Equal(t, uint64(1), *imp.ChildCount)
+		assert.Equal(t, uint64(120), imp.TotalRemainingTime)
+		err = client.BulkMove(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, []id.Id{imp.Id}, *imp.FirstChild, nil)
+		assert.NotNil(t, err)
+		err = client.BulkDelete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, []id.Id{impA.Id, impB.Id, imp.Id, *imp.FirstChild})
+		assert.Nil(t, err)
+		_, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, imp.Id)
+		assert.NotNil(t, err)
+		err = client.BulkDelete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, []id.Id{proj.Id})
+		assert.NotNil(t, err)
+
+		trash, err := client.GetTrash(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, 100)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(trash.Items))
+		assert.False(t, trash.More)
+		trashPage, err := client.GetTrash(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, 1)
+		assert.Equal(t, 1, len(trashPage.Items))
+		assert.True(t, trashPage.More)
+		err = client.Restore(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, imp.Id, nil, nil)
+		assert.NotNil(t, err)
+		err = client.Restore(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, imp.Id, nil, &impA.Id)
+		assert.NotNil(t, err)
+		err = client.Restore(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, imp.Id, nil, nil)
+		assert.Nil(t, err)
+		imp, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, imp.Id)
+		assert.Nil(t, err)
+		assert.True(t, proj.Id.Equal(*imp.Parent))
+		assert.Equal(t, uint64(1), *imp.ChildCount)
+		err = client.Restore(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, impB.Id, &imp.Id, imp.FirstChild)
+		assert.Nil(t, err)
+		imp, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, imp.Id)
+		assert.Equal(t, uint64(2), *imp.ChildCount)
+		assert.Equal(t, uint64(150), imp.TotalRemainingTime)
+		err = client.Restore(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, impB.Id, nil, nil)
+		assert.NotNil(t, err)
+		trash, err = client.GetTrash(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, 100)
+		assert.Equal(t, 1, len(trash.Items))
+		assert.True(t, impA.Id.Equal(trash.Items[0].Id))
+		assert.Equal(t, 0, base.SR.TrashPurger.Process(0, time.Now().UTC()))
+		base.SR.TrashPurger.Process(0, time.Now().UTC().AddDate(0, 0, base.SR.TrashRetentionDays+1))
+		trash, err = client.GetTrash(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, 100)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(trash.Items))
 	}, account.Endpoints, project.Endpoints, Endpoints)
 }
@@ -5,14 +5,22 @@ import (
 	"github.com/0xor1/trees/server/util/cnst"
 	"github.com/0xor1/trees/server/util/id"
 	tlog "github.com/0xor1/trees/server/util/timelog"
+	"time"
 )
 
 type Client interface {
-	Create(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, duration uint64, note *string) (*tlog.TimeLog, error)                                          //only applys to task tasks
-	CreateAndSetRemainingTime(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, remainingTime uint64, duration uint64, note *string) (*tlog.TimeLog, error) //only applys to task tasks
+	Create(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, duration uint64, loggedOn *time.Time, isBillable *bool, note *string) (*tlog.TimeLog, error)                                          //only applys to task tasks
+	CreateAndSetRemainingTime(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, remainingTime uint64, duration uint64, loggedOn *time.Time, isBillable *bool, note *string) (*tlog.TimeLog, error) //only applys to task tasks
 	Edit(css *clientsession.Store, region cnst.Region, shard int, account, project, timeLog id.Id, fields Fields) error
 	Delete(css *clientsession.Store, region cnst.Region, shard int, account, project, timeLog id.Id) error
-	Get(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, task, member, timeLog *id.Id, sortAsc bool, after *id.Id, limit int) (*GetResp, error)
+	Get(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, task, member, timeLog *id.Id, loggedOnAfter, loggedOnBefore *time.Time, sortAsc bool, after *id.Id, limit int) (*GetResp, error)
+	GetTimesheet(css *clientsession.Store, region cnst.Region, shard int, account id.Id, member *id.Id, loggedOnAfter, loggedOnBefore time.Time, format *cnst.ReportFormat) (*GetTimesheetResp, error)
+	StartTimer(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, note *string) (*Timer, error) //only applys to task tasks
+	PauseTimer(css *clientsession.Store, region cnst.Region, shard int, account id.Id) (*Timer, error)
+	ResumeTimer(css *clientsession.Store, region cnst.Region, shard int, account id.Id) (*Timer, error)
+	StopTimer(css *clientsession.Store, region cnst.Region, shard int, account id.Id, remainingTime *uint64, note *string) (*tlog.TimeLog, error)
+	DiscardTimer(css *clientsession.Store, region cnst.Region, shard int, account id.Id) error
+	GetTimer(css *clientsession.Store, region cnst.Region, shard int, account id.Id) (*Timer, error) //returns nil if I have no timer
 }
 
 func NewClient(host string) Client {
@@ -25,14 +33,16 @@ type client struct {
 	host string
 }
 
-func (c *client) Create(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, duration uint64, note *string) (*tlog.TimeLog, error) {
+func (c *client) Create(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, duration uint64, loggedOn *time.Time, isBillable *bool, note *string) (*tlog.TimeLog, error) {
 	val, e := create.DoRequest(css, c.host, region, &createArgs{
-		Shard:    shard,
-		Account:  account,
-		Project:  project,
-		Task:     task,
-		Duration: duration,
-		Note:     note,
+		Shard:      shard,
+		Account:    account,
+		Project:    project,
+		Task:       task,
+		Duration:   duration,
+		LoggedOn:   loggedOn,
+		IsBillable: isBillable,
+		Note:       note,
 	}, nil, &tlog.TimeLog{})
 	if val != nil {
 		return val.(*tlog.TimeLog), e
@@ -40,7 +50,7 @@ func (c *client) Create(css *clientsession.Store, region cnst.Region, shard int,
 	return nil, e
 }
 
-func (c *client) CreateAndSetRemainingTime(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, remainingTime uint64, duration uint64, note *string) (*tlog.TimeLog, error) {
+func (c *client) CreateAndSetRemainingTime(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, remainingTime uint64, duration uint64, loggedOn *time.Time, isBillable *bool, note *string) (*tlog.TimeLog, error) {
 	val, e := createAndSetRemainingTime.DoRequest(css, c.host, region, &createAndSetRemainingTimeArgs{
 		Shard:         shard,
 		Account:       account,
@@ -48,6 +58,8 @@ func (c *client) CreateAndSetRemainingTime(css *clientsession.Store, region cnst
 		Task:          task,
 		RemainingTime: remainingTime,
 		Duration:      duration,
+		LoggedOn:      loggedOn,
+		IsBillable:    isBillable,
 		Note:          note,
 	}, nil, &tlog.TimeLog{})
 	if val != nil {
@@ -77,20 +89,106 @@ func (c *client) Delete(css *clientsession.Store, region cnst.Region, shard int,
 	return e
 }
 
-func (c *client) Get(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, task, member, timeLog *id.Id, sortAsc bool, after *id.Id, limit int) (*GetResp, error) {
+func (c *client) Get(css *clientsession.Store, region cnst.Region, shard int, account, project id.Id, task, member, timeLog *id.Id, loggedOnAfter, loggedOnBefore *time.Time, sortAsc bool, after *id.Id, limit int) (*GetResp, error) {
 	val, e := get.DoRequest(css, c.host, region, &getArgs{
+		Shard:          shard,
+		Account:        account,
+		Project:        project,
+		Task:           task,
+		Member:         member,
+		TimeLog:        timeLog,
+		LoggedOnAfter:  loggedOnAfter,
+		LoggedOnBefore: loggedOnBefore,
+		SortAsc:        sortAsc,
+		After:          after,
+		Limit:          limit,
+	}, nil, &GetResp{})
+	if val != nil {
+		return val.(*GetResp), e
+	}
+	return nil, e
+}
+
+func (c *client) GetTimesheet(css *clientsession.Store, region cnst.Region, shard int, account id.Id, member *id.Id, loggedOnAfter, loggedOnBefore time.Time, format *cnst.ReportFormat) (*GetTimesheetResp, error) {
+	val, e := getTimesheet.DoRequest(css, c.host, region, &getTimesheetArgs{
+		Shard:          shard,
+		Account:        account,
+		Member:         member,
+		LoggedOnAfter:  loggedOnAfter,
+		LoggedOnBefore: loggedOnBefore,
+		Format:         format,
+	}, nil, &GetTimesheetResp{})
+	if val != nil {
+		return val.(*GetTimesheetResp), e
+	}
+	return nil, e
+}
+
+func (c *client) StartTimer(css *clientsession.Store, region cnst.Region, shard int, account, project, task id.Id, note *string) (*Timer, error) {
+	val, e := startTimer.DoRequest(css, c.host, region, &startTimerArgs{
 		Shard:   shard,
 		Account: account,
 		Project: project,
 		Task:    task,
-		Member:  member,
-		TimeLog: timeLog,
-		SortAsc: sortAsc,
-		After:   after,
-		Limit:   limit,
-	}, nil, &GetResp{})
+		Note:    note,
+	}, nil, &Timer{})
 	if val != nil {
-		return val.(*GetResp), e
+		return val.(*Timer), e
+	}
+	return nil, e
+}
+
+func (c *client) PauseTimer(css *clientsession.Store, region cnst.Region, shard int, account id.Id) (*Timer, error) {
+	val, e := pauseTimer.DoRequest(css, c.host, region, &timerArgs{
+		Shard:   shard,
+		Account: account,
+	}, nil, &Timer{})
+	if val != nil {
+		return val.(*Timer), e
+	}
+	return nil, e
+}
+
+func (c *client) ResumeTimer(css *clientsession.Store, region cnst.Region, shard int, account id.Id) (*Timer, error) {
+	val, e := resumeTimer.DoRequest(css, c.host, region, &timerArgs{
+		Shard:   shard,
+		Account: account,
+	}, nil, &Timer{})
+	if val != nil {
+		return val.(*Timer), e
+	}
+	return nil, e
+}
+
+func (c *client) StopTimer(css *clientsession.Store, region cnst.Region, shard int, account id.Id, remainingTime *uint64, note *string) (*tlog.TimeLog, error) {
+	val, e := stopTimer.DoRequest(css, c.host, region, &stopTimerArgs{
+		Shard:         shard,
+		Account:       account,
+		RemainingTime: remainingTime,
+		Note:          note,
+	}, nil, &tlog.TimeLog{})
+	if val != nil {
+		return val.(*tlog.TimeLog), e
+	}
+	return nil, e
+}
+
+func (c *client) DiscardTimer(css *clientsession.Store, region cnst.Region, shard int, account id.Id) error {
+	_, e := discardTimer.DoRequest(css, c.host, region, &timerArgs{
+		Shard:   shard,
+		Account: account,
+	}, nil, nil)
+	return e
+}
+
+func (c *client) GetTimer(css *clientsession.Store, region cnst.Region, shard int, account id.Id) (*Timer, error) {
+	var ti *Timer //decoded from null when I have no timer
+	val, e := getTimer.DoRequest(css, c.host, region, &timerArgs{
+		Shard:   shard,
+		Account: account,
+	}, nil, &ti)
+	if val != nil {
+		return *val.(**Timer), e
 	}
 	return nil, e
 }
@@ -2,14 +2,18 @@ package timelog
 
 import (
 	"bytes"
+	"database/sql"
 	"fmt"
 	"github.com/0xor1/panic"
 	"github.com/0xor1/trees/server/util/cachekey"
+	"github.com/0xor1/trees/server/util/cnst"
 	"github.com/0xor1/trees/server/util/ctx"
 	"github.com/0xor1/trees/server/util/db"
+	"github.com/0xor1/trees/server/util/err"
 	"github.com/0xor1/trees/server/util/id"
 	"github.com/0xor1/trees/server/util/sortdir"
 	tlog "github.com/0xor1/trees/server/util/timelog"
+	"time"
 )
 
 func dbGetTimeLog(ctx ctx.Ctx, shard int, account, project, timeLog id.Id) *tlog.TimeLog {
@@ -18,7 +22,7 @@ func dbGetTimeLog(ctx ctx.Ctx, shard int, account, project, timeLog id.Id) *tlog
 	if ctx.GetCacheValue(&tl, cacheKey) {
 		return &tl
 	}
-	panic.IfNotNil(ctx.TreeQueryRow(shard, `SELECT project, task, id, member, loggedOn, taskHasBeenDeleted, taskName, duration, note FROM timeLogs WHERE account=? AND project=? AND id=?`, account, project, timeLog).Scan(&tl.Project, &tl.Task, &tl.Id, &tl.Member, &tl.LoggedOn, &tl.TaskHasBeenDeleted, &tl.TaskName, &tl.Duration, &tl.Note))
+	panic.IfNotNil(ctx.TreeQueryRow(shard, `SELECT project, task, id, member, loggedOn, taskHasBeenDeleted, taskName, duration, note, isBillable, hourlyRate, cost FROM timeLogs WHERE account=? AND project=? AND id=?`, account, project, timeLog).Scan(&tl.Project, &tl.Task, &tl.Id, &tl.Member, &tl.LoggedOn, &tl.TaskHasBeenDeleted, &tl.TaskName, &tl.Duration, &tl.Note, &tl.IsBillable, &tl.HourlyRate, &tl.Cost))
 	ctx.SetCacheValue(tl, cacheKey)
 	return &tl
 }
@@ -27,6 +31,25 @@ func dbSetDuration(ctx ctx.Ctx, shard int, account, project, task, member, timeL
 	ctx.TouchDlms(cachekey.NewSetDlms().CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL setTimeLogDuration(?, ?, ?, ?, ?)`, account, project, timeLog, ctx.Me(), duration)).TimeLog(account, project, timeLog, &task, &member).ProjectActivities(account, project))
 }
 
+func dbReturnBadRequestNowIfTimeLogExists(ctx ctx.Ctx, shard int, account, project, task, member id.Id, loggedOn time.Time) {
+	exists := false
+	panic.IfNotNil(ctx.TreeQueryRow(shard, `SELECT COUNT(*) > 0 FROM timeLogs WHERE account=? AND project=? AND task=? AND member=? AND loggedOn=?`, account, project, task, member, loggedOn).Scan(&exists))
+	ctx.ReturnBadRequestNowIf(exists, "there is already a time log by the same member on this task at loggedOn")
+}
+
+func dbSetLoggedOn(ctx ctx.Ctx, shard int, account, project, task, member, timeLog id.Id, loggedOn time.Time) {
+	changeMade := false
+	e := ctx.TreeQueryRow(shard, `CALL setTimeLogLoggedOn(?, ?, ?, ?, ?)`, account, project, timeLog, ctx.Me(), loggedOn).Scan(&changeMade)
+	//the members time logs on a task clash in the timeLogs primary key if they are at the same second, this catches one logged since the edit checked
+	ctx.ReturnBadRequestNowIf(err.IsSqlErrDupEntryElsePanicIf(e), "there is already a time log by the same member on this task at loggedOn")
+	ctx.ReturnBadRequestNowIf(!changeMade, "no change made")
+	ctx.TouchDlms(cachekey.NewSetDlms().TimeLog(account, project, timeLog, &task, &member).ProjectActivities(account, project))
+}
+
+func dbSetIsBillable(ctx ctx.Ctx, shard int, account, project, task, member, timeLog id.Id, isBillable bool) {
+	ctx.TouchDlms(cachekey.NewSetDlms().CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL setTimeLogIsBillable(?, ?, ?, ?, ?)`, account, project, timeLog, ctx.Me(), isBillable)).TimeLog(account, project, timeLog, &task, &member).ProjectActivities(account, project))
+}
+
 func dbSetNote(ctx ctx.Ctx, shard int, account, project, task, member, timeLog id.Id, note *string) {
 	db.MakeChangeHelper(ctx, shard, `CALL setTimeLogNote(?, ?, ?, ?, ?)`, account, project, timeLog, ctx.Me(), note)
 	ctx.TouchDlms(cachekey.NewSetDlms().TimeLog(account, project, timeLog, &task, &member).ProjectActivities(account, project))
@@ -36,11 +59,11 @@ func dbDelete(ctx ctx.Ctx, shard int, account, project, task, member, timeLog id
 	ctx.TouchDlms(cachekey.NewSetDlms().CombinedTaskAndTaskChildrenSets(account, project, db.TreeChangeHelper(ctx, shard, `CALL deleteTimeLog(?, ?, ?, ?)`, account, project, timeLog, ctx.Me())).TimeLog(account, project, timeLog, &task, &member).ProjectActivities(account, project))
 }
 
-func dbGetTimeLogs(ctx ctx.Ctx, shard int, account, project id.Id, task, member, timeLog *id.Id, sortAsc bool, after *id.Id, limit int) *GetResp {
+func dbGetTimeLogs(ctx ctx.Ctx, shard int, account, project id.Id, task, member, timeLog *id.Id, loggedOnAfter, loggedOnBefore *time.Time, sortAsc bool, after *id.Id, limit int) *GetResp {
 	if timeLog != nil {
 		return &GetResp{TimeLogs: []*tlog.TimeLog{dbGetTimeLog(ctx, shard, account, project, *timeLog)}}
 	}
-	cacheKey := cachekey.NewGet("timelog.dbGetTimeLogs", shard, account, project, task, member, timeLog, sortAsc, after, limit)
+	cacheKey := cachekey.NewGet("timelog.dbGetTimeLogs", shard, account, project, task, member, timeLog, loggedOnAfter, loggedOnBefore, sortAsc, after, limit)
 	if task != nil {
 		cacheKey.TaskTimeLogSet(account, project, *task, member)
 	}
@@ -54,8 +77,8 @@ func dbGetTimeLogs(ctx ctx.Ctx, shard int, account, project id.Id, task, member,
 	if ctx.GetCacheValue(&res, cacheKey) {
 		return &res
 	}
-	query := bytes.NewBufferString(`SELECT project, task, id, member, loggedOn, taskHasBeenDeleted, taskName, duration, note FROM timeLogs WHERE account=? AND project=?`)
-	args := make([]interface{}, 0, 9)
+	query := bytes.NewBufferString(`SELECT project, task, id, member, loggedOn, taskHasBeenDeleted, taskName, duration, note, isBillable, hourlyRate, cost FROM timeLogs WHERE account=? AND project=?`)
+	args := make([]interface{}, 0, 11)
 	args = append(args, account, project)
 	if task != nil {
 		query.WriteString(` AND task=?`)
@@ -65,6 +88,14 @@ func dbGetTimeLogs(ctx ctx.Ctx, shard int, account, project id.Id, task, member,
 		query.WriteString(` AND member=?`)
 		args = append(args, *member)
 	}
+	if loggedOnAfter != nil {
+		query.WriteString(` AND loggedOn >= ?`)
+		args = append(args, *loggedOnAfter)
+	}
+	if loggedOnBefore != nil {
+		query.WriteString(` AND loggedOn < ?`)
+		args = append(args, *loggedOnBefore)
+	}
 	if after != nil {
 		query.WriteString(fmt.Sprintf(` AND loggedOn %s= (SELECT loggedOn FROM timeLogs WHERE account=? AND project=? AND id=?) AND id %s ?`, sortdir.GtLtSymbol(sortAsc), sortdir.GtLtSymbol(sortAsc)))
 		args = append(args, account, project, *after, *after)
@@ -79,7 +110,7 @@ func dbGetTimeLogs(ctx ctx.Ctx, shard int, account, project id.Id, task, member,
 	timeLogsSet := make([]*tlog.TimeLog, 0, limit+1)
 	for rows.Next() {
 		tl := tlog.TimeLog{}
-		panic.IfNotNil(rows.Scan(&tl.Project, &tl.Task, &tl.Id, &tl.Member, &tl.LoggedOn, &tl.TaskHasBeenDeleted, &tl.TaskName, &tl.Duration, &tl.Note))
+		panic.IfNotNil(rows.Scan(&tl.Project, &tl.Task, &tl.Id, &tl.Member, &tl.LoggedOn, &tl.TaskHasBeenDeleted, &tl.TaskName, &tl.Duration, &tl.Note, &tl.IsBillable, &tl.HourlyRate, &tl.Cost))
 		timeLogsSet = append(timeLogsSet, &tl)
 	}
 	if len(timeLogsSet) == limit+1 {
@@ -92,3 +123,91 @@ func dbGetTimeLogs(ctx ctx.Ctx, shard int, account, project id.Id, task, member,
 	ctx.SetCacheValue(res, cacheKey)
 	return &res
 }
+
+// dbGetTimesheet isn't cached as it spans every project in the account and time log changes are only tracked per project.
+// dbGetTimesheet only sums cost and revenue, which give away members hourly rates, on the projects I am an admin of, they
+// are zero on the rest unless readsAllProjects, which only account owners and admins can.
+func dbGetTimesheet(ctx ctx.Ctx, shard int, account, me id.Id, readsAllProjects bool, member *id.Id, loggedOnAfter, loggedOnBefore time.Time) []*TimesheetEntry {
+	query := bytes.NewBufferString(`SELECT DATE(tl.loggedOn) AS loggedOnDay, tl.project, MAX(p.name) AS projectName, tl.task, MAX(tl.taskName) AS taskName, tl.member, SUM(tl.duration), `)
+	args := make([]interface{}, 0, 12)
+	if readsAllProjects {
+		query.WriteString(`SUM(tl.cost), SUM(IF(tl.isBillable, tl.cost, 0))`)
+	} else {
+		query.WriteString(`IF(tl.project IN (SELECT project FROM projectMembers WHERE account=? AND isActive=true AND id=? AND role=?), SUM(tl.cost), 0), IF(tl.project IN (SELECT project FROM projectMembers WHERE account=? AND isActive=true AND id=? AND role=?), SUM(IF(tl.isBillable, tl.cost, 0)), 0)`)
+		args = append(args, account, me, cnst.ProjectAdmin, account, me, cnst.ProjectAdmin)
+	}
+	query.WriteString(` FROM timeLogs tl, projects p WHERE tl.account=? AND tl.loggedOn>=? AND tl.loggedOn<? AND p.account=tl.account AND p.id=tl.project`)
+	args = append(args, account, loggedOnAfter, loggedOnBefore)
+	if member != nil {
+		query.WriteString(` AND tl.member=?`)
+		args = append(args, *member)
+	}
+	if !readsAllProjects {
+		query.WriteString(` AND (p.isPublic=true OR p.id IN (SELECT project FROM projectMembers WHERE account=? AND isActive=true AND id=?))`)
+		args = append(args, account, me)
+	}
+	query.WriteString(` GROUP BY loggedOnDay, tl.project, tl.task, tl.member ORDER BY loggedOnDay, projectName, tl.project, taskName, tl.task, tl.member`)
+	rows, e := ctx.TreeQuery(shard, query.String(), args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	res := make([]*TimesheetEntry, 0, 100)
+	for rows.Next() {
+		var day time.Time
+		te := TimesheetEntry{}
+		panic.IfNotNil(rows.Scan(&day, &te.Project, &te.ProjectName, &te.Task, &te.TaskName, &te.Member, &te.Duration, &te.Cost, &te.Revenue))
+		te.Day = day.Format("2006-01-02")
+		res = append(res, &te)
+	}
+	return res
+}
+
+// dbGetTaskIsAbstract returns nil if the task doesn't exist, timers are per member so nothing about them is cached.
+func dbGetTaskIsAbstract(ctx ctx.Ctx, shard int, account, project, task id.Id) *bool {
+	isAbstract := false
+	if err.IsSqlErrNoRowsElsePanicIf(ctx.TreeQueryRow(shard, `SELECT isAbstract FROM tasks WHERE account=? AND project=? AND id=?`, account, project, task).Scan(&isAbstract)) {
+		return nil
+	}
+	return &isAbstract
+}
+
+func dbGetTimer(ctx ctx.Ctx, shard int, account, member id.Id) *Timer {
+	ti := Timer{}
+	if err.IsSqlErrNoRowsElsePanicIf(ctx.TreeQueryRow(shard, `SELECT project, task, startedOn, runningSince, elapsed, note FROM timers WHERE account=? AND member=?`, account, member).Scan(&ti.Project, &ti.Task, &ti.StartedOn, &ti.RunningSince, &ti.Elapsed, &ti.Note)) {
+		return nil
+	}
+	return &ti
+}
+
+func dbStartTimer(ctx ctx.Ctx, shard int, account, member id.Id, ti *Timer) {
+	res, e := ctx.TreeExec(shard, `INSERT IGNORE INTO timers (account, member, project, task, startedOn, runningSince, elapsed, note) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, account, member, ti.Project, ti.Task, ti.StartedOn, ti.RunningSince, ti.Elapsed, ti.Note)
+	panic.IfNotNil(e)
+	ctx.ReturnBadRequestNowIf(rowsAffected(res) == 0, "a timer is already running, stop or discard it first")
+}
+
+func dbPauseTimer(ctx ctx.Ctx, shard int, account, member id.Id, runningSince time.Time, elapsed uint64) {
+	res, e := ctx.TreeExec(shard, `UPDATE timers SET runningSince=NULL, elapsed=? WHERE account=? AND member=? AND runningSince=?`, elapsed, account, member, runningSince)
+	panic.IfNotNil(e)
+	ctx.ReturnBadRequestNowIf(rowsAffected(res) == 0, "no change made")
+}
+
+func dbResumeTimer(ctx ctx.Ctx, shard int, account, member id.Id, runningSince time.Time) {
+	res, e := ctx.TreeExec(shard, `UPDATE timers SET runningSince=? WHERE account=? AND member=? AND runningSince IS NULL`, runningSince, account, member)
+	panic.IfNotNil(e)
+	ctx.ReturnBadRequestNowIf(rowsAffected(res) == 0, "no change made")
+}
+
+// dbDeleteTimer only deletes the timer if it is still the one started at startedOn, so a timer started again on another
+// device isn't discarded by mistake.
+func dbDeleteTimer(ctx ctx.Ctx, shard int, account, member id.Id, startedOn time.Time) {
+	res, e := ctx.TreeExec(shard, `DELETE FROM timers WHERE account=? AND member=? AND startedOn=?`, account, member, startedOn)
+	panic.IfNotNil(e)
+	ctx.ReturnBadRequestNowIf(rowsAffected(res) == 0, "no change made")
+}
+
+func rowsAffected(res sql.Result) int64 {
+	n, e := res.RowsAffected()
+	panic.IfNotNil(e)
+	return n
+}
@@ -1,26 +1,37 @@
 package timelog
 
 import (
+	"github.com/0xor1/trees/server/util/cnst"
 	"github.com/0xor1/trees/server/util/ctx"
 	"github.com/0xor1/trees/server/util/db"
 	"github.com/0xor1/trees/server/util/endpoint"
 	"github.com/0xor1/trees/server/util/field"
 	"github.com/0xor1/trees/server/util/id"
+	t "github.com/0xor1/trees/server/util/time"
 	"github.com/0xor1/trees/server/util/timelog"
 	"github.com/0xor1/trees/server/util/validate"
+	"time"
+)
+
+const (
+	noteMaxRuneCount = 250
+	timesheetMaxDays = 93
 )
 
 type createArgs struct {
-	Shard    int     `json:"shard"`
-	Account  id.Id   `json:"account"`
-	Project  id.Id   `json:"project"`
-	Task     id.Id   `json:"task"`
-	Duration uint64  `json:"duration"`
-	Note     *string `json:"note,omitempty"`
+	Shard      int        `json:"shard"`
+	Account    id.Id      `json:"account"`
+	Project    id.Id      `json:"project"`
+	Task       id.Id      `json:"task"`
+	Duration   uint64     `json:"duration"`
+	LoggedOn   *time.Time `json:"loggedOn,omitempty"`
+	IsBillable *bool      `json:"isBillable,omitempty"`
+	Note       *string    `json:"note,omitempty"`
 }
 
 var create = &endpoint.Endpoint{
 	Path:                     "/api/v1/timeLog/create",
+	Note:                     "loggedOn defaults to now, it can not be in the future or further back than the projects timeLogMaxAgeDays unless you are a project admin, isBillable defaults to true, the cost is calculated from my hourly rate at the time",
 	RequiresSession:          true,
 	ExampleResponseStructure: &timelog.TimeLog{},
 	GetArgsStruct: func() interface{} {
@@ -28,18 +39,20 @@ var create = &endpoint.Endpoint{
 	},
 	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
 		args := a.(*createArgs)
-		return db.SetRemainingTimeAndOrLogTime(ctx, args.Shard, args.Account, args.Project, args.Task, nil, &args.Duration, args.Note)
+		return db.SetRemainingTimeAndOrLogTime(ctx, args.Shard, args.Account, args.Project, args.Task, nil, nil, &args.Duration, args.LoggedOn, args.IsBillable, args.Note)
 	},
 }
 
 type createAndSetRemainingTimeArgs struct {
-	Shard         int     `json:"shard"`
-	Account       id.Id   `json:"account"`
-	Project       id.Id   `json:"project"`
-	Task          id.Id   `json:"task"`
-	RemainingTime uint64  `json:"remainingTime"`
-	Duration      uint64  `json:"duration"`
-	Note          *string `json:"note,omitempty"`
+	Shard         int        `json:"shard"`
+	Account       id.Id      `json:"account"`
+	Project       id.Id      `json:"project"`
+	Task          id.Id      `json:"task"`
+	RemainingTime uint64     `json:"remainingTime"`
+	Duration      uint64     `json:"duration"`
+	LoggedOn      *time.Time `json:"loggedOn,omitempty"`
+	IsBillable    *bool      `json:"isBillable,omitempty"`
+	Note          *string    `json:"note,omitempty"`
 }
 
 var createAndSetRemainingTime = &endpoint.Endpoint{
@@ -51,7 +64,7 @@ var createAndSetRemainingTime = &endpoint.Endpoint{
 	},
 	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
 		args := a.(*createAndSetRemainingTimeArgs)
-		return db.SetRemainingTimeAndOrLogTime(ctx, args.Shard, args.Account, args.Project, args.Task, &args.RemainingTime, &args.Duration, args.Note)
+		return db.SetRemainingTimeAndOrLogTime(ctx, args.Shard, args.Account, args.Project, args.Task, nil, &args.RemainingTime, &args.Duration, args.LoggedOn, args.IsBillable, args.Note)
 	},
 }
 
@@ -78,11 +91,27 @@ var edit = &endpoint.Endpoint{
 		} else {
 			validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
 		}
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		//loggedOn is validated before any field is set so a rejected one doesn't leave the other fields changed
+		setLoggedOn := false
+		if args.Fields.LoggedOn != nil {
+			db.ValidateLoggedOn(ctx, args.Shard, args.Account, args.Project, &args.Fields.LoggedOn.Val)
+			setLoggedOn = !args.Fields.LoggedOn.Val.Equal(tl.LoggedOn)
+			if setLoggedOn {
+				dbReturnBadRequestNowIfTimeLogExists(ctx, args.Shard, args.Account, args.Project, tl.Task, tl.Member, args.Fields.LoggedOn.Val)
+			}
+		}
 		if args.Fields.Duration != nil && args.Fields.Duration.Val != tl.Duration {
-			dbSetDuration(ctx, args.Shard, args.Account, args.Project, tl.Task, ctx.Me(), tl.Id, args.Fields.Duration.Val)
+			dbSetDuration(ctx, args.Shard, args.Account, args.Project, tl.Task, tl.Member, tl.Id, args.Fields.Duration.Val)
+		}
+		if setLoggedOn {
+			dbSetLoggedOn(ctx, args.Shard, args.Account, args.Project, tl.Task, tl.Member, tl.Id, args.Fields.LoggedOn.Val)
+		}
+		if args.Fields.IsBillable != nil && args.Fields.IsBillable.Val != tl.IsBillable {
+			dbSetIsBillable(ctx, args.Shard, args.Account, args.Project, tl.Task, tl.Member, tl.Id, args.Fields.IsBillable.Val)
 		}
 		if args.Fields.Note != nil && ((args.Fields.Note.Val == nil && tl.Note != nil) || (args.Fields.Note.Val != nil && tl.Note == nil) || (tl.Note != nil && args.Fields.Note.Val != nil && *tl.Note != *args.Fields.Note.Val)) {
-			dbSetNote(ctx, args.Shard, args.Account, args.Project, tl.Task, ctx.Me(), tl.Id, args.Fields.Note.Val)
+			dbSetNote(ctx, args.Shard, args.Account, args.Project, tl.Task, tl.Member, tl.Id, args.Fields.Note.Val)
 		}
 		return nil
 	},
@@ -109,21 +138,24 @@ var delete = &endpoint.Endpoint{
 		} else {
 			validate.MemberHasProjectAdminAccess(db.GetAccountAndProjectRoles(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
 		}
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
 		dbDelete(ctx, args.Shard, args.Account, args.Project, tl.Task, tl.Member, args.TimeLog)
 		return nil
 	},
 }
 
 type getArgs struct {
-	Shard   int    `json:"shard"`
-	Account id.Id  `json:"account"`
-	Project id.Id  `json:"project"`
-	Task    *id.Id `json:"task,omitempty"`
-	Member  *id.Id `json:"member,omitempty"`
-	TimeLog *id.Id `json:"timeLog,omitempty"`
-	SortAsc bool   `json:"sortAsc"`
-	After   *id.Id `json:"after,omitempty"`
-	Limit   int    `json:"limit"`
+	Shard          int        `json:"shard"`
+	Account        id.Id      `json:"account"`
+	Project        id.Id      `json:"project"`
+	Task           *id.Id     `json:"task,omitempty"`
+	Member         *id.Id     `json:"member,omitempty"`
+	TimeLog        *id.Id     `json:"timeLog,omitempty"`
+	LoggedOnAfter  *time.Time `json:"loggedOnAfter,omitempty"`
+	LoggedOnBefore *time.Time `json:"loggedOnBefore,omitempty"`
+	SortAsc        bool       `json:"sortAsc"`
+	After          *id.Id     `json:"after,omitempty"`
+	Limit          int        `json:"limit"`
 }
 
 type GetResp struct {
@@ -133,6 +165,7 @@ type GetResp struct {
 
 var get = &endpoint.Endpoint{
 	Path:                     "/api/v1/timeLog/get",
+	Note:                     "loggedOnAfter is inclusive and loggedOnBefore is exclusive",
 	RequiresSession:          false,
 	ExampleResponseStructure: &GetResp{TimeLogs: []*timelog.TimeLog{{}}},
 	GetArgsStruct: func() interface{} {
@@ -141,7 +174,217 @@ var get = &endpoint.Endpoint{
 	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
 		args := a.(*getArgs)
 		validate.MemberHasProjectReadAccess(db.GetAccountAndProjectRolesAndProjectIsPublic(ctx, args.Shard, args.Account, args.Project, ctx.TryMe()))
-		return dbGetTimeLogs(ctx, args.Shard, args.Account, args.Project, args.Task, args.Member, args.TimeLog, args.SortAsc, args.After, validate.Limit(args.Limit, ctx.MaxProcessEntityCount()))
+		return dbGetTimeLogs(ctx, args.Shard, args.Account, args.Project, args.Task, args.Member, args.TimeLog, args.LoggedOnAfter, args.LoggedOnBefore, args.SortAsc, args.After, validate.Limit(args.Limit, ctx.MaxProcessEntityCount()))
+	},
+}
+
+type getTimesheetArgs struct {
+	Shard          int                `json:"shard"`
+	Account        id.Id              `json:"account"`
+	Member         *id.Id             `json:"member,omitempty"`
+	LoggedOnAfter  time.Time          `json:"loggedOnAfter"`
+	LoggedOnBefore time.Time          `json:"loggedOnBefore"`
+	Format         *cnst.ReportFormat `json:"format,omitempty"`
+}
+
+type TimesheetEntry struct {
+	Day         string `json:"day"` //YYYY-MM-DD in UTC
+	Project     id.Id  `json:"project"`
+	ProjectName string `json:"projectName"`
+	Task        id.Id  `json:"task"`
+	TaskName    string `json:"taskName"`
+	Member      id.Id  `json:"member"`
+	Duration    uint64 `json:"duration"`
+	Cost        uint64 `json:"cost"`
+	Revenue     uint64 `json:"revenue"` //the cost of the billable time
+}
+
+type GetTimesheetResp struct {
+	Entries       []*TimesheetEntry `json:"entries,omitempty"`
+	Csv           *string           `json:"csv,omitempty"`
+	TotalDuration uint64            `json:"totalDuration"`
+	TotalCost     uint64            `json:"totalCost"`
+	TotalRevenue  uint64            `json:"totalRevenue"`
+}
+
+var getTimesheet = &endpoint.Endpoint{
+	Path:                     "/api/v1/timeLog/getTimesheet",
+	Note:                     "sums logged minutes per day, project, task and member across every project in the account I can read, cost and revenue are zero on projects I am not an admin of, format defaults to json, csv returns the same entries as a csv string instead",
+	RequiresSession:          true,
+	ExampleResponseStructure: &GetTimesheetResp{Entries: []*TimesheetEntry{{}}},
+	GetArgsStruct: func() interface{} {
+		return &getTimesheetArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*getTimesheetArgs)
+		ctx.ReturnBadRequestNowIf(!args.LoggedOnBefore.After(args.LoggedOnAfter), "loggedOnBefore must be after loggedOnAfter")
+		ctx.ReturnBadRequestNowIf(args.LoggedOnBefore.Sub(args.LoggedOnAfter) > timesheetMaxDays*24*time.Hour, "timesheets can cover at most %d days", timesheetMaxDays)
+		accRole := db.GetAccountRole(ctx, args.Shard, args.Account, ctx.Me())
+		readsAllProjects := accRole != nil && (*accRole == cnst.AccountOwner || *accRole == cnst.AccountAdmin)
+		res := &GetTimesheetResp{Entries: dbGetTimesheet(ctx, args.Shard, args.Account, ctx.Me(), readsAllProjects, args.Member, args.LoggedOnAfter, args.LoggedOnBefore)}
+		for _, e := range res.Entries {
+			res.TotalDuration += e.Duration
+			res.TotalCost += e.Cost
+			res.TotalRevenue += e.Revenue
+		}
+		if args.Format != nil && *args.Format == cnst.ReportFormatCsv {
+			res.Csv = timesheetCsv(res.Entries)
+			res.Entries = nil
+		}
+		return res
+	},
+}
+
+type startTimerArgs struct {
+	Shard   int     `json:"shard"`
+	Account id.Id   `json:"account"`
+	Project id.Id   `json:"project"`
+	Task    id.Id   `json:"task"`
+	Note    *string `json:"note,omitempty"`
+}
+
+var startTimer = &endpoint.Endpoint{
+	Path:                     "/api/v1/timeLog/startTimer",
+	Note:                     "a member can only have one timer in an account, whether it is running or paused",
+	RequiresSession:          true,
+	ExampleResponseStructure: &Timer{},
+	GetArgsStruct: func() interface{} {
+		return &startTimerArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*startTimerArgs)
+		validate.MemberIsAProjectMemberWithWriteAccess(db.GetProjectRole(ctx, args.Shard, args.Account, args.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, args.Project)
+		if args.Note != nil {
+			validate.StringArg("note", *args.Note, 0, noteMaxRuneCount, nil)
+		}
+		isAbstract := dbGetTaskIsAbstract(ctx, args.Shard, args.Account, args.Project, args.Task)
+		ctx.ReturnBadRequestNowIf(isAbstract == nil, "no such task")
+		ctx.ReturnBadRequestNowIf(*isAbstract, "timers can only run against concrete tasks")
+		now := timerNow()
+		ti := &Timer{
+			Project:      args.Project,
+			Task:         args.Task,
+			StartedOn:    now,
+			RunningSince: &now,
+			Elapsed:      0,
+			Note:         args.Note,
+		}
+		dbStartTimer(ctx, args.Shard, args.Account, ctx.Me(), ti)
+		return ti
+	},
+}
+
+type timerArgs struct {
+	Shard   int   `json:"shard"`
+	Account id.Id `json:"account"`
+}
+
+var pauseTimer = &endpoint.Endpoint{
+	Path:                     "/api/v1/timeLog/pauseTimer",
+	RequiresSession:          true,
+	ExampleResponseStructure: &Timer{},
+	GetArgsStruct: func() interface{} {
+		return &timerArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*timerArgs)
+		ti := dbGetTimer(ctx, args.Shard, args.Account, ctx.Me())
+		ctx.ReturnBadRequestNowIf(ti == nil, "no timer")
+		ctx.ReturnBadRequestNowIf(ti.RunningSince == nil, "timer is already paused")
+		elapsed := ti.elapsedAt(timerNow())
+		dbPauseTimer(ctx, args.Shard, args.Account, ctx.Me(), *ti.RunningSince, elapsed)
+		ti.RunningSince = nil
+		ti.Elapsed = elapsed
+		return ti
+	},
+}
+
+var resumeTimer = &endpoint.Endpoint{
+	Path:                     "/api/v1/timeLog/resumeTimer",
+	RequiresSession:          true,
+	ExampleResponseStructure: &Timer{},
+	GetArgsStruct: func() interface{} {
+		return &timerArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*timerArgs)
+		ti := dbGetTimer(ctx, args.Shard, args.Account, ctx.Me())
+		ctx.ReturnBadRequestNowIf(ti == nil, "no timer")
+		ctx.ReturnBadRequestNowIf(ti.RunningSince != nil, "timer is already running")
+		now := timerNow()
+		dbResumeTimer(ctx, args.Shard, args.Account, ctx.Me(), now)
+		ti.RunningSince = &now
+		return ti
+	},
+}
+
+type stopTimerArgs struct {
+	Shard         int     `json:"shard"`
+	Account       id.Id   `json:"account"`
+	RemainingTime *uint64 `json:"remainingTime,omitempty"`
+	Note          *string `json:"note,omitempty"` //replaces the note the timer was started with
+}
+
+var stopTimer = &endpoint.Endpoint{
+	Path:                     "/api/v1/timeLog/stopTimer",
+	Note:                     "logs the time the timer ran for, rounded to the nearest minute and at least one minute, against its task",
+	RequiresSession:          true,
+	ExampleResponseStructure: &timelog.TimeLog{},
+	GetArgsStruct: func() interface{} {
+		return &stopTimerArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*stopTimerArgs)
+		ti := dbGetTimer(ctx, args.Shard, args.Account, ctx.Me())
+		ctx.ReturnBadRequestNowIf(ti == nil, "no timer")
+		validate.MemberIsAProjectMemberWithWriteAccess(db.GetProjectRole(ctx, args.Shard, args.Account, ti.Project, ctx.Me()))
+		db.ReturnBadRequestNowIfProjectIsArchived(ctx, args.Shard, args.Account, ti.Project)
+		note := ti.Note
+		if args.Note != nil {
+			validate.StringArg("note", *args.Note, 0, noteMaxRuneCount, nil)
+			note = args.Note
+		}
+		isAbstract := dbGetTaskIsAbstract(ctx, args.Shard, args.Account, ti.Project, ti.Task)
+		ctx.ReturnBadRequestNowIf(isAbstract == nil || *isAbstract, "the timers task no longer exists or is no longer concrete, the timer can only be discarded")
+		duration := (ti.elapsedAt(timerNow()) + 30) / 60
+		if duration == 0 {
+			duration = 1
+		}
+		return db.LogTimerTime(ctx, args.Shard, args.Account, ti.Project, ti.Task, ti.StartedOn, args.RemainingTime, duration, note)
+	},
+}
+
+var discardTimer = &endpoint.Endpoint{
+	Path:            "/api/v1/timeLog/discardTimer",
+	Note:            "deletes the timer without logging any time",
+	RequiresSession: true,
+	GetArgsStruct: func() interface{} {
+		return &timerArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*timerArgs)
+		ti := dbGetTimer(ctx, args.Shard, args.Account, ctx.Me())
+		ctx.ReturnBadRequestNowIf(ti == nil, "no timer")
+		dbDeleteTimer(ctx, args.Shard, args.Account, ctx.Me(), ti.StartedOn)
+		return nil
+	},
+}
+
+var getTimer = &endpoint.Endpoint{
+	Path:                     "/api/v1/timeLog/getTimer",
+	Note:                     "returns null if I have no timer",
+	RequiresSession:          true,
+	ExampleResponseStructure: &Timer{},
+	GetArgsStruct: func() interface{} {
+		return &timerArgs{}
+	},
+	CtxHandler: func(ctx ctx.Ctx, a interface{}) interface{} {
+		args := a.(*timerArgs)
+		if ti := dbGetTimer(ctx, args.Shard, args.Account, ctx.Me()); ti != nil {
+			return ti
+		}
+		return nil
 	},
 }
 
@@ -151,9 +394,39 @@ var Endpoints = []*endpoint.Endpoint{
 	edit,
 	delete,
 	get,
+	getTimesheet,
+	startTimer,
+	pauseTimer,
+	resumeTimer,
+	stopTimer,
+	discardTimer,
+	getTimer,
 }
 
 type Fields struct {
-	Duration *field.UInt64    `json:"duration,omitempty"`
-	Note     *field.StringPtr `json:"note,omitempty"`
+	Duration   *field.UInt64    `json:"duration,omitempty"`
+	LoggedOn   *field.Time      `json:"loggedOn,omitempty"`
+	IsBillable *field.Bool      `json:"isBillable,omitempty"`
+	Note       *field.StringPtr `json:"note,omitempty"`
+}
+
+type Timer struct {
+	Project      id.Id      `json:"project"`
+	Task         id.Id      `json:"task"`
+	StartedOn    time.Time  `json:"startedOn"`
+	RunningSince *time.Time `json:"runningSince,omitempty"` //nil while paused
+	Elapsed      uint64     `json:"elapsed"`                //seconds run before runningSince, add on the time since runningSince for the current total
+	Note         *string    `json:"note,omitempty"`
+}
+
+func (ti *Timer) elapsedAt(now time.Time) uint64 {
+	if ti.RunningSince == nil || now.Before(*ti.RunningSince) {
+		return ti.Elapsed
+	}
+	return ti.Elapsed + uint64(now.Sub(*ti.RunningSince)/time.Second)
+}
+
+// timerNow is truncated to match the precision timers are stored with, pausing relies on comparing runningSince.
+func timerNow() time.Time {
+	return t.Now().Truncate(time.Microsecond)
 }
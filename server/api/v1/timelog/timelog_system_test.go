@@ -15,6 +15,7 @@ import (
 
 func Test_system(t *testing.T) {
 	systemtest.Run(t, func(base *systemtest.Base) {
+		accountClient := account.NewClient(base.TestServerURL)
 		projectClient := project.NewClient(base.TestServerURL)
 		taskClient := task.NewClient(base.TestServerURL)
 		client := NewClient(base.TestServerURL)
@@ -44,7 +45,7 @@ func Test_system(t *testing.T) {
 		assert.Equal(t, uint64(0), *taskA.ChildCount)
 		assert.Equal(t, uint64(0), *taskA.DescendantCount)
 		assert.Equal(t, false, *taskA.IsParallel)
-		assert.Nil(t, taskA.Member)
+		assert.Nil(t, taskA.Members)
 		taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, &taskA.Id, "B", &desc, true, &falseVal, nil, nil)
 		taskC, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, nil, "C", &desc, true, &trueVal, nil, nil)
 		_, err = taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, proj.Id, &taskA.Id, "D", &desc, false, nil, &base.Ali.Info.Me.Id, &fourVal)
@@ -59,7 +60,7 @@ func Test_system(t *testing.T) {
 		taskM, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskL.Id, nil, "M", &desc, false, nil, &base.Ali.Info.Me.Id, &threeVal)
 
 		aNote := "word up!"
-		tl1, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, 30, &aNote)
+		tl1, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, 30, nil, nil, &aNote)
 		assert.Nil(t, err)
 		assert.True(t, tl1.Project.Equal(proj.Id))
 		assert.True(t, tl1.Member.Equal(base.Ali.Info.Me.Id))
@@ -68,7 +69,7 @@ func Test_system(t *testing.T) {
 		assert.Equal(t, uint64(30), tl1.Duration)
 		assert.InDelta(t, ti.NowUnixMillis()/1000, tl1.LoggedOn.Unix(), 1)
 
-		tl2, err := client.CreateAndSetRemainingTime(base.Bob.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, 100, 30, &aNote)
+		tl2, err := client.CreateAndSetRemainingTime(base.Bob.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id, 100, 30, nil, nil, &aNote)
 		assert.Nil(t, err)
 		assert.True(t, tl2.Project.Equal(proj.Id))
 		assert.True(t, tl2.Member.Equal(base.Bob.Info.Me.Id))
@@ -81,34 +82,209 @@ func Test_system(t *testing.T) {
 		err = client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, tl2.Id, Fields{Duration: &field.UInt64{Val: 100}, Note: &field.StringPtr{Val: &note}})
 		assert.Nil(t, err)
 
-		tls, err := client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, nil, nil, false, nil, 100)
+		tls, err := client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, nil, nil, nil, nil, false, nil, 100)
 		assert.Nil(t, err)
 		assert.Equal(t, 2, len(tls.TimeLogs))
 		assert.True(t, tls.TimeLogs[0].Id.Equal(tl2.Id))
 		assert.True(t, tls.TimeLogs[1].Id.Equal(tl1.Id))
 
-		tls, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, nil, nil, false, &tl2.Id, 100)
+		tls, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, nil, nil, nil, nil, false, &tl2.Id, 100)
 		assert.Nil(t, err)
 		assert.Equal(t, 1, len(tls.TimeLogs))
 		assert.True(t, tls.TimeLogs[0].Id.Equal(tl1.Id))
 
-		tls, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, &tl2.Id, &base.Bob.Info.Me.Id, &tl2.Id, false, nil, 100)
+		tls, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, &tl2.Id, &base.Bob.Info.Me.Id, &tl2.Id, nil, nil, false, nil, 100)
 		assert.Nil(t, err)
 		assert.Equal(t, 1, len(tls.TimeLogs))
 		assert.True(t, tls.TimeLogs[0].Id.Equal(tl2.Id))
 
 		assert.Nil(t, client.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, tl1.Id))
 
-		tls, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, nil, nil, false, nil, 100)
+		tls, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, nil, nil, nil, nil, false, nil, 100)
 		assert.Nil(t, err)
 		assert.Equal(t, 1, len(tls.TimeLogs))
 		assert.True(t, tls.TimeLogs[0].Id.Equal(tl2.Id))
 
 		assert.Nil(t, taskClient.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskM.Id))
 
-		tls, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, nil, nil, false, nil, 100)
+		tls, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, nil, nil, nil, nil, false, nil, 100)
 		assert.Nil(t, err)
 		assert.Equal(t, 1, len(tls.TimeLogs))
 		assert.Equal(t, true, tls.TimeLogs[0].TaskHasBeenDeleted)
+
+		timer, err := client.GetTimer(base.Ali.CSS, base.Region, 0, base.Org.Id)
+		assert.Nil(t, err)
+		assert.Nil(t, timer)
+		_, err = client.StartTimer(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, nil)
+		assert.NotNil(t, err)
+		_, err = client.StartTimer(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskC.Id, nil)
+		assert.NotNil(t, err)
+		timer, err = client.StartTimer(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, &aNote)
+		assert.Nil(t, err)
+		assert.NotNil(t, timer.RunningSince)
+		_, err = client.StartTimer(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, nil)
+		assert.NotNil(t, err)
+		_, err = client.ResumeTimer(base.Ali.CSS, base.Region, 0, base.Org.Id)
+		assert.NotNil(t, err)
+		timer, err = client.PauseTimer(base.Ali.CSS, base.Region, 0, base.Org.Id)
+		assert.Nil(t, err)
+		assert.Nil(t, timer.RunningSince)
+		timer, err = client.GetTimer(base.Ali.CSS, base.Region, 0, base.Org.Id)
+		assert.Nil(t, err)
+		assert.True(t, timer.Task.Equal(taskE.Id))
+		assert.Nil(t, timer.RunningSince)
+		timer, err = client.ResumeTimer(base.Ali.CSS, base.Region, 0, base.Org.Id)
+		assert.Nil(t, err)
+		assert.NotNil(t, timer.RunningSince)
+		tl3, err := client.StopTimer(base.Ali.CSS, base.Region, 0, base.Org.Id, &oneVal, nil)
+		assert.Nil(t, err)
+		assert.True(t, tl3.Task.Equal(taskE.Id))
+		assert.Equal(t, uint64(1), tl3.Duration)
+		assert.Equal(t, aNote, *tl3.Note)
+		timer, err = client.GetTimer(base.Ali.CSS, base.Region, 0, base.Org.Id)
+		assert.Nil(t, err)
+		assert.Nil(t, timer)
+		taskE, err = taskClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id)
+		assert.Equal(t, uint64(1), taskE.TotalRemainingTime)
+		assert.Equal(t, uint64(1), taskE.TotalLoggedTime)
+		_, err = client.StopTimer(base.Ali.CSS, base.Region, 0, base.Org.Id, nil, nil)
+		assert.NotNil(t, err)
+		_, err = client.StartTimer(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, client.DiscardTimer(base.Ali.CSS, base.Region, 0, base.Org.Id))
+		timer, err = client.GetTimer(base.Ali.CSS, base.Region, 0, base.Org.Id)
+		assert.Nil(t, err)
+		assert.Nil(t, timer)
+
+		sevenVal := uint16(7)
+		assert.Nil(t, projectClient.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, project.Fields{TimeLogMaxAgeDays: &field.UInt16Ptr{Val: &sevenVal}}))
+		proj, err = projectClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, sevenVal, *proj.TimeLogMaxAgeDays)
+		now := time.Now().UTC().Truncate(time.Second)
+		threeDaysAgo := now.AddDate(0, 0, -3)
+		tenDaysAgo := now.AddDate(0, 0, -10)
+		tomorrow := now.AddDate(0, 0, 1)
+		_, err = client.Create(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, 30, &tenDaysAgo, nil, nil)
+		assert.NotNil(t, err)
+		_, err = client.Create(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, 30, &tomorrow, nil, nil)
+		assert.NotNil(t, err)
+		tl4, err := client.Create(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, 30, &threeDaysAgo, nil, nil)
+		assert.Nil(t, err)
+		assert.True(t, threeDaysAgo.Equal(tl4.LoggedOn))
+		_, err = client.Create(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, 30, &threeDaysAgo, nil, nil)
+		assert.NotNil(t, err)
+		tl5, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, 30, &tenDaysAgo, nil, nil)
+		assert.Nil(t, err)
+		assert.True(t, tenDaysAgo.Equal(tl5.LoggedOn))
+		fiveDaysAgo := now.AddDate(0, 0, -5)
+		oneDayAgo := now.AddDate(0, 0, -1)
+		tls, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, &taskE.Id, nil, nil, &fiveDaysAgo, &oneDayAgo, true, nil, 100)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(tls.TimeLogs))
+		assert.True(t, tls.TimeLogs[0].Id.Equal(tl4.Id))
+		tls, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, &taskE.Id, nil, nil, &tenDaysAgo, nil, true, nil, 100)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(tls.TimeLogs))
+		assert.True(t, tls.TimeLogs[0].Id.Equal(tl5.Id))
+		assert.True(t, tls.TimeLogs[1].Id.Equal(tl4.Id))
+		assert.True(t, tls.TimeLogs[2].Id.Equal(tl3.Id))
+		assert.NotNil(t, client.Edit(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, tl4.Id, Fields{LoggedOn: &field.Time{Val: tenDaysAgo}}))
+		assert.Nil(t, client.Edit(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, tl4.Id, Fields{LoggedOn: &field.Time{Val: fiveDaysAgo}}))
+		assert.Nil(t, client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, tl4.Id, Fields{LoggedOn: &field.Time{Val: now.AddDate(0, 0, -20)}}))
+		tls, err = client.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, nil, &tl4.Id, nil, nil, true, nil, 100)
+		assert.Nil(t, err)
+		assert.True(t, now.AddDate(0, 0, -20).Equal(tls.TimeLogs[0].LoggedOn))
+
+		proj2, err := projectClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, "proj2", nil, 8, 5, nil, nil, false, false, nil)
+		assert.Nil(t, err)
+		task2, err := taskClient.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj2.Id, proj2.Id, nil, "task2", nil, false, nil, &base.Ali.Info.Me.Id, &oneVal)
+		assert.Nil(t, err)
+		_, err = client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj2.Id, task2.Id, 15, &threeDaysAgo, nil, nil)
+		assert.Nil(t, err)
+		_, err = client.GetTimesheet(base.Ali.CSS, base.Region, 0, base.Org.Id, nil, now, now.AddDate(0, 0, -1), nil)
+		assert.NotNil(t, err)
+		_, err = client.GetTimesheet(base.Ali.CSS, base.Region, 0, base.Org.Id, nil, now.AddDate(-1, 0, 0), now, nil)
+		assert.NotNil(t, err)
+		sheet, err := client.GetTimesheet(base.Ali.CSS, base.Region, 0, base.Org.Id, nil, now.AddDate(0, 0, -30), now.Add(time.Second), nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 5, len(sheet.Entries))
+		assert.Equal(t, uint64(176), sheet.TotalDuration)
+		assert.Equal(t, now.AddDate(0, 0, -20).Format("2006-01-02"), sheet.Entries[0].Day)
+		assert.True(t, sheet.Entries[0].Member.Equal(base.Cat.Info.Me.Id))
+		assert.True(t, sheet.Entries[2].Project.Equal(proj2.Id))
+		assert.Equal(t, "task2", sheet.Entries[2].TaskName)
+		assert.Equal(t, uint64(15), sheet.Entries[2].Duration)
+		sheet, err = client.GetTimesheet(base.Dan.CSS, base.Region, 0, base.Org.Id, nil, now.AddDate(0, 0, -30), now.Add(time.Second), nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 4, len(sheet.Entries))
+		assert.Equal(t, uint64(161), sheet.TotalDuration)
+		csvFormat := cnst.ReportFormatCsv
+		sheet, err = client.GetTimesheet(base.Ali.CSS, base.Region, 0, base.Org.Id, &base.Cat.Info.Me.Id, now.AddDate(0, 0, -30), now.Add(time.Second), &csvFormat)
+		assert.Nil(t, err)
+		assert.Nil(t, sheet.Entries)
+		assert.Equal(t, "day,project,project name,task,task name,member,duration,cost,revenue\n"+now.AddDate(0, 0, -20).Format("2006-01-02")+","+proj.Id.String()+",proj,"+taskE.Id.String()+",E,"+base.Cat.Info.Me.Id.String()+",30,0,0\n", *sheet.Csv)
+
+		sixtyVal := uint64(60)
+		hundredTwentyVal := uint64(120)
+		fiftyVal := uint64(50)
+		assert.NotNil(t, accountClient.SetMemberHourlyRate(base.Cat.CSS, base.Region, 0, base.Org.Id, base.Cat.Info.Me.Id, &sixtyVal))
+		assert.Nil(t, accountClient.SetMemberHourlyRate(base.Ali.CSS, base.Region, 0, base.Org.Id, base.Cat.Info.Me.Id, &sixtyVal))
+		assert.Nil(t, accountClient.SetMemberHourlyRate(base.Ali.CSS, base.Region, 0, base.Org.Id, base.Ali.Info.Me.Id, &sixtyVal))
+		assert.Nil(t, projectClient.SetMemberHourlyRate(base.Ali.CSS, base.Region, 0, base.Org.Id, proj2.Id, base.Ali.Info.Me.Id, &hundredTwentyVal))
+		assert.Nil(t, projectClient.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj2.Id, project.Fields{Budget: &field.UInt64Ptr{Val: &fiftyVal}}))
+		tl6, err := client.Create(base.Cat.CSS, base.Region, 0, base.Org.Id, proj.Id, taskE.Id, 10, nil, nil, nil)
+		assert.Nil(t, err)
+		assert.True(t, tl6.IsBillable)
+		assert.Equal(t, sixtyVal, tl6.HourlyRate)
+		assert.Equal(t, uint64(10), tl6.Cost)
+		tl7, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj2.Id, task2.Id, 30, nil, nil, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, hundredTwentyVal, tl7.HourlyRate)
+		assert.Equal(t, uint64(60), tl7.Cost)
+		tl8, err := client.Create(base.Ali.CSS, base.Region, 0, base.Org.Id, proj2.Id, task2.Id, 15, nil, &falseVal, nil)
+		assert.Nil(t, err)
+		assert.False(t, tl8.IsBillable)
+		assert.Equal(t, uint64(30), tl8.Cost)
+		sheet, err = client.GetTimesheet(base.Ali.CSS, base.Region, 0, base.Org.Id, nil, now.AddDate(0, 0, -30), now.Add(time.Minute), nil)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(100), sheet.TotalCost)
+		assert.Equal(t, uint64(70), sheet.TotalRevenue)
+		sheet, err = client.GetTimesheet(base.Dan.CSS, base.Region, 0, base.Org.Id, nil, now.AddDate(0, 0, -30), now.Add(time.Minute), nil)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(0), sheet.TotalCost)
+		assert.Equal(t, uint64(0), sheet.TotalRevenue)
+		task2, err = taskClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj2.Id, task2.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(90), task2.TotalCost)
+		assert.Equal(t, uint64(60), task2.TotalRevenue)
+		proj2, err = projectClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj2.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, fiftyVal, *proj2.Budget)
+		assert.Equal(t, uint64(90), proj2.TotalCost)
+		assert.Equal(t, uint64(60), proj2.TotalRevenue)
+		assert.True(t, proj2.IsOverBudget)
+		assert.Nil(t, client.Edit(base.Ali.CSS, base.Region, 0, base.Org.Id, proj2.Id, tl8.Id, Fields{IsBillable: &field.Bool{Val: true}}))
+		proj2, err = projectClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj2.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(90), proj2.TotalRevenue)
+		assert.Nil(t, client.Delete(base.Ali.CSS, base.Region, 0, base.Org.Id, proj2.Id, tl7.Id))
+		proj2, err = projectClient.Get(base.Ali.CSS, base.Region, 0, base.Org.Id, proj2.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(30), proj2.TotalCost)
+		assert.False(t, proj2.IsOverBudget)
+		assert.Nil(t, projectClient.SetMemberHourlyRate(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, base.Cat.Info.Me.Id, &hundredTwentyVal))
+		mems, err := projectClient.GetMembers(base.Ali.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, nil, nil, 100)
+		assert.Nil(t, err)
+		for _, mem := range mems.Members {
+			if mem.Id.Equal(base.Cat.Info.Me.Id) {
+				assert.Equal(t, hundredTwentyVal, *mem.HourlyRate)
+			}
+		}
+		mems, err = projectClient.GetMembers(base.Dan.CSS, base.Region, 0, base.Org.Id, proj.Id, nil, nil, nil, 100)
+		assert.Nil(t, err)
+		for _, mem := range mems.Members {
+			assert.Nil(t, mem.HourlyRate)
+		}
 	}, account.Endpoints, project.Endpoints, task.Endpoints, Endpoints)
 }
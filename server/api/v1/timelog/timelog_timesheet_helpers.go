@@ -0,0 +1,22 @@
+package timelog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/0xor1/panic"
+	"strconv"
+)
+
+// timesheetCsv writes entries with a header row, ids are written in the same form as in json.
+func timesheetCsv(entries []*TimesheetEntry) *string {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	panic.IfNotNil(w.Write([]string{"day", "project", "project name", "task", "task name", "member", "duration", "cost", "revenue"}))
+	for _, e := range entries {
+		panic.IfNotNil(w.Write([]string{e.Day, e.Project.String(), e.ProjectName, e.Task.String(), e.TaskName, e.Member.String(), strconv.FormatUint(e.Duration, 10), strconv.FormatUint(e.Cost, 10), strconv.FormatUint(e.Revenue, 10)}))
+	}
+	w.Flush()
+	panic.IfNotNil(w.Error())
+	s := buf.String()
+	return &s
+}
@@ -5,8 +5,13 @@ import (
 	"fmt"
 	"github.com/0xor1/trees/server/api/v1/account"
 	"github.com/0xor1/trees/server/api/v1/central"
+	"github.com/0xor1/trees/server/api/v1/comment"
+	"github.com/0xor1/trees/server/api/v1/file"
+	"github.com/0xor1/trees/server/api/v1/label"
+	"github.com/0xor1/trees/server/api/v1/notification"
 	"github.com/0xor1/trees/server/api/v1/private"
 	"github.com/0xor1/trees/server/api/v1/project"
+	"github.com/0xor1/trees/server/api/v1/recurrence"
 	"github.com/0xor1/trees/server/api/v1/task"
 	"github.com/0xor1/trees/server/api/v1/timelog"
 	"github.com/0xor1/trees/server/util/cnst"
@@ -23,16 +28,28 @@ func main() {
 	endPointSets := make([][]*endpoint.Endpoint, 0, 100)
 	switch SR.Env {
 	case cnst.LclEnv, cnst.DevEnv: //onebox environment, all endpoints run in the same service
-		endPointSets = append(endPointSets, central.Endpoints, private.Endpoints, account.Endpoints, project.Endpoints, task.Endpoints, timelog.Endpoints)
+		endPointSets = append(endPointSets, central.Endpoints, private.Endpoints, account.Endpoints, project.Endpoints, task.Endpoints, timelog.Endpoints, comment.Endpoints, file.Endpoints, label.Endpoints, notification.Endpoints, recurrence.Endpoints)
 	default:
 		switch SR.Region {
 		case cnst.CentralRegion: //central api box, only centralAccount endpoints
 			endPointSets = append(endPointSets, central.Endpoints)
 		default: //regional api box, all regional endpoints required
-			endPointSets = append(endPointSets, private.Endpoints, account.Endpoints, project.Endpoints, task.Endpoints, timelog.Endpoints)
+			endPointSets = append(endPointSets, private.Endpoints, account.Endpoints, project.Endpoints, task.Endpoints, timelog.Endpoints, comment.Endpoints, file.Endpoints, label.Endpoints, notification.Endpoints, recurrence.Endpoints)
 		}
 	}
 	appServer := server.New(SR, endPointSets...)
+	if SR.EmailOutbox != nil {
+		SR.EmailOutbox.Start()
+	}
+	if SR.Notifier != nil {
+		SR.Notifier.Start()
+	}
+	if SR.Scheduler != nil {
+		SR.Scheduler.Start()
+	}
+	if SR.TrashPurger != nil {
+		SR.TrashPurger.Start()
+	}
 	if SR.Env == cnst.LclEnv {
 		fmt.Println("server running on ", SR.BindAddress)
 		SR.LogError(http.ListenAndServe(SR.BindAddress, appServer))
@@ -92,6 +92,22 @@ func (k *Key) AccountProjectsSet(account id.Id) *Key {
 	return k.setKey("aps", account)
 }
 
+func (k *Key) AccountLabelSet(account id.Id) *Key {
+	if k.isGet {
+		k.AccountMaster(account)
+	}
+	return k.setKey("als", account)
+}
+
+func (k *Key) Label(account, label id.Id) *Key {
+	if k.isGet {
+		k.AccountMaster(account)
+	} else {
+		k.AccountLabelSet(account)
+	}
+	return k.setKey("l", label)
+}
+
 func (k *Key) ProjectMaster(account, project id.Id) *Key {
 	if k.isGet {
 		k.AccountMaster(account)
@@ -115,6 +131,20 @@ func (k *Key) ProjectActivities(account, project id.Id) *Key {
 	return k.setKey("pa", project)
 }
 
+func (k *Key) ProjectStateSet(account, project id.Id) *Key {
+	if k.isGet {
+		k.ProjectMaster(account, project)
+	}
+	return k.setKey("pss", project)
+}
+
+func (k *Key) ProjectCustomFieldSet(account, project id.Id) *Key {
+	if k.isGet {
+		k.ProjectMaster(account, project)
+	}
+	return k.setKey("pcfs", project)
+}
+
 func (k *Key) ProjectMembersSet(account, project id.Id) *Key {
 	if k.isGet {
 		k.ProjectMaster(account, project)
@@ -167,6 +197,69 @@ func (k *Key) CombinedTaskAndTaskChildrenSets(account, project id.Id, tasks []id
 	return k
 }
 
+func (k *Key) ProjectLabelledTaskSet(account, project id.Id) *Key {
+	if k.isGet {
+		k.ProjectMaster(account, project)
+	}
+	return k.setKey("plts", project)
+}
+
+func (k *Key) ProjectTrashSet(account, project id.Id) *Key {
+	if k.isGet {
+		k.ProjectMaster(account, project)
+	}
+	return k.setKey("ptrs", project)
+}
+
+func (k *Key) TaskDependencySet(account, project, task id.Id) *Key {
+	if k.isGet {
+		k.ProjectMaster(account, project)
+	}
+	return k.setKey("tds", task)
+}
+
+func (k *Key) TaskCommentSet(account, project, task id.Id) *Key {
+	if k.isGet {
+		k.Task(account, project, task) //comments are deleted along with their task
+	}
+	return k.setKey("tcms", task)
+}
+
+func (k *Key) Comment(account, project, comment id.Id, task *id.Id) *Key {
+	if k.isGet {
+		k.ProjectMaster(account, project)
+		if task != nil {
+			k.TaskCommentSet(account, project, *task)
+		}
+	} else if task != nil {
+		k.TaskCommentSet(account, project, *task)
+	} else {
+		panic.If(true, "missing task in comment dlm")
+	}
+	return k.setKey("cm", comment)
+}
+
+func (k *Key) TaskFileSet(account, project, task id.Id) *Key {
+	if k.isGet {
+		k.Task(account, project, task) //files are deleted along with their task
+	}
+	return k.setKey("tfs", task)
+}
+
+func (k *Key) File(account, project, file id.Id, task *id.Id) *Key {
+	if k.isGet {
+		k.ProjectMaster(account, project)
+		if task != nil {
+			k.TaskFileSet(account, project, *task)
+		}
+	} else if task != nil {
+		k.TaskFileSet(account, project, *task)
+	} else {
+		panic.If(true, "missing task in file dlm")
+	}
+	return k.setKey("f", file)
+}
+
 func (k *Key) ProjectTimeLogSet(account, project id.Id) *Key {
 	if k.isGet {
 		k.ProjectMaster(account, project)
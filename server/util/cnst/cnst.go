@@ -39,6 +39,25 @@ const (
 	SortByDisplayName = SortBy("displayname")
 	SortByStartOn     = SortBy("starton")
 	SortByDueOn       = SortBy("dueon")
+
+	ImportFormatMarkdown = ImportFormat("markdown")
+	ImportFormatCsv      = ImportFormat("csv")
+
+	ReportFormatJson = ReportFormat("json")
+	ReportFormatCsv  = ReportFormat("csv")
+
+	NotificationDeliveryImmediate   = NotificationDelivery(0)
+	NotificationDeliveryDailyDigest = NotificationDelivery(1)
+	NotificationDeliveryNone        = NotificationDelivery(2)
+
+	RecurrenceDaily   = RecurrenceFrequency("daily")
+	RecurrenceWeekly  = RecurrenceFrequency("weekly")
+	RecurrenceMonthly = RecurrenceFrequency("monthly")
+
+	CustomFieldText   = CustomFieldType("text")
+	CustomFieldNumber = CustomFieldType("number")
+	CustomFieldDate   = CustomFieldType("date")
+	CustomFieldSelect = CustomFieldType("select")
 )
 
 type Env string
@@ -160,3 +179,94 @@ func (sb *SortBy) UnmarshalJSON(raw []byte) error {
 	sb.Validate()
 	return nil
 }
+
+type ImportFormat string
+
+func (f *ImportFormat) Validate() {
+	err.HttpPanicf(f != nil && !(*f == ImportFormatMarkdown || *f == ImportFormatCsv), http.StatusBadRequest, "invalid import format")
+}
+
+func (f *ImportFormat) String() string {
+	return string(*f)
+}
+
+func (f *ImportFormat) UnmarshalJSON(raw []byte) error {
+	val := strings.Trim(strings.ToLower(string(raw)), `"`)
+	*f = ImportFormat(val)
+	f.Validate()
+	return nil
+}
+
+type ReportFormat string
+
+func (f *ReportFormat) Validate() {
+	err.HttpPanicf(f != nil && !(*f == ReportFormatJson || *f == ReportFormatCsv), http.StatusBadRequest, "invalid report format")
+}
+
+func (f *ReportFormat) String() string {
+	return string(*f)
+}
+
+func (f *ReportFormat) UnmarshalJSON(raw []byte) error {
+	val := strings.Trim(strings.ToLower(string(raw)), `"`)
+	*f = ReportFormat(val)
+	f.Validate()
+	return nil
+}
+
+type NotificationDelivery uint8
+
+func (d *NotificationDelivery) Validate() {
+	err.HttpPanicf(d != nil && !(*d == NotificationDeliveryImmediate || *d == NotificationDeliveryDailyDigest || *d == NotificationDeliveryNone), http.StatusBadRequest, "invalid notification delivery")
+}
+
+func (d *NotificationDelivery) String() string {
+	if d == nil {
+		return ""
+	}
+	return strconv.Itoa(int(*d))
+}
+
+type RecurrenceFrequency string
+
+func (f *RecurrenceFrequency) Validate() {
+	err.HttpPanicf(f != nil && !(*f == RecurrenceDaily || *f == RecurrenceWeekly || *f == RecurrenceMonthly), http.StatusBadRequest, "invalid recurrence frequency")
+}
+
+func (f *RecurrenceFrequency) String() string {
+	return string(*f)
+}
+
+func (f *RecurrenceFrequency) UnmarshalJSON(raw []byte) error {
+	val := strings.Trim(strings.ToLower(string(raw)), `"`)
+	*f = RecurrenceFrequency(val)
+	f.Validate()
+	return nil
+}
+
+func (d *NotificationDelivery) UnmarshalJSON(raw []byte) error {
+	val, err := strconv.ParseUint(string(raw), 10, 8)
+	if err != nil {
+		return err
+	}
+	*d = NotificationDelivery(val)
+	d.Validate()
+	return nil
+}
+
+type CustomFieldType string
+
+func (t *CustomFieldType) Validate() {
+	err.HttpPanicf(t != nil && !(*t == CustomFieldText || *t == CustomFieldNumber || *t == CustomFieldDate || *t == CustomFieldSelect), http.StatusBadRequest, "invalid custom field type")
+}
+
+func (t *CustomFieldType) String() string {
+	return string(*t)
+}
+
+func (t *CustomFieldType) UnmarshalJSON(raw []byte) error {
+	val := strings.Trim(strings.ToLower(string(raw)), `"`)
+	*t = CustomFieldType(val)
+	t.Validate()
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/0xor1/isql"
 	"github.com/0xor1/trees/server/util/avatar"
 	"github.com/0xor1/trees/server/util/cachekey"
+	"github.com/0xor1/trees/server/util/filestore"
 	"github.com/0xor1/trees/server/util/id"
 	"github.com/0xor1/trees/server/util/mail"
 	"github.com/0xor1/trees/server/util/private"
@@ -50,6 +51,7 @@ type Ctx interface {
 	PwdMinRuneCount() int
 	PwdMaxRuneCount() int
 	MaxProcessEntityCount() int
+	TrashRetentionDays() int
 	CryptCodeLen() int
 	SaltLen() int
 	ScryptN() int
@@ -59,4 +61,5 @@ type Ctx interface {
 	RegionalV1PrivateClient() private.V1Client
 	MailClient() mail.Client
 	AvatarClient() avatar.Client
+	FileClient() filestore.Client
 }
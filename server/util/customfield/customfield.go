@@ -0,0 +1,143 @@
+package customfield
+
+import (
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/err"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/validate"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	NameMinRuneCount   = 1
+	NameMaxRuneCount   = 50
+	OptionMaxRuneCount = 50
+	MaxOptions         = 25
+	TextMaxRuneCount   = 1250
+	MaxFields          = 50
+)
+
+// Field is a typed custom field defined on a project, every task in the project can hold one value for it.
+type Field struct {
+	Id      id.Id                `json:"id"`
+	Name    string               `json:"name"`
+	Type    cnst.CustomFieldType `json:"type"`
+	Options []string             `json:"options,omitempty"` //only select fields, a tasks value must be one of these
+}
+
+// Value is a tasks value for a custom field, only the member matching the fields type is set, text fields and select
+// fields both use Text. A value with nothing set clears the tasks value, or as a filter matches tasks without one.
+type Value struct {
+	Field  id.Id      `json:"field"`
+	Text   *string    `json:"text,omitempty"`
+	Number *float64   `json:"number,omitempty"`
+	Date   *time.Time `json:"date,omitempty"` //stored to the second in UTC
+}
+
+func (v *Value) IsEmpty() bool {
+	return v.Text == nil && v.Number == nil && v.Date == nil
+}
+
+func (f *Field) Validate() {
+	validate.StringArg("name", f.Name, NameMinRuneCount, NameMaxRuneCount, nil)
+	f.Type.Validate()
+	if f.Type != cnst.CustomFieldSelect {
+		err.HttpPanicf(len(f.Options) > 0, http.StatusBadRequest, "only select custom fields have options")
+		return
+	}
+	err.HttpPanicf(len(f.Options) == 0 || len(f.Options) > MaxOptions, http.StatusBadRequest, "select custom fields must have between 1 and %d options", MaxOptions)
+	seen := map[string]bool{}
+	for _, o := range f.Options {
+		validate.StringArg("option", o, 1, OptionMaxRuneCount, nil)
+		err.HttpPanicf(strings.ContainsAny(o, "\r\n"), http.StatusBadRequest, "options can not contain line breaks")
+		err.HttpPanicf(seen[o], http.StatusBadRequest, "duplicate option")
+		seen[o] = true
+	}
+}
+
+// OptionsStr is how a select fields options are stored, nil for other fields.
+func (f *Field) OptionsStr() *string {
+	if f.Type != cnst.CustomFieldSelect {
+		return nil
+	}
+	s := strings.Join(f.Options, "\n")
+	return &s
+}
+
+func (f *Field) SetOptionsStr(options *string) {
+	f.Options = nil
+	if options != nil && *options != "" {
+		f.Options = strings.Split(*options, "\n")
+	}
+}
+
+func Get(fields []*Field, field id.Id) *Field {
+	for _, f := range fields {
+		if f.Id.Equal(field) {
+			return f
+		}
+	}
+	return nil
+}
+
+// ValidateValue panics with a bad request if v isn't an empty value or a value of the right type for one of fields, dates
+// are truncated to the second in UTC to match how they are stored.
+func ValidateValue(fields []*Field, v *Value) {
+	f := Get(fields, v.Field)
+	err.HttpPanicf(f == nil, http.StatusBadRequest, "no such custom field")
+	if v.IsEmpty() {
+		return
+	}
+	switch f.Type {
+	case cnst.CustomFieldText, cnst.CustomFieldSelect:
+		err.HttpPanicf(v.Text == nil || v.Number != nil || v.Date != nil, http.StatusBadRequest, "%s custom field values must only set text", f.Type)
+		if f.Type == cnst.CustomFieldText {
+			validate.StringArg("text", *v.Text, 0, TextMaxRuneCount, nil)
+		} else {
+			isOption := false
+			for _, o := range f.Options {
+				isOption = isOption || o == *v.Text
+			}
+			err.HttpPanicf(!isOption, http.StatusBadRequest, "no such option")
+		}
+	case cnst.CustomFieldNumber:
+		err.HttpPanicf(v.Number == nil || v.Text != nil || v.Date != nil, http.StatusBadRequest, "number custom field values must only set number")
+		err.HttpPanicf(math.IsNaN(*v.Number) || math.IsInf(*v.Number, 0), http.StatusBadRequest, "invalid number")
+	case cnst.CustomFieldDate:
+		err.HttpPanicf(v.Date == nil || v.Text != nil || v.Number != nil, http.StatusBadRequest, "date custom field values must only set date")
+		d := v.Date.UTC().Truncate(time.Second)
+		v.Date = &d
+	}
+}
+
+// Matches returns true if values holds a value equal to filter, or if filter is empty, no value for its field.
+func Matches(values []*Value, filter *Value) bool {
+	for _, v := range values {
+		if v.Field.Equal(filter.Field) {
+			switch {
+			case filter.Text != nil:
+				return v.Text != nil && *v.Text == *filter.Text
+			case filter.Number != nil:
+				return v.Number != nil && *v.Number == *filter.Number
+			case filter.Date != nil:
+				return v.Date != nil && v.Date.Equal(*filter.Date)
+			default:
+				return false
+			}
+		}
+	}
+	return filter.IsEmpty()
+}
+
+// MatchesAll returns true if values matches every one of filters.
+func MatchesAll(values []*Value, filters []*Value) bool {
+	for _, filter := range filters {
+		if !Matches(values, filter) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package customfield
+
+import (
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_Field_Validate(t *testing.T) {
+	(&Field{Name: "risk", Type: cnst.CustomFieldSelect, Options: []string{"low", "high"}}).Validate()
+	defer func() {
+		assert.NotNil(t, recover())
+	}()
+	(&Field{Name: "customer", Type: cnst.CustomFieldText, Options: []string{"acme"}}).Validate()
+}
+
+func Test_Field_OptionsStr(t *testing.T) {
+	f := &Field{Type: cnst.CustomFieldSelect, Options: []string{"low", "high"}}
+	g := &Field{}
+	g.SetOptionsStr(f.OptionsStr())
+	assert.Equal(t, f.Options, g.Options)
+	assert.Nil(t, (&Field{Type: cnst.CustomFieldText}).OptionsStr())
+}
+
+func Test_ValidateValue(t *testing.T) {
+	date := &Field{Id: id.New(), Type: cnst.CustomFieldDate}
+	d := time.Date(2018, 3, 2, 15, 4, 5, 6, time.FixedZone("", 3600))
+	v := &Value{Field: date.Id, Date: &d}
+	ValidateValue([]*Field{date}, v)
+	assert.Equal(t, time.Date(2018, 3, 2, 14, 4, 5, 0, time.UTC), *v.Date)
+	ValidateValue([]*Field{date}, &Value{Field: date.Id})
+	defer func() {
+		assert.NotNil(t, recover())
+	}()
+	ValidateValue([]*Field{date}, &Value{Field: date.Id, Number: new(float64)})
+}
+
+func Test_ValidateValue_selectOption(t *testing.T) {
+	risk := &Field{Id: id.New(), Type: cnst.CustomFieldSelect, Options: []string{"low", "high"}}
+	high := "high"
+	ValidateValue([]*Field{risk}, &Value{Field: risk.Id, Text: &high})
+	defer func() {
+		assert.NotNil(t, recover())
+	}()
+	medium := "medium"
+	ValidateValue([]*Field{risk}, &Value{Field: risk.Id, Text: &medium})
+}
+
+func Test_MatchesAll(t *testing.T) {
+	customer, ticket := id.New(), id.New()
+	acme := "acme"
+	other := "other"
+	num := float64(12)
+	values := []*Value{{Field: customer, Text: &acme}}
+	assert.True(t, MatchesAll(values, []*Value{{Field: customer, Text: &acme}}))
+	assert.False(t, MatchesAll(values, []*Value{{Field: customer, Text: &other}}))
+	assert.True(t, MatchesAll(values, []*Value{{Field: ticket}}))
+	assert.False(t, MatchesAll(values, []*Value{{Field: customer}}))
+	assert.False(t, MatchesAll(values, []*Value{{Field: customer, Text: &acme}, {Field: ticket, Number: &num}}))
+}
@@ -1,16 +1,20 @@
 package db
 
 import (
+	"bytes"
+	"fmt"
 	"github.com/0xor1/panic"
 	"github.com/0xor1/trees/server/util/account"
 	"github.com/0xor1/trees/server/util/cachekey"
 	"github.com/0xor1/trees/server/util/cnst"
 	"github.com/0xor1/trees/server/util/ctx"
+	"github.com/0xor1/trees/server/util/customfield"
 	"github.com/0xor1/trees/server/util/err"
 	"github.com/0xor1/trees/server/util/id"
 	t "github.com/0xor1/trees/server/util/time"
 	"github.com/0xor1/trees/server/util/timelog"
 	"github.com/0xor1/trees/server/util/validate"
+	"strings"
 	"time"
 )
 
@@ -87,7 +91,76 @@ func GetAccount(ctx ctx.Ctx, shard int, acc id.Id) *account.Account {
 	return &res
 }
 
-func SetRemainingTimeAndOrLogTime(ctx ctx.Ctx, shard int, account, project, task id.Id, remainingTime *uint64, duration *uint64, note *string) *timelog.TimeLog {
+func GetProjectIsArchived(ctx ctx.Ctx, shard int, account, project id.Id) bool {
+	isArchived := false
+	cacheKey := cachekey.NewGet("db.GetProjectIsArchived", shard, account, project).Project(account, project)
+	if ctx.GetCacheValue(&isArchived, cacheKey) {
+		return isArchived
+	}
+	row := ctx.TreeQueryRow(shard, `SELECT isArchived FROM projects WHERE account=? AND id=?`, account, project)
+	ctx.ReturnBadRequestNowIf(err.IsSqlErrNoRowsElsePanicIf(row.Scan(&isArchived)), "no such project")
+	ctx.SetCacheValue(isArchived, cacheKey)
+	return isArchived
+}
+
+// ReturnBadRequestNowIfProjectIsArchived rejects writes to an archived project, it stays read only until an account admin
+// unarchives it through project edit.
+func ReturnBadRequestNowIfProjectIsArchived(ctx ctx.Ctx, shard int, account, project id.Id) {
+	ctx.ReturnBadRequestNowIf(GetProjectIsArchived(ctx, shard, account, project), "project is archived, it must be unarchived before it can be changed")
+}
+
+// GetProjectTimeLogMaxAgeDays returns how many days back members can log time in the project, nil for no limit.
+func GetProjectTimeLogMaxAgeDays(ctx ctx.Ctx, shard int, account, project id.Id) *uint16 {
+	var maxAgeDays *uint16
+	cacheKey := cachekey.NewGet("db.GetProjectTimeLogMaxAgeDays", shard, account, project).Project(account, project)
+	if ctx.GetCacheValue(&maxAgeDays, cacheKey) {
+		return maxAgeDays
+	}
+	row := ctx.TreeQueryRow(shard, `SELECT timeLogMaxAgeDays FROM projects WHERE account=? AND id=?`, account, project)
+	ctx.ReturnBadRequestNowIf(err.IsSqlErrNoRowsElsePanicIf(row.Scan(&maxAgeDays)), "no such project")
+	ctx.SetCacheValue(maxAgeDays, cacheKey)
+	return maxAgeDays
+}
+
+// ValidateLoggedOn rejects time logged in the future, or further back than the projects timeLogMaxAgeDays unless I'm a
+// project admin. loggedOn is truncated to the second as that is all timeLogs store.
+func ValidateLoggedOn(ctx ctx.Ctx, shard int, account, project id.Id, loggedOn *time.Time) {
+	*loggedOn = loggedOn.UTC().Truncate(time.Second)
+	now := t.Now()
+	ctx.ReturnBadRequestNowIf(loggedOn.After(now), "loggedOn can not be in the future")
+	if maxAgeDays := GetProjectTimeLogMaxAgeDays(ctx, shard, account, project); maxAgeDays != nil && loggedOn.Before(now.AddDate(0, 0, -int(*maxAgeDays))) {
+		accRole, projRole := GetAccountAndProjectRoles(ctx, shard, account, project, ctx.Me())
+		isAdmin := accRole != nil && (*accRole == cnst.AccountOwner || *accRole == cnst.AccountAdmin) || projRole != nil && *projRole == cnst.ProjectAdmin
+		ctx.ReturnBadRequestNowIf(!isAdmin, "time can only be logged up to %d days back in this project", *maxAgeDays)
+	}
+}
+
+// GetProjectCustomFields returns the projects custom fields in the order they were created.
+func GetProjectCustomFields(ctx ctx.Ctx, shard int, account, project id.Id) []*customfield.Field {
+	res := make([]*customfield.Field, 0, customfield.MaxFields)
+	cacheKey := cachekey.NewGet("db.GetProjectCustomFields", shard, account, project).ProjectCustomFieldSet(account, project)
+	if ctx.GetCacheValue(&res, cacheKey) {
+		return res
+	}
+	rows, e := ctx.TreeQuery(shard, `SELECT id, name, type, options FROM customFields WHERE account=? AND project=? ORDER BY createdOn ASC, id ASC`, account, project)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	for rows.Next() {
+		f := &customfield.Field{}
+		var options *string
+		panic.IfNotNil(rows.Scan(&f.Id, &f.Name, &f.Type, &options))
+		f.SetOptionsStr(options)
+		res = append(res, f)
+	}
+	ctx.SetCacheValue(res, cacheKey)
+	return res
+}
+
+// SetRemainingTimeAndOrLogTime logs duration at loggedOn, or now if loggedOn is nil, time is billable unless isBillable
+// is false.
+func SetRemainingTimeAndOrLogTime(ctx ctx.Ctx, shard int, account, project, task id.Id, member *id.Id, remainingTime *uint64, duration *uint64, loggedOn *time.Time, isBillable *bool, note *string) *timelog.TimeLog {
 	var timeLog *id.Id
 	if duration != nil {
 		ctx.ReturnBadRequestNowIf(*duration == 0, "none null duration must be > 0")
@@ -100,28 +173,50 @@ func SetRemainingTimeAndOrLogTime(ctx ctx.Ctx, shard int, account, project, task
 		ctx.ReturnBadRequestNowIf(true, "one of duration or remainingTime must be set")
 	}
 
-	loggedOn := t.Now()
-	return setRemainingTimeAndOrLogTime(ctx, shard, account, project, task, remainingTime, timeLog, &loggedOn, duration, note)
+	ReturnBadRequestNowIfProjectIsArchived(ctx, shard, account, project)
+
+	if loggedOn == nil {
+		now := t.Now().Truncate(time.Second)
+		loggedOn = &now
+	} else if duration != nil {
+		ValidateLoggedOn(ctx, shard, account, project, loggedOn)
+	}
+	if isBillable == nil {
+		billable := true
+		isBillable = &billable
+	}
+	return setRemainingTimeAndOrLogTime(ctx, shard, account, project, task, member, remainingTime, timeLog, loggedOn, duration, note, *isBillable, nil)
+}
+
+// LogTimerTime logs duration now from my timer started at timerStartedOn and deletes the timer in the same transaction,
+// so a timer stopped twice only has its time logged once, the caller must have checked my access to project.
+func LogTimerTime(ctx ctx.Ctx, shard int, account, project, task id.Id, timerStartedOn time.Time, remainingTime *uint64, duration uint64, note *string) *timelog.TimeLog {
+	timeLog := id.New()
+	loggedOn := t.Now().Truncate(time.Second)
+	return setRemainingTimeAndOrLogTime(ctx, shard, account, project, task, nil, remainingTime, &timeLog, &loggedOn, &duration, note, true, &timerStartedOn)
 }
 
-func setRemainingTimeAndOrLogTime(ctx ctx.Ctx, shard int, account, project, task id.Id, remainingTime *uint64, timeLog *id.Id, loggedOn *time.Time, duration *uint64, note *string) *timelog.TimeLog {
-	rows, e := ctx.TreeQuery(shard, `CALL setRemainingTimeAndOrLogTime( ?, ?, ?, ?, ?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), remainingTime, timeLog, loggedOn, duration, note)
+func setRemainingTimeAndOrLogTime(ctx ctx.Ctx, shard int, account, project, task id.Id, member *id.Id, remainingTime *uint64, timeLog *id.Id, loggedOn *time.Time, duration *uint64, note *string, isBillable bool, timerStartedOn *time.Time) *timelog.TimeLog {
+	rows, e := ctx.TreeQuery(shard, `CALL setRemainingTimeAndOrLogTime( ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, account, project, task, ctx.Me(), member, remainingTime, timeLog, loggedOn, duration, note, isBillable, timerStartedOn)
 	if rows != nil {
 		defer rows.Close()
 	}
 	panic.IfNotNil(e)
 	tasks := make([]id.Id, 0, 100)
-	var existingMember *id.Id
+	var updatedMember *id.Id
 	var taskName string
+	var hourlyRate, cost uint64
+	timeLogExists := false
 	for rows.Next() {
 		var i id.Id
-		rows.Scan(&i, existingMember, &taskName)
+		panic.IfNotNil(rows.Scan(&i, &updatedMember, &taskName, &hourlyRate, &cost, &timeLogExists))
 		tasks = append(tasks, i)
 	}
+	ctx.ReturnBadRequestNowIf(timeLogExists, "I already have a time log on this task at loggedOn")
 	ctx.ReturnBadRequestNowIf(len(tasks) == 0, "no change made")
 	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project).CombinedTaskAndTaskChildrenSets(account, project, tasks)
-	if existingMember != nil {
-		cacheKey.ProjectMember(account, project, *existingMember)
+	if updatedMember != nil {
+		cacheKey.ProjectMember(account, project, *updatedMember)
 	}
 	if timeLog != nil {
 		cacheKey.TimeLog(account, project, *timeLog, &task, ctx.TryMe())
@@ -139,11 +234,82 @@ func setRemainingTimeAndOrLogTime(ctx ctx.Ctx, shard int, account, project, task
 			TaskName:           taskName,
 			Duration:           *duration,
 			Note:               note,
+			IsBillable:         isBillable,
+			HourlyRate:         hourlyRate,
+			Cost:               cost,
 		}
 	}
 	return nil
 }
 
+const (
+	maxCopyTaskCount    = 1000
+	bulkInsertBatchSize = 500
+)
+
+func CopyTask(ctx ctx.Ctx, shard int, account, sourceProject, task, project, newParent id.Id, newPreviousSibling *id.Id, copyEstimates, copyMembers bool) id.Id {
+	//deep copies the task subtree rooted at task in sourceProject to newParent in project, returning the id of the new subtree root,
+	//copying a project node copies its children onto the root of project, which must be a different and empty project
+	var descendantCount uint64
+	ctx.ReturnBadRequestNowIf(err.IsSqlErrNoRowsElsePanicIf(ctx.TreeQueryRow(shard, `SELECT descendantCount FROM tasks WHERE account=? AND project=? AND id=?`, account, sourceProject, task).Scan(&descendantCount)), "no such task")
+	ctx.ReturnBadRequestNowIf(descendantCount+1 > maxCopyTaskCount, "can not copy more than %d tasks at once", maxCopyTaskCount)
+	newIds := make([]id.Id, 0, descendantCount+1)
+	if task.Equal(sourceProject) {
+		newIds = append(newIds, project)
+	} else {
+		newIds = append(newIds, id.New())
+	}
+	for i := uint64(0); i < descendantCount; i++ {
+		newIds = append(newIds, id.New())
+	}
+	rows, e := ctx.TreeQuery(shard, `CALL copyTask(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, account, sourceProject, task, project, newParent, ctx.Me(), newPreviousSibling, copyEstimates, copyMembers, t.Now(), id.ToHexString(newIds))
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	tasks := make([]id.Id, 0, len(newIds)+10)
+	cacheKey := cachekey.NewSetDlms().ProjectActivities(account, project).ProjectLabelledTaskSet(account, project)
+	for rows.Next() {
+		var i id.Id
+		key := ""
+		rows.Scan(&i, &key)
+		switch key {
+		case "t":
+			tasks = append(tasks, i)
+		case "m":
+			cacheKey.ProjectMember(account, project, i)
+		default:
+			panic.If(true, "unknown key value in copy task rows")
+		}
+	}
+	ctx.ReturnBadRequestNowIf(len(tasks) == 0, "no change made")
+	ctx.TouchDlms(cacheKey.CombinedTaskAndTaskChildrenSets(account, project, tasks))
+	return newIds[0]
+}
+
+func BulkInsert(ctx ctx.Ctx, shard int, insert string, rows [][]interface{}) {
+	//writes rows in batches of multi row inserts, insert must end with "VALUES "
+	for len(rows) > 0 {
+		batch := rows
+		if len(batch) > bulkInsertBatchSize {
+			batch = rows[:bulkInsertBatchSize]
+		}
+		rows = rows[len(batch):]
+		placeholder := fmt.Sprintf(`(%s?)`, strings.Repeat(`?,`, len(batch[0])-1))
+		query := bytes.NewBufferString(insert)
+		args := make([]interface{}, 0, len(batch)*len(batch[0]))
+		for i, row := range batch {
+			if i > 0 {
+				query.WriteString(`,`)
+			}
+			query.WriteString(placeholder)
+			args = append(args, row...)
+		}
+		_, e := ctx.TreeExec(shard, query.String(), args...)
+		panic.IfNotNil(e)
+	}
+}
+
 func MakeChangeHelper(ctx ctx.Ctx, shard int, sql string, args ...interface{}) {
 	row := ctx.TreeQueryRow(shard, sql, args...)
 	changeMade := false
@@ -4,8 +4,11 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/0xor1/panic"
+	"github.com/go-sql-driver/mysql"
 )
 
+const mysqlErrDupEntry = 1062
+
 type Http struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
@@ -23,6 +26,14 @@ func IsSqlErrNoRowsElsePanicIf(e error) bool {
 	return false
 }
 
+func IsSqlErrDupEntryElsePanicIf(e error) bool {
+	if me, ok := e.(*mysql.MySQLError); ok && me.Number == mysqlErrDupEntry {
+		return true
+	}
+	panic.IfNotNil(e)
+	return false
+}
+
 func HttpPanicf(condition bool, code int, messageFmt string, messageArgs ...interface{}) {
 	if condition {
 		panic.IfNotNil(&Http{Code: code, Message: fmt.Sprintf(messageFmt, messageArgs...)})
@@ -0,0 +1,101 @@
+package filestore
+
+import (
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/id"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+type Client interface {
+	MaxFileSize() int64
+	AccountQuota() int64
+	Save(key string, mimeType string, data io.Reader)
+	Load(key string) io.ReadCloser
+	Delete(key string)
+	DeletePrefix(prefix string)
+	DeleteAll()
+}
+
+// files are stored under account/project/file so a whole project or account can be cleaned up by prefix
+func Key(account, project, file id.Id) string {
+	return path.Join(ProjectPrefix(account, project), file.String())
+}
+
+func ProjectPrefix(account, project id.Id) string {
+	return path.Join(AccountPrefix(account), project.String())
+}
+
+func AccountPrefix(account id.Id) string {
+	return account.String()
+}
+
+func NewLocalClient(dir string, maxFileSize, accountQuota int64) Client {
+	panic.If(dir == "", "invalid file dir")
+	panic.If(maxFileSize <= 0 || accountQuota <= 0, "invalid file size limits")
+	dir, e := filepath.Abs(dir)
+	panic.IfNotNil(e)
+	return &localClient{
+		mtx:          &sync.Mutex{},
+		maxFileSize:  maxFileSize,
+		accountQuota: accountQuota,
+		dir:          dir,
+	}
+}
+
+type localClient struct {
+	mtx          *sync.Mutex
+	maxFileSize  int64
+	accountQuota int64
+	dir          string
+}
+
+func (c *localClient) MaxFileSize() int64 {
+	return c.maxFileSize
+}
+
+func (c *localClient) AccountQuota() int64 {
+	return c.accountQuota
+}
+
+func (c *localClient) Save(key string, mimeType string, data io.Reader) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	fileBytes, e := ioutil.ReadAll(data)
+	panic.IfNotNil(e)
+	fullPath := path.Join(c.dir, key)
+	panic.IfNotNil(os.MkdirAll(path.Dir(fullPath), os.ModePerm))
+	panic.IfNotNil(ioutil.WriteFile(fullPath, fileBytes, os.ModePerm))
+}
+
+func (c *localClient) Load(key string) io.ReadCloser {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	f, e := os.Open(path.Join(c.dir, key))
+	panic.IfNotNil(e)
+	return f
+}
+
+func (c *localClient) Delete(key string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if e := os.Remove(path.Join(c.dir, key)); e != nil && !os.IsNotExist(e) {
+		panic.IfNotNil(e)
+	}
+}
+
+func (c *localClient) DeletePrefix(prefix string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	panic.IfNotNil(os.RemoveAll(path.Join(c.dir, prefix)))
+}
+
+func (c *localClient) DeleteAll() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	panic.IfNotNil(os.RemoveAll(c.dir))
+}
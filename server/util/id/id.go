@@ -3,6 +3,7 @@ package id
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/hex"
 	"github.com/0xor1/trees/server/util/err"
 	"github.com/0xor1/trees/server/util/time"
 	"github.com/oklog/ulid"
@@ -53,6 +54,15 @@ func (id Id) Copy() Id {
 	return Id(append(make([]byte, 0, 16), id...))
 }
 
+//returns the ids hex encoded and concatenated, the format stored procedures take id lists in
+func ToHexString(ids []Id) string {
+	buf := bytes.NewBufferString(``)
+	for _, i := range ids {
+		buf.WriteString(hex.EncodeToString(i))
+	}
+	return buf.String()
+}
+
 type Identifiable interface {
 	Id() Id
 }
@@ -26,3 +26,10 @@ func Test_IdCopy(t *testing.T) {
 	id2[1] = tmp
 	assert.False(t, id1.Equal(id2))
 }
+
+func Test_ToHexString(t *testing.T) {
+	id1 := Id([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15})
+	id2 := Id([]byte{255, 254, 253, 252, 251, 250, 249, 248, 247, 246, 245, 244, 243, 242, 241, 240})
+	assert.Equal(t, "", ToHexString(nil))
+	assert.Equal(t, "000102030405060708090a0b0c0d0e0ffffefdfcfbfaf9f8f7f6f5f4f3f2f1f0", ToHexString([]Id{id1, id2}))
+}
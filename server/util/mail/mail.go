@@ -7,7 +7,7 @@ import (
 )
 
 type Client interface {
-	Send(sendTo []string, content string)
+	Send(sendTo []string, content string) error
 }
 
 func NewLocalClient() Client {
@@ -16,8 +16,9 @@ func NewLocalClient() Client {
 
 type localClient struct{}
 
-func (c *localClient) Send(sendTo []string, content string) {
+func (c *localClient) Send(sendTo []string, content string) error {
 	fmt.Println(sendTo, content)
+	return nil
 }
 
 func NewSparkPostClient(from, apiKey string) Client {
@@ -38,7 +39,7 @@ type sparkPostClient struct {
 	spClient *sp.Client
 }
 
-func (c *sparkPostClient) Send(sendTo []string, content string) {
+func (c *sparkPostClient) Send(sendTo []string, content string) error {
 	f := false
 	_, _, e := c.spClient.Send(&sp.Transmission{
 		Options: &sp.TxOptions{
@@ -54,5 +55,5 @@ func (c *sparkPostClient) Send(sendTo []string, content string) {
 			Subject: "project-trees.com registration",
 		},
 	})
-	panic.IfNotNil(e)
+	return e
 }
@@ -0,0 +1,122 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/0xor1/isql"
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/id"
+	"time"
+)
+
+const maxLastErrorLen = 1250
+
+// Outbox delivers emails that have been written to the emailOutbox table, an email is retried with exponential
+// back-off when the mail client returns an error and is dead-lettered once it has failed maxAttempts times.
+type Outbox struct {
+	db           isql.ReplicaSet
+	client       Client
+	batchSize    int
+	maxAttempts  int
+	pollPeriod   time.Duration
+	retryBackoff time.Duration
+	logError     func(error)
+}
+
+func NewOutbox(db isql.ReplicaSet, client Client, batchSize, maxAttempts int, pollPeriod, retryBackoff time.Duration, logError func(error)) *Outbox {
+	panic.If(db == nil, "outbox db must not be nil")
+	panic.If(client == nil, "outbox mail client must not be nil")
+	panic.If(batchSize < 1, "outbox batchSize must be >= 1")
+	panic.If(maxAttempts < 1, "outbox maxAttempts must be >= 1")
+	return &Outbox{
+		db:           db,
+		client:       client,
+		batchSize:    batchSize,
+		maxAttempts:  maxAttempts,
+		pollPeriod:   pollPeriod,
+		retryBackoff: retryBackoff,
+		logError:     logError,
+	}
+}
+
+// Start runs the delivery loop in a background go routine for the lifetime of the process.
+func (o *Outbox) Start() {
+	go func() {
+		for {
+			o.safeProcess()
+			time.Sleep(o.pollPeriod)
+		}
+	}()
+}
+
+func (o *Outbox) safeProcess() {
+	defer func() {
+		if r := recover(); r != nil {
+			o.logError(fmt.Errorf("email outbox: %v", r))
+		}
+	}()
+	// keep going while full batches are being returned so a backlog is cleared without waiting on the poll period
+	for o.Process() == o.batchSize {
+	}
+}
+
+// Process attempts delivery of one batch of due emails and returns the number of emails it picked up.
+func (o *Outbox) Process() int {
+	now := time.Now().UTC()
+	rows, e := o.db.Primary().QueryContext(context.TODO(), `SELECT id, sendTo, content, attemptCount, nextAttemptOn FROM emailOutbox WHERE isDeadLettered=FALSE AND nextAttemptOn<=? ORDER BY nextAttemptOn ASC LIMIT ?`, now, o.batchSize)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	batch := make([]*outboxEmail, 0, o.batchSize)
+	for rows.Next() {
+		oe := outboxEmail{}
+		panic.IfNotNil(rows.Scan(&oe.id, &oe.sendTo, &oe.content, &oe.attemptCount, &oe.nextAttemptOn))
+		batch = append(batch, &oe)
+	}
+	for _, oe := range batch {
+		o.deliver(oe, now)
+	}
+	return len(batch)
+}
+
+func (o *Outbox) deliver(oe *outboxEmail, now time.Time) {
+	// claim the email by pushing its nextAttemptOn into the future, if another worker got here first no rows are affected
+	res, e := o.db.Primary().ExecContext(context.TODO(), `UPDATE emailOutbox SET nextAttemptOn=? WHERE id=? AND nextAttemptOn=? AND isDeadLettered=FALSE`, now.Add(o.retryBackoff), oe.id, oe.nextAttemptOn)
+	panic.IfNotNil(e)
+	claimed, e := res.RowsAffected()
+	panic.IfNotNil(e)
+	if claimed != 1 {
+		return
+	}
+	sendTo := make([]string, 0, 1)
+	e = json.Unmarshal([]byte(oe.sendTo), &sendTo)
+	if e == nil {
+		e = o.client.Send(sendTo, oe.content)
+	}
+	if e == nil {
+		_, e = o.db.Primary().ExecContext(context.TODO(), `DELETE FROM emailOutbox WHERE id=?`, oe.id)
+		panic.IfNotNil(e)
+		return
+	}
+	lastError := e.Error()
+	if len(lastError) > maxLastErrorLen {
+		lastError = lastError[:maxLastErrorLen]
+	}
+	attemptCount := oe.attemptCount + 1
+	if attemptCount >= o.maxAttempts {
+		_, e = o.db.Primary().ExecContext(context.TODO(), `UPDATE emailOutbox SET attemptCount=?, lastError=?, isDeadLettered=TRUE WHERE id=?`, attemptCount, lastError, oe.id)
+	} else {
+		_, e = o.db.Primary().ExecContext(context.TODO(), `UPDATE emailOutbox SET attemptCount=?, lastError=?, nextAttemptOn=? WHERE id=?`, attemptCount, lastError, now.Add(o.retryBackoff*time.Duration(1<<uint(attemptCount-1))), oe.id)
+	}
+	panic.IfNotNil(e)
+}
+
+type outboxEmail struct {
+	id            id.Id
+	sendTo        string
+	content       string
+	attemptCount  int
+	nextAttemptOn time.Time
+}
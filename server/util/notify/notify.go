@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/0xor1/isql"
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/mail"
+	"html"
+	"time"
+)
+
+const (
+	digestPeriod             = 24 * time.Hour
+	maxNotificationsPerEmail = 100
+)
+
+// Notifier turns the project activities under watched tasks into notifications for the watching members and emails them
+// to each member either as soon as possible or as a daily digest, depending on the members notification delivery.
+type Notifier struct {
+	shards       map[int]isql.ReplicaSet
+	client       mail.Client
+	batchSize    int
+	pollPeriod   time.Duration
+	settlePeriod time.Duration
+	retryBackoff time.Duration
+	logError     func(error)
+}
+
+// NewNotifier returns a notifier for shards, settlePeriod is how old an activity must be before it is notified, which
+// gives transactions that were writing activities when a poll started time to commit before the cursor passes them.
+func NewNotifier(shards map[int]isql.ReplicaSet, client mail.Client, batchSize int, pollPeriod, settlePeriod, retryBackoff time.Duration, logError func(error)) *Notifier {
+	panic.If(len(shards) == 0, "notifier shards must not be empty")
+	panic.If(client == nil, "notifier mail client must not be nil")
+	panic.If(batchSize < 1, "notifier batchSize must be >= 1")
+	return &Notifier{
+		shards:       shards,
+		client:       client,
+		batchSize:    batchSize,
+		pollPeriod:   pollPeriod,
+		settlePeriod: settlePeriod,
+		retryBackoff: retryBackoff,
+		logError:     logError,
+	}
+}
+
+// Start runs the notification loop in a background go routine for the lifetime of the process.
+func (n *Notifier) Start() {
+	go func() {
+		for {
+			for shard := range n.shards {
+				n.safeProcess(shard)
+			}
+			time.Sleep(n.pollPeriod)
+		}
+	}()
+}
+
+func (n *Notifier) safeProcess(shard int) {
+	defer func() {
+		if r := recover(); r != nil {
+			n.logError(fmt.Errorf("notifier shard %d: %v", shard, r))
+		}
+	}()
+	// keep going while full batches are being returned so a backlog is cleared without waiting on the poll period
+	for n.Process(shard, time.Now().UTC().Add(-n.settlePeriod)) == n.batchSize {
+	}
+}
+
+// Process generates the notifications for every activity on shard up to upTo and then emails one batch of members
+// whose notifications are due, it returns the number of members it picked up.
+func (n *Notifier) Process(shard int, upTo time.Time) int {
+	db := n.shards[shard]
+	panic.If(db == nil, "no such shard %d", shard)
+	n.generate(db, upTo)
+	now := time.Now().UTC()
+	rows, e := db.Primary().QueryContext(context.TODO(), `SELECT am.account, am.id, am.email, am.notificationDelivery, am.nextNotificationOn FROM (SELECT DISTINCT account, member FROM notifications WHERE emailPending=TRUE) n INNER JOIN accountMembers am ON am.account=n.account AND am.id=n.member WHERE am.isActive=TRUE AND am.email IS NOT NULL AND am.notificationDelivery<>? AND (am.nextNotificationOn IS NULL OR am.nextNotificationOn<=?) LIMIT ?`, cnst.NotificationDeliveryNone, now, n.batchSize)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	batch := make([]*recipient, 0, n.batchSize)
+	for rows.Next() {
+		r := recipient{}
+		panic.IfNotNil(rows.Scan(&r.account, &r.member, &r.email, &r.notificationDelivery, &r.nextNotificationOn))
+		batch = append(batch, &r)
+	}
+	for _, r := range batch {
+		n.deliver(db, r, now)
+	}
+	return len(batch)
+}
+
+func (n *Notifier) generate(db isql.ReplicaSet, upTo time.Time) {
+	rows, e := db.Primary().QueryContext(context.TODO(), `SELECT c.account, c.project FROM notificationCursors c WHERE EXISTS(SELECT 1 FROM projectActivities a WHERE a.account=c.account AND a.project=c.project AND a.occurredOn>c.lastOccurredOn AND a.occurredOn<=?)`, upTo)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	projects := make([][2]id.Id, 0, 100)
+	for rows.Next() {
+		var account, project id.Id
+		panic.IfNotNil(rows.Scan(&account, &project))
+		projects = append(projects, [2]id.Id{account, project})
+	}
+	for _, p := range projects {
+		_, e := db.Primary().ExecContext(context.TODO(), `CALL generateNotifications(?, ?, ?)`, p[0], p[1], upTo)
+		panic.IfNotNil(e)
+	}
+}
+
+func (n *Notifier) deliver(db isql.ReplicaSet, r *recipient, now time.Time) {
+	// claim the member by pushing nextNotificationOn into the future, if another worker got here first no rows are affected
+	res, e := db.Primary().ExecContext(context.TODO(), `UPDATE accountMembers SET nextNotificationOn=? WHERE account=? AND id=? AND nextNotificationOn<=>?`, now.Add(n.retryBackoff), r.account, r.member, r.nextNotificationOn)
+	panic.IfNotNil(e)
+	claimed, e := res.RowsAffected()
+	panic.IfNotNil(e)
+	if claimed != 1 {
+		return
+	}
+	rows, e := db.Primary().QueryContext(context.TODO(), `SELECT n.occurredOn, COALESCE(am.displayName, am.name, ''), n.itemType, n.action, n.itemName, COALESCE(t.name, ''), COALESCE(p.name, '') FROM notifications n LEFT JOIN accountMembers am ON am.account=n.account AND am.id=n.actor LEFT JOIN tasks t ON t.account=n.account AND t.project=n.project AND t.id=n.task LEFT JOIN projects p ON p.account=n.account AND p.id=n.project WHERE n.account=? AND n.member=? AND n.emailPending=TRUE ORDER BY n.occurredOn ASC LIMIT ?`, r.account, r.member, maxNotificationsPerEmail)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	content := bytes.NewBufferString(``)
+	var lastOccurredOn time.Time
+	for rows.Next() {
+		var actor, itemType, action, taskName, projectName string
+		var itemName *string
+		panic.IfNotNil(rows.Scan(&lastOccurredOn, &actor, &itemType, &action, &itemName, &taskName, &projectName))
+		line := fmt.Sprintf("%s %s %s", actor, action, itemType)
+		if itemName != nil && *itemName != "" {
+			line += " " + *itemName
+		}
+		if taskName != "" {
+			line += " on " + taskName
+		}
+		content.WriteString(fmt.Sprintf("<p>%s: %s <small>%s</small></p>", html.EscapeString(projectName), html.EscapeString(line), lastOccurredOn.Format(time.RFC1123)))
+	}
+	panic.IfNotNil(rows.Err())
+	if content.Len() == 0 {
+		return
+	}
+	if e := n.client.Send([]string{r.email}, content.String()); e != nil {
+		// leave the claim in place so the member is retried after the back-off
+		n.logError(fmt.Errorf("notifier: %s", e))
+		return
+	}
+	_, e = db.Primary().ExecContext(context.TODO(), `UPDATE notifications SET emailPending=FALSE WHERE account=? AND member=? AND emailPending=TRUE AND occurredOn<=?`, r.account, r.member, lastOccurredOn)
+	panic.IfNotNil(e)
+	var nextNotificationOn *time.Time
+	if r.notificationDelivery == cnst.NotificationDeliveryDailyDigest {
+		next := now.Add(digestPeriod)
+		nextNotificationOn = &next
+	}
+	_, e = db.Primary().ExecContext(context.TODO(), `UPDATE accountMembers SET nextNotificationOn=? WHERE account=? AND id=?`, nextNotificationOn, r.account, r.member)
+	panic.IfNotNil(e)
+}
+
+type recipient struct {
+	account              id.Id
+	member               id.Id
+	email                string
+	notificationDelivery cnst.NotificationDelivery
+	nextNotificationOn   *time.Time
+}
@@ -15,12 +15,28 @@ type V1Client interface {
 	SetMemberDisplayName(region cnst.Region, shard int, account, me id.Id, newDisplayName *string) error
 	SetMemberHasAvatar(region cnst.Region, shard int, account, me id.Id, hasAvatar bool) error
 	MemberIsAccountOwner(region cnst.Region, shard int, account, me id.Id) (bool, error)
+	SetMemberNotificationSettings(region cnst.Region, shard int, account, me id.Id, email string, notificationDelivery cnst.NotificationDelivery) error
+	VerifyTree(region cnst.Region, shard int, account, project *id.Id, repair bool) ([]*TreeDiscrepancy, error)
 }
 
 type AddMember struct {
-	Id          id.Id            `json:"id"`
-	Name        string           `json:"name"`
-	DisplayName *string          `json:"displayName"`
-	HasAvatar   bool             `json:"hasAvatar"`
-	Role        cnst.AccountRole `json:"role"`
+	Id                   id.Id                     `json:"id"`
+	Name                 string                    `json:"name"`
+	DisplayName          *string                   `json:"displayName"`
+	HasAvatar            bool                      `json:"hasAvatar"`
+	Role                 cnst.AccountRole          `json:"role"`
+	Email                string                    `json:"email"`
+	NotificationDelivery cnst.NotificationDelivery `json:"notificationDelivery"`
+}
+
+// a task whose stored value doesn't match the one recomputed from the rest of the tree, for siblingList discrepancies stored
+// is the number of tasks linked through firstChild/nextSibling and computed is the number of children, for unreachableChild
+// discrepancies task is the parent of a task that can't be reached from the project task.
+type TreeDiscrepancy struct {
+	Account  id.Id  `json:"account"`
+	Project  id.Id  `json:"project"`
+	Task     id.Id  `json:"task"`
+	Field    string `json:"field"`
+	Stored   uint64 `json:"stored"`
+	Computed uint64 `json:"computed"`
 }
@@ -0,0 +1,123 @@
+package recur
+
+import (
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/err"
+	"net/http"
+	"time"
+)
+
+// Rule is when a recurrence comes round, occurrences are whole UTC days.
+type Rule struct {
+	Frequency cnst.RecurrenceFrequency `json:"frequency"`
+	WeekDays  []time.Weekday           `json:"weekDays,omitempty"` //weekly rules only, 0 is sunday
+	MonthDay  uint8                    `json:"monthDay,omitempty"` //monthly rules only, days past the end of a month fall on its last day
+}
+
+// NewRule returns the rule stored as frequency, the weekDays bit mask and monthDay.
+func NewRule(frequency cnst.RecurrenceFrequency, weekDays, monthDay uint8) *Rule {
+	r := &Rule{
+		Frequency: frequency,
+	}
+	switch frequency {
+	case cnst.RecurrenceWeekly:
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if weekDays&(1<<uint(d)) != 0 {
+				r.WeekDays = append(r.WeekDays, d)
+			}
+		}
+	case cnst.RecurrenceMonthly:
+		r.MonthDay = monthDay
+	}
+	return r
+}
+
+func (r *Rule) Validate() {
+	r.Frequency.Validate()
+	switch r.Frequency {
+	case cnst.RecurrenceDaily:
+		err.HttpPanicf(len(r.WeekDays) > 0 || r.MonthDay != 0, http.StatusBadRequest, "daily rules do not accept weekDays or monthDay")
+	case cnst.RecurrenceWeekly:
+		err.HttpPanicf(len(r.WeekDays) == 0, http.StatusBadRequest, "weekly rules must have weekDays set")
+		err.HttpPanicf(r.MonthDay != 0, http.StatusBadRequest, "weekly rules do not accept monthDay")
+		for _, d := range r.WeekDays {
+			err.HttpPanicf(d < time.Sunday || d > time.Saturday, http.StatusBadRequest, "invalid weekDay must be >= 0 and <= 6")
+		}
+	case cnst.RecurrenceMonthly:
+		err.HttpPanicf(len(r.WeekDays) > 0, http.StatusBadRequest, "monthly rules do not accept weekDays")
+		err.HttpPanicf(r.MonthDay == 0 || r.MonthDay > 31, http.StatusBadRequest, "invalid monthDay must be > 0 and <= 31")
+	}
+}
+
+// WeekDaysMask returns the rules week days as a bit mask, bit n set means weekday n.
+func (r *Rule) WeekDaysMask() uint8 {
+	mask := uint8(0)
+	for _, d := range r.WeekDays {
+		mask |= 1 << uint(d)
+	}
+	return mask
+}
+
+// First returns the rules first occurrence on or after the day of from.
+func (r *Rule) First(from time.Time) time.Time {
+	return r.Next(day(from).AddDate(0, 0, -1))
+}
+
+// Next returns the rules first occurrence after the day of after.
+func (r *Rule) Next(after time.Time) time.Time {
+	date := day(after).AddDate(0, 0, 1)
+	switch r.Frequency {
+	case cnst.RecurrenceWeekly:
+		mask := r.WeekDaysMask()
+		for mask&(1<<uint(date.Weekday())) == 0 {
+			date = date.AddDate(0, 0, 1)
+		}
+	case cnst.RecurrenceMonthly:
+		for {
+			candidate := monthDay(date.Year(), date.Month(), r.MonthDay)
+			if !candidate.Before(date) {
+				return candidate
+			}
+			date = time.Date(date.Year(), date.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		}
+	}
+	return date
+}
+
+// Advance returns the first occurrence after occurrence whose run is after both runOn and now, so a run is never
+// repeated when two occurrences move on to the same working day and runs missed while the scheduler was down are skipped.
+func (r *Rule) Advance(occurrence, runOn, now time.Time, daysPerWeek uint8) (time.Time, time.Time) {
+	for {
+		occurrence = r.Next(occurrence)
+		nextRunOn := RunOn(occurrence, daysPerWeek)
+		if nextRunOn.After(runOn) && nextRunOn.After(now) {
+			return occurrence, nextRunOn
+		}
+	}
+}
+
+// RunOn returns the day a task is created for occurrence, the first working day on or after it where only the first
+// daysPerWeek days of each week, starting on Monday, are worked.
+func RunOn(occurrence time.Time, daysPerWeek uint8) time.Time {
+	if daysPerWeek == 0 || daysPerWeek > 7 {
+		daysPerWeek = 7
+	}
+	date := day(occurrence)
+	for (int(date.Weekday())+6)%7 >= int(daysPerWeek) {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func monthDay(year int, month time.Month, d uint8) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if int(d) > lastDay {
+		return time.Date(year, month, lastDay, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(year, month, int(d), 0, 0, 0, 0, time.UTC)
+}
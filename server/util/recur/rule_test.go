@@ -0,0 +1,64 @@
+package recur
+
+import (
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func Test_Rule_daily(t *testing.T) {
+	r := NewRule(cnst.RecurrenceDaily, 0, 0)
+	assert.Equal(t, date(2018, 3, 2), r.First(time.Date(2018, 3, 2, 15, 4, 5, 0, time.UTC)))
+	assert.Equal(t, date(2018, 3, 3), r.Next(date(2018, 3, 2)))
+}
+
+func Test_Rule_weekly(t *testing.T) {
+	r := &Rule{Frequency: cnst.RecurrenceWeekly, WeekDays: []time.Weekday{time.Monday, time.Thursday}}
+	assert.Equal(t, uint8(18), r.WeekDaysMask())
+	assert.Equal(t, r.WeekDays, NewRule(cnst.RecurrenceWeekly, r.WeekDaysMask(), 0).WeekDays)
+	assert.Equal(t, date(2018, 3, 5), r.First(date(2018, 3, 3))) //saturday -> monday
+	assert.Equal(t, date(2018, 3, 8), r.Next(date(2018, 3, 5)))
+	assert.Equal(t, date(2018, 3, 12), r.Next(date(2018, 3, 8)))
+}
+
+func Test_Rule_monthly(t *testing.T) {
+	r := NewRule(cnst.RecurrenceMonthly, 0, 31)
+	assert.Equal(t, date(2018, 1, 31), r.First(date(2018, 1, 31)))
+	assert.Equal(t, date(2018, 2, 28), r.Next(date(2018, 1, 31)))
+	assert.Equal(t, date(2018, 3, 31), r.Next(date(2018, 2, 28)))
+	r = NewRule(cnst.RecurrenceMonthly, 0, 15)
+	assert.Equal(t, date(2018, 4, 15), r.First(date(2018, 3, 16)))
+}
+
+func Test_RunOn(t *testing.T) {
+	assert.Equal(t, date(2018, 3, 5), RunOn(date(2018, 3, 3), 5)) //saturday -> monday
+	assert.Equal(t, date(2018, 3, 3), RunOn(date(2018, 3, 3), 6))
+	assert.Equal(t, date(2018, 3, 2), RunOn(date(2018, 3, 2), 5))
+}
+
+func Test_Rule_Advance(t *testing.T) {
+	r := NewRule(cnst.RecurrenceDaily, 0, 0)
+	//friday runs, then saturday and sunday both move on to monday which only runs once
+	occurrence, runOn := r.Advance(date(2018, 3, 2), date(2018, 3, 2), date(2018, 3, 2), 5)
+	assert.Equal(t, date(2018, 3, 3), occurrence)
+	assert.Equal(t, date(2018, 3, 5), runOn)
+	occurrence, runOn = r.Advance(occurrence, runOn, runOn, 5)
+	assert.Equal(t, date(2018, 3, 6), occurrence)
+	assert.Equal(t, date(2018, 3, 6), runOn)
+	//runs missed before now are skipped
+	occurrence, runOn = r.Advance(date(2018, 3, 6), date(2018, 3, 6), time.Date(2018, 3, 8, 12, 0, 0, 0, time.UTC), 5)
+	assert.Equal(t, date(2018, 3, 9), occurrence)
+	assert.Equal(t, date(2018, 3, 9), runOn)
+}
+
+func Test_Rule_Validate(t *testing.T) {
+	defer func() {
+		assert.NotNil(t, recover())
+	}()
+	(&Rule{Frequency: cnst.RecurrenceWeekly}).Validate()
+}
@@ -0,0 +1,141 @@
+package recur
+
+import (
+	"context"
+	"fmt"
+	"github.com/0xor1/iredis"
+	"github.com/0xor1/isql"
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/cachekey"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/id"
+	t "github.com/0xor1/trees/server/util/time"
+	"time"
+)
+
+// Scheduler creates the tasks for recurrences as they come due, a single task is created for a recurrence however many of
+// its runs have been missed.
+type Scheduler struct {
+	shards     map[int]isql.ReplicaSet
+	dlmPool    iredis.Pool
+	batchSize  int
+	pollPeriod time.Duration
+	logError   func(error)
+}
+
+// NewScheduler returns a scheduler for shards, dlmPool is used to invalidate the cached tasks it changes and should be nil
+// when caching is disabled.
+func NewScheduler(shards map[int]isql.ReplicaSet, dlmPool iredis.Pool, batchSize int, pollPeriod time.Duration, logError func(error)) *Scheduler {
+	panic.If(len(shards) == 0, "scheduler shards must not be empty")
+	panic.If(batchSize < 1, "scheduler batchSize must be >= 1")
+	return &Scheduler{
+		shards:     shards,
+		dlmPool:    dlmPool,
+		batchSize:  batchSize,
+		pollPeriod: pollPeriod,
+		logError:   logError,
+	}
+}
+
+// Start runs the scheduling loop in a background go routine for the lifetime of the process.
+func (s *Scheduler) Start() {
+	go func() {
+		for {
+			for shard := range s.shards {
+				s.safeProcess(shard)
+			}
+			time.Sleep(s.pollPeriod)
+		}
+	}()
+}
+
+func (s *Scheduler) safeProcess(shard int) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logError(fmt.Errorf("scheduler shard %d: %v", shard, r))
+		}
+	}()
+	// every run moves its recurrence on past now so full batches can be repeated until the shard is caught up
+	for s.Process(shard, t.Now()) == s.batchSize {
+	}
+}
+
+// Process runs one batch of the recurrences on shard that are due at now, it returns the number of recurrences it picked up.
+func (s *Scheduler) Process(shard int, now time.Time) int {
+	db := s.shards[shard]
+	panic.If(db == nil, "no such shard %d", shard)
+	rows, e := db.Primary().QueryContext(context.TODO(), `SELECT r.account, r.project, r.id, r.frequency, r.weekDays, r.monthDay, r.nextOccurrenceOn, r.nextRunOn, p.daysPerWeek FROM recurrences r INNER JOIN projects p ON p.account=r.account AND p.id=r.project WHERE r.isActive=TRUE AND r.nextRunOn<=? ORDER BY r.nextRunOn ASC LIMIT ?`, now, s.batchSize)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	batch := make([]*dueRecurrence, 0, s.batchSize)
+	for rows.Next() {
+		r := dueRecurrence{}
+		var weekDays, monthDay uint8
+		panic.IfNotNil(rows.Scan(&r.account, &r.project, &r.id, &r.frequency, &weekDays, &monthDay, &r.occurrence, &r.runOn, &r.daysPerWeek))
+		r.rule = NewRule(r.frequency, weekDays, monthDay)
+		batch = append(batch, &r)
+	}
+	panic.IfNotNil(rows.Err())
+	rows.Close()
+	for _, r := range batch {
+		s.run(db, r, now)
+	}
+	return len(batch)
+}
+
+func (s *Scheduler) run(db isql.ReplicaSet, r *dueRecurrence, now time.Time) {
+	nextOccurrence, nextRunOn := r.rule.Advance(r.occurrence, r.runOn, now, r.daysPerWeek)
+	rows, e := db.Primary().QueryContext(context.TODO(), `CALL runRecurrence(?, ?, ?, ?, ?, ?, ?, ?)`, r.account, r.project, r.id, id.New(), r.runOn, nextOccurrence, nextRunOn, now)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	tasks := make([]id.Id, 0, 10)
+	cacheKey := cachekey.NewSetDlms()
+	for rows.Next() {
+		var i id.Id
+		key := ""
+		panic.IfNotNil(rows.Scan(&i, &key))
+		switch key {
+		case "t":
+			tasks = append(tasks, i)
+		case "m":
+			cacheKey.ProjectMember(r.account, r.project, i)
+		default:
+			panic.If(true, "unknown key value in run recurrence rows")
+		}
+	}
+	panic.IfNotNil(rows.Err())
+	if len(tasks) > 0 {
+		s.touchDlms(cacheKey.ProjectActivities(r.account, r.project).CombinedTaskAndTaskChildrenSets(r.account, r.project, tasks))
+	}
+}
+
+func (s *Scheduler) touchDlms(cacheKey *cachekey.Key) {
+	if s.dlmPool == nil || len(cacheKey.DlmKeys) == 0 {
+		return
+	}
+	setArgs := make([]interface{}, 0, len(cacheKey.DlmKeys)*2)
+	now := t.NowUnixMillis()
+	for key := range cacheKey.DlmKeys {
+		setArgs = append(setArgs, key, now)
+	}
+	cnn := s.dlmPool.Get()
+	defer cnn.Close()
+	if _, e := cnn.Do("MSET", setArgs...); e != nil {
+		s.logError(fmt.Errorf("scheduler: %s", e))
+	}
+}
+
+type dueRecurrence struct {
+	account     id.Id
+	project     id.Id
+	id          id.Id
+	frequency   cnst.RecurrenceFrequency
+	rule        *Rule
+	occurrence  time.Time
+	runOn       time.Time
+	daysPerWeek uint8
+}
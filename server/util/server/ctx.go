@@ -7,6 +7,7 @@ import (
 	"github.com/0xor1/trees/server/util/avatar"
 	"github.com/0xor1/trees/server/util/cachekey"
 	"github.com/0xor1/trees/server/util/err"
+	"github.com/0xor1/trees/server/util/filestore"
 	"github.com/0xor1/trees/server/util/id"
 	"github.com/0xor1/trees/server/util/mail"
 	"github.com/0xor1/trees/server/util/private"
@@ -230,6 +231,10 @@ func (c *_ctx) MaxProcessEntityCount() int {
 	return c.SR.MaxProcessEntityCount
 }
 
+func (c *_ctx) TrashRetentionDays() int {
+	return c.SR.TrashRetentionDays
+}
+
 func (c *_ctx) CryptCodeLen() int {
 	return c.SR.CryptCodeLen
 }
@@ -266,6 +271,10 @@ func (c *_ctx) AvatarClient() avatar.Client {
 	return c.SR.AvatarClient
 }
 
+func (c *_ctx) FileClient() filestore.Client {
+	return c.SR.FileClient
+}
+
 // helpers
 
 func (c *_ctx) useCache() bool {
@@ -10,18 +10,23 @@ import (
 	"github.com/0xor1/panic"
 	"github.com/0xor1/trees/server/util/avatar"
 	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/filestore"
 	"github.com/0xor1/trees/server/util/id"
 	"github.com/0xor1/trees/server/util/mail"
+	"github.com/0xor1/trees/server/util/notify"
 	"github.com/0xor1/trees/server/util/private"
 	"github.com/0xor1/trees/server/util/queryinfo"
+	"github.com/0xor1/trees/server/util/recur"
 	"github.com/0xor1/trees/server/util/redis"
 	t "github.com/0xor1/trees/server/util/time"
+	"github.com/0xor1/trees/server/util/trash"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/gorilla/sessions"
 	"regexp"
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // pass in empty strings for no config file
@@ -108,8 +113,40 @@ func Config(configFile string, newPrivateV1Client func(env cnst.Env, scheme, nak
 	config.SetDefault("maxAvatarDim", 250)
 	// local avatar storage directory, relative
 	config.SetDefault("lclAvatarDir", "avatar")
+	// max size in bytes of a single task file attachment
+	config.SetDefault("maxFileSize", 10000000)
+	// max total size in bytes of all task file attachments in an account
+	config.SetDefault("accountFileQuota", 1000000000)
+	// local task file attachment storage directory, relative
+	config.SetDefault("lclFileDir", "files")
+	// number of days deleted tasks are kept in a projects trash before they are purged
+	config.SetDefault("trashRetentionDays", 30)
+	// number of projects the trash purger purges per batch
+	config.SetDefault("trashPurgerBatchSize", 50)
+	// millisecs the trash purger waits between polls for expired trash
+	config.SetDefault("trashPurgerPollPeriodMillis", 3600000)
 	// api key for spark post client
 	config.SetDefault("sparkPostApiKey", "")
+	// number of emails the outbox worker picks up per batch
+	config.SetDefault("emailOutboxBatchSize", 50)
+	// number of failed delivery attempts after which an email is dead-lettered
+	config.SetDefault("emailOutboxMaxAttempts", 8)
+	// millisecs the outbox worker waits between polls for due emails
+	config.SetDefault("emailOutboxPollPeriodMillis", 5000)
+	// millisecs before the first retry of a failed email, doubled on each subsequent failure
+	config.SetDefault("emailOutboxRetryBackoffMillis", 30000)
+	// number of members the notifier emails per batch
+	config.SetDefault("notifierBatchSize", 50)
+	// millisecs the notifier waits between polls for new activities and due notifications
+	config.SetDefault("notifierPollPeriodMillis", 10000)
+	// millisecs old an activity must be before the notifier turns it into notifications
+	config.SetDefault("notifierSettlePeriodMillis", 5000)
+	// millisecs before a member whose notification email failed is retried
+	config.SetDefault("notifierRetryBackoffMillis", 300000)
+	// number of due recurrences the scheduler runs per batch
+	config.SetDefault("schedulerBatchSize", 50)
+	// millisecs the scheduler waits between polls for due recurrences
+	config.SetDefault("schedulerPollPeriodMillis", 60000)
 	// account primary sql connection
 	config.SetDefault("accountDbPrimary", "t_c_accounts:T@sk-@cc-0unt5@tcp(localhost:3306)/accounts?parseTime=true&loc=UTC&multiStatements=true")
 	// account slave sql connections
@@ -176,6 +213,7 @@ func Config(configFile string, newPrivateV1Client func(env cnst.Env, scheme, nak
 	var logError func(error)
 	var logStats func(status int, path string, reqStartUnixMillis int64, queryInfos []*queryinfo.QueryInfo)
 	var avatarClient avatar.Client
+	var fileClient filestore.Client
 	var mailClient mail.Client
 
 	if env == cnst.LclEnv {
@@ -190,6 +228,7 @@ func Config(configFile string, newPrivateV1Client func(env cnst.Env, scheme, nak
 			//fmt.Println(string(queryInfosBytes))
 		}
 		avatarClient = avatar.NewLocalClient(config.GetString("lclAvatarDir"), uint(config.GetInt("maxAvatarDim")))
+		fileClient = filestore.NewLocalClient(config.GetString("lclFileDir"), config.GetInt64("maxFileSize"), config.GetInt64("accountFileQuota"))
 		mailClient = mail.NewLocalClient()
 	} else {
 		//setup aws environment interfaces
@@ -203,8 +242,9 @@ func Config(configFile string, newPrivateV1Client func(env cnst.Env, scheme, nak
 			//fmt.Println(string(queryInfosBytes))
 		}
 		avatarClient = avatar.NewLocalClient(config.GetString("lclAvatarDir"), uint(config.GetInt("maxAvatarDim")))
+		fileClient = filestore.NewLocalClient(config.GetString("lclFileDir"), config.GetInt64("maxFileSize"), config.GetInt64("accountFileQuota"))
 		mailClient = mail.NewSparkPostClient("noreply@"+clientHost, config.GetString("sparkPostApiKey"))
-		//TODO setup aws s3 avatarStore storage
+		//TODO setup aws s3 avatarStore and fileStore storage
 		//TODO setup datadog stats and error logging
 	}
 
@@ -227,6 +267,11 @@ func Config(configFile string, newPrivateV1Client func(env cnst.Env, scheme, nak
 		accountDb = isql.MustNewReplicaSet("mysql", config.GetString("accountDbPrimary"), config.GetStringSlice("accountDbSlaves")...)
 	}
 
+	var emailOutbox *mail.Outbox
+	if accountDb != nil {
+		emailOutbox = mail.NewOutbox(accountDb, mailClient, config.GetInt("emailOutboxBatchSize"), config.GetInt("emailOutboxMaxAttempts"), time.Duration(config.GetInt("emailOutboxPollPeriodMillis"))*time.Millisecond, time.Duration(config.GetInt("emailOutboxRetryBackoffMillis"))*time.Millisecond, logError)
+	}
+
 	var pwdDb isql.ReplicaSet
 	if config.GetString("pwdDbPrimary") != "" {
 		pwdDb = isql.MustNewReplicaSet("mysql", config.GetString("pwdDbPrimary"), config.GetStringSlice("pwdDbSlaves")...)
@@ -243,9 +288,32 @@ func Config(configFile string, newPrivateV1Client func(env cnst.Env, scheme, nak
 		}
 	}
 
+	var notifier *notify.Notifier
+	if len(treeShardDbs) > 0 {
+		notifier = notify.NewNotifier(treeShardDbs, mailClient, config.GetInt("notifierBatchSize"), time.Duration(config.GetInt("notifierPollPeriodMillis"))*time.Millisecond, time.Duration(config.GetInt("notifierSettlePeriodMillis"))*time.Millisecond, time.Duration(config.GetInt("notifierRetryBackoffMillis"))*time.Millisecond, logError)
+	}
+
 	dlmAndDataRedisPool := redis.CreatePool(config.GetString("dlmAndDataRedisPool"))
 	privateKeyRedisPool := redis.CreatePool(config.GetString("privateKeyRedisPool"))
 
+	var scheduler *recur.Scheduler
+	if len(treeShardDbs) > 0 {
+		var dlmPool iredis.Pool
+		if config.GetBool("cachingEnabled") {
+			dlmPool = dlmAndDataRedisPool
+		}
+		scheduler = recur.NewScheduler(treeShardDbs, dlmPool, config.GetInt("schedulerBatchSize"), time.Duration(config.GetInt("schedulerPollPeriodMillis"))*time.Millisecond, logError)
+	}
+
+	var trashPurger *trash.Purger
+	if len(treeShardDbs) > 0 {
+		var dlmPool iredis.Pool
+		if config.GetBool("cachingEnabled") {
+			dlmPool = dlmAndDataRedisPool
+		}
+		trashPurger = trash.NewPurger(treeShardDbs, dlmPool, fileClient, config.GetInt("trashRetentionDays"), config.GetInt("trashPurgerBatchSize"), time.Duration(config.GetInt("trashPurgerPollPeriodMillis"))*time.Millisecond, logError)
+	}
+
 	regionalV1PrivateClientSecret, e := base64.RawURLEncoding.DecodeString(config.GetString("regionalV1PrivateClientSecret"))
 	panic.IfNotNil(e)
 
@@ -282,6 +350,7 @@ func Config(configFile string, newPrivateV1Client func(env cnst.Env, scheme, nak
 		PwdMinRuneCount:               config.GetInt("pwdMinRuneCount"),
 		PwdMaxRuneCount:               config.GetInt("pwdMaxRuneCount"),
 		MaxProcessEntityCount:         config.GetInt("maxProcessEntityCount"),
+		TrashRetentionDays:            config.GetInt("trashRetentionDays"),
 		CryptCodeLen:                  config.GetInt("cryptCodeLen"),
 		SaltLen:                       config.GetInt("saltLen"),
 		ScryptN:                       config.GetInt("scryptN"),
@@ -291,7 +360,12 @@ func Config(configFile string, newPrivateV1Client func(env cnst.Env, scheme, nak
 		RegionalV1PrivateClientSecret: regionalV1PrivateClientSecret,
 		RegionalV1PrivateClient:       regionalV1PrivateClient,
 		MailClient:                    mailClient,
+		EmailOutbox:                   emailOutbox,
+		Notifier:                      notifier,
+		Scheduler:                     scheduler,
+		TrashPurger:                   trashPurger,
 		AvatarClient:                  avatarClient,
+		FileClient:                    fileClient,
 		LogError:                      logError,
 		LogStats:                      logStats,
 		AccountDb:                     accountDb,
@@ -363,6 +437,8 @@ type Resources struct {
 	PwdMaxRuneCount int
 	// max number of entities that can be processed at once, also used for max limit value on queries
 	MaxProcessEntityCount int
+	// number of days deleted tasks are kept in a projects trash before they are purged
+	TrashRetentionDays int
 	// length of cryptographic codes, used in email links for validating email addresses and resetting pwds
 	CryptCodeLen int
 	// length of salts used for pwd hashing
@@ -381,8 +457,18 @@ type Resources struct {
 	RegionalV1PrivateClient private.V1Client
 	// mail client for sending emails
 	MailClient mail.Client
+	// email outbox worker for delivering queued emails, only initialised where the account db is
+	EmailOutbox *mail.Outbox
+	// notifier worker for turning activities on watched tasks into emailed notifications, only initialised where the tree shards are
+	Notifier *notify.Notifier
+	// scheduler worker for creating the tasks of due recurrences, only initialised where the tree shards are
+	Scheduler *recur.Scheduler
+	// trash purger worker for permanently deleting expired project trash, only initialised where the tree shards are
+	TrashPurger *trash.Purger
 	// avatar client for storing avatar images
 	AvatarClient avatar.Client
+	// file client for storing task file attachments
+	FileClient filestore.Client
 	// error logging function
 	LogError func(error)
 	// stats logging function
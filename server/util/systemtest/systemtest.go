@@ -99,6 +99,7 @@ func tearDown(b *Base) {
 	b.CentralClient.DeleteAccount(b.Cat.CSS, b.Cat.Info.Me.Id)
 	b.CentralClient.DeleteAccount(b.Dan.CSS, b.Dan.Info.Me.Id)
 	b.SR.AvatarClient.DeleteAll()
+	b.SR.FileClient.DeleteAll()
 	cnn := b.SR.DlmAndDataRedisPool.Get()
 	defer cnn.Close()
 	cnn.Do("FLUSHALL")
@@ -15,4 +15,7 @@ type TimeLog struct {
 	TaskName           string    `json:"taskName"`
 	Duration           uint64    `json:"duration"`
 	Note               *string   `json:"note"`
+	IsBillable         bool      `json:"isBillable"`
+	HourlyRate         uint64    `json:"hourlyRate"` //the members rate when the time was logged
+	Cost               uint64    `json:"cost"`       //duration * hourlyRate / 60 rounded to the nearest unit
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/0xor1/trees/server/api/v1/private"
+	"github.com/0xor1/trees/server/util/cnst"
+	"github.com/0xor1/trees/server/util/id"
+	"github.com/0xor1/trees/server/util/static"
+	"os"
+)
+
+func main() {
+	fs := flag.NewFlagSet("verifytree", flag.ExitOnError)
+	var configFile string
+	fs.StringVar(&configFile, "c", "config.json", "the config file of the regional api box to verify")
+	var regionStr string
+	fs.StringVar(&regionStr, "region", "", "region to verify, defaults to the configs region")
+	var shardTmp uint
+	fs.UintVar(&shardTmp, "s", 0, "shard to verify")
+	var accountStr string
+	fs.StringVar(&accountStr, "a", "", "only verify the projects in this account")
+	var projectStr string
+	fs.StringVar(&projectStr, "p", "", "only verify this project, requires -a")
+	var repair bool
+	fs.BoolVar(&repair, "r", false, "save the recomputed values for any discrepancies found")
+	fs.Parse(os.Args[1:])
+
+	SR := static.Config(configFile, private.NewClient)
+	region := SR.Region
+	if regionStr != "" {
+		region = cnst.Region(regionStr)
+		region.ValidateForDataRegions()
+	}
+	var account, project *id.Id
+	if accountStr != "" {
+		a := id.Parse(accountStr)
+		account = &a
+	}
+	if projectStr != "" {
+		p := id.Parse(projectStr)
+		project = &p
+	}
+
+	discrepancies, e := SR.RegionalV1PrivateClient.VerifyTree(region, int(shardTmp), account, project, repair)
+	if e != nil {
+		fmt.Println(e)
+		os.Exit(1)
+	}
+	for _, d := range discrepancies {
+		fmt.Println(fmt.Sprintf("account: %s project: %s task: %s %s stored: %d computed: %d", d.Account, d.Project, d.Task, d.Field, d.Stored, d.Computed))
+	}
+	if repair {
+		fmt.Println(fmt.Sprintf("%d discrepancies found and repaired, sibling lists are only reported", len(discrepancies)))
+	} else {
+		fmt.Println(fmt.Sprintf("%d discrepancies found", len(discrepancies)))
+	}
+}
@@ -0,0 +1,139 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"github.com/0xor1/iredis"
+	"github.com/0xor1/isql"
+	"github.com/0xor1/panic"
+	"github.com/0xor1/trees/server/util/cachekey"
+	"github.com/0xor1/trees/server/util/filestore"
+	"github.com/0xor1/trees/server/util/id"
+	t "github.com/0xor1/trees/server/util/time"
+	"time"
+)
+
+// Purger permanently deletes the trash items that have been in their projects trash for longer than the retention
+// period, along with the stored data of their files.
+type Purger struct {
+	shards        map[int]isql.ReplicaSet
+	dlmPool       iredis.Pool
+	fileClient    filestore.Client
+	retentionDays int
+	batchSize     int
+	pollPeriod    time.Duration
+	logError      func(error)
+}
+
+// NewPurger returns a purger for shards, dlmPool is used to invalidate the cached trash and files it deletes and should be
+// nil when caching is disabled.
+func NewPurger(shards map[int]isql.ReplicaSet, dlmPool iredis.Pool, fileClient filestore.Client, retentionDays, batchSize int, pollPeriod time.Duration, logError func(error)) *Purger {
+	panic.If(len(shards) == 0, "purger shards must not be empty")
+	panic.If(fileClient == nil, "purger file client must not be nil")
+	panic.If(retentionDays < 0, "purger retentionDays must be >= 0")
+	panic.If(batchSize < 1, "purger batchSize must be >= 1")
+	return &Purger{
+		shards:        shards,
+		dlmPool:       dlmPool,
+		fileClient:    fileClient,
+		retentionDays: retentionDays,
+		batchSize:     batchSize,
+		pollPeriod:    pollPeriod,
+		logError:      logError,
+	}
+}
+
+// Start runs the purging loop in a background go routine for the lifetime of the process.
+func (p *Purger) Start() {
+	go func() {
+		for {
+			for shard := range p.shards {
+				p.safeProcess(shard)
+			}
+			time.Sleep(p.pollPeriod)
+		}
+	}()
+}
+
+func (p *Purger) safeProcess(shard int) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.logError(fmt.Errorf("purger shard %d: %v", shard, r))
+		}
+	}()
+	// a purged project has no expired trash left so full batches can be repeated until the shard is caught up
+	for p.Process(shard, t.Now()) == p.batchSize {
+	}
+}
+
+// Process purges the expired trash of one batch of projects on shard as it is at now, it returns the number of projects
+// it picked up.
+func (p *Purger) Process(shard int, now time.Time) int {
+	db := p.shards[shard]
+	panic.If(db == nil, "no such shard %d", shard)
+	before := now.AddDate(0, 0, -p.retentionDays)
+	rows, e := db.Primary().QueryContext(context.TODO(), `SELECT DISTINCT account, project FROM trash WHERE deletedOn<? LIMIT ?`, before, p.batchSize)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	projects := make([][2]id.Id, 0, p.batchSize)
+	for rows.Next() {
+		var account, project id.Id
+		panic.IfNotNil(rows.Scan(&account, &project))
+		projects = append(projects, [2]id.Id{account, project})
+	}
+	panic.IfNotNil(rows.Err())
+	rows.Close()
+	for _, proj := range projects {
+		p.purge(db, proj[0], proj[1], before)
+	}
+	return len(projects)
+}
+
+func (p *Purger) purge(db isql.ReplicaSet, account, project id.Id, before time.Time) {
+	rows, e := db.Primary().QueryContext(context.TODO(), `CALL purgeTrash(?, ?, ?)`, account, project, before)
+	if rows != nil {
+		defer rows.Close()
+	}
+	panic.IfNotNil(e)
+	purgedTasks := make([]id.Id, 0, 100)
+	purgedFiles := make([]id.Id, 0, 100)
+	cacheKey := cachekey.NewSetDlms().ProjectTrashSet(account, project)
+	for rows.Next() {
+		var i id.Id
+		var j id.Id
+		key := ""
+		panic.IfNotNil(rows.Scan(&i, &j, &key))
+		switch key {
+		case "t":
+			purgedTasks = append(purgedTasks, i)
+		case "f":
+			purgedFiles = append(purgedFiles, i)
+			cacheKey.File(account, project, i, &j).Project(account, project)
+		default:
+			panic.If(true, "unknown key value in purge trash rows")
+		}
+	}
+	panic.IfNotNil(rows.Err())
+	for _, file := range purgedFiles {
+		p.fileClient.Delete(filestore.Key(account, project, file))
+	}
+	p.touchDlms(cacheKey.CombinedTaskAndTaskChildrenSets(account, project, purgedTasks))
+}
+
+func (p *Purger) touchDlms(cacheKey *cachekey.Key) {
+	if p.dlmPool == nil || len(cacheKey.DlmKeys) == 0 {
+		return
+	}
+	setArgs := make([]interface{}, 0, len(cacheKey.DlmKeys)*2)
+	now := t.NowUnixMillis()
+	for key := range cacheKey.DlmKeys {
+		setArgs = append(setArgs, key, now)
+	}
+	cnn := p.dlmPool.Get()
+	defer cnn.Close()
+	if _, e := cnn.Do("MSET", setArgs...); e != nil {
+		p.logError(fmt.Errorf("purger: %s", e))
+	}
+}